@@ -17,6 +17,7 @@ func main() {
 		os.Exit(core.RunCLI())
 	} else {
 		// Spawn the GUI
+		core.LoadExternalPlugins()
 		dg, err := gui.NewDeenGUI()
 		if err != nil {
 			log.Fatal(err)
@@ -0,0 +1,161 @@
+package deen
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+)
+
+// Step is one transform of a chain.
+type Step struct {
+	Plugin   string            // plugin name or alias
+	Decode   bool              // run the decode (unprocess) direction
+	Options  map[string]string // option name -> value, as on the command line
+	Disabled bool              // pass the input through unchanged
+}
+
+// Transform runs a single step over input.
+func Transform(ctx context.Context, step Step, input []byte) ([]byte, error) {
+	c, err := NewChain(step)
+	if err != nil {
+		return nil, err
+	}
+	return c.Run(ctx, input)
+}
+
+// TransformStream runs a single step from r to w.
+func TransformStream(ctx context.Context, step Step, r io.Reader, w io.Writer) error {
+	c, err := NewChain(step)
+	if err != nil {
+		return err
+	}
+	return c.Stream(ctx, r, w)
+}
+
+// Chain is an ordered list of transforms, the model behind saved GUI and web
+// UI chains and `deen run` expressions. Run and Stream may be called
+// concurrently; SetLimits, SetParams and SelectOutput may not.
+type Chain struct {
+	p *pipeline.Pipeline
+}
+
+// NewChain builds a chain from steps. Unknown plugins, unsupported decode
+// directions and invalid option values are reported immediately.
+func NewChain(steps ...Step) (*Chain, error) {
+	ps := make([]pipeline.Step, 0, len(steps))
+	for _, s := range steps {
+		ps = append(ps, pipeline.Step{
+			Plugin:    s.Plugin,
+			Unprocess: s.Decode,
+			Options:   s.Options,
+			Disabled:  s.Disabled,
+		})
+	}
+	p := pipeline.New()
+	if err := p.LoadSteps(ps); err != nil {
+		return nil, err
+	}
+	return &Chain{p: p}, nil
+}
+
+// ParseChain loads a chain JSON file as exported by the GUI, the web UI or
+// Chain.MarshalJSON.
+func ParseChain(data []byte) (*Chain, error) {
+	p := pipeline.New()
+	if err := p.LoadJSON(data); err != nil {
+		return nil, err
+	}
+	return &Chain{p: p}, nil
+}
+
+// ParseCommandLine loads a chain expression such as ".url | .base64 | json",
+// the syntax of `deen run` and of the GUI's "Copy command" output.
+func ParseCommandLine(expr string) (*Chain, error) {
+	p := pipeline.New()
+	if err := p.LoadCommandLine(expr); err != nil {
+		return nil, err
+	}
+	return &Chain{p: p}, nil
+}
+
+// Steps returns the steps of the chain.
+func (c *Chain) Steps() []Step {
+	steps := make([]Step, 0, c.p.Len())
+	for _, s := range c.p.Steps() {
+		opts := make(map[string]string, len(s.Options))
+		for k, v := range s.Options {
+			opts[k] = v
+		}
+		steps = append(steps, Step{Plugin: s.Plugin, Decode: s.Unprocess, Options: opts, Disabled: s.Disabled})
+	}
+	return steps
+}
+
+// Params returns the parameters declared by the chain.
+func (c *Chain) Params() []Param { return c.p.Params() }
+
+// SetParams binds parameter values for the following runs. Naming an
+// undeclared parameter or passing an invalid value is an error, and then
+// nothing is bound.
+func (c *Chain) SetParams(values map[string]string) error { return c.p.BindParamValues(values) }
+
+// MissingParams returns the declared parameters that have neither a bound
+// value nor a default. Steps that reference them fail until they are set.
+func (c *Chain) MissingParams() []Param { return c.p.MissingParams() }
+
+// OutputNames returns the outputs of the chain: MainOutput, then the name of
+// every branch.
+func (c *Chain) OutputNames() []string { return c.p.OutputNames() }
+
+// SelectOutput makes Run and Stream produce the named output instead of the
+// main one. The other branches are dropped, so OutputNames afterwards returns
+// only MainOutput.
+func (c *Chain) SelectOutput(name string) error { return c.p.SelectOutput(name) }
+
+// Source returns the input saved with a chain file, or nil.
+func (c *Chain) Source() []byte { return c.p.Source() }
+
+// Limits returns the limits applied when the chain runs.
+func (c *Chain) Limits() Limits { return c.p.Limits() }
+
+// SetLimits changes the limits applied when the chain runs. Chains start with
+// DefaultLimits.
+func (c *Chain) SetLimits(l Limits) { c.p.SetLimits(l) }
+
+// Run passes input through the enabled steps and returns the result. A failed
+// step is reported as a *StepError.
+func (c *Chain) Run(ctx context.Context, input []byte) ([]byte, error) {
+	var out bytes.Buffer
+	if err := c.Stream(ctx, bytes.NewReader(input), &out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Stream passes r through the enabled steps into w. The steps run
+// concurrently, connected by pipes, so large inputs are not held in memory. A
+// failed step is reported as a *StepError.
+func (c *Chain) Stream(ctx context.Context, r io.Reader, w io.Writer) error {
+	return c.p.StreamFrom(ctx, r, w)
+}
+
+// String returns the chain as a `deen run` expression.
+func (c *Chain) String() string { return c.p.CommandLine() }
+
+// MarshalJSON encodes the chain in the chain file format, without a source.
+func (c *Chain) MarshalJSON() ([]byte, error) { return c.p.ExportJSONWithoutSource() }
+
+// UnmarshalJSON replaces the chain with a chain file.
+func (c *Chain) UnmarshalJSON(data []byte) error {
+	p := pipeline.New()
+	if err := p.LoadJSON(data); err != nil {
+		return err
+	}
+	if c.p != nil {
+		p.SetLimits(c.p.Limits())
+	}
+	c.p = p
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package deen is the Go API for embedding deen: plugin lookup and listing,
+// single transforms, transform chains, detection and inspection. It is backed
+// by the same plugin registry and pipeline as the deen CLI, GUI and MCP server,
+// so chains exported from the GUI or web UI run unchanged.
+package deen
+
+import (
+	"errors"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+	"github.com/takeshixx/deen/internal/plugins"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// Plugin is the plugin contract shared with the built-in plugins. See
+// examples/example_plugin.go for an annotated reference.
+type Plugin = types.DeenPlugin
+
+// OptionRange is the inclusive range of a numeric plugin option.
+type OptionRange = types.OptionRange
+
+// Limits bounds the time and output of transforms. Zero values disable the
+// corresponding limit.
+type Limits = pipeline.Limits
+
+// Param declares a chain parameter, referenced from step options as ${name}.
+type Param = pipeline.Param
+
+// MainOutput names the result of the main chain among the outputs of a chain
+// with branches.
+const MainOutput = pipeline.MainOutput
+
+// StepError reports which step of a chain failed.
+type StepError = pipeline.StepError
+
+var (
+	// ErrOutputLimit is reported when a step writes more than
+	// Limits.MaxOutput bytes.
+	ErrOutputLimit = pipeline.ErrOutputLimit
+	// ErrExpansionLimit is reported when a step writes more than
+	// Limits.MaxExpansion bytes per input byte.
+	ErrExpansionLimit = pipeline.ErrExpansionLimit
+)
+
+// DefaultLimits returns the limits chains start with: 30s per step, 2m per
+// chain, 256 MiB of output per step and an expansion ratio of 1000.
+func DefaultLimits() Limits { return pipeline.DefaultLimits() }
+
+// Register adds a plugin to the registry used by every function of this
+// package. constructor must return a fresh instance on each call. Register
+// fails when the plugin name or one of its aliases is already taken. Call it
+// during program start-up, before plugins are looked up or chains are run.
+func Register(constructor func() *Plugin) error {
+	return plugins.Register(constructor)
+}
+
+// LoadExternalPlugins registers deen-plugin-* executables found in dirs,
+// DEEN_PLUGIN_PATH and, with DEEN_PLUGIN_SEARCH_PATH=1, PATH, and sandboxed
+// *.wasm modules found in dirs and DEEN_PLUGIN_PATH, like the deen command
+// does at start-up. Plugins that fail to load are skipped; their errors are
+// joined in the result.
+func LoadExternalPlugins(dirs ...string) error {
+	errs := plugins.LoadExternal(plugins.ExternalDirs(dirs...))
+	errs = append(errs, plugins.LoadWasm(plugins.WasmDirs(dirs...), plugins.DefaultWasmConfig())...)
+	return errors.Join(errs...)
+}
+
+// PluginInfo describes a registered plugin.
+type PluginInfo struct {
+	Name        string
+	Aliases     []string
+	Category    string
+	Description string
+	CanDecode   bool // the plugin supports the decode (unprocess) direction
+	External    bool // provided by an executable or WebAssembly module
+	Options     []Option
+}
+
+// Option describes a plugin option.
+type Option struct {
+	Name        string
+	Label       string
+	Description string
+	Kind        string // "text", "number", "bool", "select" or "secret"
+	Default     string
+	Choices     []string
+	Open        bool // Choices are suggestions; other values are accepted
+	Range       *OptionRange
+	Secret      bool
+	Multiline   bool
+
+	check func(string) error
+}
+
+// Check reports whether value is acceptable for the option.
+func (o Option) Check(value string) error {
+	if o.check == nil {
+		return nil
+	}
+	return o.check(value)
+}
+
+// Plugins returns every registered plugin, grouped by category.
+func Plugins() []PluginInfo {
+	catalog := plugins.UICatalog()
+	infos := make([]PluginInfo, 0, len(catalog))
+	for _, c := range catalog {
+		infos = append(infos, pluginInfo(c))
+	}
+	return infos
+}
+
+// LookupPlugin returns the plugin registered under name or one of its
+// aliases. A leading "." (the decode prefix) is ignored.
+func LookupPlugin(name string) (PluginInfo, bool) {
+	p, _, ok := plugins.Resolve(name)
+	if !ok {
+		return PluginInfo{}, false
+	}
+	for _, c := range plugins.UICatalog() {
+		if c.Name == p.Name {
+			return pluginInfo(c), true
+		}
+	}
+	return PluginInfo{}, false
+}
+
+func pluginInfo(c plugins.UIPluginInfo) PluginInfo {
+	info := PluginInfo{
+		Name:        c.Name,
+		Aliases:     append([]string(nil), c.Aliases...),
+		Category:    c.Category,
+		Description: c.Description,
+		CanDecode:   c.CanDecode,
+		External:    plugins.IsExternal(c.Name),
+	}
+	for _, o := range pipeline.PluginOptions(c.Name) {
+		info.Options = append(info.Options, Option{
+			Name:        o.Name,
+			Label:       o.Label,
+			Description: o.Description,
+			Kind:        o.Kind,
+			Default:     o.Default,
+			Choices:     append([]string(nil), o.Choices...),
+			Open:        o.Open,
+			Range:       o.Range,
+			Secret:      o.Secret,
+			Multiline:   o.Multiline,
+			check:       o.Check,
+		})
+	}
+	return info
+}
@@ -0,0 +1,159 @@
+package deen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/takeshixx/deen/examples"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(examples.NewPluginExample); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestTransform(t *testing.T) {
+	ctx := context.Background()
+	got, err := Transform(ctx, Step{Plugin: "base64"}, []byte("deen"))
+	if err != nil || string(got) != "ZGVlbg==" {
+		t.Fatalf("Transform = %q, %v", got, err)
+	}
+	got, err = Transform(ctx, Step{Plugin: "hex", Decode: true}, []byte("6465656e"))
+	if err != nil || string(got) != "deen" {
+		t.Fatalf("decode = %q, %v", got, err)
+	}
+	var out bytes.Buffer
+	if err := TransformStream(ctx, Step{Plugin: "example", Options: map[string]string{"url": "true"}}, strings.NewReader("\xfb\xff"), &out); err != nil || out.String() != "-_8=" {
+		t.Fatalf("TransformStream = %q, %v", out.String(), err)
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := Transform(ctx, Step{Plugin: "nope"}, nil); err == nil || !strings.Contains(err.Error(), "unknown plugin") {
+		t.Fatalf("unknown plugin err = %v", err)
+	}
+	if _, err := Transform(ctx, Step{Plugin: "base64", Options: map[string]string{"bogus": "1"}}, nil); err == nil {
+		t.Fatal("unknown option accepted")
+	}
+	_, err := Transform(ctx, Step{Plugin: "base64", Decode: true}, []byte("!!"))
+	var se *StepError
+	if !errors.As(err, &se) || se.Index != 0 {
+		t.Fatalf("decode err = %v, want *StepError for step 0", err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	c, err := NewChain(Step{Plugin: "example"}, Step{Plugin: "hex"}, Step{Plugin: "hex", Disabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Run(context.Background(), []byte("deen"))
+	if err != nil || string(got) != "5a47566c62673d3d" {
+		t.Fatalf("Run = %q, %v", got, err)
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Chain
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Steps()) != 3 || !back.Steps()[2].Disabled || back.String() != c.String() {
+		t.Fatalf("round trip = %v (%s), want %v", back.Steps(), back.String(), c.Steps())
+	}
+
+	parsed, err := ParseCommandLine(".hex | .example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = parsed.Run(context.Background(), got)
+	if err != nil || string(got) != "deen" {
+		t.Fatalf("inverse = %q, %v", got, err)
+	}
+}
+
+func TestChainLimits(t *testing.T) {
+	c, err := NewChain(Step{Plugin: "hex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Limits() != DefaultLimits() {
+		t.Fatalf("Limits = %+v, want defaults", c.Limits())
+	}
+	c.SetLimits(Limits{MaxOutput: 4})
+	if _, err := c.Run(context.Background(), []byte("deen")); !errors.Is(err, ErrOutputLimit) {
+		t.Fatalf("err = %v, want ErrOutputLimit", err)
+	}
+}
+
+func TestPlugins(t *testing.T) {
+	info, ok := LookupPlugin(".ex")
+	if !ok || info.Name != "example" || !info.CanDecode || info.External {
+		t.Fatalf("LookupPlugin = %+v, %v", info, ok)
+	}
+	if len(info.Options) != 1 || info.Options[0].Name != "url" || info.Options[0].Kind != "bool" {
+		t.Fatalf("options = %+v", info.Options)
+	}
+	bcrypt, ok := LookupPlugin("bcrypt")
+	if !ok || len(bcrypt.Options) == 0 {
+		t.Fatalf("bcrypt = %+v, %v", bcrypt, ok)
+	}
+	for _, o := range bcrypt.Options {
+		if o.Name == "cost" && o.Check("99") == nil {
+			t.Fatal("Check accepted a cost outside its range")
+		}
+	}
+	if _, ok := LookupPlugin("nope"); ok {
+		t.Fatal("LookupPlugin found an unknown plugin")
+	}
+	found := false
+	for _, p := range Plugins() {
+		found = found || p.Name == "example"
+	}
+	if !found {
+		t.Fatal("Plugins does not list registered plugins")
+	}
+	if err := Register(examples.NewPluginExample); err == nil {
+		t.Fatal("duplicate registration accepted")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	in := Inspect([]byte(`{"a":1}`))
+	if in.SHA256 != "015abd7f5cc57a2dd94b7590f04ad8084273905ee33ec5cebeae62276a97f862" {
+		t.Fatalf("SHA256 = %s", in.SHA256)
+	}
+	if !strings.HasPrefix(in.MIME, "text/plain") || !in.Metadata.UTF8 || in.Metadata.BOM != "" || in.StructuredPreview == "" {
+		t.Fatalf("Inspect = %+v", in)
+	}
+
+	data := []byte("ZGVlbiBpcyBhIGRlY29kZXIvZW5jb2Rlcg==")
+	var sug *Suggestion
+	for _, s := range Detect(data) {
+		if len(s.Steps) == 1 && s.Steps[0].Plugin == "base64" && s.Steps[0].Decode {
+			sug = &s
+			break
+		}
+	}
+	if sug == nil {
+		t.Fatalf("no base64 decode suggestion in %+v", Detect(data))
+	}
+	c, err := sug.Chain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Run(context.Background(), data)
+	if err != nil || string(got) != "deen is a decoder/encoder" {
+		t.Fatalf("suggested chain = %q, %v", got, err)
+	}
+}
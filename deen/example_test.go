@@ -0,0 +1,74 @@
+package deen_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/takeshixx/deen/deen"
+)
+
+func ExampleTransform() {
+	out, err := deen.Transform(context.Background(), deen.Step{Plugin: "base64"}, []byte("deen"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
+	// Output: ZGVlbg==
+}
+
+func ExampleParseCommandLine() {
+	c, err := deen.ParseCommandLine(".hex | .base64")
+	if err != nil {
+		panic(err)
+	}
+	out, err := c.Run(context.Background(), []byte("5a47566c626a3d3d"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
+	// Output: deen
+}
+
+func ExampleChain_SetParams() {
+	c, err := deen.ParseChain([]byte(`{"version":1,
+		"params":[{"name":"key","secret":true}],
+		"steps":[{"plugin":"hmac","options":{"key":"${key}"}}]}`))
+	if err != nil {
+		panic(err)
+	}
+	for _, p := range c.MissingParams() {
+		fmt.Println("missing:", p.Name)
+	}
+	if err := c.SetParams(map[string]string{"key": "secret"}); err != nil {
+		panic(err)
+	}
+	out, err := c.Run(context.Background(), []byte("deen"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(len(c.MissingParams()), string(out))
+	// Output:
+	// missing: key
+	// 0 7eff82e559f2bc9220229f7c0e57e97f51b0c994fb44a1dfd3f8d20257e48823
+}
+
+func ExampleChain_SelectOutput() {
+	c, err := deen.ParseChain([]byte(`{"version":2,
+		"steps":[{"plugin":"base64"},{"plugin":"hex"}],
+		"branches":[{"name":"url","after":1,"steps":[{"plugin":"url"}]}]}`))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(c.OutputNames())
+	if err := c.SelectOutput("url"); err != nil {
+		panic(err)
+	}
+	out, err := c.Run(context.Background(), []byte("deen?"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
+	// Output:
+	// [main url]
+	// ZGVlbj8%3D
+}
@@ -0,0 +1,105 @@
+package deen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+)
+
+// Suggestion is a transform chain that detection considers useful for some
+// data. Detection is heuristic: suggestions are shortcuts, not declarations
+// of the data type.
+type Suggestion struct {
+	Label      string
+	Reason     string
+	Confidence int    // 0-100; zero when unknown
+	Preview    string // excerpt of the result, when cheap to compute
+	Steps      []Step
+}
+
+// Chain builds a chain from the suggested steps.
+func (s Suggestion) Chain() (*Chain, error) { return NewChain(s.Steps...) }
+
+// Metadata summarizes data at the byte level. Large inputs are sampled.
+type Metadata struct {
+	Bytes     int
+	Lines     int
+	UTF8      bool
+	Encoding  string  // likely text encoding, or "" for binary data
+	BOM       string  // byte order mark, or ""
+	Printable int     // percentage of printable bytes
+	Entropy   float64 // Shannon entropy in bits per byte
+	Sampled   bool
+}
+
+// Inspection is the result of Inspect.
+type Inspection struct {
+	SHA256            string // hex encoded
+	MIME              string
+	Metadata          Metadata
+	StructuredPreview string // readable rendering of structured payloads, if any
+	Suggestions       []Suggestion
+}
+
+// Detect returns likely next transforms for data.
+func Detect(data []byte) []Suggestion {
+	found := pipeline.Suggestions(data)
+	out := make([]Suggestion, 0, len(found))
+	for _, s := range found {
+		steps := s.Steps
+		if len(steps) == 0 && s.Plugin != "" {
+			steps = []pipeline.SuggestionStep{{Plugin: s.Plugin, Unprocess: s.Unprocess, Options: s.Options}}
+		}
+		sug := Suggestion{Label: s.Label, Reason: s.Reason, Confidence: s.Confidence, Preview: s.Preview}
+		for _, step := range steps {
+			opts := make(map[string]string, len(step.Options))
+			for k, v := range step.Options {
+				opts[k] = v
+			}
+			sug.Steps = append(sug.Steps, Step{Plugin: step.Plugin, Decode: step.Unprocess, Options: opts})
+		}
+		out = append(out, sug)
+	}
+	return out
+}
+
+// DataMetadata returns byte-level metadata for data.
+func DataMetadata(data []byte) Metadata {
+	m := pipeline.DataMetadata(data, 0)
+	bom := m.BOM
+	if bom == "none" {
+		bom = ""
+	}
+	return Metadata{
+		Bytes:     m.Bytes,
+		Lines:     m.Lines,
+		UTF8:      m.UTF8,
+		Encoding:  m.Encoding,
+		BOM:       bom,
+		Printable: m.Printable,
+		Entropy:   m.Entropy,
+		Sampled:   m.Sampled,
+	}
+}
+
+// Inspect describes data: digest, MIME type, metadata, a structured preview
+// and detection suggestions.
+func Inspect(data []byte) Inspection {
+	sum := sha256.Sum256(data)
+	sniff := data
+	if len(sniff) > 512 {
+		sniff = sniff[:512]
+	}
+	in := Inspection{
+		SHA256:      hex.EncodeToString(sum[:]),
+		MIME:        http.DetectContentType(sniff),
+		Metadata:    DataMetadata(data),
+		Suggestions: Detect(data),
+	}
+	if preview, ok := pipeline.StructuredPreview(data); ok {
+		in.StructuredPreview = preview
+	}
+	return in
+}
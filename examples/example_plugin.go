@@ -26,6 +26,12 @@ func NewPluginExample() *types.DeenPlugin {
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.Bool("url", false, "use the URL-safe alphabet")
 	}
+	// Optional: describe the flags for help output, the GUI and web UI, and
+	// MCP schemas. Choices, ranges and Validate are checked before the
+	// transform runs.
+	p.Options = []types.OptionSpec{
+		{Name: "url", Label: "URL-safe alphabet", Description: "Use the URL-safe Base64 alphabet."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		enc := base64.StdEncoding
 		if helpers.IsBoolFlag(flags, "url") {
@@ -0,0 +1,290 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+)
+
+// batchOptions configures `deen chain -batch`.
+type batchOptions struct {
+	Pattern       string // glob or directory of input files
+	OutDir        string
+	Suffix        string // appended to input file names
+	Workers       int
+	Summary       string // summary file; empty writes to stdout
+	SummaryFormat string // "json" or "csv"
+}
+
+// batchResult is one line of the batch summary.
+type batchResult struct {
+	File         string `json:"file"`
+	Output       string `json:"output,omitempty"`
+	Status       string `json:"status"`
+	Step         int    `json:"step,omitempty"` // one-based failing step
+	Plugin       string `json:"plugin,omitempty"`
+	Error        string `json:"error,omitempty"`
+	InputBytes   int64  `json:"input_bytes"`
+	OutputBytes  int64  `json:"output_bytes"`
+	InputSHA256  string `json:"input_sha256,omitempty"`
+	OutputSHA256 string `json:"output_sha256,omitempty"`
+}
+
+// batchSummary is the JSON form of the batch summary.
+type batchSummary struct {
+	Files   int           `json:"files"`
+	OK      int           `json:"ok"`
+	Failed  int           `json:"failed"`
+	Results []batchResult `json:"results"`
+}
+
+// batchInputs returns the regular files matched by pattern: the files directly
+// inside it when it names a directory, the glob matches otherwise.
+func batchInputs(pattern string) ([]string, error) {
+	var candidates []string
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			candidates = append(candidates, filepath.Join(pattern, entry.Name()))
+		}
+	} else {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		candidates = matches
+	}
+	var files []string
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no input files match %q", pattern)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// runChainBatch applies pipe to every input file with a bounded worker pool,
+// writes the summary and returns the exit code. Failed files are reported on
+// stderr and do not stop the batch.
+func runChainBatch(pipe *pipeline.Pipeline, opts batchOptions, stdout, stderr io.Writer) int {
+	files, err := batchInputs(opts.Pattern)
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: chain:", err)
+		return 1
+	}
+	// Outputs of an earlier run into the input directory match the pattern
+	// too; leave them alone.
+	outDir := resolvedPath(filepath.Join(opts.OutDir, "x"))
+	if opts.Suffix != "" {
+		inputs := files[:0]
+		for _, file := range files {
+			if !strings.HasSuffix(file, opts.Suffix) || filepath.Dir(resolvedPath(file)) != filepath.Dir(outDir) {
+				inputs = append(inputs, file)
+			}
+		}
+		if files = inputs; len(files) == 0 {
+			fmt.Fprintf(stderr, "deen: chain: no input files match %q besides earlier %s outputs\n", opts.Pattern, opts.Suffix)
+			return 1
+		}
+	}
+	inputs := make(map[string]bool, len(files))
+	for _, file := range files {
+		inputs[resolvedPath(file)] = true
+	}
+	for _, file := range files {
+		if out := filepath.Join(opts.OutDir, filepath.Base(file)+opts.Suffix); inputs[resolvedPath(out)] {
+			fmt.Fprintf(stderr, "deen: chain: output %s would overwrite an input file; use another -out or -suffix\n", out)
+			return 2
+		}
+	}
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		fmt.Fprintln(stderr, "deen: chain:", err)
+		return 1
+	}
+
+	results := make([]batchResult, len(files))
+	outputs := make([]string, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(opts.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = batchFile(pipe, files[i], outputs[i])
+			}
+		}()
+	}
+	owners := map[string]string{}
+	for i, file := range files {
+		outputs[i] = filepath.Join(opts.OutDir, filepath.Base(file)+opts.Suffix)
+		if owner, taken := owners[outputs[i]]; taken {
+			results[i] = batchResult{File: file, Status: "error", Error: fmt.Sprintf("output %s is already written for %s", outputs[i], owner)}
+			continue
+		}
+		owners[outputs[i]] = file
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Status != "ok" {
+			failed++
+			if r.Step > 0 {
+				fmt.Fprintf(stderr, "deen: chain: %s: step %d (%s): %s\n", r.File, r.Step, r.Plugin, r.Error)
+			} else {
+				fmt.Fprintf(stderr, "deen: chain: %s: %s\n", r.File, r.Error)
+			}
+		}
+	}
+
+	summary := stdout
+	if opts.Summary != "" {
+		f, err := os.Create(opts.Summary)
+		if err != nil {
+			fmt.Fprintln(stderr, "deen: chain:", err)
+			return 1
+		}
+		defer f.Close()
+		summary = f
+	}
+	if err := writeBatchSummary(summary, opts.SummaryFormat, results, failed); err != nil {
+		fmt.Fprintln(stderr, "deen: chain: failed to write summary:", err)
+		return 1
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// batchFile runs pipe over one input file. The output is written to a
+// temporary file next to out and only renamed into place on success, so a
+// failed run leaves no partial output behind.
+func batchFile(pipe *pipeline.Pipeline, file, out string) batchResult {
+	res := batchResult{File: file, Status: "error"}
+	in, err := os.Open(file)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(out), ".deen-batch-*")
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		res.Error = err.Error()
+		return res
+	}
+
+	inHash, outHash := sha256.New(), sha256.New()
+	inCount := &hashCounter{h: inHash}
+	outCount := &hashCounter{h: outHash}
+	r := io.TeeReader(in, inCount)
+	err = pipe.StreamFrom(context.Background(), r, io.MultiWriter(tmp, outCount))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	// Hash the whole input even when a step stopped reading early.
+	if _, rerr := io.Copy(io.Discard, r); err == nil {
+		err = rerr
+	}
+	res.InputBytes = inCount.n
+	res.InputSHA256 = hex.EncodeToString(inHash.Sum(nil))
+	if err == nil {
+		err = os.Rename(tmp.Name(), out)
+	}
+	if err != nil {
+		var se *pipeline.StepError
+		if errors.As(err, &se) {
+			res.Step, res.Plugin, err = se.Index+1, se.Plugin, se.Err
+		}
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = "ok"
+	res.Output = out
+	res.OutputBytes = outCount.n
+	res.OutputSHA256 = hex.EncodeToString(outHash.Sum(nil))
+	return res
+}
+
+// resolvedPath returns path made absolute, with symbolic links in its
+// directory resolved, so different spellings of one location compare equal.
+// The directory need not exist.
+func resolvedPath(path string) string {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = real
+	}
+	return filepath.Join(dir, filepath.Base(path))
+}
+
+// hashCounter hashes and counts the bytes written to it.
+type hashCounter struct {
+	h hash.Hash
+	n int64
+}
+
+func (c *hashCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return c.h.Write(p)
+}
+
+func writeBatchSummary(w io.Writer, format string, results []batchResult, failed int) error {
+	if format == "csv" {
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"file", "output", "status", "step", "plugin", "error", "input_bytes", "output_bytes", "input_sha256", "output_sha256"})
+		for _, r := range results {
+			step := ""
+			if r.Step > 0 {
+				step = strconv.Itoa(r.Step)
+			}
+			cw.Write([]string{
+				r.File, r.Output, r.Status, step, r.Plugin, r.Error,
+				strconv.FormatInt(r.InputBytes, 10), strconv.FormatInt(r.OutputBytes, 10),
+				r.InputSHA256, r.OutputSHA256,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(batchSummary{
+		Files:   len(results),
+		OK:      len(results) - failed,
+		Failed:  failed,
+		Results: results,
+	})
+}
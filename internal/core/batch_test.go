@@ -0,0 +1,152 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeBatchInputs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunChainBatch(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"base64","unprocess":true},{"plugin":"hex"}]}`))
+	in := writeBatchInputs(t, map[string]string{"a.b64": "ZGVlbg==", "b.b64": "%%%%", "c.txt": "aGk="})
+	if err := os.Mkdir(filepath.Join(in, "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "out")
+
+	var stdout, stderr bytes.Buffer
+	code := runChainWithArgs([]string{"-batch", in, "-out", out, "-workers", "2", chainPath}, strings.NewReader(""), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("exit = %d, want 1 for a failed file; stderr = %q", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "b.b64: step 1 (base64)") {
+		t.Fatalf("stderr = %q, want per-file step error", stderr.String())
+	}
+
+	var summary batchSummary
+	if err := json.Unmarshal(stdout.Bytes(), &summary); err != nil {
+		t.Fatalf("summary: %v\n%s", err, stdout.String())
+	}
+	if summary.Files != 3 || summary.OK != 2 || summary.Failed != 1 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	a, b := summary.Results[0], summary.Results[1]
+	if a.Status != "ok" || a.InputBytes != 8 || a.OutputBytes != 8 || a.Output != filepath.Join(out, "a.b64.out") {
+		t.Fatalf("a = %+v", a)
+	}
+	if a.OutputSHA256 != "9f44788e224fe0923ff54ef4d0919d862688e5fd23382da588805309617230df" {
+		t.Fatalf("a output digest = %q", a.OutputSHA256)
+	}
+	if b.Status != "error" || b.Step != 1 || b.Plugin != "base64" || b.Output != "" || b.InputBytes != 4 || len(b.InputSHA256) != 64 {
+		t.Fatalf("b = %+v", b)
+	}
+
+	got, err := os.ReadFile(filepath.Join(out, "a.b64.out"))
+	if err != nil || string(got) != "6465656e" {
+		t.Fatalf("a output = %q, %v", got, err)
+	}
+	entries, _ := os.ReadDir(out)
+	if len(entries) != 2 {
+		t.Fatalf("output dir holds %d entries, want outputs of the two successful files", len(entries))
+	}
+}
+
+func TestRunChainBatchGlobCSV(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"hex"}]}`))
+	in := writeBatchInputs(t, map[string]string{"a.bin": "A", "b.bin": "B", "c.txt": "C"})
+	out := t.TempDir()
+	summaryPath := filepath.Join(t.TempDir(), "summary.csv")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-batch", filepath.Join(in, "*.bin"), "-out", out, "-suffix", ".hex", "-summary", summaryPath, "-summary-format", "csv", chainPath}
+	if code := runChainWithArgs(args, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("stdout = %q, want summary in file", stdout.String())
+	}
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != "file" || rows[1][2] != "ok" || rows[2][0] != filepath.Join(in, "b.bin") {
+		t.Fatalf("rows = %q", rows)
+	}
+	if got, err := os.ReadFile(filepath.Join(out, "b.bin.hex")); err != nil || string(got) != "42" {
+		t.Fatalf("b output = %q, %v", got, err)
+	}
+}
+
+func TestRunChainBatchUsage(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"hex"}]}`))
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-batch", "x", chainPath}, "-batch requires -out"},
+		{[]string{"-batch", "x", "-out", "y", "-stdin", chainPath}, "cannot be combined"},
+		{[]string{"-batch", "x", "-out", "y", "-summary-format", "xml", chainPath}, "unknown summary format"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := runChainWithArgs(tc.args, strings.NewReader(""), &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), tc.want) {
+			t.Errorf("%v: exit = %d, stderr = %q, want %q", tc.args, code, stderr.String(), tc.want)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-batch", filepath.Join(t.TempDir(), "*.none"), "-out", t.TempDir(), chainPath}
+	if code := runChainWithArgs(args, strings.NewReader(""), &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "no input files match") {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestRunChainBatchInPlace(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"hex"}]}`))
+	dir := writeBatchInputs(t, map[string]string{"a.txt": "A", "b.txt": "B"})
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-batch", filepath.Join(dir, "*.txt"), "-out", dir + string(filepath.Separator) + ".", "-suffix", "", chainPath}
+	if code := runChainWithArgs(args, strings.NewReader(""), &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "would overwrite an input") {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(got) != "A" {
+		t.Fatalf("input was overwritten with %q", got)
+	}
+
+	// Running twice into the input directory must not pick up a.txt.out.
+	for run := 1; run <= 2; run++ {
+		stdout.Reset()
+		stderr.Reset()
+		args := []string{"-batch", dir, "-out", dir, chainPath}
+		if code := runChainWithArgs(args, strings.NewReader(""), &stdout, &stderr); code != 0 {
+			t.Fatalf("run %d: exit = %d, stderr = %q", run, code, stderr.String())
+		}
+		var summary batchSummary
+		if err := json.Unmarshal(stdout.Bytes(), &summary); err != nil || summary.Files != 2 {
+			t.Fatalf("run %d: summary = %+v, %v", run, summary, err)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 4 {
+		t.Fatalf("directory holds %d entries, want two inputs and two outputs", len(entries))
+	}
+}
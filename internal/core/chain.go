@@ -2,10 +2,13 @@ package core
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/takeshixx/deen/internal/pipeline"
@@ -17,6 +20,16 @@ func runChain() int {
 }
 
 func runChainWithArgs(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "test":
+			return runChainTestWithArgs(args[1:], stdout, stderr)
+		case "import-cyberchef":
+			return runChainImportCyberChefWithArgs(args[1:], stdin, stdout, stderr)
+		case "export-cyberchef":
+			return runChainExportCyberChefWithArgs(args[1:], stdout, stderr)
+		}
+	}
 	fs := flag.NewFlagSet("chain", flag.ExitOnError)
 	fs.SetOutput(stderr)
 	fs.Usage = func() {
@@ -25,13 +38,45 @@ func runChainWithArgs(args []string, stdin io.Reader, stdout, stderr io.Writer)
 		fmt.Fprintf(stderr, "Examples:\n")
 		fmt.Fprintf(stderr, "  deen chain saved.json\n")
 		fmt.Fprintf(stderr, "  deen chain -file saved.json\n")
-		fmt.Fprintf(stderr, "  printf data | deen chain -stdin saved.json\n\n")
+		fmt.Fprintf(stderr, "  printf data | deen chain -stdin saved.json\n")
+		fmt.Fprintf(stderr, "  deen chain -batch 'captures/*.bin' -out decoded saved.json\n")
+		fmt.Fprintf(stderr, "  deen chain -p key=00112233 -stdin decrypt.json\n")
+		fmt.Fprintf(stderr, "  deen chain -output zlib -input-file blob.bin branches.json\n")
+		fmt.Fprintf(stderr, "  deen chain -inverse -input-file edited.json cookie.json\n")
+		fmt.Fprintf(stderr, "  deen chain -emit python -p key=00112233 decrypt.json > decrypt.py\n")
+		fmt.Fprintf(stderr, "  deen chain -trace - -trace-dir steps -input-file blob.bin saved.json\n")
+		fmt.Fprintf(stderr, "  deen chain test recipes/*.json\n")
+		fmt.Fprintf(stderr, "  deen chain import-cyberchef recipe.json > chain.json\n")
+		fmt.Fprintf(stderr, "  deen chain export-cyberchef -format url chain.json\n\n")
+		fmt.Fprintf(stderr, "Chain parameters are bound from -p, then %s<NAME> environment\n", pipeline.ParamEnvPrefix)
+		fmt.Fprintf(stderr, "variables, then the defaults declared in the chain file.\n\n")
+		fmt.Fprintf(stderr, "Chains with branches have one output per branch besides the main chain\n")
+		fmt.Fprintf(stderr, "result; -output selects which one is written.\n\n")
+		fmt.Fprintf(stderr, "-inverse runs the chain that turns the result back into the chain source,\n")
+		fmt.Fprintf(stderr, "e.g. to re-encode an edited document the way the saved source was encoded.\n")
+		fmt.Fprintf(stderr, "Options such as the Base64 alphabet are picked from the saved source.\n\n")
+		fmt.Fprintf(stderr, "-emit writes the chain as a standalone Go or Python program instead of\n")
+		fmt.Fprintf(stderr, "running it. Steps without a code template for the language are refused.\n\n")
 		fs.PrintDefaults()
 	}
 	chainFile := fs.String("file", "", "saved chain JSON file")
 	inputFile := fs.String("input-file", "", "override chain source with this input file")
 	stdinInput := fs.Bool("stdin", false, "override chain source with stdin")
 	newline := fs.Bool("N", false, "append a trailing newline to the output")
+	output := fs.String("output", pipeline.MainOutput, "name of the output to write: "+pipeline.MainOutput+" or a branch name")
+	inverse := fs.Bool("inverse", false, "run the inverse of the chain, which re-encodes its result")
+	emit := fs.String("emit", "", "write the chain as a program in this language instead of running it: "+strings.Join(pipeline.EmitLanguages, " or "))
+	var params stringList
+	fs.Var(&params, "p", "bind a chain parameter as name=value (repeatable)")
+	batch := fs.String("batch", "", "apply the chain to every file matching this glob or in this directory")
+	outDir := fs.String("out", "", "output directory for -batch")
+	suffix := fs.String("suffix", ".out", "suffix appended to output file names in -batch mode")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of files processed concurrently in -batch mode")
+	summary := fs.String("summary", "", "write the -batch summary to this file instead of stdout")
+	summaryFormat := fs.String("summary-format", "json", "format of the -batch summary: json or csv")
+	trace := fs.String("trace", "", "write a JSON trace line per step to this file (- for stderr)")
+	traceDir := fs.String("trace-dir", "", "write the output of every step to this directory")
+	limits := registerLimitFlags(fs, pipeline.Limits{})
 	fs.Parse(args)
 
 	args = fs.Args()
@@ -44,6 +89,35 @@ func runChainWithArgs(args []string, stdin io.Reader, stdout, stderr io.Writer)
 		fmt.Fprintln(stderr, "deen: chain: missing chain file")
 		return 2
 	}
+	if *emit != "" {
+		switch {
+		case !slices.Contains(pipeline.EmitLanguages, *emit):
+			fmt.Fprintf(stderr, "deen: chain: unknown -emit language %q\n", *emit)
+			return 2
+		case *batch != "":
+			fmt.Fprintln(stderr, "deen: chain: -emit cannot be combined with -batch")
+			return 2
+		case *trace != "" || *traceDir != "":
+			fmt.Fprintln(stderr, "deen: chain: -emit cannot be combined with -trace")
+			return 2
+		}
+	}
+	if *batch != "" {
+		switch {
+		case *outDir == "":
+			fmt.Fprintln(stderr, "deen: chain: -batch requires -out")
+			return 2
+		case *stdinInput || *inputFile != "" || len(args) > 0:
+			fmt.Fprintln(stderr, "deen: chain: -batch cannot be combined with other input")
+			return 2
+		case *trace != "" || *traceDir != "":
+			fmt.Fprintln(stderr, "deen: chain: -batch cannot be combined with -trace")
+			return 2
+		case *summaryFormat != "json" && *summaryFormat != "csv":
+			fmt.Fprintf(stderr, "deen: chain: unknown summary format %q\n", *summaryFormat)
+			return 2
+		}
+	}
 
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -51,47 +125,107 @@ func runChainWithArgs(args []string, stdin io.Reader, stdout, stderr io.Writer)
 		return 1
 	}
 	pipe := pipeline.New()
-	if err := pipe.ImportJSON(data); err != nil {
+	pipe.SetLimits(limits())
+	if err := pipe.LoadJSON(data); err != nil {
 		fmt.Fprintf(stderr, "deen: chain: failed to import chain: %s\n", err)
 		return 1
 	}
-
-	if *stdinInput || *inputFile != "" || len(args) > 0 {
-		r, cleanup, err := selectChainInput(*inputFile, *stdinInput, args, stdin)
+	if err := pipe.SelectOutput(*output); err != nil {
+		fmt.Fprintln(stderr, "deen: chain:", err)
+		return 2
+	}
+	if err := pipe.BindParams(params); err != nil {
+		fmt.Fprintln(stderr, "deen: chain:", err)
+		return 2
+	}
+	if missing := pipe.MissingParams(); len(missing) > 0 {
+		fmt.Fprintf(stderr, "deen: chain: missing value for parameter %q (use -p %s=... or %s)\n", missing[0].Name, missing[0].Name, missing[0].EnvName())
+		return 2
+	}
+	if *inverse {
+		if err := pipe.Invert(); err != nil {
+			fmt.Fprintln(stderr, "deen: chain: cannot invert chain:", err)
+			return 1
+		}
+	}
+	if *emit != "" {
+		program, err := pipe.Emit(*emit)
 		if err != nil {
+			fmt.Fprintln(stderr, "deen: chain: cannot emit chain:", err)
+			return 1
+		}
+		if _, err := io.WriteString(stdout, program); err != nil {
 			fmt.Fprintln(stderr, "deen: chain:", err)
 			return 1
 		}
-		defer cleanup()
-		input, err := io.ReadAll(r)
-		if err != nil {
-			fmt.Fprintf(stderr, "deen: chain: failed to read input: %s\n", err)
+		return 0
+	}
+	if *batch != "" {
+		return runChainBatch(pipe, batchOptions{
+			Pattern:       *batch,
+			OutDir:        *outDir,
+			Suffix:        *suffix,
+			Workers:       *workers,
+			Summary:       *summary,
+			SummaryFormat: *summaryFormat,
+		}, stdout, stderr)
+	}
+
+	var tracer func(pipeline.StepTrace, []byte) error
+	if *trace != "" || *traceDir != "" {
+		var tw io.Writer
+		switch *trace {
+		case "":
+		case "-":
+			tw = stderr
+		default:
+			f, err := os.Create(*trace)
+			if err != nil {
+				fmt.Fprintln(stderr, "deen: chain:", err)
+				return 1
+			}
+			defer f.Close()
+			tw = f
+		}
+		if tracer, err = chainTracer(tw, *traceDir); err != nil {
+			fmt.Fprintln(stderr, "deen: chain:", err)
 			return 1
 		}
-		pipe.SetSourceOwned(input)
 	}
 
 	out := bufio.NewWriter(stdout)
-	if _, err := out.Write(pipe.Result()); err != nil {
-		fmt.Fprintln(stderr, "deen:", err)
+	if *stdinInput || *inputFile != "" || len(args) > 0 {
+		r, cleanup, ierr := selectChainInput(*inputFile, *stdinInput, args, stdin)
+		if ierr != nil {
+			fmt.Fprintln(stderr, "deen: chain:", ierr)
+			return 1
+		}
+		defer cleanup()
+		if tracer != nil {
+			err = pipe.TraceFrom(context.Background(), r, out, tracer)
+		} else {
+			err = pipe.StreamFrom(context.Background(), r, out)
+		}
+	} else if tracer != nil {
+		err = pipe.Trace(context.Background(), out, tracer)
+	} else {
+		err = pipe.Stream(context.Background(), out)
+	}
+	if ferr := out.Flush(); err == nil && ferr != nil {
+		err = ferr
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: chain:", err)
 		return 1
 	}
+
 	globalNewline := newlinePtr != nil && *newlinePtr
 	if *newline || globalNewline {
-		if _, err := out.WriteString("\n"); err != nil {
+		if _, err := io.WriteString(stdout, "\n"); err != nil {
 			fmt.Fprintln(stderr, "deen:", err)
 			return 1
 		}
 	}
-	if err := out.Flush(); err != nil {
-		fmt.Fprintln(stderr, "deen:", err)
-		return 1
-	}
-
-	if step, err := firstChainError(pipe); err != nil {
-		fmt.Fprintf(stderr, "deen: chain: step %d (%s): %s\n", step+1, pipe.Steps()[step].Plugin, err)
-		return 1
-	}
 	return 0
 }
 
@@ -113,11 +247,12 @@ func selectChainInput(inputFile string, stdinInput bool, args []string, stdin io
 	return strings.NewReader(""), noop, nil
 }
 
-func firstChainError(pipe *pipeline.Pipeline) (int, error) {
-	for i := range pipe.Steps() {
-		if err := pipe.Err(i); err != nil {
-			return i, err
-		}
-	}
-	return -1, nil
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
 }
@@ -2,13 +2,12 @@ package core
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
-
-	"github.com/takeshixx/deen/internal/pipeline"
 )
 
 func TestSelectChainInputUsesArgs(t *testing.T) {
@@ -97,6 +96,31 @@ func TestRunChainReportsStepError(t *testing.T) {
 	}
 }
 
+func TestRunChainStreamsMultiStepChain(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"gzip"},{"plugin":"base64"},{"plugin":"base64","unprocess":true},{"plugin":"gzip","unprocess":true},{"plugin":"hex","disabled":true}]}`))
+	input := strings.Repeat("stream me ", 10000)
+	var stdout, stderr bytes.Buffer
+	code := runChainWithArgs([]string{"-stdin", chainPath}, strings.NewReader(input), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if stdout.String() != input {
+		t.Fatalf("stdout length = %d, want round-tripped input of %d bytes", stdout.Len(), len(input))
+	}
+}
+
+func TestRunChainMaxOutput(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"base64"}]}`))
+	var stdout, stderr bytes.Buffer
+	code := runChainWithArgs([]string{"-max-output", "1K", "-stdin", chainPath}, strings.NewReader(strings.Repeat("x", 2048)), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("exit = %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "step 1 (base64): output size limit exceeded (1024 bytes)") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
 func TestRunChainMissingFile(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	code := runChainWithArgs(nil, strings.NewReader(""), &stdout, &stderr)
@@ -108,19 +132,64 @@ func TestRunChainMissingFile(t *testing.T) {
 	}
 }
 
-func TestFirstChainError(t *testing.T) {
-	p := pipeline.New()
-	p.SetSource([]byte("%%%%"))
-	p.AddStep("base64", true)
-	i, err := firstChainError(p)
-	if err == nil {
-		t.Fatal("expected chain error")
+func TestRunChainParams(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":1,"params":[{"name":"key","secret":true},{"name":"alg","default":"sha256"}],"steps":[{"plugin":"hmac","options":{"key":"${key}","alg":"${alg}"}}]}`))
+	run := func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := runChainWithArgs(append(args, "-stdin", chainPath), strings.NewReader("deen"), &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
 	}
-	if i != 0 {
-		t.Fatalf("error step = %d, want 0", i)
+
+	code, _, stderr := run()
+	if code != 2 || !strings.Contains(stderr, `missing value for parameter "key" (use -p key=... or DEEN_PARAM_KEY)`) {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr)
+	}
+	code, flagOut, stderr := run("-p", "key=k1")
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr)
+	}
+	t.Setenv("DEEN_PARAM_KEY", "k1")
+	if code, envOut, stderr := run(); code != 0 || envOut != flagOut {
+		t.Fatalf("env binding: exit = %d, stdout = %q, stderr = %q; want %q", code, envOut, stderr, flagOut)
 	}
-	if !strings.Contains(err.Error(), "base64") {
-		t.Fatalf("chain error = %q", err)
+	if code, out, _ := run("-p", "key=k2"); code != 0 || out == flagOut {
+		t.Fatal("-p did not take precedence over the environment")
+	}
+	if code, out, _ := run("-p", "alg=md5"); code != 0 || out == flagOut {
+		t.Fatal("-p did not override the default")
+	}
+	if code, _, stderr := run("-p", "nope=1"); code != 2 || !strings.Contains(stderr, `unknown parameter "nope"`) {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestRunChainTrace(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"base64"},{"plugin":"hex","unprocess":true}]}`))
+	dir := filepath.Join(t.TempDir(), "steps")
+	var stdout, stderr bytes.Buffer
+	code := runChainWithArgs([]string{"-trace", "-", "-trace-dir", dir, "-stdin", chainPath}, strings.NewReader("deen"), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("exit = %d, want 1; stderr = %q", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if len(lines) != 3 || lines[2] != "deen: chain: step 2 (hex): encoding/hex: invalid byte: U+005A 'Z'" {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+	var first, second chainTraceRecord
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatal(err)
+	}
+	if first.Plugin != "base64" || first.OutputBytes != 8 || first.OutputFile == "" {
+		t.Fatalf("first trace = %+v", first)
+	}
+	if data, err := os.ReadFile(first.OutputFile); err != nil || string(data) != "ZGVlbg==" {
+		t.Fatalf("intermediate output = %q, %v", data, err)
+	}
+	if second.Direction != "unprocess" || second.Error == "" || second.OutputFile != "" {
+		t.Fatalf("second trace = %+v", second)
 	}
 }
 
@@ -132,3 +201,92 @@ func writeTestChain(t *testing.T, data []byte) string {
 	}
 	return path
 }
+
+func TestRunChainSelectsBranchOutput(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":2,"steps":[{"plugin":"base64","unprocess":true},{"plugin":"hex"}],
+		"branches":[{"name":"raw","after":1,"steps":[]},{"name":"sha","after":1,"steps":[{"plugin":"sha256"}]}]}`))
+	for _, tt := range []struct{ output, want string }{
+		{"main", "6869"},
+		{"raw", "hi"},
+	} {
+		var stdout, stderr bytes.Buffer
+		code := runChainWithArgs([]string{"-output", tt.output, chainPath, "aGk="}, strings.NewReader(""), &stdout, &stderr)
+		if code != 0 {
+			t.Fatalf("-output %s: exit = %d, stderr = %q", tt.output, code, stderr.String())
+		}
+		if got := stdout.String(); got != tt.want {
+			t.Fatalf("-output %s: stdout = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	if code := runChainWithArgs([]string{"-output", "md5", chainPath, "aGk="}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Fatalf("unknown output: exit = %d, want 2", code)
+	}
+	if !strings.Contains(stderr.String(), "outputs: main, raw, sha") {
+		t.Fatalf("stderr = %q, want the output names", stderr.String())
+	}
+}
+
+func TestRunChainInverse(t *testing.T) {
+	// The saved source "aGk" is unpadded Base64, so the inverse encodes raw.
+	chainPath := writeTestChain(t, []byte(`{"version":1,"source":"YUdr","steps":[{"plugin":"base64","unprocess":true},{"plugin":"hex"}]}`))
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "aGk"},
+		{[]string{"68656c6c6f"}, "aGVsbG8"},
+	} {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"-inverse", chainPath}, tt.args...)
+		if code := runChainWithArgs(args, strings.NewReader(""), &stdout, &stderr); code != 0 {
+			t.Fatalf("%v: exit = %d, stderr = %q", args, code, stderr.String())
+		}
+		if got := stdout.String(); got != tt.want {
+			t.Fatalf("%v: stdout = %q, want %q", args, got, tt.want)
+		}
+	}
+
+	chainPath = writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"sha256"}]}`))
+	var stdout, stderr bytes.Buffer
+	if code := runChainWithArgs([]string{"-inverse", chainPath}, strings.NewReader(""), &stdout, &stderr); code != 1 {
+		t.Fatalf("one-way chain: exit = %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "step 1 (sha256): sha256 is one-way") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestRunChainEmit(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":1,"params":[{"name":"key"}],"steps":[
+		{"plugin":"base64","unprocess":true},
+		{"plugin":"hmac","options":{"key":"${key}","alg":"sha1"}}
+	]}`))
+	var stdout, stderr bytes.Buffer
+	if code := runChainWithArgs([]string{"-emit", "python", "-p", "key=secret", chainPath}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if want := `return hmac.new(b"secret", data, hashlib.sha1).hexdigest().encode()`; !strings.Contains(stdout.String(), want) {
+		t.Errorf("stdout lacks %q:\n%s", want, stdout.String())
+	}
+
+	// -emit writes the inverse when combined with -inverse.
+	stdout.Reset()
+	if code := runChainWithArgs([]string{"-emit", "go", "-inverse", writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"hex","unprocess":true}]}`))}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("inverse: exit = %d, stderr = %q", code, stderr.String())
+	}
+	if want := "// step1 runs: deen hex\n"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("stdout lacks %q:\n%s", want, stdout.String())
+	}
+
+	stderr.Reset()
+	if code := runChainWithArgs([]string{"-emit", "go", writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"lzw"}]}`))}, strings.NewReader(""), &stdout, &stderr); code != 1 {
+		t.Errorf("untemplated plugin: exit = %d, want 1", code)
+	}
+	if got := stderr.String(); got != "deen: chain: cannot emit chain: step 1 (lzw): no Go template for lzw\n" {
+		t.Errorf("stderr = %q", got)
+	}
+	if code := runChainWithArgs([]string{"-emit", "rust", chainPath}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Errorf("unknown language: exit = %d, want 2", code)
+	}
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"context"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+)
+
+// chainTestResult is the outcome of one test case of a chain file.
+type chainTestResult struct {
+	file     string
+	name     string
+	err      error // nil when the case passed
+	skip     string
+	duration time.Duration
+}
+
+// runChainTestWithArgs implements `deen chain test`: it runs the test cases
+// embedded in chain files and reports them as TAP or JUnit XML.
+func runChainTestWithArgs(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("chain test", flag.ExitOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage of chain test:\n\n")
+		fmt.Fprintf(stderr, "Run the test cases embedded in saved chain files.\n\n")
+		fmt.Fprintf(stderr, "Examples:\n")
+		fmt.Fprintf(stderr, "  deen chain test recipes/*.json\n")
+		fmt.Fprintf(stderr, "  deen chain test -format junit recipes/*.json > report.xml\n\n")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "tap", "report format: tap or junit")
+	limits := registerLimitFlags(fs, pipeline.DefaultLimits())
+	fs.Parse(args)
+
+	if *format != "tap" && *format != "junit" {
+		fmt.Fprintf(stderr, "deen: chain test: unknown format %q\n", *format)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "deen: chain test: missing chain files")
+		return 2
+	}
+
+	var results []chainTestResult
+	for _, path := range fs.Args() {
+		results = append(results, runChainFileTests(path, limits())...)
+	}
+	var err error
+	if *format == "junit" {
+		err = writeJUnit(stdout, results)
+	} else {
+		err = writeTAP(stdout, results)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: chain test:", err)
+		return 1
+	}
+	for _, r := range results {
+		if r.err != nil {
+			return 1
+		}
+	}
+	return 0
+}
+
+// runChainFileTests runs the test cases of one chain file. A file that cannot
+// be loaded is reported as a single failed case.
+func runChainFileTests(path string, limits pipeline.Limits) []chainTestResult {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []chainTestResult{{file: path, name: "load", err: err}}
+	}
+	pipe := pipeline.New()
+	pipe.SetLimits(limits)
+	if err := pipe.LoadJSON(data); err != nil {
+		return []chainTestResult{{file: path, name: "load", err: err}}
+	}
+	tests := pipe.Tests()
+	if len(tests) == 0 {
+		return []chainTestResult{{file: path, name: "tests", skip: "no test cases"}}
+	}
+	results := make([]chainTestResult, 0, len(tests))
+	for i, tc := range tests {
+		name := tc.Name
+		if name == "" {
+			name = fmt.Sprintf("case %d", i+1)
+		}
+		start := time.Now()
+		err := pipe.RunTest(context.Background(), tc)
+		results = append(results, chainTestResult{file: path, name: name, err: err, duration: time.Since(start)})
+	}
+	return results
+}
+
+func writeTAP(w io.Writer, results []chainTestResult) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", len(results))
+	for i, r := range results {
+		switch {
+		case r.skip != "":
+			fmt.Fprintf(&b, "ok %d - %s: %s # SKIP %s\n", i+1, r.file, r.name, r.skip)
+		case r.err == nil:
+			fmt.Fprintf(&b, "ok %d - %s: %s\n", i+1, r.file, r.name)
+		default:
+			fmt.Fprintf(&b, "not ok %d - %s: %s\n", i+1, r.file, r.name)
+			fmt.Fprintf(&b, "  ---\n  message: %q\n  ...\n", r.err.Error())
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+func writeJUnit(w io.Writer, results []chainTestResult) error {
+	report := junitSuites{}
+	var elapsed []time.Duration
+	for _, r := range results {
+		if len(report.Suites) == 0 || report.Suites[len(report.Suites)-1].Name != r.file {
+			report.Suites = append(report.Suites, junitSuite{Name: r.file})
+			elapsed = append(elapsed, 0)
+		}
+		n := len(report.Suites) - 1
+		suite := &report.Suites[n]
+		c := junitCase{Name: r.name, ClassName: r.file, Time: junitSeconds(r.duration)}
+		switch {
+		case r.skip != "":
+			c.Skipped = &junitMessage{Message: r.skip}
+			suite.Skipped++
+			report.Skipped++
+		case r.err != nil:
+			c.Failure = &junitMessage{Message: r.err.Error()}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.Tests++
+		report.Tests++
+		elapsed[n] += r.duration
+		suite.Time = junitSeconds(elapsed[n])
+		suite.Cases = append(suite.Cases, c)
+	}
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
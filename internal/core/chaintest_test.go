@@ -0,0 +1,85 @@
+package core
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const testedChain = `{
+  "version": 1,
+  "params": [{"name": "alg", "default": "sha256"}],
+  "steps": [{"plugin": "base64", "unprocess": true}, {"plugin": "hmac", "options": {"key": "k", "alg": "${alg}"}}, {"plugin": "hex"}],
+  "tests": [
+    {"name": "default", "input": "ZGVlbg==", "sha256": "976cc616f22fd9e1464a9fe3eed87f864ac37d0941c021201d830445fff86689"},
+    {"name": "md5", "input": "ZGVlbg==", "params": {"alg": "md5"}, "contains": "3162313037"},
+    {"name": "bad input", "input": "!!!!", "error": "step 1 (base64)"}
+  ]
+}`
+
+func TestRunChainTestTAP(t *testing.T) {
+	good := writeTestChain(t, []byte(testedChain))
+	bad := writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"hex"}],"tests":[{"input":"A","output":"42"},{"name":"wrong","input":"A","output":"43"}]}`))
+	untested := writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"hex"}]}`))
+
+	var stdout, stderr bytes.Buffer
+	code := runChainWithArgs([]string{"test", good, bad, untested}, strings.NewReader(""), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("exit = %d, want 1; stderr = %q", code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"TAP version 13\n1..6\n",
+		"ok 1 - " + good + ": default\n",
+		"ok 3 - " + good + ": bad input\n",
+		"ok 4 - " + bad + ": case 1\n",
+		"not ok 5 - " + bad + ": wrong\n",
+		`message: "output \"41\", want \"43\""`,
+		"ok 6 - " + untested + ": tests # SKIP no test cases\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("TAP output lacks %q:\n%s", want, out)
+		}
+	}
+
+	stdout.Reset()
+	if code := runChainWithArgs([]string{"test", good}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d for passing tests:\n%s", code, stdout.String())
+	}
+}
+
+func TestRunChainTestJUnit(t *testing.T) {
+	bad := writeTestChain(t, []byte(`{"version":1,"steps":[{"plugin":"hex"}],"tests":[{"input":"A","output":"41"},{"input":"A","error":"boom"}]}`))
+	missing := bad + ".missing"
+
+	var stdout, stderr bytes.Buffer
+	code := runChainWithArgs([]string{"test", "-format", "junit", bad, missing}, strings.NewReader(""), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("exit = %d, want 1", code)
+	}
+	var report junitSuites
+	if err := xml.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("%v\n%s", err, stdout.String())
+	}
+	if report.Tests != 3 || report.Failures != 2 || len(report.Suites) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if c := report.Suites[0].Cases[1]; c.Failure == nil || !strings.Contains(c.Failure.Message, `want error containing "boom"`) {
+		t.Fatalf("case = %+v", c)
+	}
+	if c := report.Suites[1].Cases[0]; c.Name != "load" || c.Failure == nil {
+		t.Fatalf("missing file case = %+v", c)
+	}
+}
+
+func TestRunChainTestUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runChainWithArgs([]string{"test"}, strings.NewReader(""), &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "missing chain files") {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	stderr.Reset()
+	if code := runChainWithArgs([]string{"test", "-format", "xml", "x.json"}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+)
+
+// chainTraceRecord is one line of the `deen chain -trace` output.
+type chainTraceRecord struct {
+	pipeline.StepTrace
+	OutputFile string `json:"output_file,omitempty"`
+}
+
+// chainTracer returns the per-step callback for pipeline.Trace. It writes one
+// JSON line per step to w, when w is not nil, and each step output to a file
+// in dir, when dir is not empty.
+func chainTracer(w io.Writer, dir string) (func(pipeline.StepTrace, []byte) error, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	var enc *json.Encoder
+	if w != nil {
+		enc = json.NewEncoder(w)
+	}
+	return func(t pipeline.StepTrace, out []byte) error {
+		rec := chainTraceRecord{StepTrace: t}
+		if dir != "" && t.Error == "" {
+			rec.OutputFile = filepath.Join(dir, fmt.Sprintf("%02d-%s-%s.bin", t.Step, t.Plugin, t.Direction))
+			if err := os.WriteFile(rec.OutputFile, out, 0o644); err != nil {
+				return err
+			}
+		}
+		if enc == nil {
+			return nil
+		}
+		return enc.Encode(rec)
+	}, nil
+}
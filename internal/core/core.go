@@ -7,7 +7,9 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 
+	"github.com/takeshixx/deen/internal/pipeline"
 	"github.com/takeshixx/deen/internal/plugins"
 	"github.com/takeshixx/deen/pkg/helpers"
 	"github.com/takeshixx/deen/pkg/types"
@@ -32,6 +34,7 @@ var printPluginsJSONPtr *bool
 var versionPtr *bool
 var filePtr *string
 var newlinePtr *bool
+var pluginDirPtr *string
 
 // ParseFlags parses the global (pre-subcommand) flags and handles the
 // informational ones (-l, -lj, -version) that exit immediately.
@@ -41,14 +44,17 @@ func ParseFlags() {
 	versionPtr = flag.Bool("version", false, "print version")
 	filePtr = flag.String("file", "", "read input from file")
 	newlinePtr = flag.Bool("N", false, "append a trailing newline to the output")
+	pluginDirPtr = flag.String("plugin-dir", "", "search this directory for deen-plugin-* executables and *.wasm plugins first")
 	flag.Usage = printCLIUsage
 	flag.Parse()
 
 	switch {
 	case *printPluginsPtr:
+		LoadExternalPlugins()
 		plugins.PrintAvailable(false)
 		os.Exit(0)
 	case *printPluginsJSONPtr:
+		LoadExternalPlugins()
 		plugins.PrintAvailable(true)
 		os.Exit(0)
 	case *versionPtr:
@@ -61,6 +67,29 @@ func ParseFlags() {
 	}
 }
 
+var loadExternalOnce sync.Once
+
+// LoadExternalPlugins registers deen-plugin-* executables from -plugin-dir,
+// DEEN_PLUGIN_PATH and, when DEEN_PLUGIN_SEARCH_PATH asks for it, PATH, and
+// sandboxed *.wasm modules from -plugin-dir and DEEN_PLUGIN_PATH. Broken
+// plugins are reported and skipped. Loading runs every executable and
+// compiles every module, so it happens once and only for commands that may
+// need them: listings, chains, the MCP server, the GUI and plugin names that
+// are not built in.
+func LoadExternalPlugins() {
+	loadExternalOnce.Do(func() {
+		var dir string
+		if pluginDirPtr != nil {
+			dir = *pluginDirPtr
+		}
+		errs := plugins.LoadExternal(plugins.ExternalDirs(dir))
+		errs = append(errs, plugins.LoadWasm(plugins.WasmDirs(dir), plugins.DefaultWasmConfig())...)
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, "deen: plugin:", err)
+		}
+	})
+}
+
 func printCLIUsage() {
 	out := flag.CommandLine.Output()
 	fmt.Fprintln(out, "deen - encode, decode, hash, compress and inspect data")
@@ -69,9 +98,15 @@ func printCLIUsage() {
 	fmt.Fprintln(out, "  deen [global flags] <plugin> [plugin flags] [input]")
 	fmt.Fprintln(out, "  deen [global flags] .<plugin> [plugin flags] [input]")
 	fmt.Fprintln(out, "  deen chain [chain flags] <chain.json> [input]")
+	fmt.Fprintln(out, "  deen chain test [test flags] <chain.json>...")
+	fmt.Fprintln(out, "  deen chain import-cyberchef [flags] <recipe>")
+	fmt.Fprintln(out, "  deen chain export-cyberchef [flags] <chain.json>")
+	fmt.Fprintln(out, "  deen run [run flags] '<step> | <step> ...' [input]")
+	fmt.Fprintln(out, "  deen map [map flags] '<step> | <step> ...' [input]")
+	fmt.Fprintln(out, "  deen sweep [sweep flags] -p <option>=<values> '<step>' [input]")
 	fmt.Fprintln(out, "  deen inspect [inspect flags] [input]")
 	fmt.Fprintln(out, "  deen detect [detect flags] [input]")
-	fmt.Fprintln(out, "  deen mcp serve")
+	fmt.Fprintln(out, "  deen mcp serve [flags]")
 	fmt.Fprintln(out, "  deen serve [serve flags]")
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "Run a plugin by name to transform data. Prefix a reversible plugin with '.'")
@@ -81,15 +116,26 @@ func printCLIUsage() {
 	fmt.Fprintln(out, "  Input is read from remaining arguments, stdin, or -file.")
 	fmt.Fprintln(out, "  Output is raw by default, without a trailing newline; pass -N for terminals.")
 	fmt.Fprintln(out)
+	fmt.Fprintln(out, "External plugins:")
+	fmt.Fprintln(out, "  Executables named deen-plugin-<name> in -plugin-dir or $DEEN_PLUGIN_PATH")
+	fmt.Fprintln(out, "  ($PATH too with DEEN_PLUGIN_SEARCH_PATH=1), and sandboxed WASI modules")
+	fmt.Fprintln(out, "  (*.wasm) in -plugin-dir or $DEEN_PLUGIN_PATH, are listed and run like")
+	fmt.Fprintln(out, "  built-in plugins.")
+	fmt.Fprintln(out)
 	fmt.Fprintln(out, "Common commands:")
 	fmt.Fprintln(out, "  deen -l                         list plugins by category")
 	fmt.Fprintln(out, "  deen -lj                        list plugins as JSON")
 	fmt.Fprintln(out, "  deen base64 test                encode text as Base64")
 	fmt.Fprintln(out, "  deen .base64 dGVzdA==           decode Base64")
 	fmt.Fprintln(out, "  deen chain saved.json           run a saved Web/GUI chain")
+	fmt.Fprintln(out, "  deen chain test recipes/*.json  run the test cases of saved chains")
+	fmt.Fprintln(out, "  deen run '.base64 | .gzip'      run an inline chain expression")
+	fmt.Fprintln(out, "  deen map .base64 < blobs.log    run a chain on each line of the input")
+	fmt.Fprintln(out, "  deen sweep -p value=0..255 xor  rank the outputs of every XOR byte")
 	fmt.Fprintln(out, "  deen inspect -file sample.txt   inspect data as structured JSON")
 	fmt.Fprintln(out, "  deen detect -file sample.txt    suggest likely decode/inspection steps")
 	fmt.Fprintln(out, "  deen mcp serve                  run a stdio MCP server for agents")
+	fmt.Fprintln(out, "  deen selftest                   check plugin round trips and test vectors")
 	fmt.Fprintln(out, "  printf secret | deen sha256     hash stdin")
 	fmt.Fprintln(out, "  deen base64 -h                  show plugin-specific flags")
 	fmt.Fprintln(out, "  deen serve --port 9090          serve the WebAssembly UI")
@@ -98,7 +144,8 @@ func printCLIUsage() {
 	flag.PrintDefaults()
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "Use 'deen <plugin> -h' for plugin flags, 'deen chain -h' for saved chains,")
-	fmt.Fprintln(out, "'deen inspect -h'/'deen detect -h' for agent-friendly JSON, or")
+	fmt.Fprintln(out, "'deen run -h' for inline chains, 'deen inspect -h'/'deen detect -h' for")
+	fmt.Fprintln(out, "agent-friendly JSON, or")
 	fmt.Fprintln(out, "'deen serve -h' for web server flags.")
 }
 
@@ -108,9 +155,22 @@ func RunCLI() int {
 	if cmd == "serve" {
 		return runServe()
 	}
+	switch cmd {
+	case "chain", "run", "map", "sweep", "mcp":
+		LoadExternalPlugins()
+	}
 	if cmd == "chain" {
 		return runChain()
 	}
+	if cmd == "run" {
+		return runRun()
+	}
+	if cmd == "map" {
+		return runMap()
+	}
+	if cmd == "sweep" {
+		return runSweep()
+	}
 	if cmd == "inspect" {
 		return runInspect()
 	}
@@ -120,7 +180,14 @@ func RunCLI() int {
 	if cmd == "mcp" {
 		return runMCP()
 	}
+	if cmd == "selftest" {
+		return runSelftest()
+	}
 	plugin, unprocess, ok := plugins.Resolve(cmd)
+	if !ok {
+		LoadExternalPlugins()
+		plugin, unprocess, ok = plugins.Resolve(cmd)
+	}
 	if !ok {
 		fmt.Fprintf(os.Stderr, "deen: invalid command: %q (use -l to list plugins)\n", cmd)
 		return 2
@@ -143,10 +210,20 @@ func runPlugin(plugin *types.DeenPlugin, unprocess bool, cmd string) int {
 	fs.Usage = usageFor(fs, plugin)
 	newline := fs.Bool("N", false, "append a trailing newline to the output")
 	fileFlag := fs.String("file", "", "read input from file")
+	regionFlag := fs.String("region", "", "transform only this part of the input: OFFSET:LENGTH, re:REGEX or jq:PATH")
 	if plugin.RegisterFlags != nil {
 		plugin.RegisterFlags(fs)
 	}
 	fs.Parse(helpers.RemoveBeforeSubcommand(os.Args, cmd))
+	if err := plugin.ValidateFlags(fs); err != nil {
+		fmt.Fprintf(os.Stderr, "deen: %s: %s\n", plugin.Name, err)
+		return 2
+	}
+	transform, err := pipeline.RegionTransform(*regionFlag, transform)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "deen: %s: %s\n", plugin.Name, err)
+		return 2
+	}
 
 	reader, cleanup, err := selectInput(*fileFlag, fs.Args())
 	if err != nil {
@@ -207,6 +284,66 @@ func usageFor(fs *flag.FlagSet, plugin *types.DeenPlugin) func() {
 		if plugin.Description != "" {
 			fmt.Fprintf(os.Stderr, "%s\n\n", plugin.Description)
 		}
-		fs.PrintDefaults()
+		printPluginFlags(os.Stderr, fs, plugin)
+	}
+}
+
+// printPluginFlags prints the flags of fs like flag.PrintDefaults, using the
+// plugin's option schema for descriptions, allowed values and ranges.
+func printPluginFlags(w io.Writer, fs *flag.FlagSet, plugin *types.DeenPlugin) {
+	options := make(map[string]pipeline.Option)
+	for _, opt := range pipeline.PluginOptions(plugin.Name) {
+		options[opt.Name] = opt
 	}
+	fs.VisitAll(func(f *flag.Flag) {
+		typeName, usage := flag.UnquoteUsage(f)
+		if spec, ok := plugin.OptionSpec(f.Name); ok && spec.Hidden {
+			return
+		}
+		opt, described := options[f.Name]
+		if described {
+			usage = opt.Description
+		}
+		line := "  -" + f.Name
+		if typeName != "" {
+			line += " " + typeName
+		}
+		fmt.Fprintf(w, "%s\n    \t%s", line, strings.ReplaceAll(usage, "\n", "\n    \t"))
+
+		var details []string
+		if len(opt.Choices) > 0 {
+			choices := make([]string, len(opt.Choices))
+			for i, c := range opt.Choices {
+				if c == "" {
+					c = `""`
+				}
+				choices[i] = c
+			}
+			if opt.Open {
+				details = append(details, "e.g. "+strings.Join(choices, ", "))
+			} else {
+				details = append(details, "one of: "+strings.Join(choices, ", "))
+			}
+		}
+		if opt.Range != nil {
+			details = append(details, fmt.Sprintf("range: %d-%d", opt.Range.Min, opt.Range.Max))
+		}
+		if opt.Secret {
+			details = append(details, "secret")
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			if typeName == "string" {
+				details = append(details, fmt.Sprintf("default %q", f.DefValue))
+			} else {
+				details = append(details, "default "+f.DefValue)
+			}
+		}
+		if len(details) > 0 {
+			fmt.Fprintf(w, " (%s)", strings.Join(details, "; "))
+		}
+		if opt.HelpURL != "" {
+			fmt.Fprintf(w, "\n    \tSee %s", opt.HelpURL)
+		}
+		fmt.Fprintln(w)
+	})
 }
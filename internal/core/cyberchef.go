@@ -0,0 +1,186 @@
+package core
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+)
+
+// runChainImportCyberChefWithArgs implements `deen chain import-cyberchef`:
+// it translates a CyberChef recipe into a chain file.
+func runChainImportCyberChefWithArgs(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("chain import-cyberchef", flag.ExitOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage of chain import-cyberchef:\n\n")
+		fmt.Fprintf(stderr, "Translate a CyberChef recipe into a deen chain file. The recipe is a file, the\n")
+		fmt.Fprintf(stderr, "recipe itself in JSON or Chef format, a CyberChef URL, or - for stdin.\n")
+		fmt.Fprintf(stderr, "Operations that could not be translated are reported on stderr.\n\n")
+		fmt.Fprintf(stderr, "Examples:\n")
+		fmt.Fprintf(stderr, "  deen chain import-cyberchef recipe.json > chain.json\n")
+		fmt.Fprintf(stderr, "  deen chain import-cyberchef -o chain.json \"From_Base64('A-Za-z0-9+/=',true)Gunzip()\"\n\n")
+		fs.PrintDefaults()
+	}
+	output := fs.String("o", "", "write the chain file to this file instead of stdout")
+	strict := fs.Bool("strict", false, "fail when an operation could not be translated")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "deen: chain import-cyberchef: want exactly one recipe")
+		return 2
+	}
+	recipe, err := readRecipeArg(fs.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: chain import-cyberchef:", err)
+		return 1
+	}
+	steps, notes, err := pipeline.ImportCyberChef(recipe)
+	failed := reportCyberChefNotes(stderr, "import-cyberchef", notes)
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: chain import-cyberchef:", err)
+		return 1
+	}
+	pipe := pipeline.New()
+	if err := pipe.LoadSteps(steps); err != nil {
+		fmt.Fprintln(stderr, "deen: chain import-cyberchef:", err)
+		return 1
+	}
+	data, err := pipe.ExportJSONWithoutSource()
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: chain import-cyberchef:", err)
+		return 1
+	}
+	if err := writeOutputFile(*output, stdout, append(data, '\n')); err != nil {
+		fmt.Fprintln(stderr, "deen: chain import-cyberchef:", err)
+		return 1
+	}
+	if *strict && failed {
+		return 1
+	}
+	return 0
+}
+
+// runChainExportCyberChefWithArgs implements `deen chain export-cyberchef`:
+// it translates the main chain, or the chain of one output, of a chain file
+// into a CyberChef recipe.
+func runChainExportCyberChefWithArgs(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("chain export-cyberchef", flag.ExitOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage of chain export-cyberchef:\n\n")
+		fmt.Fprintf(stderr, "Translate the main chain of a saved chain file into a CyberChef recipe.\n")
+		fmt.Fprintf(stderr, "Steps and branches that could not be translated are reported on stderr;\n")
+		fmt.Fprintf(stderr, "-output exports the chain that produces a branch instead.\n\n")
+		fmt.Fprintf(stderr, "Examples:\n")
+		fmt.Fprintf(stderr, "  deen chain export-cyberchef chain.json > recipe.json\n")
+		fmt.Fprintf(stderr, "  deen chain export-cyberchef -output preview chain.json\n")
+		fmt.Fprintf(stderr, "  deen chain export-cyberchef -format url -p key=00112233 decrypt.json\n\n")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "json", "recipe format: json, chef or url")
+	output := fs.String("o", "", "write the recipe to this file instead of stdout")
+	selected := fs.String("output", pipeline.MainOutput, "name of the output to export: "+pipeline.MainOutput+" or a branch name")
+	strict := fs.Bool("strict", false, "fail when a step could not be translated")
+	var params stringList
+	fs.Var(&params, "p", "bind a chain parameter as name=value (repeatable)")
+	fs.Parse(args)
+
+	if *format != "json" && *format != "chef" && *format != "url" {
+		fmt.Fprintf(stderr, "deen: chain export-cyberchef: unknown format %q\n", *format)
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "deen: chain export-cyberchef: want exactly one chain file")
+		return 2
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "deen: chain export-cyberchef: failed to read chain file: %s\n", err)
+		return 1
+	}
+	pipe := pipeline.New()
+	if err := pipe.LoadJSON(data); err != nil {
+		fmt.Fprintf(stderr, "deen: chain export-cyberchef: failed to import chain: %s\n", err)
+		return 1
+	}
+	if err := pipe.BindParams(params); err != nil {
+		fmt.Fprintln(stderr, "deen: chain export-cyberchef:", err)
+		return 2
+	}
+	if *selected != pipeline.MainOutput {
+		if err := pipe.SelectOutput(*selected); err != nil {
+			fmt.Fprintln(stderr, "deen: chain export-cyberchef:", err)
+			return 2
+		}
+	}
+	ops, notes, err := pipe.ExportCyberChef()
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: chain export-cyberchef:", err)
+		return 1
+	}
+	failed := reportCyberChefNotes(stderr, "export-cyberchef", notes)
+	if len(ops) == 0 {
+		fmt.Fprintln(stderr, "deen: chain export-cyberchef: no step of the chain could be translated")
+		return 1
+	}
+	var recipe []byte
+	switch *format {
+	case "json":
+		recipe, err = pipeline.FormatCyberChefJSON(ops)
+	case "chef":
+		var s string
+		s, err = pipeline.FormatCyberChefChef(ops)
+		recipe = []byte(s)
+	case "url":
+		var s string
+		s, err = pipeline.FormatCyberChefURL(ops)
+		recipe = []byte(s + "\n")
+	}
+	if err == nil {
+		err = writeOutputFile(*output, stdout, recipe)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: chain export-cyberchef:", err)
+		return 1
+	}
+	if *strict && failed {
+		return 1
+	}
+	return 0
+}
+
+// readRecipeArg returns the recipe an argument names: the contents of a
+// file, stdin for -, or the argument itself.
+func readRecipeArg(arg string, stdin io.Reader) (string, error) {
+	if arg == "-" {
+		data, err := io.ReadAll(stdin)
+		return string(data), err
+	}
+	if data, err := os.ReadFile(arg); err == nil {
+		return string(data), nil
+	}
+	return arg, nil
+}
+
+// reportCyberChefNotes writes the notes of a translation to w and reports
+// whether anything was skipped.
+func reportCyberChefNotes(w io.Writer, cmd string, notes []pipeline.CyberChefNote) bool {
+	skipped := false
+	for _, n := range notes {
+		fmt.Fprintf(w, "deen: chain %s: %s\n", cmd, n)
+		skipped = skipped || n.Skipped
+	}
+	return skipped
+}
+
+// writeOutputFile writes data to path, or to stdout when path is empty.
+func writeOutputFile(path string, stdout io.Writer, data []byte) error {
+	if path == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunChainImportCyberChef(t *testing.T) {
+	recipe := `From_Base64('A-Za-z0-9+/=',true,false)Render_Image('Raw')Gunzip()`
+	var stdout, stderr bytes.Buffer
+	if code := runChainWithArgs([]string{"import-cyberchef", recipe}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	want := `{
+  "version": 1,
+  "steps": [
+    {
+      "plugin": "base64",
+      "unprocess": true
+    },
+    {
+      "plugin": "gzip",
+      "unprocess": true
+    }
+  ]
+}
+`
+	if stdout.String() != want {
+		t.Errorf("stdout =\n%s\nwant\n%s", stdout.String(), want)
+	}
+	if got := stderr.String(); got != "deen: chain import-cyberchef: 2. Render Image: skipped: no deen equivalent\n" {
+		t.Errorf("stderr = %q", got)
+	}
+
+	// The written chain runs like any other.
+	chainPath := writeTestChain(t, stdout.Bytes())
+	stdout.Reset()
+	stderr.Reset()
+	if code := runChainWithArgs([]string{chainPath, "H4sIAAAAAAAAA8vIBACsKpPYAgAAAA=="}, strings.NewReader(""), &stdout, &stderr); code != 0 || stdout.String() != "hi" {
+		t.Fatalf("chain: exit = %d, stdout = %q, stderr = %q", code, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := runChainWithArgs([]string{"import-cyberchef", "-strict", "-", recipe}, strings.NewReader(recipe), &stdout, &stderr); code != 2 {
+		t.Errorf("two recipes: exit = %d, want 2", code)
+	}
+	if code := runChainWithArgs([]string{"import-cyberchef", "-strict", "-"}, strings.NewReader(recipe), &stdout, &stderr); code != 1 {
+		t.Errorf("-strict with a skipped operation: exit = %d, want 1", code)
+	}
+	if code := runChainWithArgs([]string{"import-cyberchef", "Magic(3,false,false,'')"}, strings.NewReader(""), &stdout, &stderr); code != 1 {
+		t.Errorf("untranslatable recipe: exit = %d, want 1", code)
+	}
+}
+
+func TestRunChainExportCyberChef(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":1,"params":[{"name":"key","secret":true}],"steps":[
+		{"plugin":"base64","unprocess":true},
+		{"plugin":"hmac","options":{"key":"${key}","alg":"sha1"}},
+		{"plugin":"lzw"}
+	]}`))
+	var stdout, stderr bytes.Buffer
+	if code := runChainWithArgs([]string{"export-cyberchef", "-format", "chef", "-p", "key=k", chainPath}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if want := "From_Base64('A-Za-z0-9+/=',true,false)\nHMAC({'option':'UTF8','string':'k'},'SHA1')\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if got := stderr.String(); got != "deen: chain export-cyberchef: 3. lzw: skipped: no CyberChef equivalent\n" {
+		t.Errorf("stderr = %q", got)
+	}
+
+	out := filepath.Join(t.TempDir(), "recipe.txt")
+	stdout.Reset()
+	stderr.Reset()
+	if code := runChainWithArgs([]string{"export-cyberchef", "-format", "url", "-o", out, "-p", "key=k", chainPath}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if data, err := os.ReadFile(out); err != nil || string(data) != "https://gchq.github.io/CyberChef/#recipe=From_Base64('A-Za-z0-9%2B/%3D',true,false)HMAC(%7B'option':'UTF8','string':'k'%7D,'SHA1')\n" {
+		t.Errorf("url recipe = %q, %v", data, err)
+	}
+
+	if code := runChainWithArgs([]string{"export-cyberchef", chainPath}, strings.NewReader(""), &stdout, &stderr); code != 1 {
+		t.Errorf("unbound parameter: exit = %d, want 1", code)
+	}
+	if code := runChainWithArgs([]string{"export-cyberchef", "-format", "yaml", chainPath}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Errorf("unknown format: exit = %d, want 2", code)
+	}
+}
+
+func TestRunChainExportCyberChefBranches(t *testing.T) {
+	chainPath := writeTestChain(t, []byte(`{"version":2,"steps":[{"plugin":"base64"}],
+		"branches":[{"name":"hex","after":1,"steps":[{"plugin":"hex"}]}]}`))
+	var stdout, stderr bytes.Buffer
+	if code := runChainWithArgs([]string{"export-cyberchef", "-format", "chef", "-strict", chainPath}, strings.NewReader(""), &stdout, &stderr); code != 1 {
+		t.Fatalf("exit = %d, want 1 for the dropped branch", code)
+	}
+	if got := stderr.String(); !strings.Contains(got, "2. hex: skipped: branch of 1 step(s) after step 1") {
+		t.Errorf("stderr = %q", got)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := runChainWithArgs([]string{"export-cyberchef", "-format", "chef", "-output", "hex", chainPath}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if want := "To_Base64('A-Za-z0-9+/=')\nTo_Hex('None',0)\n"; stdout.String() != want || stderr.Len() != 0 {
+		t.Errorf("stdout = %q, stderr = %q, want %q", stdout.String(), stderr.String(), want)
+	}
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+)
+
+// registerLimitFlags registers the execution limit flags shared by chain, run,
+// mcp serve and serve. The returned function reports the parsed limits,
+// starting from def.
+func registerLimitFlags(fs *flag.FlagSet, def pipeline.Limits) func() pipeline.Limits {
+	l := def
+	fs.DurationVar(&l.StepTimeout, "timeout", def.StepTimeout, "per-step time budget, e.g. 30s (0 disables)")
+	fs.DurationVar(&l.ChainTimeout, "chain-timeout", def.ChainTimeout, "time budget for the whole chain (0 disables)")
+	fs.Var((*byteSize)(&l.MaxOutput), "max-output", "maximum output bytes per step, e.g. 64M (0 disables)")
+	fs.Float64Var(&l.MaxExpansion, "max-expansion", def.MaxExpansion, "maximum output bytes per input byte for a step (0 disables)")
+	return func() pipeline.Limits { return l }
+}
+
+// byteSize is a flag value for byte counts with an optional binary K, M or G
+// suffix.
+type byteSize int64
+
+func (b *byteSize) String() string {
+	n := int64(*b)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}} {
+		if n != 0 && n%unit.size == 0 {
+			return strconv.FormatInt(n/unit.size, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func (b *byteSize) Set(s string) error {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q", s)
+	}
+	*b = byteSize(n * mult)
+	return nil
+}
@@ -0,0 +1,32 @@
+package core
+
+import "testing"
+
+func TestByteSize(t *testing.T) {
+	for in, want := range map[string]int64{
+		"0":     0,
+		"512":   512,
+		"4k":    4 << 10,
+		"64M":   64 << 20,
+		"64MiB": 64 << 20,
+		"2GB":   2 << 30,
+	} {
+		var b byteSize
+		if err := b.Set(in); err != nil {
+			t.Fatalf("Set(%q): %v", in, err)
+		}
+		if int64(b) != want {
+			t.Errorf("Set(%q) = %d, want %d", in, b, want)
+		}
+	}
+	for _, in := range []string{"", "M", "-1", "12T", "1.5M"} {
+		var b byteSize
+		if err := b.Set(in); err == nil {
+			t.Errorf("Set(%q) succeeded", in)
+		}
+	}
+	b := byteSize(256 << 20)
+	if got := b.String(); got != "256M" {
+		t.Errorf("String() = %q, want 256M", got)
+	}
+}
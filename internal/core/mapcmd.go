@@ -0,0 +1,86 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+	"github.com/takeshixx/deen/pkg/helpers"
+)
+
+func runMap() int {
+	return runMapWithArgs(helpers.RemoveBeforeSubcommand(os.Args, "map"), os.Stdin, os.Stdout, os.Stderr)
+}
+
+func runMapWithArgs(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("map", flag.ExitOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage of map:\n\n")
+		fmt.Fprintf(stderr, "Split the input into pieces, run a chain expression on each piece and put\n")
+		fmt.Fprintf(stderr, "the results back in place. Pieces are lines, the text between delimiters,\n")
+		fmt.Fprintf(stderr, "regex matches (or their first capture group) or JSON values selected by a\n")
+		fmt.Fprintf(stderr, "JSONPath such as $.items[*].token. This is the command form of map steps.\n\n")
+		fmt.Fprintf(stderr, "Examples:\n")
+		fmt.Fprintf(stderr, "  deen map '.base64 | .gzip' < blobs.log\n")
+		fmt.Fprintf(stderr, "  deen map -split regex -pattern 'session=([^;\\s]+)' '.url | .base64' < headers.txt\n")
+		fmt.Fprintf(stderr, "  deen map -split json -pattern '$..token' .base64 < tokens.json\n\n")
+		fs.PrintDefaults()
+	}
+	split := fs.String("split", pipeline.SplitLines, "how to split the input: "+strings.Join(pipeline.SplitModes, ", "))
+	pattern := fs.String("pattern", "", "delimiter, regex or JSONPath of the split")
+	region := fs.String("region", "", "map over only this part of the input: OFFSET:LENGTH, re:REGEX or jq:PATH")
+	inputFile := fs.String("file", "", "read input from file")
+	newline := fs.Bool("N", false, "append a trailing newline to the output")
+	limits := registerLimitFlags(fs, pipeline.Limits{})
+	fs.Parse(args)
+
+	args = fs.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "deen: map: missing chain expression")
+		return 2
+	}
+	steps, err := pipeline.ParseMapChain(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: map:", err)
+		return 2
+	}
+	pipe := pipeline.New()
+	pipe.SetLimits(limits())
+	m := &pipeline.Map{Split: *split, Pattern: *pattern, Steps: steps}
+	if err := pipe.LoadSteps([]pipeline.Step{{Plugin: pipeline.MapPlugin, Map: m, Region: *region}}); err != nil {
+		fmt.Fprintln(stderr, "deen: map:", err)
+		return 2
+	}
+
+	r, cleanup, err := selectChainInput(*inputFile, true, args[1:], stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: map:", err)
+		return 1
+	}
+	defer cleanup()
+
+	out := bufio.NewWriter(stdout)
+	err = pipe.StreamFrom(context.Background(), r, out)
+	if ferr := out.Flush(); err == nil && ferr != nil {
+		err = ferr
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: map:", err)
+		return 1
+	}
+
+	globalNewline := newlinePtr != nil && *newlinePtr
+	if *newline || globalNewline {
+		if _, err := io.WriteString(stdout, "\n"); err != nil {
+			fmt.Fprintln(stderr, "deen:", err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -78,6 +79,31 @@ type mcpRunChainArgs struct {
 	Text      string `json:"text,omitempty"`
 	Base64    string `json:"base64,omitempty"`
 	ChainJSON string `json:"chain_json"`
+	Trace     bool   `json:"trace,omitempty"`
+}
+
+// mcpChainResult is the run_chain result when a trace was requested.
+type mcpChainResult struct {
+	inspectResponse
+	Trace []pipeline.StepTrace `json:"trace"`
+}
+
+type mcpSweepArgs struct {
+	Text      string            `json:"text,omitempty"`
+	Base64    string            `json:"base64,omitempty"`
+	Plugin    string            `json:"plugin"`
+	Unprocess bool              `json:"unprocess,omitempty"`
+	Options   map[string]string `json:"options,omitempty"`
+	Vary      []string          `json:"vary"`
+	Score     string            `json:"score,omitempty"`
+	Crib      string            `json:"crib,omitempty"`
+	Top       int               `json:"top,omitempty"`
+}
+
+// mcpSweepResult is the sweep result: the best runs out of Runs.
+type mcpSweepResult struct {
+	Runs    int                    `json:"runs"`
+	Results []pipeline.SweepResult `json:"results"`
 }
 
 type mcpSearchArgs struct {
@@ -99,6 +125,7 @@ type mcpStoredResult struct {
 type mcpSession struct {
 	results map[string]mcpStoredResult
 	nextID  int
+	limits  pipeline.Limits
 }
 
 func runMCP() int {
@@ -111,24 +138,32 @@ func runMCPWithArgs(args []string, stdin io.Reader, stdout, stderr io.Writer) in
 	fs.Usage = func() {
 		fmt.Fprintln(stderr, "Usage of mcp:")
 		fmt.Fprintln(stderr)
-		fmt.Fprintln(stderr, "  deen mcp serve")
+		fmt.Fprintln(stderr, "  deen mcp serve [flags]")
 		fmt.Fprintln(stderr)
 		fmt.Fprintln(stderr, "Run a stdio MCP server for local, agent-friendly deen tools.")
+		fmt.Fprintln(stderr)
+		fs.PrintDefaults()
 	}
+	limits := registerLimitFlags(fs, pipeline.DefaultLimits())
 	fs.Parse(args)
-	if fs.NArg() != 1 || fs.Arg(0) != "serve" {
+	if fs.NArg() < 1 || fs.Arg(0) != "serve" {
 		fs.Usage()
 		return 2
 	}
-	if err := serveMCP(stdin, stdout); err != nil {
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	if err := serveMCP(stdin, stdout, limits()); err != nil {
 		fmt.Fprintln(stderr, "deen: mcp:", err)
 		return 1
 	}
 	return 0
 }
 
-func serveMCP(stdin io.Reader, stdout io.Writer) error {
-	session := &mcpSession{results: map[string]mcpStoredResult{}}
+func serveMCP(stdin io.Reader, stdout io.Writer, limits pipeline.Limits) error {
+	session := &mcpSession{results: map[string]mcpStoredResult{}, limits: limits}
 	scanner := bufio.NewScanner(stdin)
 	// MCP messages are small JSON-RPC requests; allow comfortably larger tool
 	// payloads without turning the scanner into an unbounded read.
@@ -257,10 +292,10 @@ func (s *mcpSession) mcpResources() []map[string]string {
 func (s *mcpSession) readMCPResource(uri string) (any, *mcpError) {
 	switch {
 	case uri == "deen://plugins":
-		return mcpResourceText(uri, plugins.UICatalog()), nil
+		return mcpResourceText(uri, mcpPluginCatalog(plugins.UICatalog())), nil
 	case strings.HasPrefix(uri, "deen://plugins/"):
 		name := strings.TrimPrefix(uri, "deen://plugins/")
-		for _, info := range plugins.UICatalog() {
+		for _, info := range mcpPluginCatalog(plugins.UICatalog()) {
 			if info.Name == name {
 				return mcpResourceText(uri, info), nil
 			}
@@ -412,7 +447,7 @@ func mcpTools() []mcpTool {
 		},
 		{
 			Name:        "transform",
-			Description: "Run one deen plugin transform locally on text or base64 input. Use unprocess=true for decode/reverse mode.",
+			Description: "Run one deen plugin transform locally on text or base64 input. Use unprocess=true for decode/reverse mode. Valid options for each plugin are described by OptionsSchema in list_plugins and search_plugins.",
 			InputSchema: map[string]any{
 				"type":     "object",
 				"required": []string{"plugin"},
@@ -435,6 +470,30 @@ func mcpTools() []mcpTool {
 					"text":       map[string]any{"type": "string"},
 					"base64":     map[string]any{"type": "string"},
 					"chain_json": map[string]any{"type": "string"},
+					"trace":      map[string]any{"type": "boolean", "description": "Also return a per-step trace with sizes, hashes, timing and errors."},
+				},
+			},
+		},
+		{
+			Name:        "sweep",
+			Description: "Run one deen plugin over every combination of option values and rank the outputs by printability, entropy, magic bytes or a crib, e.g. to find an unknown XOR byte (vary value=0x00..0xff) or LZW bit order (vary order=0,1). Each result lists the varied options; add them to the fixed options to reproduce it with transform.",
+			InputSchema: map[string]any{
+				"type":     "object",
+				"required": []string{"plugin", "vary"},
+				"properties": map[string]any{
+					"text":      map[string]any{"type": "string"},
+					"base64":    map[string]any{"type": "string"},
+					"plugin":    map[string]any{"type": "string"},
+					"unprocess": map[string]any{"type": "boolean"},
+					"options":   map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}, "description": "Options every run uses."},
+					"vary": map[string]any{
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "Options to vary as name=values: comma separated values and integer ranges such as 0x00..0xff, or * for every choice of an option.",
+					},
+					"score": map[string]any{"type": "string", "enum": pipeline.ScoreModes, "description": "How to rank outputs; auto ranks crib hits, then magic bytes, then printable text."},
+					"crib":  map[string]any{"type": "string", "description": "Text a correct output contains."},
+					"top":   map[string]any{"type": "integer", "minimum": 1, "description": "Number of results to return, 10 by default."},
 				},
 			},
 		},
@@ -502,7 +561,7 @@ func (s *mcpSession) callMCPTool(params mcpCallParams) (any, *mcpError) {
 		if err := json.Unmarshal(params.Arguments, &args); err != nil {
 			return nil, mcpInvalidParams("invalid transform arguments")
 		}
-		data, err := mcpTransform(args)
+		data, err := mcpTransform(args, s.limits)
 		if err != nil {
 			return mcpToolResult(err.Error(), map[string]any{"error": err.Error()}, true), nil
 		}
@@ -514,21 +573,38 @@ func (s *mcpSession) callMCPTool(params mcpCallParams) (any, *mcpError) {
 		if err := json.Unmarshal(params.Arguments, &args); err != nil {
 			return nil, mcpInvalidParams("invalid run_chain arguments")
 		}
-		data, err := mcpRunChain(args)
+		data, trace, err := mcpRunChain(args, s.limits)
 		if err != nil {
-			return mcpToolResult(err.Error(), map[string]any{"error": err.Error()}, true), nil
+			failure := map[string]any{"error": err.Error()}
+			if args.Trace {
+				failure["trace"] = trace
+			}
+			return mcpToolResult(err.Error(), failure, true), nil
 		}
 		result := inspectData(data, defaultAgentPreviewBytes)
 		s.attachResultRef(&result, data, "chain result")
+		if args.Trace {
+			return mcpToolResult("Chain complete.", mcpChainResult{inspectResponse: result, Trace: trace}, false), nil
+		}
 		return mcpToolResult("Chain complete.", result, false), nil
+	case "sweep":
+		var args mcpSweepArgs
+		if err := json.Unmarshal(params.Arguments, &args); err != nil {
+			return nil, mcpInvalidParams("invalid sweep arguments")
+		}
+		result, err := mcpSweep(args, s.limits)
+		if err != nil {
+			return mcpToolResult(err.Error(), map[string]any{"error": err.Error()}, true), nil
+		}
+		return mcpToolResult("Sweep complete.", result, false), nil
 	case "list_plugins":
-		return mcpToolResult("Plugin list complete.", plugins.UICatalog(), false), nil
+		return mcpToolResult("Plugin list complete.", mcpPluginCatalog(plugins.UICatalog()), false), nil
 	case "search_plugins":
 		var args mcpSearchArgs
 		if err := json.Unmarshal(params.Arguments, &args); err != nil {
 			return nil, mcpInvalidParams("invalid search_plugins arguments")
 		}
-		return mcpToolResult("Plugin search complete.", plugins.SearchUICatalog(args.Query), false), nil
+		return mcpToolResult("Plugin search complete.", mcpPluginCatalog(plugins.SearchUICatalog(args.Query)), false), nil
 	case "read_result_range":
 		var args mcpReadRangeArgs
 		if err := json.Unmarshal(params.Arguments, &args); err != nil {
@@ -544,6 +620,66 @@ func (s *mcpSession) callMCPTool(params mcpCallParams) (any, *mcpError) {
 	}
 }
 
+// mcpPluginInfo is a catalog entry with a JSON Schema for the plugin options
+// accepted by the transform tool.
+type mcpPluginInfo struct {
+	plugins.UIPluginInfo
+	OptionsSchema map[string]any `json:",omitempty"`
+}
+
+func mcpPluginCatalog(infos []plugins.UIPluginInfo) []mcpPluginInfo {
+	out := make([]mcpPluginInfo, 0, len(infos))
+	for _, info := range infos {
+		out = append(out, mcpPluginInfo{
+			UIPluginInfo:  info,
+			OptionsSchema: mcpOptionsSchema(info.Name),
+		})
+	}
+	return out
+}
+
+// mcpOptionsSchema describes the options of a plugin as a JSON Schema object.
+// Values are strings, as in saved chains, so types are expressed as enums and
+// patterns.
+func mcpOptionsSchema(name string) map[string]any {
+	opts := pipeline.PluginOptions(name)
+	if len(opts) == 0 {
+		return nil
+	}
+	properties := make(map[string]any, len(opts))
+	for _, opt := range opts {
+		prop := map[string]any{
+			"type":        "string",
+			"title":       opt.Label,
+			"description": opt.Description,
+			"default":     opt.Default,
+		}
+		switch {
+		case opt.IsBool:
+			prop["enum"] = []string{"true", "false"}
+		case len(opt.Choices) > 0 && opt.Open:
+			prop["examples"] = opt.Choices
+		case len(opt.Choices) > 0:
+			prop["enum"] = opt.Choices
+		case opt.Kind == "number":
+			prop["pattern"] = "^-?[0-9]+$"
+		}
+		if opt.Range != nil {
+			prop["description"] = fmt.Sprintf("%s Integer from %d to %d.", opt.Description, opt.Range.Min, opt.Range.Max)
+		}
+		if opt.Secret {
+			prop["writeOnly"] = true
+			delete(prop, "default")
+		}
+		properties[opt.Name] = prop
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
 func mcpToolResult(summary string, structured any, isError bool) map[string]any {
 	return map[string]any{
 		"content": []map[string]string{
@@ -565,7 +701,7 @@ func mcpInputBytes(text, encoded string) ([]byte, error) {
 	return []byte(text), nil
 }
 
-func mcpTransform(args mcpTransformArgs) ([]byte, error) {
+func mcpTransform(args mcpTransformArgs, limits pipeline.Limits) ([]byte, error) {
 	if strings.TrimSpace(args.Plugin) == "" {
 		return nil, errors.New("plugin is required")
 	}
@@ -574,6 +710,7 @@ func mcpTransform(args mcpTransformArgs) ([]byte, error) {
 		return nil, err
 	}
 	pipe := pipeline.New()
+	pipe.SetLimits(limits)
 	pipe.SetSourceOwned(data)
 	pipe.AddStepWithOptions(args.Plugin, args.Unprocess, args.Options)
 	if err := pipe.Err(0); err != nil {
@@ -582,25 +719,79 @@ func mcpTransform(args mcpTransformArgs) ([]byte, error) {
 	return append([]byte(nil), pipe.Result()...), nil
 }
 
-func mcpRunChain(args mcpRunChainArgs) ([]byte, error) {
+func mcpSweep(args mcpSweepArgs, limits pipeline.Limits) (mcpSweepResult, error) {
+	if strings.TrimSpace(args.Plugin) == "" {
+		return mcpSweepResult{}, errors.New("plugin is required")
+	}
+	data, err := mcpInputBytes(args.Text, args.Base64)
+	if err != nil {
+		return mcpSweepResult{}, err
+	}
+	sweep := pipeline.Sweep{Plugin: args.Plugin, Unprocess: args.Unprocess, Options: args.Options, Score: args.Score, Crib: args.Crib}
+	for _, spec := range args.Vary {
+		param, err := pipeline.ParseSweepParam(spec)
+		if err != nil {
+			return mcpSweepResult{}, err
+		}
+		sweep.Params = append(sweep.Params, param)
+	}
+	results, err := sweep.Run(context.Background(), limits, data)
+	if err != nil {
+		return mcpSweepResult{}, err
+	}
+	top := args.Top
+	if top <= 0 {
+		top = 10
+	}
+	result := mcpSweepResult{Runs: len(results), Results: results}
+	if len(results) > top {
+		result.Results = results[:top]
+	}
+	return result, nil
+}
+
+// mcpRunChain runs a chain recipe. With args.Trace set, the steps run one at
+// a time and the trace of every step that ran is returned, also on failure.
+func mcpRunChain(args mcpRunChainArgs, limits pipeline.Limits) ([]byte, []pipeline.StepTrace, error) {
 	if strings.TrimSpace(args.ChainJSON) == "" {
-		return nil, errors.New("chain_json is required")
+		return nil, nil, errors.New("chain_json is required")
 	}
 	data, err := mcpInputBytes(args.Text, args.Base64)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	// Load without computing, so every path below runs the chain once.
 	pipe := pipeline.New()
-	if err := pipe.ImportJSON([]byte(args.ChainJSON)); err != nil {
-		return nil, err
+	pipe.SetLimits(limits)
+	if err := pipe.LoadJSON([]byte(args.ChainJSON)); err != nil {
+		return nil, nil, err
+	}
+	// Like a new source, the input replaces the chain's manual edits.
+	hasInput := args.Text != "" || args.Base64 != ""
+	ctx := context.Background()
+	var out bytes.Buffer
+	if args.Trace {
+		trace := []pipeline.StepTrace{}
+		tracer := func(t pipeline.StepTrace, _ []byte) error {
+			trace = append(trace, t)
+			return nil
+		}
+		if hasInput {
+			err = pipe.TraceFrom(ctx, bytes.NewReader(data), &out, tracer)
+		} else {
+			err = pipe.Trace(ctx, &out, tracer)
+		}
+		return out.Bytes(), trace, err
 	}
-	if args.Text != "" || args.Base64 != "" {
-		pipe.SetSourceOwned(data)
+	if hasInput {
+		err = pipe.StreamFrom(ctx, bytes.NewReader(data), &out)
+	} else {
+		err = pipe.Stream(ctx, &out)
 	}
-	if step, err := firstChainError(pipe); err != nil {
-		return nil, fmt.Errorf("step %d (%s): %w", step+1, pipe.Steps()[step].Plugin, err)
+	if err != nil {
+		return nil, nil, err
 	}
-	return append([]byte(nil), pipe.Result()...), nil
+	return out.Bytes(), nil, nil
 }
 
 func mcpInvalidParams(msg string) *mcpError {
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/takeshixx/deen/internal/pipeline"
 )
 
 func TestRunMCPWithArgsRejectsMissingServe(t *testing.T) {
@@ -211,6 +213,56 @@ func TestServeMCPRunChainTool(t *testing.T) {
 	if preview := jsonPath[string](t, out[0], "result", "structuredContent", "preview", "text"); !strings.Contains(preview, `"ok": true`) {
 		t.Fatalf("chain preview = %q", preview)
 	}
+
+	out = serveMCPTranscript(t,
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":"chain","method":"tools/call","params":{"name":"run_chain","arguments":{"text":"%%zz","chain_json":%q}}}`, chain),
+	)
+	if got := jsonPath[string](t, out[0], "result", "content", "0", "text"); !strings.Contains(got, "step 1 (url)") {
+		t.Fatalf("error = %q, want step 1 (url)", got)
+	}
+}
+
+func TestServeMCPRunChainTrace(t *testing.T) {
+	chain := `{"version":1,"steps":[{"plugin":"base64"},{"plugin":"hex","unprocess":true}]}`
+	out := serveMCPTranscript(t,
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":"chain","method":"tools/call","params":{"name":"run_chain","arguments":{"text":"deen","chain_json":%q,"trace":true}}}`, chain),
+	)
+	if !jsonPath[bool](t, out[0], "result", "isError") {
+		t.Fatal("run_chain succeeded, want step 2 failure")
+	}
+	trace := jsonPath[[]any](t, out[0], "result", "structuredContent", "trace")
+	if len(trace) != 2 {
+		t.Fatalf("trace = %#v, want two steps", trace)
+	}
+	if got := jsonPath[float64](t, out[0], "result", "structuredContent", "trace", "0", "output_bytes"); got != 8 {
+		t.Fatalf("step 1 output_bytes = %v, want 8", got)
+	}
+	if got := jsonPath[string](t, out[0], "result", "structuredContent", "trace", "1", "error"); got == "" {
+		t.Fatal("step 2 trace has no error")
+	}
+}
+
+func TestServeMCPSweepTool(t *testing.T) {
+	input := base64.StdEncoding.EncodeToString([]byte("23z<6;="))
+	out := serveMCPTranscript(t,
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":"sweep","method":"tools/call","params":{"name":"sweep","arguments":{"base64":%q,"plugin":"xor","vary":["value=0x00..0xff"],"crib":"flag","top":2}}}`, input),
+		`{"jsonrpc":"2.0","id":"bad","method":"tools/call","params":{"name":"sweep","arguments":{"text":"x","plugin":"xor","vary":["value=1..0"]}}}`,
+	)
+	if got := jsonPath[float64](t, out[0], "result", "structuredContent", "runs"); got != 256 {
+		t.Fatalf("runs = %v", got)
+	}
+	if got := jsonPath[[]any](t, out[0], "result", "structuredContent", "results"); len(got) != 2 {
+		t.Fatalf("results = %#v, want top 2", got)
+	}
+	if got := jsonPath[string](t, out[0], "result", "structuredContent", "results", "0", "options", "value"); got != "0x5a" {
+		t.Fatalf("best value = %q", got)
+	}
+	if got := jsonPath[string](t, out[0], "result", "structuredContent", "results", "0", "preview"); got != "hi flag" {
+		t.Fatalf("best preview = %q", got)
+	}
+	if !jsonPath[bool](t, out[1], "result", "isError") {
+		t.Fatal("invalid range should be a tool error")
+	}
 }
 
 func TestServeMCPPluginTools(t *testing.T) {
@@ -227,6 +279,35 @@ func TestServeMCPPluginTools(t *testing.T) {
 	}
 }
 
+func TestServeMCPPluginOptionSchemas(t *testing.T) {
+	out := serveMCPTranscript(t,
+		`{"jsonrpc":"2.0","id":"search","method":"tools/call","params":{"name":"search_plugins","arguments":{"query":"aes"}}}`,
+		`{"jsonrpc":"2.0","id":"transform","method":"tools/call","params":{"name":"transform","arguments":{"text":"x","plugin":"aes","options":{"mode":"ecb"}}}}`,
+	)
+	var aes map[string]any
+	for _, item := range jsonPath[[]any](t, out[0], "result", "structuredContent") {
+		if m, ok := item.(map[string]any); ok && m["Name"] == "aes" {
+			aes = m
+		}
+	}
+	if aes == nil {
+		t.Fatal("missing aes search result")
+	}
+	modes := jsonPath[[]any](t, aes, "OptionsSchema", "properties", "mode", "enum")
+	if len(modes) != 3 || modes[0] != "gcm" {
+		t.Fatalf("aes mode enum = %#v", modes)
+	}
+	if writeOnly := jsonPath[bool](t, aes, "OptionsSchema", "properties", "key", "writeOnly"); !writeOnly {
+		t.Fatal("aes key should be write-only")
+	}
+	if !jsonPath[bool](t, out[1], "result", "isError") {
+		t.Fatal("invalid aes mode was accepted")
+	}
+	if got := jsonPath[string](t, out[1], "result", "structuredContent", "error"); !strings.Contains(got, "must be one of gcm, cbc, ctr") {
+		t.Fatalf("transform error = %q", got)
+	}
+}
+
 func TestServeMCPReadResultRangeTool(t *testing.T) {
 	out := serveMCPTranscript(t,
 		`{"jsonrpc":"2.0","id":"transform","method":"tools/call","params":{"name":"transform","arguments":{"text":"test","plugin":"base64"}}}`,
@@ -286,7 +367,7 @@ func serveMCPTranscriptExpectResponses(t *testing.T, wantResponses int, lines ..
 		in.WriteByte('\n')
 	}
 	var out bytes.Buffer
-	if err := serveMCP(strings.NewReader(in.String()), &out); err != nil {
+	if err := serveMCP(strings.NewReader(in.String()), &out, pipeline.DefaultLimits()); err != nil {
 		t.Fatal(err)
 	}
 	var responses []map[string]any
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+	"github.com/takeshixx/deen/pkg/helpers"
+)
+
+func runRun() int {
+	return runRunWithArgs(helpers.RemoveBeforeSubcommand(os.Args, "run"), os.Stdin, os.Stdout, os.Stderr)
+}
+
+func runRunWithArgs(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage of run:\n\n")
+		fmt.Fprintf(stderr, "Run an inline chain expression. Steps are separated by '|' and use the\n")
+		fmt.Fprintf(stderr, "same syntax as single plugin invocations; a leading 'deen' is optional,\n")
+		fmt.Fprintf(stderr, "so the GUI's \"Copy command\" output can be pasted as is.\n\n")
+		fmt.Fprintf(stderr, "Examples:\n")
+		fmt.Fprintf(stderr, "  deen run '.url | .base64 | .gzip | json -no-color' < payload.txt\n")
+		fmt.Fprintf(stderr, "  deen run 'deen .base64 | deen .gzip' H4sIAAAAAAAA/ypJLS4BBAAA//8Mfn/YBAAAAA==\n\n")
+		fs.PrintDefaults()
+	}
+	inputFile := fs.String("file", "", "read input from file")
+	newline := fs.Bool("N", false, "append a trailing newline to the output")
+	limits := registerLimitFlags(fs, pipeline.Limits{})
+	fs.Parse(args)
+
+	args = fs.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "deen: run: missing chain expression")
+		return 2
+	}
+	pipe := pipeline.New()
+	pipe.SetLimits(limits())
+	if err := pipe.LoadCommandLine(args[0]); err != nil {
+		fmt.Fprintln(stderr, "deen: run:", err)
+		return 2
+	}
+
+	r, cleanup, err := selectChainInput(*inputFile, true, args[1:], stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: run:", err)
+		return 1
+	}
+	defer cleanup()
+
+	out := bufio.NewWriter(stdout)
+	err = pipe.StreamFrom(context.Background(), r, out)
+	if ferr := out.Flush(); err == nil && ferr != nil {
+		err = ferr
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: run:", err)
+		return 1
+	}
+
+	globalNewline := newlinePtr != nil && *newlinePtr
+	if *newline || globalNewline {
+		if _, err := io.WriteString(stdout, "\n"); err != nil {
+			fmt.Fprintln(stderr, "deen:", err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunExpressionWithArgsInput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runRunWithArgs([]string{"-N", "deen .base64 | deen .gzip", "H4sIAAAAAAAA/ypJLS4BBAAA//8Mfn/YBAAAAA=="}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if got := stdout.String(); got != "test\n" {
+		t.Fatalf("stdout = %q, want test", got)
+	}
+}
+
+func TestRunExpressionWithStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runRunWithArgs([]string{".url | json -no-color"}, strings.NewReader("%7B%22a%22%3A1%7D"), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"a": 1`) {
+		t.Fatalf("stdout = %q, want formatted JSON", stdout.String())
+	}
+}
+
+func TestRunExpressionReportsStepError(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runRunWithArgs([]string{"base64 | .hex", "test"}, strings.NewReader(""), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("exit = %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "step 2 (hex)") {
+		t.Fatalf("stderr = %q, want step error", stderr.String())
+	}
+}
+
+func TestRunExpressionAcceptsOpenAndAliasedOptions(t *testing.T) {
+	for _, tt := range []struct{ expr, input, want string }{
+		{"csv -in ';' -out csv", "a;b", "a,b\n"},
+		{"csv -in '|' -out tsv", "a|b", "a\tb\n"},
+		{"unicode-normalize -form NFC", "abc", "abc"},
+		{"timestamp -unit S -layout 2006", "0", "1970"},
+		{"unicode -encoding UTF16LE", "A", "A\x00"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := runRunWithArgs([]string{tt.expr, tt.input}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+			t.Errorf("%s: exit = %d, stderr = %q", tt.expr, code, stderr.String())
+			continue
+		}
+		if got := stdout.String(); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestRunExpressionParseError(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runRunWithArgs([]string{"base64 | nope"}, strings.NewReader(""), &stdout, &stderr)
+	if code != 2 {
+		t.Fatalf("exit = %d, want 2", code)
+	}
+	if !strings.Contains(stderr.String(), `step 2: unknown plugin "nope"`) {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestRunMissingExpression(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runRunWithArgs(nil, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Fatalf("exit = %d, want 2", code)
+	}
+}
+
+func TestMapRunsChainPerPiece(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runMapWithArgs([]string{"-split", "regex", "-pattern", `t=(\S+)`, ".base64 | hex"}, strings.NewReader("t=aGk= x\nt=Ynll\n"), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if got := stdout.String(); got != "t=6869 x\nt=627965\n" {
+		t.Fatalf("stdout = %q", got)
+	}
+}
+
+func TestMapCookieExample(t *testing.T) {
+	// The README and usage example: the newline after the cookie is not part
+	// of the piece.
+	var stdout, stderr bytes.Buffer
+	input := "Cookie: theme=dark; session=eyJ1c2VyIjoiYWxpY2UifQ%3D%3D\n"
+	code := runMapWithArgs([]string{"-split", "regex", "-pattern", `session=([^;\s]+)`, ".url | .base64"}, strings.NewReader(input), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if got := stdout.String(); got != "Cookie: theme=dark; session={\"user\":\"alice\"}\n" {
+		t.Fatalf("stdout = %q", got)
+	}
+}
+
+func TestMapReportsFailingPiece(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runMapWithArgs([]string{".base64"}, strings.NewReader("aGk=\n!!\n"), &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("exit = %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "piece 2: step 1 (base64)") {
+		t.Fatalf("stderr = %q, want piece error", stderr.String())
+	}
+}
+
+func TestRunExpressionWithRegion(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runRunWithArgs([]string{`.base64 -region 're:session=([^;]+)'`, "a=1; session=aGk=; b=2"}, strings.NewReader(""), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	if got := stdout.String(); got != "a=1; session=hi; b=2" {
+		t.Fatalf("stdout = %q", got)
+	}
+}
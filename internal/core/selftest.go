@@ -0,0 +1,90 @@
+package core
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/takeshixx/deen/internal/selftest"
+	"github.com/takeshixx/deen/pkg/helpers"
+)
+
+// selftestRecord is one line of `deen selftest -json`.
+type selftestRecord struct {
+	selftest.Result
+	Status string `json:"status"` // "ok", "fail" or "skip"
+	Error  string `json:"error,omitempty"`
+}
+
+func runSelftest() int {
+	return runSelftestWithArgs(helpers.RemoveBeforeSubcommand(os.Args, "selftest"), os.Stdout, os.Stderr)
+}
+
+func runSelftestWithArgs(args []string, stdout, stderr io.Writer) int {
+	def := selftest.DefaultOptions()
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage of selftest:\n\n")
+		fmt.Fprintf(stderr, "Check the built-in plugins: decoders must undo their encoders across the\n")
+		fmt.Fprintf(stderr, "declared options and must not panic on malformed input, and hashes and\n")
+		fmt.Fprintf(stderr, "ciphers must reproduce published NIST and RFC test vectors.\n\n")
+		fmt.Fprintf(stderr, "Examples:\n")
+		fmt.Fprintf(stderr, "  deen selftest\n")
+		fmt.Fprintf(stderr, "  deen selftest -n 500 -seed 42 lzw base85 unicode\n\n")
+		fs.PrintDefaults()
+	}
+	seed := fs.Uint64("seed", def.Seed, "seed of the generated and fuzzed inputs")
+	iterations := fs.Int("n", def.Iterations, "random inputs per option set")
+	timeout := fs.Duration("timeout", def.Timeout, "time a single transform may take")
+	verbose := fs.Bool("v", false, "also list passed and skipped checks")
+	jsonOut := fs.Bool("json", false, "write one JSON object per check")
+	fs.Parse(args)
+
+	results, err := selftest.Run(selftest.Options{
+		Seed:       *seed,
+		Iterations: *iterations,
+		Plugins:    fs.Args(),
+		Timeout:    *timeout,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: selftest:", err)
+		return 2
+	}
+
+	enc := json.NewEncoder(stdout)
+	failed, skipped := 0, 0
+	for _, r := range results {
+		rec := selftestRecord{Result: r, Status: "ok"}
+		switch {
+		case r.Err != nil:
+			rec.Status, rec.Error = "fail", r.Err.Error()
+			failed++
+		case r.Skip != "":
+			rec.Status = "skip"
+			skipped++
+		}
+		switch {
+		case *jsonOut:
+			if err := enc.Encode(rec); err != nil {
+				fmt.Fprintln(stderr, "deen: selftest:", err)
+				return 1
+			}
+		case rec.Status == "fail":
+			fmt.Fprintf(stdout, "FAIL %s: %s\n", r, rec.Error)
+		case *verbose && rec.Status == "skip":
+			fmt.Fprintf(stdout, "skip %s: %s\n", r, r.Skip)
+		case *verbose:
+			fmt.Fprintf(stdout, "ok   %s\n", r)
+		}
+	}
+	if !*jsonOut {
+		fmt.Fprintf(stdout, "%d checks, %d failed, %d skipped (seed %d)\n", len(results), failed, skipped, *seed)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunSelftest(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runSelftestWithArgs([]string{"-n", "2", "-v", "base64", "sha256", "xml"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stdout = %q, stderr = %q", code, stdout.String(), stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{"ok   base64 roundtrip [url=true]", "ok   sha256 vector FIPS 180-4 example", "skip xml roundtrip:", "failed"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunSelftestJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runSelftestWithArgs([]string{"-json", "-n", "2", "hex"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	var rec selftestRecord
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Plugin != "hex" || rec.Status != "ok" {
+		t.Fatalf("record = %+v", rec)
+	}
+}
+
+func TestRunSelftestUnknownPlugin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runSelftestWithArgs([]string{"nope"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), `unknown built-in plugin "nope"`) {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+}
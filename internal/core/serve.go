@@ -3,11 +3,13 @@ package core
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 
+	"github.com/takeshixx/deen/internal/pipeline"
 	"github.com/takeshixx/deen/internal/server"
 	"github.com/takeshixx/deen/internal/web"
 	"github.com/takeshixx/deen/pkg/helpers"
@@ -31,6 +33,7 @@ func runServe() int {
 	authPass := fs.String("auth-pass", "", "HTTP basic auth password (random if omitted)")
 	csp := fs.String("csp", server.DefaultCSP, "Content-Security-Policy header (empty to disable)")
 	logReq := fs.Bool("log", false, "log requests")
+	limits := registerLimitFlags(fs, pipeline.DefaultLimits())
 	fs.Parse(helpers.RemoveBeforeSubcommand(os.Args, "serve"))
 
 	pass := *authPass
@@ -44,17 +47,26 @@ func runServe() int {
 		return 1
 	}
 
-	err := server.Run(server.Config{
-		Host:     *host,
-		Port:     *port,
-		Root:     *root,
-		Assets:   web.FS(),
-		TLSCert:  *tlsCert,
-		TLSKey:   *tlsKey,
-		AuthUser: *authUser,
-		AuthPass: pass,
-		CSP:      *csp,
-		Log:      *logReq,
+	clientConfig, err := json.Marshal(struct {
+		Limits pipeline.Limits `json:"limits"`
+	}{limits()})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "deen:", err)
+		return 1
+	}
+
+	err = server.Run(server.Config{
+		Host:         *host,
+		Port:         *port,
+		Root:         *root,
+		Assets:       web.FS(),
+		TLSCert:      *tlsCert,
+		TLSKey:       *tlsKey,
+		AuthUser:     *authUser,
+		AuthPass:     pass,
+		CSP:          *csp,
+		Log:          *logReq,
+		ClientConfig: clientConfig,
 	})
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "deen:", err)
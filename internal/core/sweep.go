@@ -0,0 +1,152 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+	"github.com/takeshixx/deen/pkg/helpers"
+)
+
+func runSweep() int {
+	return runSweepWithArgs(helpers.RemoveBeforeSubcommand(os.Args, "sweep"), os.Stdin, os.Stdout, os.Stderr)
+}
+
+func runSweepWithArgs(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage of sweep:\n\n")
+		fmt.Fprintf(stderr, "Run one step over every combination of option values given with -p and rank\n")
+		fmt.Fprintf(stderr, "the outputs. Values are comma separated values and integer ranges such as\n")
+		fmt.Fprintf(stderr, "0x00..0xff; * tries every choice of an option. Scores run from 0 to 100.\n\n")
+		fmt.Fprintf(stderr, "Examples:\n")
+		fmt.Fprintf(stderr, "  deen sweep -p value=0x00..0xff xor < blob.bin\n")
+		fmt.Fprintf(stderr, "  deen sweep -p value=0x00..0xff -crib flag{ -top 3 xor < blob.bin\n")
+		fmt.Fprintf(stderr, "  deen sweep -p order=0,1 -p lit-width=6..8 -score magic .lzw < blob.lzw\n")
+		fmt.Fprintf(stderr, "  deen sweep -p unit=* -json timestamp 1700000000000\n\n")
+		fmt.Fprintf(stderr, "Score modes:\n")
+		fmt.Fprintf(stderr, "  auto       crib hits first, then magic bytes, then printable text\n")
+		fmt.Fprintf(stderr, "  printable  share of printable bytes\n")
+		fmt.Fprintf(stderr, "  entropy    low Shannon entropy\n")
+		fmt.Fprintf(stderr, "  magic      output starts with known file magic\n")
+		fmt.Fprintf(stderr, "  crib       output contains the -crib text\n\n")
+		fs.PrintDefaults()
+	}
+	var params stringList
+	fs.Var(&params, "p", "vary an option as name=values (repeatable)")
+	score := fs.String("score", pipeline.ScoreAuto, "how to rank outputs: "+strings.Join(pipeline.ScoreModes, ", "))
+	crib := fs.String("crib", "", "text a correct output contains")
+	top := fs.Int("top", 10, "show only the best N results (0 for all)")
+	jsonOut := fs.Bool("json", false, "write the results as JSON")
+	inputFile := fs.String("file", "", "read input from file")
+	limits := registerLimitFlags(fs, pipeline.Limits{})
+	fs.Parse(args)
+
+	args = fs.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "deen: sweep: missing step")
+		return 2
+	}
+	steps, err := pipeline.ParseMapChain(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: sweep:", err)
+		return 2
+	}
+	if len(steps) != 1 || steps[0].Map != nil {
+		fmt.Fprintln(stderr, "deen: sweep: want a single plugin step, such as xor or '.lzw -lit-width 8'")
+		return 2
+	}
+	s := pipeline.Sweep{
+		Plugin:    steps[0].Plugin,
+		Unprocess: steps[0].Unprocess,
+		Options:   steps[0].Options,
+		Score:     *score,
+		Crib:      *crib,
+	}
+	for _, spec := range params {
+		param, err := pipeline.ParseSweepParam(spec)
+		if err != nil {
+			fmt.Fprintln(stderr, "deen: sweep:", err)
+			return 2
+		}
+		s.Params = append(s.Params, param)
+	}
+	if _, err := s.Runs(); err != nil {
+		fmt.Fprintln(stderr, "deen: sweep:", err)
+		return 2
+	}
+
+	r, cleanup, err := selectChainInput(*inputFile, true, args[1:], stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: sweep:", err)
+		return 1
+	}
+	defer cleanup()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: sweep:", err)
+		return 1
+	}
+	results, err := s.Run(context.Background(), limits(), data)
+	if err != nil {
+		fmt.Fprintln(stderr, "deen: sweep:", err)
+		return 1
+	}
+	failed := !slices.ContainsFunc(results, func(r pipeline.SweepResult) bool { return r.Error == "" })
+	if *top > 0 && len(results) > *top {
+		results = results[:*top]
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			fmt.Fprintln(stderr, "deen: sweep:", err)
+			return 1
+		}
+	} else {
+		writeSweepTable(stdout, s, results)
+	}
+	if failed {
+		fmt.Fprintln(stderr, "deen: sweep: every run failed")
+		return 1
+	}
+	return 0
+}
+
+// writeSweepTable writes results as an aligned table with the varied options
+// in their own columns.
+func writeSweepTable(w io.Writer, s pipeline.Sweep, results []pipeline.SweepResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"RANK", "SCORE"}
+	for _, p := range s.Params {
+		header = append(header, strings.ToUpper(p.Name))
+	}
+	header = append(header, "PRINTABLE", "ENTROPY", "MAGIC", "OUTPUT")
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for i, r := range results {
+		row := []string{fmt.Sprint(i + 1), fmt.Sprint(r.Score)}
+		for _, p := range s.Params {
+			row = append(row, r.Options[p.Name])
+		}
+		if r.Error != "" {
+			row = append(row, "-", "-", "-", "error: "+r.Error)
+		} else {
+			magic := r.Magic
+			if magic == "" {
+				magic = "-"
+			}
+			row = append(row, fmt.Sprintf("%d%%", r.Printable), fmt.Sprintf("%.2f", r.Entropy), magic, r.Preview)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
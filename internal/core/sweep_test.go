@@ -0,0 +1,80 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+)
+
+func TestRunSweepTable(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	// "hi flag" XORed with 0x5a.
+	args := []string{"-p", "value=0x00..0xff", "-crib", "flag", "-top", "2", "xor"}
+	if code := runSweepWithArgs(args, strings.NewReader("23z<6;="), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "RANK  SCORE  VALUE") {
+		t.Fatalf("table = %q", stdout.String())
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "1" || fields[1] != "100" || fields[2] != "0x5a" || !strings.HasSuffix(lines[1], "hi flag") {
+		t.Fatalf("best row = %q", lines[1])
+	}
+}
+
+func TestRunSweepJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-p", "strict=*", "-json", ".base64 -raw", "aGk"}
+	if code := runSweepWithArgs(args, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	var results []pipeline.SweepResult
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		t.Fatalf("JSON: %v\n%s", err, stdout.String())
+	}
+	if len(results) != 2 || results[0].Preview != "hi" || results[1].Error == "" {
+		t.Fatalf("results = %+v", results)
+	}
+}
+
+func TestRunSweepUsageExample(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"-p", "unit=*", "-json", "timestamp", "1700000000000"}
+	if code := runSweepWithArgs(args, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+	var results []pipeline.SweepResult
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		t.Fatalf("JSON: %v\n%s", err, stdout.String())
+	}
+	var found bool
+	for _, r := range results {
+		found = found || r.Options["unit"] == "ms" && r.Preview == "2023-11-14T22:13:20Z"
+	}
+	if len(results) != 5 || !found {
+		t.Fatalf("results = %+v", results)
+	}
+}
+
+func TestRunSweepErrors(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		code int
+		want string
+	}{
+		{nil, 2, "missing step"},
+		{[]string{"-p", "value=1", "xor | hex"}, 2, "single plugin step"},
+		{[]string{"xor"}, 2, "at least one option"},
+		{[]string{"-p", "value", "xor"}, 2, "want NAME=VALUES"},
+		{[]string{"-p", "value=1", "-score", "nope", "xor"}, 2, "unknown score mode"},
+		{[]string{"-p", "order=0,1", ".lzw", "not lzw"}, 1, "every run failed"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := runSweepWithArgs(tt.args, strings.NewReader(""), &stdout, &stderr); code != tt.code || !strings.Contains(stderr.String(), tt.want) {
+			t.Errorf("sweep %q: exit = %d, stderr = %q, want %d and %q", tt.args, code, stderr.String(), tt.code, tt.want)
+		}
+	}
+}
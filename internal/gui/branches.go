@@ -0,0 +1,193 @@
+//go:build gui
+
+package gui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+	"github.com/takeshixx/deen/internal/plugins"
+)
+
+// outputRow shows the result of one pipeline output in the branch tree.
+type outputRow struct {
+	branch int // index in pipe.Branches(), -1 for the main chain
+	meta   *widget.Label
+	body   *widget.Entry
+}
+
+// forkLabel names the data a branch forks off: the input or a step output.
+func (dg *DeenGUI) forkLabel(after int) string {
+	if after == 0 {
+		return "Input"
+	}
+	step := dg.pipe.Steps()[after-1]
+	name := plugins.PluginLabel(step.Plugin)
+	if step.Unprocess {
+		name = "." + name
+	}
+	return fmt.Sprintf("Step %d · %s", after, name)
+}
+
+// newBranchTree builds the outputs card: the main chain result and the
+// branches grouped under the input or step they fork off.
+func (dg *DeenGUI) newBranchTree() fyne.CanvasObject {
+	dg.outputRows = dg.outputRows[:0]
+	items := []fyne.CanvasObject{}
+	subtitle := widget.NewLabel("Branches fork off the input or a step and run their own chain on it. Each branch result is a named output next to the main result.")
+	subtitle.Importance = widget.LowImportance
+	subtitle.Wrapping = fyne.TextWrapWord
+	items = append(items, subtitle, dg.outputRow(-1, pipeline.MainOutput))
+
+	branches := dg.pipe.Branches()
+	for after := 0; after <= dg.pipe.Len(); after++ {
+		var children []fyne.CanvasObject
+		for b, branch := range branches {
+			if branch.After == after {
+				children = append(children, dg.branchNode(b))
+			}
+		}
+		if len(children) == 0 {
+			continue
+		}
+		fork := widget.NewLabelWithStyle(dg.forkLabel(after), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		items = append(items, widget.NewSeparator(), fork,
+			container.NewBorder(nil, nil, widget.NewLabel("  └"), nil, container.NewVBox(children...)))
+	}
+	items = append(items, widget.NewSeparator(), dg.addBranchForm())
+	return widget.NewCard("Outputs", "", container.NewVBox(items...))
+}
+
+// branchNode builds the editor of branch b: its name, chain and result.
+func (dg *DeenGUI) branchNode(b int) fyne.CanvasObject {
+	branch := dg.pipe.Branches()[b]
+	name := widget.NewEntry()
+	name.SetText(branch.Name)
+	name.Validator = pipeline.CheckBranchName
+	// Renaming keeps the last valid name while the entry holds an invalid or
+	// taken one.
+	name.OnChanged = func(s string) {
+		if err := dg.pipe.RenameBranch(b, s); err != nil {
+			name.SetValidationError(err)
+		}
+	}
+	chain := widget.NewEntry()
+	chain.SetPlaceHolder("no steps: the data the branch forks off")
+	chain.SetText(branch.ChainString())
+	chain.Validator = func(s string) error {
+		_, err := pipeline.ParseMapChain(s)
+		return err
+	}
+	chain.OnChanged = func(s string) {
+		steps, err := pipeline.ParseMapChain(s)
+		if err != nil {
+			return
+		}
+		if err := dg.pipe.SetBranchSteps(b, steps); err != nil {
+			chain.SetValidationError(err)
+			return
+		}
+		dg.refreshOutputs()
+	}
+	remove := stepIconButton(theme.DeleteIcon(), func() {
+		dg.pipe.RemoveBranch(b)
+		dg.rebuild()
+	})
+	header := container.NewBorder(nil, nil, nil, remove,
+		container.NewGridWithColumns(2, name, chain))
+	return container.NewVBox(header, dg.outputRow(b, branch.Name))
+}
+
+// outputRow builds the result view of an output and registers it for
+// refreshOutputs.
+func (dg *DeenGUI) outputRow(branch int, name string) fyne.CanvasObject {
+	row := &outputRow{branch: branch, meta: widget.NewLabel(""), body: multilineEntry(3)}
+	row.meta.Importance = widget.LowImportance
+	row.body.Disable()
+	dg.outputRows = append(dg.outputRows, row)
+	dg.refreshOutputRow(row)
+	copyButton := stepIconButton(theme.ContentCopyIcon(), func() {
+		data := dg.outputData(row.branch)
+		if pipeline.IsLargeData(data) {
+			dialog.ShowInformation("Copy output", "Output is too large to copy safely from the GUI.", dg.window)
+			return
+		}
+		dg.window.Clipboard().SetContent(string(data))
+	})
+	var title fyne.CanvasObject = row.meta
+	if branch < 0 {
+		title = container.NewHBox(widget.NewLabelWithStyle(name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true, Monospace: true}), row.meta)
+	}
+	return container.NewVBox(container.NewBorder(nil, nil, nil, copyButton, title), row.body)
+}
+
+// outputData returns the result of branch b, or of the main chain for -1.
+func (dg *DeenGUI) outputData(b int) []byte {
+	if b < 0 {
+		return dg.pipe.Result()
+	}
+	return dg.pipe.Branches()[b].Result()
+}
+
+func (dg *DeenGUI) refreshOutputRow(row *outputRow) {
+	data := dg.outputData(row.branch)
+	meta := pipeline.DataMetadata(data, 0).Summary()
+	if row.branch >= 0 {
+		if err := dg.pipe.Branches()[row.branch].Err(); err != nil {
+			meta = "Error: " + err.Error()
+		}
+	}
+	row.meta.SetText(meta)
+	text, _ := guiTextDisplay(data)
+	dg.setText(row.body, text)
+}
+
+// refreshOutputs updates the results shown in the branch tree.
+func (dg *DeenGUI) refreshOutputs() {
+	for _, row := range dg.outputRows {
+		dg.refreshOutputRow(row)
+	}
+}
+
+// addBranchForm builds the controls that add a branch off the input or a
+// step.
+func (dg *DeenGUI) addBranchForm() fyne.CanvasObject {
+	forks := make([]string, 0, dg.pipe.Len()+1)
+	for after := 0; after <= dg.pipe.Len(); after++ {
+		forks = append(forks, dg.forkLabel(after))
+	}
+	fork := widget.NewSelect(forks, nil)
+	fork.SetSelected(forks[len(forks)-1])
+	name := widget.NewEntry()
+	name.SetPlaceHolder("name, e.g. zlib")
+	name.Validator = pipeline.CheckBranchName
+	chain := widget.NewEntry()
+	chain.SetPlaceHolder(".zlib | hex")
+	chain.Validator = func(s string) error {
+		_, err := pipeline.ParseMapChain(s)
+		return err
+	}
+	add := widget.NewButtonWithIcon("Add branch", theme.ContentAddIcon(), func() {
+		steps, err := pipeline.ParseMapChain(chain.Text)
+		if err != nil {
+			dialog.ShowError(err, dg.window)
+			return
+		}
+		after := fork.SelectedIndex()
+		dg.runPipelineWork("Processing", func() error {
+			_, err := dg.pipe.AddBranch(name.Text, after, steps)
+			return err
+		}, dg.rebuild)
+	})
+	label := widget.NewLabelWithStyle("Add branch", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	label.Importance = widget.LowImportance
+	return container.NewVBox(label,
+		container.NewGridWithColumns(3, fork, name, chain),
+		container.NewHBox(add))
+}
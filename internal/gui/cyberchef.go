@@ -0,0 +1,96 @@
+//go:build gui
+
+package gui
+
+import (
+	"errors"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+)
+
+// showCyberChef opens the dialog that imports a CyberChef recipe as the chain
+// or exports the chain as a recipe. Notes on operations that were left out
+// or translated with a difference are listed below the recipe.
+func (dg *DeenGUI) showCyberChef() {
+	recipe := widget.NewMultiLineEntry()
+	recipe.SetPlaceHolder("Paste a CyberChef recipe: JSON, Chef format or a CyberChef URL")
+	recipe.Wrapping = fyne.TextWrapBreak
+	recipe.SetMinRowsVisible(8)
+	notes := widget.NewLabel("")
+	notes.Wrapping = fyne.TextWrapWord
+	format := widget.NewSelect([]string{"json", "chef", "url"}, nil)
+	format.SetSelected("json")
+
+	var d dialog.Dialog
+	importRecipe := widget.NewButton("Import recipe", func() {
+		var result []pipeline.CyberChefNote
+		text := recipe.Text
+		dg.runPipelineWork("Importing recipe", func() error {
+			var err error
+			result, err = dg.pipe.ImportCyberChef(text)
+			if err != nil && len(result) > 0 {
+				return errors.New(err.Error() + ":\n" + cyberChefNotesText(result))
+			}
+			return err
+		}, func() {
+			dg.stepsExpanded = false
+			dg.rebuild()
+			if len(result) == 0 {
+				d.Hide()
+				return
+			}
+			notes.SetText(cyberChefNotesText(result))
+		})
+	})
+	importRecipe.Importance = widget.HighImportance
+	exportChain := widget.NewButton("Export chain", func() {
+		ops, result, err := dg.pipe.ExportCyberChef()
+		if err != nil {
+			dialog.ShowError(err, dg.window)
+			return
+		}
+		var text string
+		switch format.Selected {
+		case "chef":
+			text, err = pipeline.FormatCyberChefChef(ops)
+		case "url":
+			text, err = pipeline.FormatCyberChefURL(ops)
+		default:
+			var data []byte
+			data, err = pipeline.FormatCyberChefJSON(ops)
+			text = string(data)
+		}
+		if err != nil {
+			dialog.ShowError(err, dg.window)
+			return
+		}
+		recipe.SetText(text)
+		dg.window.Clipboard().SetContent(text)
+		notes.SetText(strings.TrimSpace("Recipe copied.\n" + cyberChefNotesText(result)))
+	})
+
+	content := container.NewBorder(
+		nil,
+		container.NewVBox(container.NewHBox(importRecipe, exportChain, format), notes),
+		nil, nil,
+		recipe,
+	)
+	d = dialog.NewCustom("CyberChef recipe", "Close", content, dg.window)
+	d.Resize(fyne.NewSize(620, 460))
+	d.Show()
+}
+
+// cyberChefNotesText lists notes one per line.
+func cyberChefNotesText(notes []pipeline.CyberChefNote) string {
+	lines := make([]string, len(notes))
+	for i, n := range notes {
+		lines[i] = n.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -48,6 +48,7 @@ type DeenGUI struct {
 	sourceFullStrings  bool
 	stepsBox           *fyne.Container // holds the source card, step cards and add-slot
 	cards              []*stepCard     // parallel to pipe.Steps()
+	outputRows         []*outputRow    // results shown in the branch tree
 	history            *fyne.Container // horizontal transformer-chain overview
 	chainView          fyne.CanvasObject
 	tabButtons         []*navTab
@@ -138,7 +139,12 @@ func (dg *DeenGUI) homeMenuBar() fyne.CanvasObject {
 		dg.menuButton("Chain", theme.FileTextIcon(), fyne.NewMenu("Chain",
 			fyne.NewMenuItemWithIcon("Open chain", theme.FileTextIcon(), dg.openChain),
 			fyne.NewMenuItemWithIcon("Save chain", theme.DocumentCreateIcon(), dg.saveChain),
+			fyne.NewMenuItemWithIcon("Parameters", theme.SettingsIcon(), dg.editParams),
 			fyne.NewMenuItemWithIcon("Copy command", theme.MailForwardIcon(), dg.copyCommand),
+			fyne.NewMenuItemWithIcon("Copy Go code", theme.ContentCopyIcon(), func() { dg.copyCode(pipeline.EmitGo) }),
+			fyne.NewMenuItemWithIcon("Copy Python code", theme.ContentCopyIcon(), func() { dg.copyCode(pipeline.EmitPython) }),
+			fyne.NewMenuItemWithIcon("Invert chain", theme.ViewRefreshIcon(), dg.invertChain),
+			fyne.NewMenuItemWithIcon("CyberChef recipe", theme.ContentPasteIcon(), dg.showCyberChef),
 		)),
 		dg.menuButton("Workflow", theme.HistoryIcon(), fyne.NewMenu("Workflow",
 			fyne.NewMenuItemWithIcon("Presets", theme.HistoryIcon(), dg.showPresets),
@@ -255,12 +261,14 @@ func (dg *DeenGUI) homeActions() fyne.CanvasObject {
 	saveChain := widget.NewButtonWithIcon("Save chain", theme.DocumentCreateIcon(), dg.saveChain)
 	presets := widget.NewButtonWithIcon("Presets", theme.HistoryIcon(), dg.showPresets)
 	copyCommand := widget.NewButtonWithIcon("Copy command", theme.MailForwardIcon(), dg.copyCommand)
+	invert := widget.NewButtonWithIcon("Invert chain", theme.ViewRefreshIcon(), dg.invertChain)
+	cyberChef := widget.NewButtonWithIcon("CyberChef recipe", theme.ContentPasteIcon(), dg.showCyberChef)
 
 	compare := widget.NewButtonWithIcon("Compare", theme.ViewFullScreenIcon(), dg.showCompare)
 
 	return container.NewVBox(
 		actionGroup("Result", copyResult, save, open),
-		actionGroup("Chain", openChain, saveChain, copyCommand),
+		actionGroup("Chain", openChain, saveChain, copyCommand, invert, cyberChef),
 		actionGroup("Workflow", presets, compare, stepLayout, undo, redo, clear),
 	)
 }
@@ -346,7 +354,12 @@ func (dg *DeenGUI) mainMenu() *fyne.MainMenu {
 	chainMenu := fyne.NewMenu("Chain",
 		fyne.NewMenuItemWithIcon("Open chain", theme.FileTextIcon(), dg.openChain),
 		fyne.NewMenuItemWithIcon("Save chain", theme.DocumentCreateIcon(), dg.saveChain),
+		fyne.NewMenuItemWithIcon("Parameters", theme.SettingsIcon(), dg.editParams),
 		fyne.NewMenuItemWithIcon("Copy command", theme.MailForwardIcon(), dg.copyCommand),
+		fyne.NewMenuItemWithIcon("Copy Go code", theme.ContentCopyIcon(), func() { dg.copyCode(pipeline.EmitGo) }),
+		fyne.NewMenuItemWithIcon("Copy Python code", theme.ContentCopyIcon(), func() { dg.copyCode(pipeline.EmitPython) }),
+		fyne.NewMenuItemWithIcon("Invert chain", theme.ViewRefreshIcon(), dg.invertChain),
+		fyne.NewMenuItemWithIcon("CyberChef recipe", theme.ContentPasteIcon(), dg.showCyberChef),
 	)
 	workflowMenu := fyne.NewMenu("Workflow",
 		fyne.NewMenuItemWithIcon("Presets", theme.HistoryIcon(), dg.showPresets),
@@ -393,8 +406,18 @@ Decoder. The result of each step feeds into the next.
 - Use **Search transformers** in the **Add transformer step** card to search the catalog and append a transform.
 - Use **Presets** to load starter chains while keeping the current input.
 - Use **Detect next** in the **Add transformer step** card to detect likely next transforms from the current result.
+  Its **Sweep options** section tries ranges of option values, such as every
+  XOR byte, and ranks the outputs by printability, entropy, magic bytes or a crib.
 - Use **Compare** in the Transformer Chain panel to inspect any two pipeline points side by side.
-- Copy the equivalent shell pipeline from the toolbar.
+- Use **Add branch** in the **Outputs** card to fork off the input or a step
+  with its own chain, e.g. to try several decoders on one intermediate. Each
+  branch result is a named output.
+- Use **Invert chain** to turn a decoding chain into the one that re-encodes
+  its result the same way, e.g. to edit a decoded cookie and encode it again.
+- Use **CyberChef recipe** to import a CyberChef recipe as the chain, or to
+  export the chain as a recipe. Operations without a deen equivalent are listed.
+- Copy the equivalent shell pipeline from the toolbar, or the chain as a
+  standalone Go or Python program from the Chain menu.
 - Editing any step's output recomputes everything below it.
 - Use the disclosure arrow to **collapse/expand** a step, the trash icon
   to remove it.
@@ -518,8 +541,8 @@ func (dg *DeenGUI) exampleCard(example pipeline.Example) fyne.CanvasObject {
 	})
 
 	body := container.NewVBox(desc, source, chain)
-	if example.WantContains != "" {
-		want := widget.NewLabel("Expected result contains: " + example.WantContains)
+	if example.Want.Contains != "" {
+		want := widget.NewLabel("Expected result contains: " + example.Want.Contains)
 		want.Importance = widget.LowImportance
 		want.Wrapping = fyne.TextWrapBreak
 		body.Add(want)
@@ -659,6 +682,7 @@ func (dg *DeenGUI) rebuild() {
 		dg.stepsBox.Add(c.container)
 	}
 	dg.stepsBox.Add(dg.newAddSlot())
+	dg.stepsBox.Add(dg.newBranchTree())
 	dg.stepsBox.Refresh()
 	dg.updateHistory()
 }
@@ -716,6 +740,12 @@ func guiChainStepPill(i int, step *pipeline.Step) fyne.CanvasObject {
 		metaParts = append(metaParts, k+"="+v)
 	}
 	sort.Strings(metaParts)
+	if step.Map != nil {
+		metaParts = append(metaParts, step.Map.Summary())
+	}
+	if step.Region != "" {
+		metaParts = append(metaParts, "region="+step.Region)
+	}
 	var meta fyne.CanvasObject
 	if len(metaParts) > 0 {
 		text := canvas.NewText(strings.Join(metaParts, ", "), theme.Color(theme.ColorNamePlaceHolder))
@@ -783,6 +813,7 @@ func (dg *DeenGUI) refreshFrom(from int) {
 		}
 		dg.cards[i].refresh()
 	}
+	dg.refreshOutputs()
 }
 
 // setText updates an entry programmatically without triggering its OnChanged.
@@ -860,6 +891,60 @@ func (dg *DeenGUI) openChain() {
 			}
 			dg.stepsExpanded = false
 			return nil
+		}, func() {
+			dg.rebuild()
+			if len(dg.pipe.Params()) > 0 {
+				dg.editParams()
+			}
+		})
+	}, dg.window)
+}
+
+// editParams prompts for the values of the parameters declared by the chain.
+// Secret parameters use password entries; an empty entry keeps the default.
+func (dg *DeenGUI) editParams() {
+	params := dg.pipe.Params()
+	if len(params) == 0 {
+		dialog.ShowInformation("Chain parameters", "This chain declares no parameters. Parameters are declared in the chain file and referenced from step options as ${name}.", dg.window)
+		return
+	}
+	entries := make([]*widget.Entry, len(params))
+	items := make([]*widget.FormItem, len(params))
+	for i, pr := range params {
+		entry := widget.NewEntry()
+		if pr.Secret {
+			entry = widget.NewPasswordEntry()
+		}
+		if pr.Default != "" {
+			entry.SetPlaceHolder("default: " + pr.Default)
+		}
+		if v, ok := dg.pipe.ParamValue(pr.Name); ok && v != pr.Default {
+			entry.SetText(v)
+		}
+		entry.Validator = func(s string) error {
+			if s == "" && pr.Default != "" {
+				return nil
+			}
+			return pr.Check(s)
+		}
+		entries[i] = entry
+		items[i] = widget.NewFormItem(pr.Name, entry)
+		items[i].HintText = pr.Description
+	}
+	dialog.ShowForm("Chain parameters", "Apply", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		values := map[string]string{}
+		for i, pr := range params {
+			if entries[i].Text == "" && pr.Default != "" {
+				values[pr.Name] = pr.Default
+			} else {
+				values[pr.Name] = entries[i].Text
+			}
+		}
+		dg.runPipelineWork("Processing", func() error {
+			return dg.pipe.SetParams(values)
 		}, dg.rebuild)
 	}, dg.window)
 }
@@ -904,6 +989,31 @@ func (dg *DeenGUI) copyCommand() {
 	dialog.ShowCustom("Command copied", "Close", entry, dg.window)
 }
 
+// copyCode copies the chain as a standalone program in lang.
+func (dg *DeenGUI) copyCode(lang string) {
+	program, err := dg.pipe.Emit(lang)
+	if err != nil {
+		dialog.ShowError(err, dg.window)
+		return
+	}
+	dg.window.Clipboard().SetContent(program)
+	entry := widget.NewMultiLineEntry()
+	entry.SetText(program)
+	entry.TextStyle = fyne.TextStyle{Monospace: true}
+	entry.SetMinRowsVisible(16)
+	entry.Disable()
+	d := dialog.NewCustom("Code copied", "Close", entry, dg.window)
+	d.Resize(fyne.NewSize(720, 520))
+	d.Show()
+}
+
+// invertChain replaces the chain with its inverse and the source with the
+// result, so an edited result can be re-encoded the way the source was.
+func (dg *DeenGUI) invertChain() {
+	dg.sourceName = ""
+	dg.runPipelineWork("Inverting", dg.pipe.Invert, dg.rebuild)
+}
+
 type comparePoint struct {
 	Label string
 	Data  []byte
@@ -917,6 +1027,9 @@ func (dg *DeenGUI) comparePoints() []comparePoint {
 			Data:  dg.pipe.Output(i),
 		})
 	}
+	for _, b := range dg.pipe.Branches() {
+		points = append(points, comparePoint{Label: "Output " + b.Name, Data: b.Result()})
+	}
 	return points
 }
 
@@ -1009,16 +1122,19 @@ func (dg *DeenGUI) showSuggestions() {
 
 func (dg *DeenGUI) showSuggestionsDialog(suggestions []pipeline.Suggestion) {
 	list := container.NewVBox()
+	var d dialog.Dialog
+	hide := func() {
+		if d != nil {
+			d.Hide()
+		}
+	}
 	if len(suggestions) == 0 {
 		list.Add(widget.NewLabel("No likely transforms detected."))
-		dialog.ShowCustom("Suggested transforms", "Close", list, dg.window)
-		return
 	}
 	sort.SliceStable(suggestions, func(i, j int) bool {
 		return suggestions[i].Confidence > suggestions[j].Confidence
 	})
 
-	var d dialog.Dialog
 	for _, s := range suggestions {
 		s := s
 		detail := s.Reason
@@ -1030,9 +1146,7 @@ func (dg *DeenGUI) showSuggestionsDialog(suggestions []pipeline.Suggestion) {
 			actionLabel = "Apply chain"
 		}
 		action := widget.NewButton(actionLabel, func() {
-			if d != nil {
-				d.Hide()
-			}
+			hide()
 			dg.runPipelineWork("Processing", func() error {
 				dg.pipe.AddSuggestion(s)
 				return nil
@@ -1047,8 +1161,10 @@ func (dg *DeenGUI) showSuggestionsDialog(suggestions []pipeline.Suggestion) {
 		}
 		list.Add(container.NewBorder(nil, nil, nil, action, itemContent))
 	}
+	list.Add(widget.NewSeparator())
+	list.Add(dg.newSweepForm(hide))
 	d = dialog.NewCustom("Suggested transforms", "Close", container.NewVScroll(list), dg.window)
-	d.Resize(fyne.NewSize(560, 360))
+	d.Resize(fyne.NewSize(560, 480))
 	d.Show()
 }
 
@@ -427,10 +427,15 @@ func (dg *DeenGUI) newStepCard(i int) *stepCard {
 			return nil
 		}, dg.rebuild)
 	}
-	selectors := dg.categorySelectors(step.Plugin, func(name string) {
-		c.pluginName = name
-		apply()
-	})
+	var selectors fyne.CanvasObject
+	if step.Map != nil {
+		selectors = dg.mapEditor(c)
+	} else {
+		selectors = dg.categorySelectors(step.Plugin, func(name string) {
+			c.pluginName = name
+			apply()
+		})
+	}
 	c.decode.OnChanged = func(bool) { apply() }
 	toggleEnabled := func() {
 		dg.runPipelineWork("Processing", func() error {
@@ -575,6 +580,78 @@ func (dg *DeenGUI) newStepCard(i int) *stepCard {
 	return c
 }
 
+// mapEditor builds the split and nested chain controls of a map step card.
+// The nested chain is edited as a chain expression.
+func (dg *DeenGUI) mapEditor(c *stepCard) fyne.CanvasObject {
+	m := dg.pipe.Steps()[c.index].Map
+	split := widget.NewSelect(pipeline.SplitModes, nil)
+	split.SetSelected(m.Split)
+	pattern := widget.NewEntry()
+	pattern.SetText(m.Pattern)
+	chain := widget.NewEntry()
+	chain.SetPlaceHolder(".base64 | .gzip")
+	chain.SetText(pipeline.MapChainString(m.Steps))
+	chain.Validator = func(s string) error {
+		_, err := pipeline.ParseMapChain(s)
+		return err
+	}
+	setPlaceholder := func() {
+		switch split.Selected {
+		case pipeline.SplitLines:
+			pattern.SetPlaceHolder("not used for lines")
+		case pipeline.SplitDelimiter:
+			pattern.SetPlaceHolder(`delimiter, e.g. , or \t`)
+		case pipeline.SplitRegex:
+			pattern.SetPlaceHolder(`regex, e.g. token=([^&]+)`)
+		case pipeline.SplitJSON:
+			pattern.SetPlaceHolder("JSONPath, default: $[*]")
+		}
+	}
+	setPlaceholder()
+
+	current := func() (pipeline.Map, bool) {
+		steps, err := pipeline.ParseMapChain(chain.Text)
+		return pipeline.Map{Split: split.Selected, Pattern: pattern.Text, Steps: steps}, err == nil
+	}
+	split.OnChanged = func(string) {
+		setPlaceholder()
+		next, ok := current()
+		if !ok {
+			return
+		}
+		dg.runPipelineWork("Processing", func() error {
+			dg.pipe.SetMap(c.index, next)
+			return nil
+		}, func() {
+			c.updateSummary()
+			dg.refreshFrom(c.index)
+		})
+	}
+	// Text edits apply on every keystroke like plugin option entries; an
+	// unparsable chain keeps the last valid one.
+	edited := func(string) {
+		if next, ok := current(); ok {
+			dg.pipe.SetMap(c.index, next)
+			c.updateSummary()
+			dg.refreshFrom(c.index)
+		}
+	}
+	pattern.OnChanged = edited
+	chain.OnChanged = edited
+
+	label := func(text string) fyne.CanvasObject {
+		return widget.NewLabelWithStyle(text, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	help := widget.NewLabel("Runs the chain on each piece of the input and puts the results back in place.")
+	help.Importance = widget.LowImportance
+	help.Wrapping = fyne.TextWrapWord
+	return container.NewVBox(
+		container.NewGridWithColumns(2,
+			container.NewVBox(label("Split"), split),
+			container.NewVBox(label("Pattern"), pattern)),
+		label("Chain per piece"), chain, help)
+}
+
 // toggleCollapse hides or shows the step's detail section.
 func (c *stepCard) toggleCollapse() {
 	c.collapsed = !c.collapsed
@@ -596,12 +673,27 @@ func (c *stepCard) updateSummary() {
 		c.summary.Refresh()
 		return
 	}
+	step := c.gui.pipe.Steps()[c.index]
+	if m := step.Map; m != nil {
+		c.summary.Text = "  map · " + m.Summary()
+		if step.Region != "" {
+			c.summary.Text += " · " + step.Region
+		}
+		if !c.enabled.Checked {
+			c.summary.Text += " · disabled"
+		}
+		c.summary.Refresh()
+		return
+	}
 	dir := "encode"
 	if c.decode.Checked {
 		dir = "decode"
 	}
 	cat := plugins.CategoryOf(name)
 	c.summary.Text = fmt.Sprintf("  %s / %s · %s", plugins.CategoryLabel(cat), plugins.PluginLabel(name), dir)
+	if step.Region != "" {
+		c.summary.Text += " · " + step.Region
+	}
 	if !c.enabled.Checked {
 		c.summary.Text += " · disabled"
 	}
@@ -613,10 +705,6 @@ func (c *stepCard) rebuildOptions() {
 	c.options.RemoveAll()
 	step := c.gui.pipe.Steps()[c.index]
 	opts := pipeline.PluginOptions(step.Plugin)
-	if len(opts) == 0 {
-		c.options.Refresh()
-		return
-	}
 	var checkOptions []fyne.CanvasObject
 	var fieldOptions []fyne.CanvasObject
 	for _, opt := range opts {
@@ -624,7 +712,9 @@ func (c *stepCard) rebuildOptions() {
 		label := widget.NewLabelWithStyle(opt.Label, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
 		var control fyne.CanvasObject
 		var target *[]fyne.CanvasObject
-		if opt.IsBool {
+		// An option bound to a chain parameter is edited as text.
+		paramRef := pipeline.HasParamRef(step.Options[opt.Name])
+		if opt.IsBool && !paramRef {
 			chk := widget.NewCheck("", nil)
 			chk.SetChecked(step.Options[opt.Name] == "true")
 			chk.OnChanged = func(b bool) {
@@ -639,7 +729,7 @@ func (c *stepCard) rebuildOptions() {
 			}
 			control = chk
 			target = &checkOptions
-		} else if opt.Kind == "select" {
+		} else if opt.Kind == "select" && !paramRef {
 			selectInput := widget.NewSelect(opt.Choices, func(s string) {
 				c.gui.runPipelineWork("Processing", func() error {
 					c.gui.pipe.SetOption(c.index, opt.Name, s)
@@ -662,6 +752,12 @@ func (c *stepCard) rebuildOptions() {
 				entry = widget.NewPasswordEntry()
 			}
 			entry.SetPlaceHolder(optionPlaceholder(opt))
+			entry.Validator = func(s string) error {
+				if s == "" || pipeline.HasParamRef(s) {
+					return nil
+				}
+				return opt.Check(s)
+			}
 			if v, ok := step.Options[opt.Name]; ok {
 				entry.SetText(v)
 			}
@@ -680,14 +776,50 @@ func (c *stepCard) rebuildOptions() {
 	if len(fieldOptions) > 0 {
 		c.options.Add(optionSection("Inputs", fieldOptions))
 	}
+	if step.Plugin != "" {
+		c.options.Add(c.regionEntry(step.Region))
+	}
 	c.options.Refresh()
 }
 
+// regionEntry builds the entry that restricts the step to a region of its
+// input. An invalid region keeps the last valid one.
+func (c *stepCard) regionEntry(current string) fyne.CanvasObject {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("whole input, or 10:32, re:token=([^&]+), jq:.data")
+	entry.SetText(current)
+	entry.Validator = func(s string) error {
+		if s == "" {
+			return nil
+		}
+		return pipeline.CheckRegion(s)
+	}
+	entry.OnChanged = func(s string) {
+		if entry.Validator(s) != nil {
+			return
+		}
+		c.gui.pipe.SetRegion(c.index, s)
+		c.updateSummary()
+		c.gui.refreshFrom(c.index)
+	}
+	help := widget.NewLabel("Transforms only this part of the input and splices the result back in: OFFSET:LENGTH, re:REGEX (first capture group) or jq:PATH for JSON.")
+	help.Importance = widget.LowImportance
+	help.Wrapping = fyne.TextWrapWord
+	return optionSection("Region", []fyne.CanvasObject{entry, help})
+}
+
 func optionPlaceholder(opt pipeline.Option) string {
-	if opt.Default == "" {
-		return opt.Label
+	placeholder := opt.Label
+	if opt.Default != "" {
+		placeholder = fmt.Sprintf("default: %s", opt.Default)
 	}
-	return fmt.Sprintf("default: %s", opt.Default)
+	if opt.Range != nil {
+		placeholder += fmt.Sprintf(" (%d-%d)", opt.Range.Min, opt.Range.Max)
+	}
+	if opt.Open && len(opt.Choices) > 0 {
+		placeholder += " (e.g. " + strings.Join(opt.Choices, ", ") + ")"
+	}
+	return placeholder
 }
 
 func optionBlock(label, control fyne.CanvasObject, opt pipeline.Option) fyne.CanvasObject {
@@ -909,6 +1041,12 @@ func (dg *DeenGUI) newAddSlot() fyne.CanvasObject {
 	actions := container.NewHBox(
 		widget.NewButtonWithIcon("Search transformers", theme.SearchIcon(), dg.showPluginSearch),
 		widget.NewButtonWithIcon("Detect next", theme.ContentAddIcon(), dg.showSuggestions),
+		widget.NewButtonWithIcon("Map over pieces", theme.ListIcon(), func() {
+			dg.runPipelineWork("Processing", func() error {
+				dg.pipe.AddMapStep(pipeline.Map{Split: pipeline.SplitLines})
+				return nil
+			}, dg.rebuild)
+		}),
 	)
 	subtitle := widget.NewLabel("Choose a transformer by category or search the catalog.")
 	subtitle.Importance = widget.LowImportance
@@ -0,0 +1,133 @@
+//go:build gui
+
+package gui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/takeshixx/deen/internal/pipeline"
+	"github.com/takeshixx/deen/internal/plugins"
+)
+
+// maxSweepRows bounds the ranked results listed in the suggestions dialog.
+const maxSweepRows = 10
+
+// sweepPlugins returns the plugins with options a sweep can vary.
+func sweepPlugins() []string {
+	var names []string
+	for _, name := range plugins.Names() {
+		if len(pipeline.PluginOptions(name)) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newSweepForm builds the sweep section of the suggestions dialog: it runs a
+// plugin over ranges of option values on the current result and lists the
+// best outputs, each of which can be added as a step. hide closes the dialog.
+func (dg *DeenGUI) newSweepForm(hide func()) fyne.CanvasObject {
+	plugin := widget.NewSelect(sweepPlugins(), nil)
+	plugin.SetSelected("xor")
+	decode := widget.NewCheck("decode", nil)
+	vary := widget.NewEntry()
+	vary.SetText("value=0x00..0xff")
+	vary.SetPlaceHolder("order=0,1 lit-width=6..8")
+	vary.Validator = func(s string) error {
+		_, err := parseSweepParams(s)
+		return err
+	}
+	score := widget.NewSelect(pipeline.ScoreModes, nil)
+	score.SetSelected(pipeline.ScoreAuto)
+	crib := widget.NewEntry()
+	crib.SetPlaceHolder("crib, e.g. flag{")
+	results := container.NewVBox()
+
+	run := widget.NewButton("Run sweep", func() {
+		params, err := parseSweepParams(vary.Text)
+		if err != nil {
+			vary.SetValidationError(err)
+			return
+		}
+		sweep := pipeline.Sweep{
+			Plugin:    plugin.Selected,
+			Unprocess: decode.Checked,
+			Params:    params,
+			Score:     score.Selected,
+			Crib:      crib.Text,
+		}
+		var ranked []pipeline.SweepResult
+		dg.runPipelineWork("Sweeping", func() error {
+			var err error
+			ranked, err = sweep.Run(context.Background(), dg.pipe.Limits(), dg.pipe.Result())
+			return err
+		}, func() {
+			dg.showSweepResults(results, sweep, ranked, hide)
+		})
+	})
+
+	help := widget.NewLabel("Try every value of plugin options on the current result and rank the outputs. Separate options with spaces; values are lists and ranges like 0x00..0xff, or * for every choice.")
+	help.Importance = widget.LowImportance
+	help.Wrapping = fyne.TextWrapWord
+	return container.NewVBox(
+		widget.NewLabelWithStyle("Sweep options", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		help,
+		container.NewGridWithColumns(2, plugin, decode),
+		vary,
+		container.NewGridWithColumns(2, score, crib),
+		container.NewHBox(run),
+		results,
+	)
+}
+
+// showSweepResults lists the best sweep results in box.
+func (dg *DeenGUI) showSweepResults(box *fyne.Container, sweep pipeline.Sweep, ranked []pipeline.SweepResult, hide func()) {
+	box.RemoveAll()
+	if len(ranked) > maxSweepRows {
+		ranked = ranked[:maxSweepRows]
+	}
+	for _, r := range ranked {
+		var varied []string
+		for _, p := range sweep.Params {
+			varied = append(varied, p.Name+"="+r.Options[p.Name])
+		}
+		title := widget.NewLabelWithStyle(fmt.Sprintf("%d · %s", r.Score, strings.Join(varied, " ")), fyne.TextAlignLeading, fyne.TextStyle{Bold: true, Monospace: true})
+		detail := "Error: " + r.Error
+		if r.Error == "" {
+			detail = guiSafeSuggestionPreview(r.Preview)
+		}
+		step := sweep.Step(r)
+		add := widget.NewButton("Add", func() {
+			hide()
+			dg.runPipelineWork("Processing", func() error {
+				dg.pipe.AddStepWithOptions(step.Plugin, step.Unprocess, step.Options)
+				return nil
+			}, dg.rebuild)
+		})
+		if r.Error != "" {
+			add.Disable()
+		}
+		box.Add(container.NewBorder(nil, nil, nil, add, container.NewVBox(title, widget.NewLabel(detail))))
+	}
+}
+
+// parseSweepParams parses space separated NAME=VALUES sweep parameters.
+func parseSweepParams(s string) ([]pipeline.SweepParam, error) {
+	var params []pipeline.SweepParam
+	for _, spec := range strings.Fields(s) {
+		p, err := pipeline.ParseSweepParam(spec)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
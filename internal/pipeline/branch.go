@@ -0,0 +1,244 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MainOutput names the result of the main chain among the outputs of a
+// pipeline with branches.
+const MainOutput = "main"
+
+var branchNameRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// Branch is a named side chain of a pipeline. It forks off the main chain
+// after its first After steps, or off the source when After is 0, and runs
+// its own steps on that data. The result of every branch is a named output
+// next to the result of the main chain, so several decoders or digests of one
+// intermediate can be compared without duplicating the chain in front of it.
+type Branch struct {
+	Name  string
+	After int
+
+	steps []*Step
+	input []byte
+}
+
+type branchSnapshot struct {
+	Name  string
+	After int
+	Steps []stepSnapshot
+}
+
+type chainFileBranch struct {
+	Name  string          `json:"name"`
+	After int             `json:"after"`
+	Steps []chainFileStep `json:"steps"`
+}
+
+// Steps returns the steps of the branch. They are replaced as a whole with
+// Pipeline.SetBranchSteps.
+func (b *Branch) Steps() []*Step { return b.steps }
+
+// Input returns the data the branch forks off.
+func (b *Branch) Input() []byte { return b.input }
+
+// Result returns the output of the last step of the branch, or its input if
+// it has no steps.
+func (b *Branch) Result() []byte {
+	if len(b.steps) == 0 {
+		return b.input
+	}
+	return b.steps[len(b.steps)-1].effective
+}
+
+// Err returns the error of the first failing step of the branch.
+func (b *Branch) Err() error {
+	for i, s := range b.steps {
+		if s.err != nil {
+			return &StepError{Index: i, Plugin: s.Plugin, Err: s.err}
+		}
+	}
+	return nil
+}
+
+// ChainString formats the steps of the branch as a chain expression, as
+// branch editors show it and ParseMapChain reads it back.
+func (b *Branch) ChainString() string {
+	steps := make([]Step, len(b.steps))
+	for i, s := range b.steps {
+		steps[i] = *s
+	}
+	return MapChainString(steps)
+}
+
+func (b *Branch) snapshot() branchSnapshot {
+	bs := branchSnapshot{Name: b.Name, After: b.After, Steps: make([]stepSnapshot, 0, len(b.steps))}
+	for _, s := range b.steps {
+		ss := stepSnapshotOf(s)
+		ss.Override, ss.HasOverride = nil, false
+		bs.Steps = append(bs.Steps, ss)
+	}
+	return bs
+}
+
+func (bs branchSnapshot) branch() *Branch {
+	return &Branch{Name: bs.Name, After: bs.After, steps: newSteps(bs.Steps)}
+}
+
+// checkBranches validates the names and fork points of branches for a main
+// chain of n steps.
+func checkBranches(branches []branchSnapshot, n int) error {
+	seen := map[string]bool{}
+	for _, b := range branches {
+		if err := CheckBranchName(b.Name); err != nil {
+			return err
+		}
+		if seen[b.Name] {
+			return fmt.Errorf("duplicate branch %q", b.Name)
+		}
+		seen[b.Name] = true
+		if b.After < 0 || b.After > n {
+			return fmt.Errorf("branch %s forks off after step %d, but the chain has %d steps", b.Name, b.After, n)
+		}
+	}
+	return nil
+}
+
+// CheckBranchName reports whether name is a valid branch name, so editors can
+// flag invalid names before a branch is added or renamed.
+func CheckBranchName(name string) error {
+	if name == MainOutput {
+		return fmt.Errorf("branch name %q is reserved for the main chain", name)
+	}
+	if !branchNameRe.MatchString(name) {
+		return fmt.Errorf("invalid branch name %q: use letters, digits, '_', '.' and '-'", name)
+	}
+	return nil
+}
+
+// Branches returns the branches of the pipeline in the order they were added.
+func (p *Pipeline) Branches() []*Branch { return p.branches }
+
+// AddBranch adds a branch that forks off after the first after steps of the
+// main chain and runs steps, and returns its index. Steps are checked like in
+// LoadSteps.
+func (p *Pipeline) AddBranch(name string, after int, steps []Step) (int, error) {
+	bs := branchSnapshot{Name: name, After: after}
+	branches := append(p.branchSnapshots(), bs)
+	if err := checkBranches(branches, len(p.steps)); err != nil {
+		return -1, err
+	}
+	ss, err := checkedSteps(steps)
+	if err != nil {
+		return -1, fmt.Errorf("branch %s: %w", name, err)
+	}
+	bs.Steps = ss
+	p.record()
+	p.branches = append(p.branches, bs.branch())
+	p.Compute()
+	return len(p.branches) - 1, nil
+}
+
+// SetBranchSteps replaces the steps of branch b. Steps are checked like in
+// LoadSteps.
+func (p *Pipeline) SetBranchSteps(b int, steps []Step) error {
+	if b < 0 || b >= len(p.branches) {
+		return nil
+	}
+	ss, err := checkedSteps(steps)
+	if err != nil {
+		return fmt.Errorf("branch %s: %w", p.branches[b].Name, err)
+	}
+	p.record()
+	p.branches[b].steps = newSteps(ss)
+	p.Compute()
+	return nil
+}
+
+// RenameBranch renames branch b, which renames its output.
+func (p *Pipeline) RenameBranch(b int, name string) error {
+	if b < 0 || b >= len(p.branches) || p.branches[b].Name == name {
+		return nil
+	}
+	branches := p.branchSnapshots()
+	branches[b].Name = name
+	if err := checkBranches(branches, len(p.steps)); err != nil {
+		return err
+	}
+	p.record()
+	p.branches[b].Name = name
+	return nil
+}
+
+// RemoveBranch removes branch b and its output.
+func (p *Pipeline) RemoveBranch(b int) {
+	if b < 0 || b >= len(p.branches) {
+		return
+	}
+	p.record()
+	p.branches = append(p.branches[:b], p.branches[b+1:]...)
+}
+
+// OutputNames returns the names of the pipeline outputs: MainOutput followed
+// by the branch names.
+func (p *Pipeline) OutputNames() []string {
+	names := []string{MainOutput}
+	for _, b := range p.branches {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+// SelectOutput turns the pipeline into the linear chain that produces the
+// named output, so Stream, Trace and the other runners produce it: the main
+// chain is cut where the branch forks off, the branch steps are appended and
+// all branches are dropped. Like LoadJSON, it neither records undo history
+// nor computes step outputs. Selecting MainOutput only drops the branches.
+func (p *Pipeline) SelectOutput(name string) error {
+	if name != MainOutput {
+		i := p.branchIndex(name)
+		if i < 0 {
+			return fmt.Errorf("unknown output %q (outputs: %s)", name, strings.Join(p.OutputNames(), ", "))
+		}
+		b := p.branches[i]
+		p.steps = append(p.steps[:b.After:b.After], b.steps...)
+	}
+	p.branches = nil
+	return nil
+}
+
+func (p *Pipeline) branchIndex(name string) int {
+	for i, b := range p.branches {
+		if b.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *Pipeline) branchSnapshots() []branchSnapshot {
+	out := make([]branchSnapshot, 0, len(p.branches))
+	for _, b := range p.branches {
+		out = append(out, b.snapshot())
+	}
+	return out
+}
+
+// computeBranches brings the branches up to date with the main chain.
+func (p *Pipeline) computeBranches(ctx context.Context) {
+	for _, b := range p.branches {
+		in, inSum := p.source, p.sourceDigest()
+		if b.After > 0 {
+			s := p.steps[b.After-1]
+			in, inSum = s.effective, s.effSum
+		}
+		b.input = in
+		for _, s := range b.steps {
+			p.computeStep(ctx, s, in, inSum)
+			in, inSum = s.effective, s.effSum
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func branchChain(t *testing.T, expr string) []Step {
+	t.Helper()
+	steps, err := ParseMapChain(expr)
+	if err != nil {
+		t.Fatalf("ParseMapChain(%q): %v", expr, err)
+	}
+	return steps
+}
+
+func TestBranchesComputeNamedOutputs(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("aGk="))
+	p.AddStep("base64", true)
+	p.AddStep("hex", false)
+	if _, err := p.AddBranch("b64", 1, branchChain(t, "base64")); err != nil {
+		t.Fatalf("AddBranch: %v", err)
+	}
+	if _, err := p.AddBranch("src", 0, nil); err != nil {
+		t.Fatalf("AddBranch: %v", err)
+	}
+	if got := string(p.Result()); got != "6869" {
+		t.Fatalf("Result = %q", got)
+	}
+	b := p.Branches()
+	if got := string(b[0].Result()); got != "aGk=" || b[0].Err() != nil {
+		t.Fatalf("branch b64 Result = %q, Err = %v", got, b[0].Err())
+	}
+	if got := string(b[1].Result()); got != "aGk=" {
+		t.Fatalf("branch src Result = %q", got)
+	}
+	if names := strings.Join(p.OutputNames(), ","); names != "main,b64,src" {
+		t.Fatalf("OutputNames = %s", names)
+	}
+
+	// Branches follow the main chain.
+	p.SetSource([]byte("Ynll"))
+	if got := string(b[0].Result()); got != "Ynll" {
+		t.Fatalf("branch b64 Result after SetSource = %q", got)
+	}
+	if err := p.SetBranchSteps(0, branchChain(t, ".hex")); err != nil {
+		t.Fatalf("SetBranchSteps: %v", err)
+	}
+	if err := p.Branches()[0].Err(); err == nil || !strings.Contains(err.Error(), "step 1 (hex)") {
+		t.Fatalf("branch Err = %v, want hex decode error", err)
+	}
+}
+
+func TestBranchValidation(t *testing.T) {
+	p := New()
+	p.AddStep("hex", false)
+	for _, tt := range []struct {
+		name  string
+		after int
+		steps []Step
+		want  string
+	}{
+		{"main", 0, nil, "reserved"},
+		{"a b", 0, nil, "invalid branch name"},
+		{"late", 2, nil, "chain has 1 steps"},
+		{"bad", 1, []Step{{Plugin: "nope"}}, `branch bad: step 1: unknown plugin "nope"`},
+	} {
+		if _, err := p.AddBranch(tt.name, tt.after, tt.steps); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("AddBranch(%q, %d) = %v, want %q", tt.name, tt.after, err, tt.want)
+		}
+	}
+	if _, err := p.AddBranch("x", 1, nil); err != nil {
+		t.Fatalf("AddBranch: %v", err)
+	}
+	if _, err := p.AddBranch("x", 0, nil); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Fatalf("duplicate AddBranch = %v", err)
+	}
+	if _, err := p.AddBranch("y", 0, nil); err != nil {
+		t.Fatalf("AddBranch: %v", err)
+	}
+	if err := p.RenameBranch(1, "x"); err == nil {
+		t.Fatal("RenameBranch to a taken name = nil, want error")
+	}
+	if err := p.RenameBranch(1, "z"); err != nil || p.Branches()[1].Name != "z" {
+		t.Fatalf("RenameBranch = %v, name %q", err, p.Branches()[1].Name)
+	}
+}
+
+func TestBranchForkPointsFollowSteps(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("a"))
+	p.AddStep("hex", false)    // 61
+	p.AddStep("base64", false) // NjE=
+	p.AddStep("url", false)    // NjE%3D
+	p.AddBranch("after-b64", 2, nil)
+	p.AddBranch("after-url", 3, nil)
+
+	p.MoveStep(1, 0)
+	if got := p.Branches()[0]; got.After != 1 || string(got.Result()) != "YQ==" {
+		t.Fatalf("after MoveStep: After = %d, Result = %q", got.After, got.Result())
+	}
+	p.DuplicateStep(0)
+	if a, b := p.Branches()[0].After, p.Branches()[1].After; a != 1 || b != 4 {
+		t.Fatalf("after DuplicateStep: After = %d, %d", a, b)
+	}
+	p.RemoveStep(0)
+	if a, b := p.Branches()[0].After, p.Branches()[1].After; a != 0 || b != 3 {
+		t.Fatalf("after RemoveStep: After = %d, %d", a, b)
+	}
+}
+
+func TestBranchUndoRedo(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("hi"))
+	p.AddStep("hex", false)
+	p.AddBranch("b", 1, branchChain(t, "base64"))
+	p.SetBranchSteps(0, branchChain(t, "url"))
+	p.RenameBranch(0, "c")
+	p.RemoveBranch(0)
+	if len(p.Branches()) != 0 {
+		t.Fatalf("Branches = %d after RemoveBranch", len(p.Branches()))
+	}
+
+	if !p.Undo() || p.Branches()[0].Name != "c" {
+		t.Fatal("Undo did not restore the removed branch")
+	}
+	if !p.Undo() || p.Branches()[0].Name != "b" || p.Branches()[0].ChainString() != "url" {
+		t.Fatalf("Undo did not restore the branch name: %+v", p.Branches()[0])
+	}
+	if !p.Undo() || p.Branches()[0].ChainString() != "base64" || string(p.Branches()[0].Result()) != "Njg2OQ==" {
+		t.Fatalf("Undo did not restore the branch steps: %q", p.Branches()[0].ChainString())
+	}
+	if !p.Undo() || len(p.Branches()) != 0 {
+		t.Fatal("Undo did not remove the added branch")
+	}
+	if !p.Redo() || len(p.Branches()) != 1 || string(p.Branches()[0].Result()) != "Njg2OQ==" {
+		t.Fatal("Redo did not add the branch back")
+	}
+}
+
+func TestBranchChainJSON(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("aGk="))
+	p.AddStep("base64", true)
+	data, err := p.ExportJSON()
+	if err != nil {
+		t.Fatalf("ExportJSON: %v", err)
+	}
+	if !strings.Contains(string(data), `"version": 1`) {
+		t.Fatalf("chain without branches is not version 1:\n%s", data)
+	}
+
+	p.AddBranch("zlib", 1, branchChain(t, "zlib | base64"))
+	data, err = p.ExportJSON()
+	if err != nil {
+		t.Fatalf("ExportJSON: %v", err)
+	}
+	if !strings.Contains(string(data), `"version": 2`) || !strings.Contains(string(data), `"name": "zlib"`) {
+		t.Fatalf("chain with branches:\n%s", data)
+	}
+	q := New()
+	if err := q.ImportJSON(data); err != nil {
+		t.Fatalf("ImportJSON: %v", err)
+	}
+	if got, want := q.Branches()[0].Result(), p.Branches()[0].Result(); !bytes.Equal(got, want) {
+		t.Fatalf("imported branch Result = %q, want %q", got, want)
+	}
+
+	for _, tt := range []struct{ chain, want string }{
+		{`{"version": 1, "steps": [], "branches": [{"name": "a", "after": 0, "steps": []}]}`, "need chain version 2"},
+		{`{"version": 3, "steps": []}`, "unsupported chain version 3"},
+		{`{"version": 2, "steps": [], "branches": [{"name": "a", "after": 1, "steps": []}]}`, "chain has 0 steps"},
+		{`{"version": 2, "steps": [], "branches": [{"name": "a", "after": 0, "steps": [{"plugin": "nope"}]}]}`, "branch a: step 1"},
+	} {
+		if err := New().LoadJSON([]byte(tt.chain)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadJSON(%s) = %v, want %q", tt.chain, err, tt.want)
+		}
+	}
+}
+
+func TestSelectOutputStreamsBranch(t *testing.T) {
+	p := New()
+	data := []byte(`{"version": 2, "params": [{"name": "k", "default": "key"}],
+		"steps": [{"plugin": "base64", "unprocess": true}, {"plugin": "hex"}],
+		"branches": [{"name": "mac", "after": 1, "steps": [{"plugin": "hmac", "options": {"key": "${k}"}}, {"plugin": "hex"}]}]}`)
+	if err := p.LoadJSON(data); err != nil {
+		t.Fatalf("LoadJSON: %v", err)
+	}
+	if err := p.SelectOutput("nope"); err == nil || !strings.Contains(err.Error(), "outputs: main, mac") {
+		t.Fatalf("SelectOutput(nope) = %v", err)
+	}
+	if err := p.SelectOutput("mac"); err != nil {
+		t.Fatalf("SelectOutput: %v", err)
+	}
+	if len(p.Branches()) != 0 || p.Len() != 3 {
+		t.Fatalf("after SelectOutput: %d steps, %d branches", p.Len(), len(p.Branches()))
+	}
+	var buf bytes.Buffer
+	if err := p.StreamFrom(context.Background(), strings.NewReader("aGk="), &buf); err != nil {
+		t.Fatalf("StreamFrom: %v", err)
+	}
+	// hex of a 32-byte HMAC-SHA256, itself hex encoded.
+	if buf.Len() != 128 {
+		t.Fatalf("StreamFrom = %q, want a hex encoded HMAC", buf.String())
+	}
+}
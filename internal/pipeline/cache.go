@@ -0,0 +1,146 @@
+package pipeline
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+
+	"github.com/takeshixx/deen/internal/plugins"
+)
+
+// defaultCacheSize is the number of output bytes kept by the step cache.
+const defaultCacheSize = 64 << 20
+
+// digest identifies content by its SHA-256 hash.
+type digest [sha256.Size]byte
+
+func digestOf(b []byte) digest { return sha256.Sum256(b) }
+
+// stepKey addresses a step result by everything that determines it: the input
+// content, the plugin, the direction, the option values and the region, and
+// for map steps the split settings and nested steps.
+func stepKey(in digest, s *Step) digest {
+	h := sha256.New()
+	h.Write(in[:])
+	writeStep(h, s)
+	var k digest
+	h.Sum(k[:0])
+	return k
+}
+
+// volatile reports whether the result of s depends on more than its key, so it
+// must neither be cached nor reused: its plugin, or one of its nested map
+// steps, is non-deterministic.
+func volatile(s *Step) bool {
+	if s.Map != nil {
+		for i := range s.Map.Steps {
+			if nested := &s.Map.Steps[i]; !nested.Disabled && nested.Plugin != "" && volatile(nested) {
+				return true
+			}
+		}
+		return false
+	}
+	plugin, _, ok := plugins.Resolve(s.Plugin)
+	return ok && plugin.NonDeterministic
+}
+
+func writeStep(h hash.Hash, s *Step) {
+	writeField(h, s.Plugin)
+	if s.Unprocess {
+		h.Write([]byte{1})
+	} else {
+		h.Write([]byte{0})
+	}
+	for _, name := range sortedOptionNames(s.Options) {
+		writeField(h, name)
+		writeField(h, s.Options[name])
+	}
+	writeField(h, s.Region)
+	if s.Map == nil {
+		return
+	}
+	writeField(h, s.Map.Split)
+	writeField(h, s.Map.Pattern)
+	for i := range s.Map.Steps {
+		nested := &s.Map.Steps[i]
+		if nested.Disabled || nested.Plugin == "" {
+			continue
+		}
+		writeField(h, "step")
+		writeStep(h, nested)
+	}
+}
+
+// writeField writes a length-prefixed string so that adjacent fields cannot
+// run into each other.
+func writeField(h hash.Hash, s string) {
+	var n [binary.MaxVarintLen64]byte
+	h.Write(n[:binary.PutUvarint(n[:], uint64(len(s)))])
+	h.Write([]byte(s))
+}
+
+// stepCache is a content-addressed LRU of successful step outputs, bounded by
+// the total size of the cached outputs.
+type stepCache struct {
+	max     int64
+	size    int64
+	order   *list.List // front is most recently used
+	entries map[digest]*list.Element
+}
+
+type cacheEntry struct {
+	key    digest
+	output []byte
+	sum    digest // digest of output
+}
+
+func newStepCache(max int64) *stepCache {
+	return &stepCache{max: max, order: list.New(), entries: map[digest]*list.Element{}}
+}
+
+func (c *stepCache) get(k digest) (*cacheEntry, bool) {
+	el, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry), true
+}
+
+func (c *stepCache) put(e *cacheEntry) {
+	n := int64(len(e.output))
+	if n > c.max {
+		return
+	}
+	if el, ok := c.entries[e.key]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[e.key] = c.order.PushFront(e)
+	c.size += n
+	c.evict()
+}
+
+func (c *stepCache) resize(max int64) {
+	c.max = max
+	c.evict()
+}
+
+// evict drops least recently used entries until the cache fits its bound.
+func (c *stepCache) evict() {
+	for c.size > c.max {
+		el := c.order.Back()
+		old := el.Value.(*cacheEntry)
+		c.order.Remove(el)
+		delete(c.entries, old.key)
+		c.size -= int64(len(old.output))
+	}
+}
+
+// SetCacheSize bounds the bytes of step outputs kept for reuse across
+// recomputations. Zero disables the cache; steps whose input and settings are
+// unchanged still keep their previous output.
+func (p *Pipeline) SetCacheSize(n int64) {
+	p.cache.resize(max(n, 0))
+}
@@ -0,0 +1,165 @@
+package pipeline
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/takeshixx/deen/internal/plugins"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// counterRuns numbers the runs of the test-counter plugin, whose output
+// changes on every run without it declaring itself non-deterministic. That
+// makes reuse of earlier results observable.
+var counterRuns int
+
+func init() {
+	err := plugins.Register(func() *types.DeenPlugin {
+		p := types.NewPlugin()
+		p.Name = "test-counter"
+		p.Category = "misc"
+		p.Process = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+			counterRuns++
+			_, err := fmt.Fprintf(w, "run %d", counterRuns)
+			return err
+		}
+		return p
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func addCounterStep(p *Pipeline) int {
+	return p.AddStep("test-counter", false)
+}
+
+func TestComputeKeepsEarlierSteps(t *testing.T) {
+	p := New()
+	addCounterStep(p)
+	first := p.Output(0)
+	p.AddStep("base64", false)
+	p.SetOption(1, "url", "true")
+	p.SetStepDisabled(1, true)
+	p.EditOutput(1, []byte("manual"))
+	if !bytes.Equal(p.Output(0), first) {
+		t.Fatalf("step 0 was recomputed: %q, want %q", p.Output(0), first)
+	}
+}
+
+func TestComputeReRunsNonDeterministicSteps(t *testing.T) {
+	p := New()
+	p.AddStepWithOptions("uuid", false, map[string]string{"gen": "true"})
+	first := p.Output(0)
+	p.AddStep("base64", false)
+	p.SetOption(1, "url", "true")
+	if bytes.Equal(p.Output(0), first) {
+		t.Fatalf("uuid step kept %q after a later step changed", first)
+	}
+	second := p.Output(0)
+	p.RemoveStep(1)
+	p.Undo()
+	if bytes.Equal(p.Output(0), second) {
+		t.Fatalf("uuid step reused %q from the cache", second)
+	}
+}
+
+func TestComputeReusesCacheAcrossUndo(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("x"))
+	addCounterStep(p)
+	first := p.Output(0)
+
+	p.RemoveStep(0)
+	if !p.Undo() {
+		t.Fatal("Undo failed")
+	}
+	if !bytes.Equal(p.Output(0), first) {
+		t.Fatalf("after undo = %q, want cached %q", p.Output(0), first)
+	}
+
+	p.SetStepDisabled(0, true)
+	if !bytes.Equal(p.Output(0), first) || string(p.Result()) != "x" {
+		t.Fatalf("disabled: output %q, result %q", p.Output(0), p.Result())
+	}
+	p.Undo()
+	if !bytes.Equal(p.Result(), first) {
+		t.Fatalf("result after re-enable = %q, want %q", p.Result(), first)
+	}
+}
+
+func TestComputeRecomputesChangedInput(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("a"))
+	p.AddStep("base64", false)
+	p.AddStep("hex", false)
+	p.SetOption(0, "url", "true")
+	p.SetSource([]byte("b"))
+	if got := string(p.Result()); got != "59673d3d" {
+		t.Fatalf("result = %q, want hex of Yg==", got)
+	}
+	p.Undo()
+	if got := string(p.Result()); got != "59513d3d" {
+		t.Fatalf("result after undo = %q, want hex of YQ==", got)
+	}
+}
+
+func TestComputeWithoutCache(t *testing.T) {
+	p := New()
+	p.SetCacheSize(0)
+	addCounterStep(p)
+	first := p.Output(0)
+	p.AddStep("base64", false)
+	if !bytes.Equal(p.Output(0), first) {
+		t.Fatal("unchanged step was recomputed with the cache disabled")
+	}
+	p.RemoveStep(0)
+	p.Undo()
+	if bytes.Equal(p.Output(0), first) {
+		t.Fatal("restored step reused a result with the cache disabled")
+	}
+}
+
+func TestStepCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newStepCache(8)
+	a, b, d := digestOf([]byte("a")), digestOf([]byte("b")), digestOf([]byte("d"))
+	c.put(&cacheEntry{key: a, output: []byte("aaaa")})
+	c.put(&cacheEntry{key: b, output: []byte("bbbb")})
+	c.get(a)
+	c.put(&cacheEntry{key: d, output: []byte("dd")})
+	if _, ok := c.get(b); ok {
+		t.Fatal("least recently used entry was kept")
+	}
+	if _, ok := c.get(a); !ok {
+		t.Fatal("recently used entry was evicted")
+	}
+	c.put(&cacheEntry{key: b, output: make([]byte, 9)})
+	if _, ok := c.get(b); ok || c.size > c.max {
+		t.Fatalf("oversized entry cached, size %d", c.size)
+	}
+}
+
+func TestStepKeyCoversSettings(t *testing.T) {
+	in := digestOf([]byte("in"))
+	base := &Step{Plugin: "base64", Options: map[string]string{"url": "true"}}
+	same := &Step{Plugin: "base64", Options: map[string]string{"url": "true"}}
+	if stepKey(in, base) != stepKey(in, same) {
+		t.Fatal("equal steps have different keys")
+	}
+	for _, s := range []*Step{
+		{Plugin: "base64", Unprocess: true, Options: map[string]string{"url": "true"}},
+		{Plugin: "base64", Options: map[string]string{"url": "false"}},
+		{Plugin: "base64", Options: map[string]string{"ur": "ltrue"}},
+		{Plugin: "base32", Options: map[string]string{"url": "true"}},
+	} {
+		if stepKey(in, s) == stepKey(in, base) {
+			t.Errorf("%+v shares the key of %+v", s, base)
+		}
+	}
+	if stepKey(digestOf([]byte("other")), base) == stepKey(in, base) {
+		t.Error("different inputs share a key")
+	}
+}
@@ -1,8 +1,14 @@
 package pipeline
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"sort"
 	"strings"
+
+	"github.com/takeshixx/deen/internal/plugins"
 )
 
 // CommandLine returns a shell pipeline equivalent to the enabled transform
@@ -14,24 +20,250 @@ func (p *Pipeline) CommandLine() string {
 		if step.Disabled || step.Plugin == "" {
 			continue
 		}
-		cmd := "deen "
-		if step.Unprocess {
-			cmd += "."
+		commands = append(commands, "deen "+stepCommand(step))
+	}
+	return strings.Join(commands, " | ")
+}
+
+// stepCommand returns the arguments of the deen command that runs step. Map
+// steps become `map` commands with their nested chain as one argument.
+func stepCommand(step *Step) string {
+	region := ""
+	if step.Region != "" {
+		region = " -region " + shellQuote(step.Region)
+	}
+	if step.Map != nil {
+		cmd := MapPlugin + " -split " + shellQuote(step.Map.Split)
+		if step.Map.Pattern != "" {
+			cmd += " -pattern " + shellQuote(step.Map.Pattern)
+		}
+		return cmd + region + " " + shellQuote(MapChainString(step.Map.Steps))
+	}
+	cmd := ""
+	if step.Unprocess {
+		cmd += "."
+	}
+	cmd += shellQuote(step.Plugin)
+	for _, name := range sortedOptionNames(step.Options) {
+		value := step.Options[name]
+		if value == "" || value == "false" {
+			continue
+		}
+		cmd += " -" + shellQuote(name)
+		if value != "true" {
+			cmd += " " + shellQuote(value)
+		}
+	}
+	return cmd + region
+}
+
+// LoadCommandLine replaces the pipeline with the steps of a chain expression
+// such as ".url | .base64 | json -no-color". Steps are separated by unquoted
+// pipes and may carry a leading "deen", so the output of CommandLine can be
+// loaded back unchanged. Like LoadJSON it neither records undo history nor
+// computes step outputs.
+func (p *Pipeline) LoadCommandLine(expr string) error {
+	s, err := parseCommandLine(expr)
+	if err != nil {
+		return err
+	}
+	p.load(s)
+	return nil
+}
+
+func parseCommandLine(expr string) (snapshot, error) {
+	segments, err := splitCommandLine(expr)
+	if err != nil {
+		return snapshot{}, err
+	}
+	s := snapshot{Steps: make([]stepSnapshot, 0, len(segments))}
+	for i, args := range segments {
+		if len(args) > 0 && args[0] == "deen" {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			return snapshot{}, fmt.Errorf("step %d: missing plugin", i+1)
+		}
+		args, region, err := cutRegionFlag(args)
+		if err == nil && region != "" {
+			err = CheckRegion(region)
+		}
+		if err != nil {
+			return snapshot{}, fmt.Errorf("step %d (%s): %w", i+1, args[0], err)
+		}
+		if args[0] == MapPlugin {
+			m, err := parseMapCommand(args[1:])
+			if err != nil {
+				return snapshot{}, fmt.Errorf("step %d (%s): %w", i+1, MapPlugin, err)
+			}
+			s.Steps = append(s.Steps, stepSnapshot{Plugin: MapPlugin, Map: m, Region: region})
+			continue
+		}
+		plugin, unprocess, ok := plugins.Resolve(args[0])
+		if !ok {
+			return snapshot{}, fmt.Errorf("step %d: unknown plugin %q", i+1, args[0])
+		}
+		if unprocess && plugin.Unprocess == nil {
+			return snapshot{}, fmt.Errorf("step %d (%s): does not support decoding", i+1, plugin.Name)
+		}
+
+		fs := flag.NewFlagSet(plugin.Name, flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		if plugin.RegisterFlags != nil {
+			plugin.RegisterFlags(fs)
+		}
+		if err := fs.Parse(args[1:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				err = errors.New("help is not available inside a chain expression")
+			}
+			return snapshot{}, fmt.Errorf("step %d (%s): %w", i+1, plugin.Name, err)
+		}
+		if err := plugin.ValidateFlags(fs); err != nil {
+			return snapshot{}, fmt.Errorf("step %d (%s): %w", i+1, plugin.Name, err)
+		}
+		if fs.NArg() > 0 {
+			return snapshot{}, fmt.Errorf("step %d (%s): unexpected argument %q", i+1, plugin.Name, fs.Arg(0))
 		}
-		cmd += shellQuote(step.Plugin)
-		for _, name := range sortedOptionNames(step.Options) {
-			value := step.Options[name]
-			if value == "" || value == "false" {
-				continue
+		opts := make(map[string]string)
+		fs.Visit(func(f *flag.Flag) {
+			opts[f.Name] = f.Value.String()
+		})
+		s.Steps = append(s.Steps, stepSnapshot{
+			Plugin:    plugin.Name,
+			Unprocess: unprocess,
+			Options:   normalizeStepOptions(plugin.Name, opts),
+			Region:    region,
+		})
+	}
+	return s, nil
+}
+
+// cutRegionFlag removes the -region flag, which every step accepts, from the
+// arguments of a step and returns its value.
+func cutRegionFlag(args []string) ([]string, string, error) {
+	rest := args[:1:1]
+	region := ""
+	for j := 1; j < len(args); j++ {
+		a := args[j]
+		switch {
+		case a == "--":
+			return append(rest, args[j:]...), region, nil
+		case a == "-region" || a == "--region":
+			if j+1 >= len(args) {
+				return nil, "", errors.New("flag needs an argument: -region")
 			}
-			cmd += " -" + shellQuote(name)
-			if value != "true" {
-				cmd += " " + shellQuote(value)
+			j++
+			region = args[j]
+		case strings.HasPrefix(a, "-region=") || strings.HasPrefix(a, "--region="):
+			_, region, _ = strings.Cut(a, "=")
+		default:
+			rest = append(rest, a)
+		}
+	}
+	return rest, region, nil
+}
+
+// parseMapCommand parses the arguments of a map step in a chain expression:
+// the -split and -pattern flags followed by the nested chain expression.
+func parseMapCommand(args []string) (*Map, error) {
+	fs := flag.NewFlagSet(MapPlugin, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	m := &Map{}
+	fs.StringVar(&m.Split, "split", SplitLines, "how to split the input: "+strings.Join(SplitModes, ", "))
+	fs.StringVar(&m.Pattern, "pattern", "", "delimiter, regex or JSONPath of the split")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			err = errors.New("help is not available inside a chain expression")
+		}
+		return nil, err
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		return nil, errors.New("expected the nested chain as one quoted argument")
+	}
+	steps, err := ParseMapChain(fs.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	m.Steps = steps
+	return m, nil
+}
+
+// splitCommandLine tokenizes a chain expression with POSIX shell quoting
+// rules and splits it into one argument list per pipe-separated step.
+func splitCommandLine(expr string) ([][]string, error) {
+	var (
+		segments [][]string
+		args     []string
+		word     strings.Builder
+		inWord   bool
+		quote    rune
+		escaped  bool
+	)
+	endWord := func() {
+		if inWord {
+			args = append(args, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	for _, r := range expr {
+		switch {
+		case escaped:
+			// Inside double quotes a backslash only escapes the characters
+			// that are special there and is kept before any other.
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", r) {
+				word.WriteByte('\\')
+			}
+			if r != '\n' {
+				word.WriteRune(r) // a backslash before a newline joins lines
 			}
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case r == '|':
+			endWord()
+			segments = append(segments, args)
+			args = nil
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			endWord()
+		default:
+			word.WriteRune(r)
+			inWord = true
 		}
-		commands = append(commands, cmd)
 	}
-	return strings.Join(commands, " | ")
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	endWord()
+	if len(segments) == 0 && len(args) == 0 {
+		return nil, errors.New("empty chain expression")
+	}
+	return append(segments, args), nil
 }
 
 func sortedOptionNames(options map[string]string) []string {
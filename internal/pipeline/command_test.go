@@ -1,6 +1,10 @@
 package pipeline
 
-import "testing"
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestCommandLine(t *testing.T) {
 	p := New()
@@ -50,3 +54,93 @@ func TestShellQuoteSingleQuote(t *testing.T) {
 		t.Fatalf("quote = %q", got)
 	}
 }
+
+func TestLoadCommandLine(t *testing.T) {
+	p := New()
+	if err := p.LoadCommandLine(".url | .b64 | .gzip | json -no-color"); err != nil {
+		t.Fatalf("LoadCommandLine: %v", err)
+	}
+	want := []struct {
+		plugin    string
+		unprocess bool
+		options   map[string]string
+	}{
+		{"url", true, map[string]string{}},
+		{"base64", true, map[string]string{}},
+		{"gzip", true, map[string]string{}},
+		{"json", false, map[string]string{"no-color": "true"}},
+	}
+	if p.Len() != len(want) {
+		t.Fatalf("steps = %d, want %d", p.Len(), len(want))
+	}
+	for i, w := range want {
+		s := p.Steps()[i]
+		if s.Plugin != w.plugin || s.Unprocess != w.unprocess || !reflect.DeepEqual(s.Options, w.options) {
+			t.Errorf("step %d = %s/%v/%v, want %s/%v/%v", i, s.Plugin, s.Unprocess, s.Options, w.plugin, w.unprocess, w.options)
+		}
+	}
+}
+
+func TestSplitCommandLineBackslashes(t *testing.T) {
+	for _, tt := range []struct {
+		expr string
+		want []string
+	}{
+		{`regex -pattern "\d+"`, []string{"regex", "-pattern", `\d+`}},
+		{`regex -pattern "a\"b"`, []string{"regex", "-pattern", `a"b`}},
+		{`x "\\ \$ \` + "`" + `"`, []string{"x", `\ $ ` + "`"}},
+		{"x \"a\\\nb\"", []string{"x", "ab"}},
+		{`x '\d+' \d`, []string{"x", `\d+`, "d"}},
+	} {
+		segments, err := splitCommandLine(tt.expr)
+		if err != nil {
+			t.Fatalf("splitCommandLine(%q): %v", tt.expr, err)
+		}
+		if len(segments) != 1 || !reflect.DeepEqual(segments[0], tt.want) {
+			t.Errorf("splitCommandLine(%q) = %q, want %q", tt.expr, segments, tt.want)
+		}
+	}
+}
+
+func TestLoadCommandLineRoundTrip(t *testing.T) {
+	p := New()
+	p.AddStep("jq", false)
+	p.SetOption(0, "q", `.items[] | select(.name == "it's")`)
+	p.SetOption(0, "plain", "true")
+	p.AddStep("base64", false)
+	p.SetOption(1, "url", "true")
+	p.AddStep("gzip", true)
+	cmd := p.CommandLine()
+
+	q := New()
+	if err := q.LoadCommandLine(cmd); err != nil {
+		t.Fatalf("LoadCommandLine(%q): %v", cmd, err)
+	}
+	if got := q.CommandLine(); got != cmd {
+		t.Fatalf("round trip = %q, want %q", got, cmd)
+	}
+	if got := q.Steps()[0].Options["q"]; got != `.items[] | select(.name == "it's")` {
+		t.Fatalf("jq query = %q", got)
+	}
+}
+
+func TestLoadCommandLineErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "empty chain expression"},
+		{"base64 | nope", `step 2: unknown plugin "nope"`},
+		{"base64 | | hex", "step 2: missing plugin"},
+		{"hex | base64 -bogus", "step 2 (base64): flag provided but not defined: -bogus"},
+		{"base64 extra", `step 1 (base64): unexpected argument "extra"`},
+		{".sha256", "step 1 (sha256): does not support decoding"},
+		{"jq -q '.a", "unterminated ' quote"},
+	}
+	for _, tt := range tests {
+		err := New().LoadCommandLine(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadCommandLine(%q) err = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,1359 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CyberChefOp is one operation of a CyberChef recipe in its JSON form, e.g.
+// {"op": "From Base64", "args": ["A-Za-z0-9+/=", true, false]}.
+type CyberChefOp struct {
+	Op         string `json:"op"`
+	Args       []any  `json:"args"`
+	Disabled   bool   `json:"disabled,omitempty"`
+	Breakpoint bool   `json:"breakpoint,omitempty"`
+}
+
+// CyberChefNote reports an operation or step that a translation between
+// CyberChef recipes and deen chains left out, or translated with a
+// difference worth knowing about.
+type CyberChefNote struct {
+	Index   int    `json:"index"` // zero-based position in the recipe or chain
+	Name    string `json:"name"`  // CyberChef operation or deen plugin
+	Skipped bool   `json:"skipped,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+func (n CyberChefNote) String() string {
+	if n.Skipped {
+		return fmt.Sprintf("%d. %s: skipped: %s", n.Index+1, n.Name, n.Reason)
+	}
+	return fmt.Sprintf("%d. %s: %s", n.Index+1, n.Name, n.Reason)
+}
+
+// CyberChefURL is the public CyberChef instance export links point to.
+const CyberChefURL = "https://gchq.github.io/CyberChef/"
+
+// Patterns CyberChef itself uses to read the Chef format, in which arguments
+// are JSON with single-quoted strings, e.g. From_Base64('A-Za-z0-9+/=',true).
+var (
+	chefOpRe         = regexp.MustCompile(`([^(]+)\(((?:'[^'\\]*(?:\\.[^'\\]*)*'|[^)/'])*)(/[^)]+)?\)`)
+	chefOpenQuoteRe  = regexp.MustCompile(`(^|,|\{|:)'`)
+	chefCloseQuoteRe = regexp.MustCompile(`([^\\]|(?:\\\\)+)'(,|:|\}|$)`)
+	jsonStringRe     = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+)
+
+// ParseCyberChefRecipe reads a CyberChef recipe in any of the forms CyberChef
+// saves or shares: JSON, the Chef format, or a CyberChef URL with a recipe
+// parameter.
+func ParseCyberChefRecipe(recipe string) ([]CyberChefOp, error) {
+	recipe = strings.TrimSpace(recipe)
+	if i := strings.Index(recipe, "recipe="); i >= 0 && !strings.HasPrefix(recipe, "[") {
+		recipe, _, _ = strings.Cut(recipe[i+len("recipe="):], "&")
+		if unescaped, err := url.PathUnescape(recipe); err == nil {
+			recipe = unescaped
+		}
+		recipe = strings.TrimSpace(recipe)
+	}
+	if recipe == "" {
+		return nil, errors.New("empty CyberChef recipe")
+	}
+	if strings.HasPrefix(recipe, "[") {
+		var ops []CyberChefOp
+		if err := json.Unmarshal([]byte(recipe), &ops); err != nil {
+			return nil, fmt.Errorf("invalid CyberChef recipe JSON: %w", err)
+		}
+		for i, op := range ops {
+			if op.Op == "" {
+				return nil, fmt.Errorf("CyberChef recipe operation %d has no name", i+1)
+			}
+		}
+		return ops, nil
+	}
+	recipe = strings.ReplaceAll(recipe, "\n", "")
+	var ops []CyberChefOp
+	for _, m := range chefOpRe.FindAllStringSubmatch(recipe, -1) {
+		args := strings.ReplaceAll(m[2], `"`, `\"`)
+		args = chefOpenQuoteRe.ReplaceAllString(args, `${1}"`)
+		args = chefCloseQuoteRe.ReplaceAllString(args, `${1}"${2}`)
+		args = strings.ReplaceAll(args, `\'`, `'`)
+		op := CyberChefOp{Op: strings.ReplaceAll(strings.TrimSpace(m[1]), "_", " ")}
+		if err := json.Unmarshal([]byte("["+args+"]"), &op.Args); err != nil {
+			return nil, fmt.Errorf("invalid arguments of CyberChef operation %s: %w", op.Op, err)
+		}
+		op.Disabled = strings.Contains(m[3], "disabled")
+		op.Breakpoint = strings.Contains(m[3], "breakpoint")
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		return nil, errors.New("no operations found in CyberChef recipe")
+	}
+	return ops, nil
+}
+
+// FormatCyberChefJSON formats ops as a CyberChef recipe in JSON, one
+// operation per line.
+func FormatCyberChefJSON(ops []CyberChefOp) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for i, op := range ops {
+		line, err := marshalNoEscape(op)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  ")
+		b.Write(line)
+	}
+	b.WriteString("\n]\n")
+	return b.Bytes(), nil
+}
+
+// FormatCyberChefChef formats ops in the Chef format CyberChef uses in URLs,
+// one operation per line.
+func FormatCyberChefChef(ops []CyberChefOp) (string, error) {
+	var b strings.Builder
+	for _, op := range ops {
+		args, err := marshalNoEscape(op.Args)
+		if err != nil {
+			return "", err
+		}
+		s := string(args)
+		if s == "null" {
+			s = "[]"
+		}
+		s = strings.ReplaceAll(s[1:len(s)-1], "'", `\'`)
+		s = jsonStringRe.ReplaceAllString(s, "'${1}'")
+		s = strings.ReplaceAll(s, `\"`, `"`)
+		b.WriteString(strings.ReplaceAll(op.Op, " ", "_") + "(" + s)
+		if op.Disabled {
+			b.WriteString("/disabled")
+		}
+		if op.Breakpoint {
+			b.WriteString("/breakpoint")
+		}
+		b.WriteString(")\n")
+	}
+	return b.String(), nil
+}
+
+// FormatCyberChefURL returns a link that opens ops in CyberChef.
+func FormatCyberChefURL(ops []CyberChefOp) (string, error) {
+	chef, err := FormatCyberChefChef(ops)
+	if err != nil {
+		return "", err
+	}
+	return CyberChefURL + "#recipe=" + encodeURIFragment(strings.ReplaceAll(chef, "\n", "")), nil
+}
+
+// encodeURIFragment escapes s like CyberChef does for its URLs, keeping the
+// characters of the Chef format that are safe in a URL fragment.
+func encodeURIFragment(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-._~!$'()*,;:@/?", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// marshalNoEscape marshals v like JSON.stringify, without escaping HTML
+// characters.
+func marshalNoEscape(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// ImportCyberChef translates a CyberChef recipe into deen steps. Operations
+// without a deen equivalent are left out and reported as skipped notes;
+// operations translated with a difference, such as a hex delimiter deen does
+// not write, are reported too. Fork and Subsection blocks become map steps.
+// The error is set when the recipe cannot be read or nothing of it could be
+// translated; the notes explain the latter.
+func ImportCyberChef(recipe string) ([]Step, []CyberChefNote, error) {
+	ops, err := ParseCyberChefRecipe(recipe)
+	if err != nil {
+		return nil, nil, err
+	}
+	steps, notes := importCyberChefOps(ops)
+	if len(steps) == 0 {
+		return nil, notes, errors.New("no operation of the CyberChef recipe could be translated")
+	}
+	return steps, notes, nil
+}
+
+// ccFrame is an open Fork or Subsection block of a recipe being imported.
+type ccFrame struct {
+	index   int  // position of the operation that opened the block
+	m       *Map // nil for the top level
+	dropped bool // the block could not be translated
+	steps   []Step
+}
+
+func importCyberChefOps(ops []CyberChefOp) ([]Step, []CyberChefNote) {
+	var notes []CyberChefNote
+	stack := []*ccFrame{{index: -1}}
+	merge := func() {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		parent := stack[len(stack)-1]
+		if f.dropped || len(f.steps) == 0 {
+			return
+		}
+		f.m.Steps = f.steps
+		parent.steps = append(parent.steps, Step{Plugin: MapPlugin, Map: f.m})
+	}
+	for i, op := range ops {
+		top := stack[len(stack)-1]
+		switch op.Op {
+		case "Fork", "Subsection":
+			m, note, err := importCyberChefBlock(op)
+			f := &ccFrame{index: i, m: m, dropped: top.dropped || err != nil}
+			switch {
+			case top.dropped:
+				notes = append(notes, CyberChefNote{Index: i, Name: op.Op, Skipped: true, Reason: fmt.Sprintf("inside the skipped block of operation %d", top.index+1)})
+			case err != nil:
+				notes = append(notes, CyberChefNote{Index: i, Name: op.Op, Skipped: true, Reason: err.Error() + "; the operations up to its Merge are skipped too"})
+			case note != "":
+				notes = append(notes, CyberChefNote{Index: i, Name: op.Op, Reason: note})
+			}
+			stack = append(stack, f)
+		case "Merge":
+			if len(stack) == 1 {
+				notes = append(notes, CyberChefNote{Index: i, Name: op.Op, Skipped: true, Reason: "no Fork or Subsection to merge"})
+				continue
+			}
+			if ccArgs(op.Args).boolean(0, true) {
+				for len(stack) > 1 {
+					merge()
+				}
+			} else {
+				merge()
+			}
+		default:
+			if top.dropped {
+				notes = append(notes, CyberChefNote{Index: i, Name: op.Op, Skipped: true, Reason: fmt.Sprintf("inside the skipped block of operation %d", top.index+1)})
+				continue
+			}
+			imp := cyberChefImports[op.Op]
+			if imp == nil {
+				notes = append(notes, CyberChefNote{Index: i, Name: op.Op, Skipped: true, Reason: "no deen equivalent"})
+				continue
+			}
+			steps, note, err := imp(ccArgs(op.Args))
+			if err != nil {
+				notes = append(notes, CyberChefNote{Index: i, Name: op.Op, Skipped: true, Reason: err.Error()})
+				continue
+			}
+			if note != "" {
+				notes = append(notes, CyberChefNote{Index: i, Name: op.Op, Reason: note})
+			}
+			for _, s := range steps {
+				s.Disabled = op.Disabled
+				top.steps = append(top.steps, s)
+			}
+		}
+	}
+	// CyberChef merges blocks left open at the end of a recipe.
+	for len(stack) > 1 {
+		merge()
+	}
+	return stack[0].steps, notes
+}
+
+// importCyberChefBlock returns the map settings of a Fork or Subsection.
+func importCyberChefBlock(op CyberChefOp) (*Map, string, error) {
+	a := ccArgs(op.Args)
+	var notes []string
+	if op.Op == "Subsection" {
+		pattern := regexFlags(a.str(0, ""), a.boolean(1, false), false, false)
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, "", fmt.Errorf("regex is not supported by Go: %s", err)
+		}
+		if !a.boolean(2, true) {
+			notes = append(notes, "deen transforms every match, not only the first")
+		}
+		if a.boolean(3, false) {
+			notes = append(notes, "deen stops at the first match that fails")
+		}
+		return &Map{Split: SplitRegex, Pattern: pattern}, strings.Join(notes, "; "), nil
+	}
+	split, join := a.str(0, `\n`), a.str(1, `\n`)
+	if split == "" {
+		return nil, "", errors.New("empty split delimiter")
+	}
+	if join != split {
+		notes = append(notes, "deen joins the pieces with the split delimiter "+strconv.Quote(split))
+	}
+	if a.boolean(2, false) {
+		notes = append(notes, "deen stops at the first piece that fails")
+	}
+	m := &Map{Split: SplitDelimiter, Pattern: split}
+	if split == `\n` {
+		m = &Map{Split: SplitLines}
+	}
+	return m, strings.Join(notes, "; "), nil
+}
+
+// ccArgs are the arguments of a CyberChef operation. The accessors return
+// the default for missing arguments and arguments of another type.
+type ccArgs []any
+
+func (a ccArgs) str(i int, def string) string {
+	if i < len(a) {
+		if s, ok := a[i].(string); ok {
+			return s
+		}
+	}
+	return def
+}
+
+func (a ccArgs) boolean(i int, def bool) bool {
+	if i < len(a) {
+		if b, ok := a[i].(bool); ok {
+			return b
+		}
+	}
+	return def
+}
+
+func (a ccArgs) number(i int, def float64) float64 {
+	if i < len(a) {
+		switch v := a[i].(type) {
+		case float64:
+			return v
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+		}
+	}
+	return def
+}
+
+// bytes decodes a toggle string argument, {"option": "Hex", "string": "41"},
+// the way CyberChef does for keys.
+func (a ccArgs) bytes(i int) ([]byte, error) {
+	if i >= len(a) {
+		return nil, nil
+	}
+	m, ok := a[i].(map[string]any)
+	if !ok {
+		if s, ok := a[i].(string); ok {
+			return []byte(s), nil
+		}
+		return nil, fmt.Errorf("argument %d is not a string", i+1)
+	}
+	s, _ := m["string"].(string)
+	switch format, _ := m["option"].(string); format {
+	case "Hex":
+		b, err := hex.DecodeString(hexDelimiters.Replace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex %q", s)
+		}
+		return b, nil
+	case "UTF8", "":
+		return []byte(s), nil
+	case "Latin1":
+		var b []byte
+		for _, r := range s {
+			if r > 0xff {
+				return nil, fmt.Errorf("%q is not Latin-1", s)
+			}
+			b = append(b, byte(r))
+		}
+		return b, nil
+	case "Base64":
+		b, err := decodeBase64Loose(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Base64 %q", s)
+		}
+		return b, nil
+	case "Decimal":
+		var b []byte
+		for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+			n, err := strconv.ParseUint(f, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid decimal byte %q", f)
+			}
+			b = append(b, byte(n))
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("%s keys are not supported", format)
+	}
+}
+
+var hexDelimiters = strings.NewReplacer(" ", "", ",", "", ":", "", "0x", "", `\x`, "", "\n", "", "\r", "")
+
+func decodeBase64Loose(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func ccStep(plugin string, unprocess bool, options ...string) Step {
+	s := Step{Plugin: plugin, Unprocess: unprocess, Options: map[string]string{}}
+	for i := 0; i+1 < len(options); i += 2 {
+		s.Options[options[i]] = options[i+1]
+	}
+	return s
+}
+
+// ccImport translates the arguments of a CyberChef operation into steps. The
+// note describes a difference of the translation; the error why there is
+// none.
+type ccImport func(a ccArgs) (steps []Step, note string, err error)
+
+// simpleImport translates an operation whose arguments deen has no use for.
+func simpleImport(plugin string, unprocess bool) ccImport {
+	return func(ccArgs) ([]Step, string, error) {
+		return []Step{ccStep(plugin, unprocess)}, "", nil
+	}
+}
+
+// punycodeImport imports CyberChef's Punycode operations, which deen only
+// supports for whole hostnames.
+func punycodeImport(unprocess bool) ccImport {
+	return func(a ccArgs) ([]Step, string, error) {
+		if !a.boolean(0, false) {
+			return nil, "", errors.New("deen converts whole hostnames; enable Internationalised domain name")
+		}
+		return []Step{ccStep("idna", unprocess, "profile", "punycode")}, "", nil
+	}
+}
+
+// cyberChefZ85 is CyberChef's alphabet argument for ZeroMQ's Z85.
+const cyberChefZ85 = "0-9a-zA-Z.\\-:+=^!/*?&<>()[]{}@%$#"
+
+// Base64 and Base32 alphabets of CyberChef that deen supports, with the
+// options that select them.
+var (
+	cyberChefBase64 = map[string][]string{
+		"A-Za-z0-9+/=": nil,
+		"A-Za-z0-9+/":  {"raw", "true"},
+		"A-Za-z0-9-_=": {"url", "true"},
+		"A-Za-z0-9-_":  {"url", "true", "raw", "true"},
+	}
+	cyberChefBase32 = map[string][]string{
+		"A-Z2-7=": nil,
+		"A-Z2-7":  {"no-pad", "true"},
+		"0-9A-V=": {"hex", "true"},
+		"0-9A-V":  {"hex", "true", "no-pad", "true"},
+	}
+	// cyberChefBase45 is the alphabet argument of CyberChef's Base45
+	// operations.
+	cyberChefBase45 = "0-9A-Z $%*+\\-./:"
+	// cyberChefBase85 maps the Base85 alphabets of CyberChef that deen
+	// supports to the base85 variant.
+	cyberChefBase85 = map[string]string{
+		"!-u":        "ascii85",
+		cyberChefZ85: "z85",
+	}
+	// cyberChefNamedAlphabets maps the plugins with an -alphabet choice to
+	// the CyberChef alphabet of each choice.
+	cyberChefNamedAlphabets = map[string]map[string]string{
+		"base58": {
+			"bitcoin": "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz",
+			"ripple":  "rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz",
+			"flickr":  "123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ",
+		},
+		"base62": {
+			"standard": "0-9A-Za-z",
+			"inverted": "0-9a-zA-Z",
+		},
+	}
+)
+
+// namedAlphabetImport translates a CyberChef operation whose first argument is
+// an alphabet into a step of plugin with the matching -alphabet choice.
+func namedAlphabetImport(plugin string, unprocess bool) ccImport {
+	return func(a ccArgs) ([]Step, string, error) {
+		for choice, alphabet := range cyberChefNamedAlphabets[plugin] {
+			if a.str(0, alphabet) == alphabet {
+				return []Step{ccStep(plugin, unprocess, "alphabet", choice)}, "", nil
+			}
+		}
+		return nil, "", fmt.Errorf("alphabet %q is not supported", a.str(0, ""))
+	}
+}
+
+// namedAlphabetExport is the reverse of namedAlphabetImport. Decode
+// operations get extra args, such as CyberChef's "remove non-alphabet chars".
+func namedAlphabetExport(plugin, op string, args ...any) ccExport {
+	return func(opts map[string]string) ([]CyberChefOp, string, error) {
+		choice := opts["alphabet"]
+		if choice == "" {
+			choice = pluginDefaults(plugin)["alphabet"]
+		}
+		return ccOp(op, append([]any{cyberChefNamedAlphabets[plugin][choice]}, args...)...), "", nil
+	}
+}
+
+// Code pages of CyberChef's Encode text and Decode text operations and the
+// encodings of the unicode plugin, and the operation names of those
+// encodings.
+var (
+	cyberChefCodePages = map[string]string{
+		"65001": "utf8",
+		"1200":  "utf16le",
+		"1201":  "utf16be",
+		"12000": "utf32le",
+		"12001": "utf32be",
+		"28591": "latin1",
+		"1252":  "windows1252",
+		"932":   "shiftjis",
+		"20932": "eucjp",
+		"936":   "gbk",
+		"54936": "gb18030",
+		"950":   "big5",
+		"51949": "euckr",
+		"20866": "koi8r",
+	}
+	cyberChefEncodings = map[string]string{
+		"utf8":    "UTF-8 (65001)",
+		"utf16le": "UTF-16LE (1200)",
+		"utf16be": "UTF-16BE (1201)",
+		"utf32le": "UTF-32LE (12000)",
+		"utf32be": "UTF-32BE (12001)",
+		"latin1":  "ISO-8859-1 Latin 1; Western European (ISO) (28591)",
+	}
+	codePageRe = regexp.MustCompile(`\((\d+)\)\s*$`)
+)
+
+// Layouts of the timestamp plugin that match the output of CyberChef's From
+// UNIX Timestamp.
+const (
+	cyberChefTimeLayout     = "Mon 2 January 2006 15:04:05 UTC"
+	cyberChefTimeLayoutFrac = "Mon 2 January 2006 15:04:05.000 UTC"
+)
+
+var cyberChefUnits = map[string]string{
+	"Seconds (s)":       "s",
+	"Milliseconds (ms)": "ms",
+	"Microseconds (μs)": "us",
+	"Nanoseconds (ns)":  "ns",
+}
+
+var cyberChefSHA2 = map[string]string{
+	"224":     "sha224",
+	"256":     "sha256",
+	"384":     "sha384",
+	"512":     "sha512",
+	"512/224": "sha512-224",
+	"512/256": "sha512-256",
+}
+
+var cyberChefHMAC = map[string]string{
+	"MD5":    "md5",
+	"SHA1":   "sha1",
+	"SHA224": "sha224",
+	"SHA256": "sha256",
+	"SHA384": "sha384",
+	"SHA512": "sha512",
+}
+
+// cyberChefImports maps CyberChef operations to their deen translation.
+var cyberChefImports map[string]ccImport
+
+func init() {
+	cyberChefImports = map[string]ccImport{
+		"From Base64": func(a ccArgs) ([]Step, string, error) {
+			opts, ok := cyberChefBase64[a.str(0, "A-Za-z0-9+/=")]
+			if !ok {
+				return nil, "", fmt.Errorf("alphabet %q is not supported", a.str(0, ""))
+			}
+			s := ccStep("base64", true, opts...)
+			if a.boolean(2, false) {
+				s.Options["strict"] = "true"
+			}
+			return []Step{s}, "", nil
+		},
+		"To Base64": func(a ccArgs) ([]Step, string, error) {
+			opts, ok := cyberChefBase64[a.str(0, "A-Za-z0-9+/=")]
+			if !ok {
+				return nil, "", fmt.Errorf("alphabet %q is not supported", a.str(0, ""))
+			}
+			return []Step{ccStep("base64", false, opts...)}, "", nil
+		},
+		"From Base32": func(a ccArgs) ([]Step, string, error) {
+			opts, ok := cyberChefBase32[a.str(0, "A-Z2-7=")]
+			if !ok {
+				return nil, "", fmt.Errorf("alphabet %q is not supported", a.str(0, ""))
+			}
+			return []Step{ccStep("base32", true, opts...)}, "", nil
+		},
+		"To Base32": func(a ccArgs) ([]Step, string, error) {
+			opts, ok := cyberChefBase32[a.str(0, "A-Z2-7=")]
+			if !ok {
+				return nil, "", fmt.Errorf("alphabet %q is not supported", a.str(0, ""))
+			}
+			return []Step{ccStep("base32", false, opts...)}, "", nil
+		},
+		"From Base58": namedAlphabetImport("base58", true),
+		"To Base58":   namedAlphabetImport("base58", false),
+		"From Base62": namedAlphabetImport("base62", true),
+		"To Base62":   namedAlphabetImport("base62", false),
+		"From Base85": func(a ccArgs) ([]Step, string, error) {
+			variant, ok := cyberChefBase85[a.str(0, "!-u")]
+			if !ok {
+				return nil, "", fmt.Errorf("alphabet %q is not supported", a.str(0, ""))
+			}
+			if variant == "ascii85" {
+				return []Step{ccStep("base85", true)}, "", nil
+			}
+			return []Step{ccStep("base85", true, "variant", variant)}, "", nil
+		},
+		"To Base85": func(a ccArgs) ([]Step, string, error) {
+			variant, ok := cyberChefBase85[a.str(0, "!-u")]
+			if !ok {
+				return nil, "", fmt.Errorf("alphabet %q is not supported", a.str(0, ""))
+			}
+			if variant == "ascii85" && a.boolean(1, false) {
+				variant = "adobe"
+			}
+			return []Step{ccStep("base85", false, "variant", variant)}, "", nil
+		},
+		"From Base45":   simpleImport("base45", true),
+		"To Base45":     simpleImport("base45", false),
+		"From Punycode": punycodeImport(true),
+		"To Punycode":   punycodeImport(false),
+		"From Hex": func(a ccArgs) ([]Step, string, error) {
+			var note string
+			if d := a.str(0, "Auto"); d != "Auto" && d != "None" {
+				note = "deen reads hex without delimiters, not with " + d
+			}
+			return []Step{ccStep("hex", true)}, note, nil
+		},
+		"To Hex": func(a ccArgs) ([]Step, string, error) {
+			var note string
+			if d := a.str(0, "Space"); d != "None" || a.number(1, 0) != 0 {
+				note = "deen writes hex without delimiters or line breaks"
+			}
+			return []Step{ccStep("hex", false)}, note, nil
+		},
+		"URL Decode": simpleImport("url", true),
+		"URL Encode": func(ccArgs) ([]Step, string, error) {
+			return []Step{ccStep("url", false)}, "deen encodes spaces as + and escapes all reserved characters", nil
+		},
+		"From HTML Entity":      simpleImport("html", true),
+		"To HTML Entity":        simpleImport("html", false),
+		"From Quoted Printable": simpleImport("quoted-printable", true),
+		"To Quoted Printable":   simpleImport("quoted-printable", false),
+		"ROT13": func(a ccArgs) ([]Step, string, error) {
+			if !a.boolean(0, true) || !a.boolean(1, true) || a.boolean(2, false) || a.number(3, 13) != 13 {
+				return nil, "", errors.New("deen only rotates letters by 13")
+			}
+			return []Step{ccStep("rot13", false)}, "", nil
+		},
+		"Gunzip":            simpleImport("gzip", true),
+		"Gzip":              simpleImport("gzip", false),
+		"Zlib Inflate":      simpleImport("zlib", true),
+		"Zlib Deflate":      simpleImport("zlib", false),
+		"Raw Inflate":       simpleImport("flate", true),
+		"Raw Deflate":       simpleImport("flate", false),
+		"Bzip2 Decompress":  simpleImport("bzip2", true),
+		"Bzip2 Compress":    simpleImport("bzip2", false),
+		"Brotli Decompress": simpleImport("brotli", true),
+		"JSON Beautify": func(a ccArgs) ([]Step, string, error) {
+			var note string
+			if !a.boolean(1, false) || a.str(0, "    ") != "    " {
+				note = "deen sorts object keys and indents by four spaces"
+			}
+			return []Step{ccStep("json", false)}, note, nil
+		},
+		"JSON Minify":  simpleImport("json", true),
+		"XML Beautify": simpleImport("xml", false),
+		"XML Minify":   simpleImport("xml", true),
+		"XOR": func(a ccArgs) ([]Step, string, error) {
+			if scheme := a.str(1, "Standard"); scheme != "Standard" {
+				return nil, "", fmt.Errorf("the %s scheme is not supported", scheme)
+			}
+			if a.boolean(2, false) {
+				return nil, "", errors.New("null preserving is not supported")
+			}
+			return byteKeyImport("xor", a)
+		},
+		"ADD": func(a ccArgs) ([]Step, string, error) { return byteKeyImport("add", a) },
+		"SUB": func(a ccArgs) ([]Step, string, error) { return byteKeyImport("sub", a) },
+		"NOT": simpleImport("not", false),
+		"AES Decrypt": func(a ccArgs) ([]Step, string, error) {
+			return aesImport(a, true)
+		},
+		"AES Encrypt": func(a ccArgs) ([]Step, string, error) {
+			return aesImport(a, false)
+		},
+		"MD4": simpleImport("md4", false),
+		"MD5": simpleImport("md5", false),
+		"SHA1": func(a ccArgs) ([]Step, string, error) {
+			if a.number(0, 80) != 80 {
+				return nil, "", errors.New("deen only runs the standard 80 rounds")
+			}
+			return []Step{ccStep("sha1", false)}, "", nil
+		},
+		"SHA2": func(a ccArgs) ([]Step, string, error) {
+			size := a.str(0, "512")
+			plugin, ok := cyberChefSHA2[size]
+			if !ok {
+				return nil, "", fmt.Errorf("size %s is not supported", size)
+			}
+			rounds := 64.0
+			if size != "224" && size != "256" {
+				rounds = 160
+			}
+			if a.number(1, rounds) != rounds {
+				return nil, "", fmt.Errorf("deen only runs the standard %d rounds", int(rounds))
+			}
+			return []Step{ccStep(plugin, false)}, "", nil
+		},
+		"SHA3": func(a ccArgs) ([]Step, string, error) {
+			size := a.str(0, "512")
+			switch size {
+			case "224", "256", "384", "512":
+				return []Step{ccStep("sha3-"+size, false)}, "", nil
+			}
+			return nil, "", fmt.Errorf("size %s is not supported", size)
+		},
+		"RIPEMD": func(a ccArgs) ([]Step, string, error) {
+			if size := a.str(0, "160"); size != "160" {
+				return nil, "", fmt.Errorf("size %s is not supported", size)
+			}
+			return []Step{ccStep("ripemd160", false)}, "", nil
+		},
+		"CRC-32 Checksum":   simpleImport("crc32", false),
+		"Adler-32 Checksum": simpleImport("adler32", false),
+		"CRC Checksum": func(a ccArgs) ([]Step, string, error) {
+			if alg := a.str(0, "CRC-32"); alg != "CRC-32" {
+				return nil, "", fmt.Errorf("algorithm %s is not supported", alg)
+			}
+			return []Step{ccStep("crc32", false)}, "", nil
+		},
+		"HMAC": func(a ccArgs) ([]Step, string, error) {
+			key, err := a.bytes(0)
+			if err != nil {
+				return nil, "", err
+			}
+			if !utf8.Valid(key) {
+				return nil, "", errors.New("deen takes HMAC keys as text")
+			}
+			fn := a.str(1, "MD5")
+			alg, ok := cyberChefHMAC[fn]
+			if !ok {
+				return nil, "", fmt.Errorf("hash function %s is not supported", fn)
+			}
+			return []Step{ccStep("hmac", false, "key", string(key), "alg", alg)}, "", nil
+		},
+		"Decode text": func(a ccArgs) ([]Step, string, error) { return textImport(a, true) },
+		"Encode text": func(a ccArgs) ([]Step, string, error) { return textImport(a, false) },
+		"From UNIX Timestamp": func(a ccArgs) ([]Step, string, error) {
+			units := a.str(0, "Seconds (s)")
+			unit, ok := cyberChefUnits[units]
+			if !ok {
+				return nil, "", fmt.Errorf("unit %s is not supported", units)
+			}
+			layout := cyberChefTimeLayoutFrac
+			if unit == "s" {
+				layout = cyberChefTimeLayout
+			}
+			return []Step{ccStep("timestamp", false, "unit", unit, "layout", layout)}, "", nil
+		},
+		"Find / Replace": func(a ccArgs) ([]Step, string, error) {
+			if len(a) == 0 {
+				return nil, "", errors.New("missing search term")
+			}
+			find, _ := a[0].(map[string]any)
+			term, _ := find["string"].(string)
+			switch kind, _ := find["option"].(string); kind {
+			case "Regex":
+			case "Simple string":
+				term = regexp.QuoteMeta(term)
+			default:
+				return nil, "", fmt.Errorf("%s search terms are not supported", kind)
+			}
+			replace := a.str(1, "")
+			if replace == "" {
+				return nil, "", errors.New("deen cannot replace matches with nothing")
+			}
+			var notes []string
+			if !a.boolean(2, true) {
+				notes = append(notes, "deen replaces every match, not only the first")
+			}
+			pattern := regexFlags(term, a.boolean(3, false), a.boolean(4, true), a.boolean(5, false))
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, "", fmt.Errorf("regex is not supported by Go: %s", err)
+			}
+			replace = jsGroupRe.ReplaceAllString(strings.ReplaceAll(replace, "$&", "${0}"), "$${$1}")
+			return []Step{ccStep("regex", false, "re", pattern, "replace", replace)}, strings.Join(notes, "; "), nil
+		},
+		"Regular expression": func(a ccArgs) ([]Step, string, error) {
+			pattern := regexFlags(a.str(1, ""), a.boolean(2, true), a.boolean(3, true), a.boolean(4, false))
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, "", fmt.Errorf("regex is not supported by Go: %s", err)
+			}
+			switch output := a.str(8, "Highlight matches"); {
+			case output == "List matches":
+				return []Step{ccStep("regex", false, "re", pattern)}, "", nil
+			case output == "List capture groups" && re.NumSubexp() == 1:
+				return []Step{ccStep("regex", false, "re", pattern, "group", "1")}, "", nil
+			default:
+				return nil, "", fmt.Errorf("output %q is not supported", output)
+			}
+		},
+		"JWT Decode": func(ccArgs) ([]Step, string, error) {
+			return []Step{ccStep("jwt", true)}, "deen shows the header and signature besides the payload", nil
+		},
+	}
+}
+
+// jsGroupRe matches group references of JavaScript replacement strings.
+var jsGroupRe = regexp.MustCompile(`\$(\d{1,2})`)
+
+// regexFlags prefixes pattern with the Go flags of CyberChef's options.
+func regexFlags(pattern string, caseInsensitive, multiline, dotAll bool) string {
+	var flags string
+	if caseInsensitive {
+		flags += "i"
+	}
+	if multiline {
+		flags += "m"
+	}
+	if dotAll {
+		flags += "s"
+	}
+	if flags == "" {
+		return pattern
+	}
+	return "(?" + flags + ")" + pattern
+}
+
+// byteKeyImport translates an arithmetic operation with a one byte key.
+func byteKeyImport(plugin string, a ccArgs) ([]Step, string, error) {
+	key, err := a.bytes(0)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(key) != 1 {
+		return nil, "", fmt.Errorf("deen %s takes a single byte key, not %d bytes", plugin, len(key))
+	}
+	return []Step{ccStep(plugin, false, "value", fmt.Sprintf("0x%02x", key[0]))}, "", nil
+}
+
+// aesImport translates AES Decrypt and AES Encrypt. Hex input and output
+// become hex steps around the aes step.
+func aesImport(a ccArgs, decrypt bool) ([]Step, string, error) {
+	key, err := a.bytes(0)
+	if err != nil {
+		return nil, "", fmt.Errorf("key: %w", err)
+	}
+	iv, err := a.bytes(1)
+	if err != nil {
+		return nil, "", fmt.Errorf("IV: %w", err)
+	}
+	s := ccStep("aes", decrypt, "key", hex.EncodeToString(key))
+	if len(iv) > 0 {
+		s.Options["iv"] = hex.EncodeToString(iv)
+	}
+	switch mode := a.str(2, "CBC"); mode {
+	case "CBC", "CTR", "GCM":
+		s.Options["mode"] = strings.ToLower(mode)
+	case "CBC/NoPadding":
+		s.Options["mode"] = "cbc"
+		s.Options["padding"] = "none"
+	default:
+		return nil, "", fmt.Errorf("mode %s is not supported", mode)
+	}
+	aadArg := 5
+	inFormat, outFormat := a.str(3, "Raw"), a.str(4, "Hex")
+	if decrypt {
+		aadArg = 6
+		inFormat, outFormat = a.str(3, "Hex"), a.str(4, "Raw")
+	}
+	if aad, err := a.bytes(aadArg); err != nil || !utf8.Valid(aad) {
+		return nil, "", errors.New("deen takes additional authenticated data as text")
+	} else if len(aad) > 0 {
+		s.Options["aad"] = string(aad)
+	}
+	var note string
+	if s.Options["mode"] == "gcm" {
+		note = "deen appends the GCM tag to the ciphertext"
+		if decrypt {
+			if tag, _ := a.bytes(5); len(tag) > 0 {
+				note = "deen expects the GCM tag appended to the ciphertext, not as an argument"
+			}
+		}
+	}
+	var steps []Step
+	for _, f := range []string{inFormat, outFormat} {
+		if f != "Raw" && f != "Hex" {
+			return nil, "", fmt.Errorf("%s data is not supported", f)
+		}
+	}
+	if inFormat == "Hex" {
+		steps = append(steps, ccStep("hex", true))
+	}
+	steps = append(steps, s)
+	if outFormat == "Hex" {
+		steps = append(steps, ccStep("hex", false))
+	}
+	return steps, note, nil
+}
+
+// textImport translates Decode text and Encode text, whose argument names a
+// code page such as "UTF-16LE (1200)".
+func textImport(a ccArgs, decode bool) ([]Step, string, error) {
+	name := a.str(0, "UTF-8 (65001)")
+	m := codePageRe.FindStringSubmatch(name)
+	if m == nil || cyberChefCodePages[m[1]] == "" {
+		return nil, "", fmt.Errorf("encoding %s is not supported", name)
+	}
+	return []Step{ccStep("unicode", decode, "encoding", cyberChefCodePages[m[1]])}, "", nil
+}
+
+// ExportCyberChef translates steps into a CyberChef recipe, the other way
+// round than ImportCyberChef. Disabled steps stay disabled; map steps become
+// Fork or Subsection blocks. Steps without a CyberChef equivalent are left
+// out and reported as skipped notes. Options must not reference parameters;
+// Pipeline.ExportCyberChef binds them first.
+func ExportCyberChef(steps []Step) ([]CyberChefOp, []CyberChefNote) {
+	var ops []CyberChefOp
+	var notes []CyberChefNote
+	for i, s := range steps {
+		stepOps, stepNotes := exportCyberChefStep(s)
+		for _, n := range stepNotes {
+			n.Index = i
+			notes = append(notes, n)
+		}
+		ops = append(ops, stepOps...)
+	}
+	return ops, notes
+}
+
+func exportCyberChefStep(s Step) ([]CyberChefOp, []CyberChefNote) {
+	name := s.Plugin
+	if s.Unprocess {
+		name = "." + name
+	}
+	skip := func(reason string) ([]CyberChefOp, []CyberChefNote) {
+		return nil, []CyberChefNote{{Name: name, Skipped: true, Reason: reason}}
+	}
+	var ops []CyberChefOp
+	var notes []CyberChefNote
+	if s.Map != nil {
+		switch s.Map.Split {
+		case SplitLines:
+			ops = append(ops, CyberChefOp{Op: "Fork", Args: []any{`\n`, `\n`, false}})
+		case SplitDelimiter:
+			ops = append(ops, CyberChefOp{Op: "Fork", Args: []any{s.Map.Pattern, s.Map.Pattern, false}})
+		case SplitRegex:
+			ops = append(ops, CyberChefOp{Op: "Subsection", Args: []any{s.Map.Pattern, false, true, false}})
+		default:
+			return skip(s.Map.Split + " map steps have no CyberChef equivalent")
+		}
+		for _, nested := range s.Map.Steps {
+			nestedOps, nestedNotes := exportCyberChefStep(nested)
+			ops = append(ops, nestedOps...)
+			notes = append(notes, nestedNotes...)
+		}
+		ops = append(ops, CyberChefOp{Op: "Merge", Args: []any{false}})
+	} else {
+		exp := cyberChefExports[name]
+		if exp == nil {
+			return skip("no CyberChef equivalent")
+		}
+		var note string
+		var err error
+		ops, note, err = exp(s.Options)
+		if err != nil {
+			return skip(err.Error())
+		}
+		if note != "" {
+			notes = append(notes, CyberChefNote{Name: name, Reason: note})
+		}
+	}
+	if s.Region != "" {
+		r, err := parseRegion(s.Region)
+		if err != nil || r.re == nil {
+			return skip("only re: regions have a CyberChef equivalent")
+		}
+		pattern := strings.TrimPrefix(s.Region, "re:")
+		ops = append([]CyberChefOp{{Op: "Subsection", Args: []any{pattern, false, true, false}}}, ops...)
+		ops = append(ops, CyberChefOp{Op: "Merge", Args: []any{false}})
+	}
+	for i := range ops {
+		ops[i].Disabled = s.Disabled
+	}
+	return ops, notes
+}
+
+// ccExport translates the options of a step into CyberChef operations.
+type ccExport func(opts map[string]string) (ops []CyberChefOp, note string, err error)
+
+func ccOp(name string, args ...any) []CyberChefOp {
+	if args == nil {
+		args = []any{}
+	}
+	return []CyberChefOp{{Op: name, Args: args}}
+}
+
+// simpleExport translates a step whose options CyberChef has no use for.
+func simpleExport(name string, args ...any) ccExport {
+	return func(map[string]string) ([]CyberChefOp, string, error) {
+		return ccOp(name, args...), "", nil
+	}
+}
+
+func optBool(opts map[string]string, name string) bool {
+	b, _ := strconv.ParseBool(opts[name])
+	return b
+}
+
+// alphabetOf returns the CyberChef alphabet whose options, taken from
+// cyberChefBase64 or cyberChefBase32, are the switches set in opts.
+func alphabetOf(alphabets map[string][]string, opts map[string]string) string {
+	for alphabet, set := range alphabets {
+		want := map[string]bool{}
+		for i := 0; i+1 < len(set); i += 2 {
+			want[set[i]] = true
+		}
+		if optBool(opts, "url") == want["url"] && optBool(opts, "raw") == want["raw"] &&
+			optBool(opts, "hex") == want["hex"] && optBool(opts, "no-pad") == want["no-pad"] {
+			return alphabet
+		}
+	}
+	return ""
+}
+
+// cyberChefExports maps deen steps, named by plugin and with a leading dot
+// for the decode direction, to their CyberChef translation.
+var cyberChefExports map[string]ccExport
+
+// punycodeExport exports the idna plugin. CyberChef converts labels without
+// the IDNA mapping, like the punycode profile.
+func punycodeExport(op string) ccExport {
+	return func(opts map[string]string) ([]CyberChefOp, string, error) {
+		if optBool(opts, "inspect") {
+			return nil, "", errors.New("CyberChef has no hostname inspection")
+		}
+		var note string
+		switch opts["profile"] {
+		case "punycode":
+		case "registration":
+			note = "CyberChef does not validate hostnames for registration"
+		default:
+			note = "CyberChef does not apply the IDNA lookup mapping"
+		}
+		return ccOp(op, true), note, nil
+	}
+}
+
+func init() {
+	cyberChefExports = map[string]ccExport{
+		".base64": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			return ccOp("From Base64", alphabetOf(cyberChefBase64, opts), true, optBool(opts, "strict")), "", nil
+		},
+		"base64": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			return ccOp("To Base64", alphabetOf(cyberChefBase64, opts)), "", nil
+		},
+		".base32": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			return ccOp("From Base32", alphabetOf(cyberChefBase32, opts), true), "", nil
+		},
+		"base32": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			return ccOp("To Base32", alphabetOf(cyberChefBase32, opts)), "", nil
+		},
+		".base58": namedAlphabetExport("base58", "From Base58", true),
+		"base58":  namedAlphabetExport("base58", "To Base58"),
+		".base62": namedAlphabetExport("base62", "From Base62"),
+		"base62":  namedAlphabetExport("base62", "To Base62"),
+		".base85": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			switch opts["variant"] {
+			case "btoa":
+				return nil, "", errors.New("CyberChef has no btoa variant")
+			case "z85":
+				return ccOp("From Base85", cyberChefZ85, true, ""), "", nil
+			}
+			return ccOp("From Base85", "!-u", true, "z"), "", nil
+		},
+		"base85": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			switch opts["variant"] {
+			case "btoa":
+				return nil, "", errors.New("CyberChef has no btoa variant")
+			case "z85":
+				return ccOp("To Base85", cyberChefZ85, false), "", nil
+			}
+			return ccOp("To Base85", "!-u", opts["variant"] == "adobe"), "", nil
+		},
+		".base45": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			ops := ccOp("From Base45", cyberChefBase45, true)
+			if prefix := opts["prefix"]; prefix != "" {
+				ops = append(ccOp("Drop bytes", 0, len(prefix), false), ops...)
+			}
+			return ops, "", nil
+		},
+		"base45": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			var note string
+			if prefix := opts["prefix"]; prefix != "" {
+				note = "CyberChef does not add the " + prefix + " prefix"
+			}
+			return ccOp("To Base45", cyberChefBase45), note, nil
+		},
+		".idna":             punycodeExport("From Punycode"),
+		"idna":              punycodeExport("To Punycode"),
+		".hex":              simpleExport("From Hex", "Auto"),
+		"hex":               simpleExport("To Hex", "None", 0),
+		".url":              simpleExport("URL Decode"),
+		"url":               simpleExport("URL Encode", true),
+		".html":             simpleExport("From HTML Entity"),
+		"html":              simpleExport("To HTML Entity", false, "Named entities"),
+		".quoted-printable": simpleExport("From Quoted Printable"),
+		"quoted-printable":  simpleExport("To Quoted Printable"),
+		"rot13":             simpleExport("ROT13", true, true, false, 13),
+		".rot13":            simpleExport("ROT13", true, true, false, 13),
+		".gzip":             simpleExport("Gunzip"),
+		"gzip":              simpleExport("Gzip", "Dynamic Huffman Coding", "", "", false),
+		".zlib":             simpleExport("Zlib Inflate", 0, 0, "Adaptive", false, false),
+		"zlib":              simpleExport("Zlib Deflate", "Dynamic Huffman Coding"),
+		".flate":            simpleExport("Raw Inflate", 0, 0, "Adaptive", false, false),
+		"flate":             simpleExport("Raw Deflate", "Dynamic Huffman Coding"),
+		".bzip2":            simpleExport("Bzip2 Decompress", false),
+		"bzip2":             simpleExport("Bzip2 Compress", 9, 30),
+		".brotli":           simpleExport("Brotli Decompress"),
+		"json":              simpleExport("JSON Beautify", "    ", true, false),
+		".json":             simpleExport("JSON Minify"),
+		"xml":               simpleExport("XML Beautify", `\t`),
+		".xml":              simpleExport("XML Minify", false),
+		"xor": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			return byteKeyExport("XOR", opts, "0xff", "Standard", false)
+		},
+		"add": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			return byteKeyExport("ADD", opts, "1")
+		},
+		"sub": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			return byteKeyExport("SUB", opts, "1")
+		},
+		"not":        simpleExport("NOT"),
+		".aes":       func(opts map[string]string) ([]CyberChefOp, string, error) { return aesExport(opts, true) },
+		"aes":        func(opts map[string]string) ([]CyberChefOp, string, error) { return aesExport(opts, false) },
+		"md4":        simpleExport("MD4"),
+		"md5":        simpleExport("MD5"),
+		"sha1":       simpleExport("SHA1", 80),
+		"sha224":     simpleExport("SHA2", "224", 64),
+		"sha256":     simpleExport("SHA2", "256", 64),
+		"sha384":     simpleExport("SHA2", "384", 160),
+		"sha512":     simpleExport("SHA2", "512", 160),
+		"sha512-224": simpleExport("SHA2", "512/224", 160),
+		"sha512-256": simpleExport("SHA2", "512/256", 160),
+		"sha3-224":   simpleExport("SHA3", "224"),
+		"sha3-256":   simpleExport("SHA3", "256"),
+		"sha3-384":   simpleExport("SHA3", "384"),
+		"sha3-512":   simpleExport("SHA3", "512"),
+		"ripemd160":  simpleExport("RIPEMD", "160"),
+		"crc32":      simpleExport("CRC-32 Checksum"),
+		"adler32":    simpleExport("Adler-32 Checksum"),
+		"hmac": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			alg := opts["alg"]
+			if alg == "" {
+				alg = "sha256"
+			}
+			for fn, a := range cyberChefHMAC {
+				if a == alg {
+					return ccOp("HMAC", map[string]any{"option": "UTF8", "string": opts["key"]}, fn), "", nil
+				}
+			}
+			return nil, "", fmt.Errorf("hash algorithm %s is not supported", alg)
+		},
+		".unicode": func(opts map[string]string) ([]CyberChefOp, string, error) { return textExport("Decode text", opts) },
+		"unicode":  func(opts map[string]string) ([]CyberChefOp, string, error) { return textExport("Encode text", opts) },
+		"timestamp": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			unit := opts["unit"]
+			var notes []string
+			if unit == "" || unit == "auto" {
+				unit = "s"
+				notes = append(notes, "CyberChef needs a unit; seconds assumed")
+			}
+			if layout := opts["layout"]; layout != "" && layout != cyberChefTimeLayout && layout != cyberChefTimeLayoutFrac {
+				notes = append(notes, "CyberChef formats times with its own layout")
+			}
+			for units, u := range cyberChefUnits {
+				if u == unit {
+					return ccOp("From UNIX Timestamp", units), strings.Join(notes, "; "), nil
+				}
+			}
+			return nil, "", fmt.Errorf("unit %s is not supported", unit)
+		},
+		"regex": func(opts map[string]string) ([]CyberChefOp, string, error) {
+			pattern := opts["re"]
+			if replace := opts["replace"]; replace != "" {
+				replace = goGroupRe.ReplaceAllString(replace, "$$$1")
+				return ccOp("Find / Replace", map[string]any{"option": "Regex", "string": pattern}, replace, true, false, false, false), "", nil
+			}
+			output := "List matches"
+			if group := opts["group"]; group != "" && group != "0" {
+				re, err := regexp.Compile(pattern)
+				if err != nil || group != "1" || re.NumSubexp() != 1 {
+					return nil, "", errors.New("CyberChef lists all capture groups, not one of several")
+				}
+				output = "List capture groups"
+			}
+			var note string
+			if opts["all"] == "false" {
+				note = "CyberChef lists every match, not only the first"
+			}
+			return ccOp("Regular expression", "User defined", pattern, false, false, false, false, false, false, output), note, nil
+		},
+		".jwt": simpleExport("JWT Decode"),
+	}
+}
+
+// goGroupRe matches numbered group references of Go replacement strings.
+var goGroupRe = regexp.MustCompile(`\$\{?(\d+)\}?`)
+
+// byteKeyExport translates an arithmetic step with a one byte value into an
+// operation with a hex key followed by extra arguments.
+func byteKeyExport(name string, opts map[string]string, def string, extra ...any) ([]CyberChefOp, string, error) {
+	value := opts["value"]
+	if value == "" {
+		value = def
+	}
+	b, err := parseByteValue(value)
+	if err != nil {
+		return nil, "", err
+	}
+	args := append([]any{map[string]any{"option": "Hex", "string": fmt.Sprintf("%02x", b)}}, extra...)
+	return ccOp(name, args...), "", nil
+}
+
+// parseByteValue reads a byte the way the arithmetic plugins do: as a
+// decimal or 0x-prefixed number, or a single character.
+func parseByteValue(value string) (byte, error) {
+	if n, err := strconv.ParseUint(value, 0, 8); err == nil {
+		return byte(n), nil
+	}
+	if len(value) == 1 {
+		return value[0], nil
+	}
+	return 0, fmt.Errorf("invalid byte value %q", value)
+}
+
+// aesExport translates an aes step with raw input and output.
+func aesExport(opts map[string]string, decrypt bool) ([]CyberChefOp, string, error) {
+	key, err := hexOrBase64(opts["key"])
+	if err != nil {
+		return nil, "", fmt.Errorf("key: %w", err)
+	}
+	var iv []byte
+	if opts["iv"] != "" {
+		if iv, err = hexOrBase64(opts["iv"]); err != nil {
+			return nil, "", fmt.Errorf("IV: %w", err)
+		}
+	}
+	mode := strings.ToUpper(opts["mode"])
+	if mode == "" {
+		mode = "GCM"
+	}
+	if mode == "CBC" && opts["padding"] == "none" {
+		mode = "CBC/NoPadding"
+	}
+	var notes []string
+	if mode == "GCM" {
+		notes = append(notes, "CyberChef keeps the GCM tag apart from the ciphertext")
+		if tl := opts["tag-len"]; tl != "" && tl != "16" {
+			notes = append(notes, "CyberChef only uses 16 byte tags")
+		}
+	}
+	hexArg := func(b []byte) map[string]any { return map[string]any{"option": "Hex", "string": hex.EncodeToString(b)} }
+	aad := map[string]any{"option": "UTF8", "string": opts["aad"]}
+	if decrypt {
+		return ccOp("AES Decrypt", hexArg(key), hexArg(iv), mode, "Raw", "Raw", hexArg(nil), aad), strings.Join(notes, "; "), nil
+	}
+	return ccOp("AES Encrypt", hexArg(key), hexArg(iv), mode, "Raw", "Raw", aad), strings.Join(notes, "; "), nil
+}
+
+// hexOrBase64 decodes key material the way the aes plugin reads it.
+func hexOrBase64(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(s); err == nil {
+		return b, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is neither hex nor Base64", s)
+}
+
+// textExport translates a unicode step into Encode text or Decode text.
+func textExport(name string, opts map[string]string) ([]CyberChefOp, string, error) {
+	encoding := opts["encoding"]
+	if encoding == "" {
+		encoding = "utf8"
+	}
+	cp, ok := cyberChefEncodings[encoding]
+	if !ok {
+		return nil, "", fmt.Errorf("encoding %s is not supported", encoding)
+	}
+	if optBool(opts, "big") || (opts["bom"] != "" && opts["bom"] != "ignore") {
+		return nil, "", errors.New("byte order options are not supported")
+	}
+	return ccOp(name, cp), "", nil
+}
+
+// ImportCyberChef replaces the steps with a translation of a CyberChef recipe
+// and keeps the source, since recipes carry no input. The previous state is
+// kept in undo history.
+func (p *Pipeline) ImportCyberChef(recipe string) ([]CyberChefNote, error) {
+	steps, notes, err := ImportCyberChef(recipe)
+	if err != nil {
+		return notes, err
+	}
+	ss, err := checkedSteps(steps)
+	if err != nil {
+		return notes, err
+	}
+	p.record()
+	p.restore(snapshot{Source: p.source, Steps: ss})
+	return notes, nil
+}
+
+// ExportCyberChef translates the main chain into a CyberChef recipe with
+// ExportCyberChef, after binding parameter references to their values.
+// Recipes have a single output, so every branch is reported as skipped.
+func (p *Pipeline) ExportCyberChef() ([]CyberChefOp, []CyberChefNote, error) {
+	steps := make([]Step, 0, len(p.steps))
+	for _, s := range p.steps {
+		bound, err := p.bindStep(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		steps = append(steps, *bound)
+	}
+	ops, notes := ExportCyberChef(steps)
+	for _, b := range p.branches {
+		notes = append(notes, CyberChefNote{Index: b.After, Name: b.Name, Skipped: true,
+			Reason: fmt.Sprintf("branch of %d step(s) after step %d; a recipe has a single output", len(b.steps), b.After)})
+	}
+	return ops, notes, nil
+}
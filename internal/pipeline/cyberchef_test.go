@@ -0,0 +1,322 @@
+package pipeline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestParseCyberChefRecipe(t *testing.T) {
+	want := []CyberChefOp{
+		{Op: "From Base64", Args: []any{"A-Za-z0-9+/=", true, false}},
+		{Op: "Find / Replace", Args: []any{map[string]any{"option": "Regex", "string": "it's"}, `say "hi"`, true, false, true, false}, Disabled: true},
+		{Op: "Gunzip", Args: []any{}},
+	}
+	for _, recipe := range []string{
+		`[{"op":"From Base64","args":["A-Za-z0-9+/=",true,false]},
+		  {"op":"Find / Replace","args":[{"option":"Regex","string":"it's"},"say \"hi\"",true,false,true,false],"disabled":true},
+		  {"op":"Gunzip","args":[]}]`,
+		"From_Base64('A-Za-z0-9+/=',true,false)\nFind_/_Replace({'option':'Regex','string':'it\\'s'},'say \"hi\"',true,false,true,false/disabled)\nGunzip()",
+		"https://gchq.github.io/CyberChef/#recipe=From_Base64('A-Za-z0-9%2B/%3D',true,false)Find_/_Replace(%7B'option':'Regex','string':'it%5C's'%7D,'say%20%22hi%22',true,false,true,false/disabled)Gunzip()&input=SGk",
+	} {
+		got, err := ParseCyberChefRecipe(recipe)
+		if err != nil {
+			t.Fatalf("ParseCyberChefRecipe(%q): %v", recipe, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseCyberChefRecipe(%q) =\n%#v\nwant\n%#v", recipe, got, want)
+		}
+	}
+
+	chef, err := FormatCyberChefChef(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantChef := "From_Base64('A-Za-z0-9+/=',true,false)\nFind_/_Replace({'option':'Regex','string':'it\\'s'},'say \"hi\"',true,false,true,false/disabled)\nGunzip()\n"; chef != wantChef {
+		t.Errorf("FormatCyberChefChef = %q, want %q", chef, wantChef)
+	}
+	for _, bad := range []string{"", "[{\"args\":[]}]", "not a recipe", "Gunzip('unterminated)"} {
+		if _, err := ParseCyberChefRecipe(bad); err == nil {
+			t.Errorf("ParseCyberChefRecipe(%q) = nil error", bad)
+		}
+	}
+}
+
+func TestImportCyberChefRuns(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(`{"b":1,"a":"x"}`))
+	w.Close()
+	src := base64.StdEncoding.EncodeToString(xorBytes(base64.StdEncoding.EncodeToString(gz.Bytes()), 0x20))
+
+	recipe := `From_Base64('A-Za-z0-9+/=',true,false)XOR({'option':'Hex','string':'20'},'Standard',false)` +
+		`From_Base64('A-Za-z0-9+/=',true,false)Gunzip()Parse_QR_Code(false)JSON_Beautify('    ',false,true)`
+	steps, notes, err := ImportCyberChef(recipe)
+	if err != nil {
+		t.Fatalf("ImportCyberChef: %v", err)
+	}
+	p := New()
+	if err := p.LoadSteps(steps); err != nil {
+		t.Fatalf("LoadSteps: %v", err)
+	}
+	p.SetSource([]byte(src))
+	if got, want := string(p.Result()), "{\n    \"a\": \"x\",\n    \"b\": 1\n}"; got != want {
+		t.Fatalf("result = %q, want %q (steps %+v)", got, want, steps)
+	}
+	wantNotes := []string{
+		"5. Parse QR Code: skipped: no deen equivalent",
+		"6. JSON Beautify: deen sorts object keys and indents by four spaces",
+	}
+	if got := noteStrings(notes); !reflect.DeepEqual(got, wantNotes) {
+		t.Errorf("notes = %q, want %q", got, wantNotes)
+	}
+}
+
+func TestImportCyberChefTranslations(t *testing.T) {
+	for _, tt := range []struct {
+		recipe string
+		want   string // CommandLine of the steps
+		notes  []string
+	}{
+		{
+			recipe: `From_Hex('Space')AES_Decrypt({'option':'UTF8','string':'0123456789abcdef'},{'option':'Hex','string':'00 01 02 03 04 05 06 07 08 09 0a 0b 0c 0d 0e 0f'},'CBC/NoPadding','Raw','Hex',{'option':'Hex','string':''},{'option':'Hex','string':''})`,
+			want:   "deen .hex | deen .aes -iv 000102030405060708090a0b0c0d0e0f -key 30313233343536373839616263646566 -mode cbc -padding none | deen hex",
+			notes:  []string{"1. From Hex: deen reads hex without delimiters, not with Space"},
+		},
+		{
+			recipe: `To_Base64('A-Za-z0-9-_')Decode_text('UTF-16LE (1200)')From_UNIX_Timestamp('Milliseconds (ms)')`,
+			want:   "deen base64 -raw -url | deen .unicode -encoding utf16le | deen timestamp -layout 'Mon 2 January 2006 15:04:05.000 UTC' -unit ms",
+		},
+		{
+			recipe: `XOR({'option':'UTF8','string':'key'},'Standard',false)SHA2('256',64)Find_/_Replace({'option':'Simple string','string':'a.b'},'[$1$&]',false,false,true,false)ROT13(true,true,false,5)`,
+			want:   `deen sha256 | deen regex -re '(?m)a\.b' -replace '[${1}${0}]'`,
+			notes: []string{
+				"1. XOR: skipped: deen xor takes a single byte key, not 3 bytes",
+				"3. Find / Replace: deen replaces every match, not only the first",
+				"4. ROT13: skipped: deen only rotates letters by 13",
+			},
+		},
+		{
+			recipe: `Fork('\\n','\\n',false)From_Base64('A-Za-z0-9+/=',true,false)Merge(true)Subsection('id=(\\d+)',false,true,false)To_Hex('None',0)Merge(true)`,
+			want:   `deen map -split lines .base64 | deen map -split regex -pattern 'id=(\d+)' hex`,
+		},
+		{
+			recipe: `Fork(',',';',false)Subsection('(?<=x)y',false,true,false)Gunzip()Merge(false)MD5()`,
+			want:   `deen map -split delimiter -pattern , md5`,
+			notes: []string{
+				`1. Fork: deen joins the pieces with the split delimiter ","`,
+				"2. Subsection: skipped: regex is not supported by Go: error parsing regexp: invalid named capture: `(?<=x)y`; the operations up to its Merge are skipped too",
+				"3. Gunzip: skipped: inside the skipped block of operation 2",
+			},
+		},
+	} {
+		steps, notes, err := ImportCyberChef(tt.recipe)
+		if err != nil {
+			t.Fatalf("ImportCyberChef(%s): %v", tt.recipe, err)
+		}
+		p := New()
+		if err := p.LoadSteps(steps); err != nil {
+			t.Fatalf("LoadSteps(%s): %v", tt.recipe, err)
+		}
+		if got := p.CommandLine(); got != tt.want {
+			t.Errorf("ImportCyberChef(%s) =\n%s\nwant\n%s", tt.recipe, got, tt.want)
+		}
+		if got := noteStrings(notes); !reflect.DeepEqual(got, tt.notes) {
+			t.Errorf("ImportCyberChef(%s) notes =\n%q\nwant\n%q", tt.recipe, got, tt.notes)
+		}
+	}
+
+	if _, notes, err := ImportCyberChef(`Magic(3,false,false,'')`); err == nil || len(notes) != 1 {
+		t.Errorf("untranslatable recipe: err = %v, notes = %v", err, notes)
+	}
+}
+
+func TestExportCyberChefRoundTrip(t *testing.T) {
+	chain := ".base64 -url | .zlib | map -split lines '.hex | xor -value 0x41' | .unicode -encoding utf16le -region 're:\"[^\"]*\"' | regex -re 'a(b)' -replace '${1}' | .aes -key 000102030405060708090a0b0c0d0e0f -iv 000102030405060708090a0b0c0d0e0f -mode cbc | sha512 | hex"
+	steps, err := ParseMapChain(chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, notes := ExportCyberChef(steps)
+	if len(notes) != 0 {
+		t.Fatalf("notes = %q", noteStrings(notes))
+	}
+	link, err := FormatCyberChefURL(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, notes, err := ImportCyberChef(link)
+	if err != nil || len(notes) != 0 {
+		t.Fatalf("ImportCyberChef(%s): %v %q", link, err, noteStrings(notes))
+	}
+	p := New()
+	if err := p.LoadSteps(back); err != nil {
+		t.Fatal(err)
+	}
+	want := `deen .base64 -url | deen .zlib | deen map -split lines '.hex | xor -value 0x41' | deen map -split regex -pattern '"[^"]*"' '.unicode -encoding utf16le' | deen regex -re 'a(b)' -replace '${1}' | deen .aes -iv 000102030405060708090a0b0c0d0e0f -key 000102030405060708090a0b0c0d0e0f -mode cbc | deen sha512 | deen hex`
+	if got := p.CommandLine(); got != want {
+		t.Errorf("round trip =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportCyberChefNotes(t *testing.T) {
+	p := New()
+	if err := p.LoadJSON([]byte(`{"version": 1, "params": [{"name": "key"}], "steps": [
+		{"plugin": "lzw", "unprocess": true},
+		{"plugin": "base64", "region": "0:4"},
+		{"plugin": "xor", "options": {"value": "${key}"}, "disabled": true},
+		{"plugin": "aes", "options": {"key": "000102030405060708090a0b0c0d0e0f", "iv": "000102030405060708090a0b"}}
+	]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.ExportCyberChef(); err == nil {
+		t.Fatal("ExportCyberChef with unbound parameter succeeded")
+	}
+	if err := p.SetParam("key", "k"); err != nil {
+		t.Fatal(err)
+	}
+	ops, notes, err := p.ExportCyberChef()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNotes := []string{
+		"1. .lzw: skipped: no CyberChef equivalent",
+		"2. base64: skipped: only re: regions have a CyberChef equivalent",
+		"4. aes: CyberChef keeps the GCM tag apart from the ciphertext",
+	}
+	if got := noteStrings(notes); !reflect.DeepEqual(got, wantNotes) {
+		t.Errorf("notes = %q, want %q", got, wantNotes)
+	}
+	js, err := FormatCyberChefJSON(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {"op":"XOR","args":[{"option":"Hex","string":"6b"},"Standard",false],"disabled":true},
+  {"op":"AES Encrypt","args":[{"option":"Hex","string":"000102030405060708090a0b0c0d0e0f"},{"option":"Hex","string":"000102030405060708090a0b"},"GCM","Raw","Raw",{"option":"UTF8","string":""}]}
+]
+`
+	if string(js) != want {
+		t.Errorf("FormatCyberChefJSON =\n%s\nwant\n%s", js, want)
+	}
+}
+
+func noteStrings(notes []CyberChefNote) []string {
+	var out []string
+	for _, n := range notes {
+		out = append(out, n.String())
+	}
+	return out
+}
+
+func TestCyberChefBaseN(t *testing.T) {
+	steps, notes, err := ImportCyberChef(`From_Base58('rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz',true)To_Base62('0-9a-zA-Z')From_Base45('0-9A-Z $%*+\\-./:',true)`)
+	if err != nil || len(notes) != 0 {
+		t.Fatalf("ImportCyberChef: %v %q", err, noteStrings(notes))
+	}
+	p := New()
+	if err := p.LoadSteps(steps); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.CommandLine(), "deen .base58 -alphabet ripple | deen base62 -alphabet inverted | deen .base45"; got != want {
+		t.Errorf("import = %s, want %s", got, want)
+	}
+	if _, _, err := ImportCyberChef(`To_Base58('0123')`); err == nil {
+		t.Error("importing an unknown Base58 alphabet succeeded")
+	}
+
+	steps, notes, err = ImportCyberChef(`From_Base85('!-u',true,'z')To_Base85('!-u',true)From_Base85('0-9a-zA-Z.\\-:+=^!/*?&<>()[]{}@%$#',true,'')`)
+	if err != nil || len(notes) != 0 {
+		t.Fatalf("ImportCyberChef: %v %q", err, noteStrings(notes))
+	}
+	if err := p.LoadSteps(steps); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.CommandLine(), "deen .base85 | deen base85 -variant adobe | deen .base85 -variant z85"; got != want {
+		t.Errorf("import = %s, want %s", got, want)
+	}
+	ops, notes := ExportCyberChef(steps)
+	if len(notes) != 0 || len(ops) != 3 || ops[1].Args[1] != true || ops[2].Args[0] != cyberChefZ85 {
+		t.Errorf("export = %v, notes %q", ops, noteStrings(notes))
+	}
+	btoa, err := ParseMapChain(".base85 -variant btoa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, notes := ExportCyberChef(btoa); len(notes) != 1 {
+		t.Errorf("btoa export notes = %q", noteStrings(notes))
+	}
+
+	steps, err = ParseMapChain(".base45 -prefix HC1: | .base58 | base45 -prefix HC1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, notes = ExportCyberChef(steps)
+	if got, want := noteStrings(notes), []string{"3. base45: CyberChef does not add the HC1: prefix"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notes = %q, want %q", got, want)
+	}
+	chef, err := FormatCyberChefChef(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Drop_bytes(0,4,false)\nFrom_Base45('0-9A-Z $%*+\\\\-./:',true)\nFrom_Base58('123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz',true)\nTo_Base45('0-9A-Z $%*+\\\\-./:')\n"; chef != want {
+		t.Errorf("export =\n%s\nwant\n%s", chef, want)
+	}
+}
+
+func TestCyberChefPunycode(t *testing.T) {
+	steps, notes, err := ImportCyberChef(`From_Punycode(true)To_Punycode(true)`)
+	if err != nil || len(notes) != 0 {
+		t.Fatalf("ImportCyberChef: %v %q", err, noteStrings(notes))
+	}
+	p := New()
+	if err := p.LoadSteps(steps); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.CommandLine(), "deen .idna -profile punycode | deen idna -profile punycode"; got != want {
+		t.Errorf("import = %s, want %s", got, want)
+	}
+	if _, _, err := ImportCyberChef(`From_Punycode(false)`); err == nil {
+		t.Error("importing raw Punycode succeeded")
+	}
+
+	steps, err = ParseMapChain(".idna | idna -inspect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, notes := ExportCyberChef(steps)
+	if got, want := noteStrings(notes), []string{"1. .idna: CyberChef does not apply the IDNA lookup mapping", "2. idna: skipped: CyberChef has no hostname inspection"}; len(ops) != 1 || !reflect.DeepEqual(got, want) {
+		t.Errorf("export = %v, notes %q, want %q", ops, got, want)
+	}
+}
+
+func TestCyberChefRegularExpression(t *testing.T) {
+	steps, notes, err := ImportCyberChef(`Regular_expression('User defined','id=(\\d+)',true,true,false,false,false,false,'List capture groups')Regular_expression('User defined','[a-z]+',false,false,false,false,false,true,'List matches')`)
+	if err != nil || len(notes) != 0 {
+		t.Fatalf("ImportCyberChef: %v %q", err, noteStrings(notes))
+	}
+	p := New()
+	if err := p.LoadSteps(steps); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.CommandLine(), `deen regex -group 1 -re '(?im)id=(\d+)' | deen regex -re '[a-z]+'`; got != want {
+		t.Errorf("import = %s, want %s", got, want)
+	}
+	if _, _, err := ImportCyberChef(`Regular_expression('User defined','a',true,true,false,false,false,false,'Highlight matches')`); err == nil {
+		t.Error("importing highlighted matches succeeded")
+	}
+
+	ops, notes := ExportCyberChef(steps)
+	if len(notes) != 0 {
+		t.Errorf("export notes = %q", noteStrings(notes))
+	}
+	chef, err := FormatCyberChefChef(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Regular_expression('User defined','(?im)id=(\\\\d+)',false,false,false,false,false,false,'List capture groups')\nRegular_expression('User defined','[a-z]+',false,false,false,false,false,false,'List matches')\n"; chef != want {
+		t.Errorf("export =\n%s\nwant\n%s", chef, want)
+	}
+}
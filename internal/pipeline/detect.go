@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"bytes"
+	"context"
 	"encoding/asn1"
 	"encoding/base64"
 	"encoding/binary"
@@ -22,6 +23,7 @@ import (
 
 	"github.com/fxamacker/cbor/v2"
 	"github.com/liyue201/goqr"
+	"github.com/takeshixx/deen/internal/plugins"
 	"github.com/vmihailenco/msgpack/v5"
 )
 
@@ -144,6 +146,10 @@ func oneStepSuggestions(data []byte) []Suggestion {
 	if looksLikeProtobuf(trimmed) {
 		add("protobuf", false, "Decode protobuf", "input looks like binary protobuf wire data")
 	}
+	names, detections := plugins.Detect(trimmed)
+	for i, d := range detections {
+		out = append(out, suggestionForStep(SuggestionStep{Plugin: names[i], Unprocess: true, Options: d.Options}, d.Label, d.Reason, d.Confidence))
+	}
 	return out
 }
 
@@ -286,7 +292,9 @@ func stepLabel(step SuggestionStep) string {
 
 func canExpandAutomatedChain(s Suggestion) bool {
 	switch s.Plugin {
-	case "base64", "hex", "url", "html", "gzip", "zlib", "unicode", "pem":
+	case "base64", "hex", "url", "html", "gzip", "zlib", "unicode", "pem",
+		"base58", "base58check", "base62", "base45", "base91", "base85",
+		"uuencode", "xxencode", "yenc":
 		return s.Unprocess
 	default:
 		return false
@@ -303,7 +311,7 @@ func canFinishAutomatedChain(s Suggestion) bool {
 }
 
 func applySuggestionStep(data []byte, step SuggestionStep) ([]byte, bool) {
-	out, err := runStep(&Step{
+	out, err := runStep(context.Background(), DefaultLimits(), &Step{
 		Plugin:    step.Plugin,
 		Unprocess: step.Unprocess,
 		Options:   cloneSuggestionOptions(step.Options),
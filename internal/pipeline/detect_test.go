@@ -3,6 +3,7 @@ package pipeline
 import (
 	"bytes"
 	"compress/gzip"
+	"compress/zlib"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
@@ -30,6 +31,10 @@ func TestSuggestionsDetectCommonTransforms(t *testing.T) {
 		{"gzip", []byte{0x1f, 0x8b, 0x08, 0x00}, "gzip", true},
 		{"zlib", []byte{0x78, 0x9c, 0x00}, "zlib", true},
 		{"protobuf", []byte{0x08, 0x96, 0x01}, "protobuf", false},
+		{"base58check", []byte("1PMycacnJaSqwwJqjawXBErnLsZ7RkXUAs"), "base58check", true},
+		{"base45", []byte("HC1:QED8WEX0"), "base45", true},
+		{"idna", []byte("xn--80ak6aa92e.com"), "idna", true},
+		{"bech32", []byte("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"), "bech32", true},
 		{"uuid", []byte("550e8400-e29b-41d4-a716-446655440000"), "uuid", false},
 		{"asn1", []byte{0x30, 0x03, 0x02, 0x01, 0x2a}, "asn1", false},
 		{"dns", []byte{3, 'w', 'w', 'w', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0}, "dns", true},
@@ -167,6 +172,33 @@ func TestSuggestionsDetectAutomatedDecodeChain(t *testing.T) {
 	}
 }
 
+func TestSuggestionsDetectHealthCertificateChain(t *testing.T) {
+	cb, err := cbor.Marshal(map[string]any{"v": []any{map[string]any{"dn": 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(cb)
+	zw.Close()
+	p := New()
+	p.AddStepWithOptions("base45", false, map[string]string{"prefix": "HC1:"})
+	p.SetSource(z.Bytes())
+	input := p.Result()
+
+	suggestions := Suggestions(input)
+	if !hasSuggestionOption(suggestions, "base45", true, "prefix", "HC1:") {
+		t.Fatalf("missing HC1 base45 suggestion in %#v", suggestions)
+	}
+	if findSuggestionChain(suggestions, []SuggestionStep{
+		{Plugin: "base45", Unprocess: true, Options: map[string]string{"prefix": "HC1:"}},
+		{Plugin: "zlib", Unprocess: true},
+		{Plugin: "cbor", Unprocess: true},
+	}) == nil {
+		t.Fatalf("missing base45 -> zlib -> cbor chain in %#v", suggestions)
+	}
+}
+
 func TestSuggestionsJSONPreviewHasNoANSIColor(t *testing.T) {
 	suggestions := Suggestions([]byte(`{"ok":true}`))
 	if !hasSuggestionOption(suggestions, "json", false, "no-color", "true") {
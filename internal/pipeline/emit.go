@@ -0,0 +1,433 @@
+package pipeline
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/takeshixx/deen/internal/plugins"
+)
+
+// Languages that Emit writes programs in.
+const (
+	EmitGo     = "go"
+	EmitPython = "python"
+)
+
+// EmitLanguages lists the languages Emit supports.
+var EmitLanguages = []string{EmitGo, EmitPython}
+
+var emitLanguageNames = map[string]string{EmitGo: "Go", EmitPython: "Python"}
+
+// Emit returns a standalone program in lang that runs the enabled steps of
+// the chain on stdin and writes the result to stdout. Parameters are bound
+// first and their values written into the program. Steps whose plugin has no
+// template for lang are reported as a *StepError.
+func (p *Pipeline) Emit(lang string) (string, error) {
+	steps := make([]Step, 0, len(p.steps))
+	for _, s := range p.steps {
+		bound, err := p.bindStep(s)
+		if err != nil {
+			return "", err
+		}
+		steps = append(steps, *bound)
+	}
+	return EmitSteps(steps, lang)
+}
+
+// EmitSteps is Emit for a list of steps. Disabled steps are left out.
+func EmitSteps(steps []Step, lang string) (string, error) {
+	if _, ok := emitTemplates[lang]; !ok {
+		return "", fmt.Errorf("unknown language %q (supported: %s)", lang, strings.Join(EmitLanguages, ", "))
+	}
+	e := &emitter{lang: lang, imports: map[string]bool{}}
+	var funcs []string
+	var commands []string
+	for i, s := range steps {
+		if s.Disabled || s.Plugin == "" {
+			continue
+		}
+		command := "deen " + stepCommand(&s)
+		body, err := e.step(s)
+		if err != nil {
+			return "", &StepError{Index: i, Plugin: s.Plugin, Err: err}
+		}
+		funcs = append(funcs, e.function(len(funcs)+1, command, body))
+		commands = append(commands, command)
+	}
+	if len(funcs) == 0 {
+		return "", errors.New("no enabled steps to emit")
+	}
+	if lang == EmitGo {
+		return e.goProgram(commands, funcs)
+	}
+	return e.pythonProgram(commands, funcs), nil
+}
+
+// emitter collects the imports of the program a chain is emitted as.
+type emitter struct {
+	lang    string
+	imports map[string]bool
+	err     error // first error of a template function or option
+}
+
+// step executes the template of s and returns the body of its function.
+func (e *emitter) step(s Step) (string, error) {
+	if s.Map != nil {
+		return "", errors.New("map steps have no code template")
+	}
+	if s.Region != "" {
+		return "", errors.New("regions have no code template")
+	}
+	name := s.Plugin
+	if s.Unprocess {
+		name = "." + name
+	}
+	t := emitTemplates[e.lang][name]
+	if t == nil {
+		return "", fmt.Errorf("no %s template for %s", emitLanguageNames[e.lang], name)
+	}
+	t, err := t.Clone()
+	if err != nil {
+		return "", err
+	}
+	t.Funcs(e.funcs())
+	opts := emitOptions{e: e, values: pluginDefaults(s.Plugin)}
+	for k, v := range s.Options {
+		opts.values[k] = v
+	}
+	var b strings.Builder
+	e.err = nil
+	if err := t.Execute(&b, opts); err != nil {
+		if e.err != nil {
+			return "", e.err
+		}
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// pluginDefaults returns the default value of every flag of a plugin.
+func pluginDefaults(name string) map[string]string {
+	values := map[string]string{}
+	p, _, ok := plugins.Resolve(name)
+	if !ok || p.RegisterFlags == nil {
+		return values
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	p.RegisterFlags(fs)
+	fs.VisitAll(func(f *flag.Flag) { values[f.Name] = f.DefValue })
+	return values
+}
+
+// fail records err as the reason a template failed.
+func (e *emitter) fail(err error) error {
+	if e.err == nil {
+		e.err = err
+	}
+	return err
+}
+
+// funcs returns the functions templates of the language can call.
+func (e *emitter) funcs() template.FuncMap {
+	return template.FuncMap{
+		// import adds an import to the program: a package path in Go, an
+		// import statement in Python.
+		"import": func(imp string) string {
+			e.imports[imp] = true
+			return ""
+		},
+		// bytes returns a byte string literal.
+		"bytes": func(v any) string {
+			var b []byte
+			switch v := v.(type) {
+			case []byte:
+				b = v
+			case string:
+				b = []byte(v)
+			}
+			if e.lang == EmitGo {
+				return goBytesLiteral(b)
+			}
+			return pythonBytesLiteral(b)
+		},
+		// hashNew returns the constructor of a hash, called without
+		// arguments in Go and with the data in Python.
+		"hashNew": func(name string) (string, error) {
+			h, ok := emitHashes[e.lang][name]
+			if !ok {
+				return "", e.fail(fmt.Errorf("hash %s is not available in %s", name, emitLanguageNames[e.lang]))
+			}
+			e.imports[h.imp] = true
+			return h.ctor, nil
+		},
+		// hashFunc returns a hash constructor to pass to HMAC.
+		"hashFunc": func(name string) (string, error) {
+			h, ok := emitHashes[e.lang][name]
+			if !ok {
+				return "", e.fail(fmt.Errorf("hash %s is not available in %s", name, emitLanguageNames[e.lang]))
+			}
+			e.imports[h.imp] = true
+			if h.wrap {
+				e.imports["hash"] = true
+				return "func() hash.Hash { return " + h.ctor + "() }", nil
+			}
+			return h.ctor, nil
+		},
+		"fail": func(msg string) (string, error) {
+			return "", e.fail(errors.New(msg))
+		},
+		"lower": strings.ToLower,
+	}
+}
+
+// emitOptions are the options of a step, with defaults, as templates see
+// them.
+type emitOptions struct {
+	e      *emitter
+	values map[string]string
+}
+
+// Opt returns an option as it was set.
+func (o emitOptions) Opt(name string) string { return o.values[name] }
+
+// Bool returns a boolean option.
+func (o emitOptions) Bool(name string) bool {
+	b, _ := strconv.ParseBool(o.values[name])
+	return b
+}
+
+// Int returns an integer option.
+func (o emitOptions) Int(name string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(o.values[name]))
+	if err != nil {
+		return 0, o.e.fail(fmt.Errorf("invalid -%s %q", name, o.values[name]))
+	}
+	return n, nil
+}
+
+// Byte returns a byte value option of the arithmetic plugins as a hex
+// literal.
+func (o emitOptions) Byte(name string) (string, error) {
+	b, err := parseByteValue(strings.TrimSpace(o.values[name]))
+	if err != nil {
+		return "", o.e.fail(fmt.Errorf("-%s: %w", name, err))
+	}
+	return fmt.Sprintf("0x%02x", b), nil
+}
+
+// Key returns key material given as hex or Base64.
+func (o emitOptions) Key(name string) ([]byte, error) {
+	value := strings.TrimSpace(o.values[name])
+	if value == "" {
+		return nil, o.e.fail(fmt.Errorf("missing -%s", name))
+	}
+	b, err := hexOrBase64(value)
+	if err != nil {
+		return nil, o.e.fail(fmt.Errorf("-%s: %w", name, err))
+	}
+	return b, nil
+}
+
+func goBytesLiteral(b []byte) string {
+	if len(b) == 0 {
+		return "nil"
+	}
+	printable := true
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			printable = false
+			break
+		}
+	}
+	if printable {
+		return "[]byte(" + strconv.Quote(string(b)) + ")"
+	}
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("0x%02x", c)
+	}
+	return "[]byte{" + strings.Join(parts, ", ") + "}"
+}
+
+func pythonBytesLiteral(b []byte) string {
+	var sb strings.Builder
+	sb.WriteString(`b"`)
+	for _, c := range b {
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c >= 0x20 && c <= 0x7e:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, `\x%02x`, c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// function wraps the body of step n in a function of the program.
+func (e *emitter) function(n int, command, body string) string {
+	indent := "\t"
+	if e.lang == EmitPython {
+		indent = "    "
+	}
+	var b strings.Builder
+	if e.lang == EmitGo {
+		fmt.Fprintf(&b, "// step%d runs: %s\nfunc step%d(data []byte) ([]byte, error) {\n", n, oneLine(command), n)
+	} else {
+		fmt.Fprintf(&b, "def step%d(data):\n%s# %s\n", n, indent, oneLine(command))
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		b.WriteString(indent + line + "\n")
+	}
+	if e.lang == EmitGo {
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// oneLine keeps a command that spans lines on one comment line.
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(s)
+}
+
+// sortedImports returns the imports of the program, those from the standard
+// library first, and the third-party modules or packages they need.
+func (e *emitter) sortedImports() (std, other, needs []string) {
+	for imp := range e.imports {
+		if dep := emitDependency(e.lang, imp); dep != "" {
+			other = append(other, imp)
+			if !slices.Contains(needs, dep) {
+				needs = append(needs, dep)
+			}
+		} else {
+			std = append(std, imp)
+		}
+	}
+	slices.Sort(std)
+	slices.Sort(other)
+	slices.Sort(needs)
+	return std, other, needs
+}
+
+// emitDependency returns the module or package an import is part of, or ""
+// for the standard library.
+func emitDependency(lang, imp string) string {
+	if lang == EmitGo {
+		first, _, _ := strings.Cut(imp, "/")
+		if !strings.Contains(first, ".") {
+			return ""
+		}
+		parts := strings.SplitN(imp, "/", 4)
+		return strings.Join(parts[:min(3, len(parts))], "/")
+	}
+	if rest, ok := strings.CutPrefix(imp, "from "); ok {
+		pkg, _, _ := strings.Cut(rest, ".")
+		pkg, _, _ = strings.Cut(pkg, " ")
+		if pkg == "cryptography" {
+			return pkg
+		}
+	}
+	return ""
+}
+
+func (e *emitter) goProgram(commands, funcs []string) (string, error) {
+	e.imports["fmt"] = true
+	e.imports["io"] = true
+	e.imports["os"] = true
+	std, other, needs := e.sortedImports()
+
+	var b strings.Builder
+	b.WriteString("// Command chain runs the deen chain\n//\n")
+	for _, c := range commands {
+		b.WriteString("//\t" + oneLine(c) + "\n")
+	}
+	b.WriteString("//\n// on stdin and writes the result to stdout.")
+	if len(needs) > 0 {
+		b.WriteString(" It needs the " + strings.Join(needs, ", ") + " module.")
+	}
+	b.WriteString("\npackage main\n\nimport (\n")
+	for _, imp := range std {
+		b.WriteString("\t" + strconv.Quote(imp) + "\n")
+	}
+	if len(other) > 0 {
+		b.WriteString("\n")
+		for _, imp := range other {
+			b.WriteString("\t" + strconv.Quote(imp) + "\n")
+		}
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+	b.WriteString("\tdata, err := io.ReadAll(os.Stdin)\n\tif err != nil {\n\t\tfmt.Fprintln(os.Stderr, err)\n\t\tos.Exit(1)\n\t}\n")
+	names := make([]string, len(funcs))
+	for i := range funcs {
+		names[i] = fmt.Sprintf("step%d", i+1)
+	}
+	fmt.Fprintf(&b, "\tfor i, step := range []func([]byte) ([]byte, error){%s} {\n", strings.Join(names, ", "))
+	b.WriteString("\t\tif data, err = step(data); err != nil {\n\t\t\tfmt.Fprintf(os.Stderr, \"step %d: %v\\n\", i+1, err)\n\t\t\tos.Exit(1)\n\t\t}\n\t}\n")
+	b.WriteString("\tos.Stdout.Write(data)\n}\n")
+	for _, f := range funcs {
+		b.WriteString("\n" + f)
+	}
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return "", fmt.Errorf("generated Go code does not parse: %w", err)
+	}
+	return string(src), nil
+}
+
+func (e *emitter) pythonProgram(commands, funcs []string) string {
+	e.imports["import sys"] = true
+	std, other, needs := e.sortedImports()
+
+	var b strings.Builder
+	b.WriteString("#!/usr/bin/env python3\n# Runs the deen chain\n#\n")
+	for _, c := range commands {
+		b.WriteString("#     " + oneLine(c) + "\n")
+	}
+	b.WriteString("#\n# on stdin and writes the result to stdout.")
+	if len(needs) > 0 {
+		b.WriteString(" Needs the " + strings.Join(needs, ", ") + " package.")
+	}
+	b.WriteString("\n\n")
+	for i, group := range [][]string{std, other} {
+		if i > 0 && len(std) > 0 && len(other) > 0 {
+			b.WriteString("\n")
+		}
+		// Plain imports come before from-imports, as isort orders them.
+		slices.SortFunc(group, func(a, b string) int {
+			if fa, fb := strings.HasPrefix(a, "from "), strings.HasPrefix(b, "from "); fa != fb {
+				if fa {
+					return 1
+				}
+				return -1
+			}
+			return strings.Compare(a, b)
+		})
+		for _, imp := range group {
+			b.WriteString(imp + "\n")
+		}
+	}
+	for _, f := range funcs {
+		b.WriteString("\n\n" + f)
+	}
+	names := make([]string, len(funcs))
+	for i := range funcs {
+		names[i] = fmt.Sprintf("step%d", i+1)
+	}
+	b.WriteString("\n\ndef main():\n    data = sys.stdin.buffer.read()\n")
+	fmt.Fprintf(&b, "    for i, step in enumerate([%s], 1):\n", strings.Join(names, ", "))
+	b.WriteString("        try:\n            data = step(data)\n        except Exception as err:\n            sys.exit(\"step %d: %s\" % (i, err))\n")
+	b.WriteString("    sys.stdout.buffer.write(data)\n\n\nif __name__ == \"__main__\":\n    main()\n")
+	return b.String()
+}
@@ -0,0 +1,171 @@
+package pipeline
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmitRunsLikeTheChain(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	nonce := []byte("nonce-012345")
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	sealed := aead.Seal(nil, nonce, []byte(`{"b":[1,2],"a":"<x>"}`), []byte("v1"))
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	w.Write(sealed)
+	w.Close()
+
+	p := New()
+	if err := p.LoadJSON([]byte(`{"version": 1, "params": [{"name": "key", "secret": true}], "steps": [
+		{"plugin": "base64", "unprocess": true, "options": {"url": "true"}},
+		{"plugin": "zlib", "unprocess": true},
+		{"plugin": "aes", "unprocess": true, "options": {"key": "${key}", "iv": "6e6f6e63652d303132333435", "aad": "v1"}},
+		{"plugin": "sha256", "disabled": true},
+		{"plugin": "json", "options": {"no-color": "true"}},
+		{"plugin": "xor", "options": {"value": "0x20"}},
+		{"plugin": "hex"}
+	]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Emit(EmitGo); err == nil {
+		t.Fatal("Emit with an unbound parameter succeeded")
+	}
+	if err := p.SetParam("key", "30313233343536373839616263646566"); err != nil {
+		t.Fatal(err)
+	}
+	src := base64.URLEncoding.EncodeToString(z.Bytes())
+	p.SetSource([]byte(src))
+	want := string(p.Result())
+	if p.Err(p.Len()-1) != nil {
+		t.Fatal(p.Err(p.Len() - 1))
+	}
+
+	goSrc, err := p.Emit(EmitGo)
+	if err != nil {
+		t.Fatalf("Emit(go): %v", err)
+	}
+	for _, s := range []string{"\t\"crypto/cipher\"\n", "// step3 runs: deen .aes -aad v1 -iv 6e6f6e63652d303132333435 -key 30313233343536373839616263646566\n", "out[i] = b ^ 0x20", "[]byte(\"0123456789abcdef\")"} {
+		if !strings.Contains(goSrc, s) {
+			t.Errorf("Go program lacks %q:\n%s", s, goSrc)
+		}
+	}
+	if strings.Contains(goSrc, "sha256") {
+		t.Errorf("Go program contains the disabled step:\n%s", goSrc)
+	}
+	pySrc, err := p.Emit(EmitPython)
+	if err != nil {
+		t.Fatalf("Emit(python): %v", err)
+	}
+	for _, s := range []string{"from cryptography.hazmat.primitives.ciphers.aead import AESGCM\n", "Needs the cryptography package.", "return AESGCM(b\"0123456789abcdef\").decrypt(b\"nonce-012345\", data, b\"v1\")"} {
+		if !strings.Contains(pySrc, s) {
+			t.Errorf("Python program lacks %q:\n%s", s, pySrc)
+		}
+	}
+
+	if testing.Short() {
+		return
+	}
+	dir := t.TempDir()
+	if goBin, err := exec.LookPath("go"); err == nil {
+		path := filepath.Join(dir, "main.go")
+		if err := os.WriteFile(path, []byte(goSrc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(goBin, "run", path)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=", "GO111MODULE=off")
+		cmd.Stdin = strings.NewReader(src)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("go run: %v: %s", err, stderrOf(err))
+		}
+		if string(out) != want {
+			t.Errorf("Go program output = %q, want %q", out, want)
+		}
+	}
+	if python, err := exec.LookPath("python3"); err == nil {
+		// The cryptography package may be missing, so compile the full
+		// program and run one without AES.
+		cmd := exec.Command(python, "-c", "import sys; compile(sys.stdin.read(), 'chain.py', 'exec')")
+		cmd.Stdin = strings.NewReader(pySrc)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("python3 compile: %v: %s", err, out)
+		}
+		steps, err := ParseMapChain(".base64 -url | .zlib | xor -value 0x20 | hex")
+		if err != nil {
+			t.Fatal(err)
+		}
+		program, err := EmitSteps(steps, EmitPython)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "chain.py")
+		if err := os.WriteFile(path, []byte(program), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmd = exec.Command(python, path)
+		cmd.Stdin = strings.NewReader(src)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("python3: %v: %s", err, stderrOf(err))
+		}
+		var x bytes.Buffer
+		for _, b := range sealed {
+			x.WriteByte(b ^ 0x20)
+		}
+		if wantHex := hex.EncodeToString(x.Bytes()); string(out) != wantHex {
+			t.Errorf("Python program output = %q, want %q", out, wantHex)
+		}
+	}
+}
+
+func TestEmitRefusesStepsWithoutTemplate(t *testing.T) {
+	for _, tt := range []struct {
+		chain, lang, want string
+	}{
+		{"base64 | lzw", EmitGo, "step 2 (lzw): no Go template for lzw"},
+		{"md4 | ripemd160", EmitPython, "step 1 (md4): no Python template for md4"},
+		{"bzip2", EmitGo, "step 1 (bzip2): no Go template for bzip2"},
+		{"hmac -alg md5 -key k | .hex | map -split lines hex", EmitPython, "step 3 (map): map steps have no code template"},
+		{"hex -region 0:4", EmitGo, "step 1 (hex): regions have no code template"},
+		{".aes -key 00112233445566778899aabbccddeeff", EmitGo, "step 1 (aes): missing -iv"},
+		{".aes -key 00112233445566778899aabbccddeeff -iv 000102030405060708090a0b -tag-len 12", EmitPython, "step 1 (aes): AESGCM in Python only uses 16 byte tags"},
+		{"hmac -alg sha512 -key k | sha512-224", EmitPython, "step 2 (sha512-224): no Python template for sha512-224"},
+	} {
+		steps, err := ParseMapChain(tt.chain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = EmitSteps(steps, tt.lang)
+		var stepErr *StepError
+		if err == nil || !errors.As(err, &stepErr) || err.Error() != tt.want {
+			t.Errorf("EmitSteps(%s, %s) error = %v, want %s", tt.chain, tt.lang, err, tt.want)
+		}
+	}
+
+	if _, err := EmitSteps(nil, "rust"); err == nil {
+		t.Error("EmitSteps with an unknown language succeeded")
+	}
+	if _, err := EmitSteps([]Step{{Plugin: "hex", Disabled: true}}, EmitGo); err == nil {
+		t.Error("EmitSteps without enabled steps succeeded")
+	}
+}
+
+func stderrOf(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(exitErr.Stderr)
+	}
+	return ""
+}
@@ -0,0 +1,403 @@
+package pipeline
+
+import (
+	"strings"
+	"text/template"
+)
+
+// emitTemplates holds the code templates Emit writes steps with, by language
+// and by plugin name with a leading dot for the decode direction. A template
+// is the body of a function that takes the input as data and returns the
+// output: ([]byte, error) in Go, bytes in Python. It executes with the
+// step's emitOptions and adds the imports it needs with the import function.
+var emitTemplates = map[string]map[string]*template.Template{
+	EmitGo:     {},
+	EmitPython: {},
+}
+
+// emitHash is the constructor of a hash in an emitted program.
+type emitHash struct {
+	imp  string
+	ctor string
+	wrap bool // Go: the constructor does not return a hash.Hash
+}
+
+var emitHashes = map[string]map[string]emitHash{
+	EmitGo: {
+		"md4":        {"golang.org/x/crypto/md4", "md4.New", false},
+		"md5":        {"crypto/md5", "md5.New", false},
+		"sha1":       {"crypto/sha1", "sha1.New", false},
+		"sha224":     {"crypto/sha256", "sha256.New224", false},
+		"sha256":     {"crypto/sha256", "sha256.New", false},
+		"sha384":     {"crypto/sha512", "sha512.New384", false},
+		"sha512":     {"crypto/sha512", "sha512.New", false},
+		"sha512-224": {"crypto/sha512", "sha512.New512_224", false},
+		"sha512-256": {"crypto/sha512", "sha512.New512_256", false},
+		"sha3-224":   {"crypto/sha3", "sha3.New224", true},
+		"sha3-256":   {"crypto/sha3", "sha3.New256", true},
+		"sha3-384":   {"crypto/sha3", "sha3.New384", true},
+		"sha3-512":   {"crypto/sha3", "sha3.New512", true},
+		"ripemd160":  {"golang.org/x/crypto/ripemd160", "ripemd160.New", false},
+		"adler32":    {"hash/adler32", "adler32.New", true},
+		"crc32":      {"hash/crc32", "crc32.NewIEEE", true},
+		"fnv32":      {"hash/fnv", "fnv.New32", true},
+		"fnv32a":     {"hash/fnv", "fnv.New32a", true},
+		"fnv64":      {"hash/fnv", "fnv.New64", true},
+		"fnv64a":     {"hash/fnv", "fnv.New64a", true},
+		"fnv128":     {"hash/fnv", "fnv.New128", false},
+		"fnv128a":    {"hash/fnv", "fnv.New128a", false},
+	},
+	EmitPython: {
+		"md5":      {"import hashlib", "hashlib.md5", false},
+		"sha1":     {"import hashlib", "hashlib.sha1", false},
+		"sha224":   {"import hashlib", "hashlib.sha224", false},
+		"sha256":   {"import hashlib", "hashlib.sha256", false},
+		"sha384":   {"import hashlib", "hashlib.sha384", false},
+		"sha512":   {"import hashlib", "hashlib.sha512", false},
+		"sha3-224": {"import hashlib", "hashlib.sha3_224", false},
+		"sha3-256": {"import hashlib", "hashlib.sha3_256", false},
+		"sha3-384": {"import hashlib", "hashlib.sha3_384", false},
+		"sha3-512": {"import hashlib", "hashlib.sha3_512", false},
+	},
+}
+
+// goBase64Encoding selects the encoding the base64 plugin uses.
+const goBase64Encoding = `{{if .Bool "url"}}{{if .Bool "raw"}}RawURLEncoding{{else}}URLEncoding{{end}}` +
+	`{{else if .Bool "raw"}}RawStdEncoding{{else}}StdEncoding{{end}}`
+
+var goTemplates = map[string]string{
+	"base64": `{{import "encoding/base64"}}
+return []byte(base64.` + goBase64Encoding + `.EncodeToString(data)), nil`,
+	".base64": `{{import "bytes"}}{{import "encoding/base64"}}
+data = bytes.TrimSpace(data)
+{{if .Bool "strict"}}return base64.StdEncoding.DecodeString(string(data))
+{{else if or (.Bool "url") (.Bool "raw")}}return base64.` + goBase64Encoding + `.DecodeString(string(data))
+{{else}}var err error
+for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+	var out []byte
+	if out, err = enc.DecodeString(string(data)); err == nil {
+		return out, nil
+	}
+}
+return nil, err
+{{end}}`,
+	"base32": `{{import "encoding/base32"}}
+enc := base32.{{if .Bool "hex"}}HexEncoding{{else}}StdEncoding{{end}}{{if .Bool "no-pad"}}.WithPadding(base32.NoPadding){{end}}
+return []byte(enc.EncodeToString(data)), nil`,
+	".base32": `{{import "bytes"}}{{import "encoding/base32"}}
+enc := base32.{{if .Bool "hex"}}HexEncoding{{else}}StdEncoding{{end}}{{if .Bool "no-pad"}}.WithPadding(base32.NoPadding){{end}}
+return enc.DecodeString(string(bytes.TrimSpace(data)))`,
+	"hex": `{{import "encoding/hex"}}
+return []byte(hex.EncodeToString(data)), nil`,
+	".hex": `{{import "bytes"}}{{import "encoding/hex"}}
+return hex.DecodeString(string(bytes.TrimSpace(data)))`,
+	"url": `{{import "net/url"}}
+return []byte(url.QueryEscape(string(data))), nil`,
+	".url": `{{import "net/url"}}
+s, err := url.QueryUnescape(string(data))
+return []byte(s), err`,
+	"html": `{{import "html"}}
+return []byte(html.EscapeString(string(data))), nil`,
+	".html": `{{import "html"}}
+return []byte(html.UnescapeString(string(data))), nil`,
+	"rot13":  goROT13,
+	".rot13": goROT13,
+	"gzip":   goCompress(`gzip.NewWriterLevel(&buf, {{.Int "level"}})`, "compress/gzip"),
+	"zlib":   goCompress(`zlib.NewWriterLevel(&buf, {{.Int "level"}})`, "compress/zlib"),
+	"flate":  goCompress(`flate.NewWriter(&buf, {{.Int "level"}})`, "compress/flate"),
+	".gzip": `{{import "bytes"}}{{import "compress/gzip"}}{{import "io"}}
+r, err := gzip.NewReader(bytes.NewReader(data))
+if err != nil {
+	return nil, err
+}
+defer r.Close()
+return io.ReadAll(r)`,
+	".zlib": `{{import "bytes"}}{{import "compress/zlib"}}{{import "io"}}
+r, err := zlib.NewReader(bytes.NewReader(data))
+if err != nil {
+	return nil, err
+}
+defer r.Close()
+return io.ReadAll(r)`,
+	".flate": `{{import "bytes"}}{{import "compress/flate"}}{{import "io"}}
+r := flate.NewReader(bytes.NewReader(data))
+defer r.Close()
+return io.ReadAll(r)`,
+	".bzip2": `{{import "bytes"}}{{import "compress/bzip2"}}{{import "io"}}
+return io.ReadAll(bzip2.NewReader(bytes.NewReader(data)))`,
+	"json": `{{import "bytes"}}{{import "encoding/json"}}
+var v any
+if err := json.Unmarshal(data, &v); err != nil {
+	return nil, err
+}
+var buf bytes.Buffer
+enc := json.NewEncoder(&buf)
+enc.SetIndent("", "    ")
+err := enc.Encode(v)
+return buf.Bytes(), err`,
+	".json": `{{import "bytes"}}{{import "encoding/json"}}
+var buf bytes.Buffer
+err := json.Compact(&buf, bytes.TrimSpace(data))
+return buf.Bytes(), err`,
+	"xor":  goArithmetic(`b ^ {{.Byte "value"}}`),
+	".xor": goArithmetic(`b ^ {{.Byte "value"}}`),
+	"add":  goArithmetic(`b + {{.Byte "value"}}`),
+	".add": goArithmetic(`b - {{.Byte "value"}}`),
+	"sub":  goArithmetic(`b - {{.Byte "value"}}`),
+	".sub": goArithmetic(`b + {{.Byte "value"}}`),
+	"not":  goArithmetic(`^b`),
+	".not": goArithmetic(`^b`),
+	"hmac": `{{import "crypto/hmac"}}{{import "encoding/hex"}}
+mac := hmac.New({{hashFunc (.Opt "alg")}}, {{bytes (.Opt "key")}})
+mac.Write(data)
+return []byte(hex.EncodeToString(mac.Sum(nil))), nil`,
+	"aes":               goAES(false),
+	".aes":              goAES(true),
+	"chacha20poly1305":  goChaCha(false),
+	".chacha20poly1305": goChaCha(true),
+}
+
+const goROT13 = `out := make([]byte, len(data))
+for i, b := range data {
+	switch {
+	case b >= 'A' && b <= 'Z':
+		out[i] = 'A' + (b-'A'+13)%26
+	case b >= 'a' && b <= 'z':
+		out[i] = 'a' + (b-'a'+13)%26
+	default:
+		out[i] = b
+	}
+}
+return out, nil`
+
+// goCompress returns the template of a compression whose writer newWriter
+// creates on buf.
+func goCompress(newWriter, pkg string) string {
+	return `{{import "bytes"}}{{import "` + pkg + `"}}
+var buf bytes.Buffer
+w, err := ` + newWriter + `
+if err != nil {
+	return nil, err
+}
+if _, err := w.Write(data); err != nil {
+	return nil, err
+}
+if err := w.Close(); err != nil {
+	return nil, err
+}
+return buf.Bytes(), nil`
+}
+
+// goArithmetic returns the template that maps every byte b with expr.
+func goArithmetic(expr string) string {
+	return `out := make([]byte, len(data))
+for i, b := range data {
+	out[i] = ` + expr + `
+}
+return out, nil`
+}
+
+func goAES(decrypt bool) string {
+	gcm := `aead.Seal(nil, {{bytes (.Key "iv")}}, data, {{bytes (.Opt "aad")}}), nil`
+	cbc := `{{if eq (lower (.Opt "padding")) "none" "no"}}{{import "errors"}}if len(data)%aes.BlockSize != 0 {
+	return nil, errors.New("plaintext is not a multiple of the block size")
+}
+{{else}}{{import "bytes"}}n := aes.BlockSize - len(data)%aes.BlockSize
+data = append(data, bytes.Repeat([]byte{byte(n)}, n)...)
+{{end}}out := make([]byte, len(data))
+cipher.NewCBCEncrypter(block, {{bytes (.Key "iv")}}).CryptBlocks(out, data)
+return out, nil`
+	if decrypt {
+		gcm = `aead.Open(nil, {{bytes (.Key "iv")}}, data, {{bytes (.Opt "aad")}})`
+		cbc = `{{import "errors"}}if len(data)%aes.BlockSize != 0 {
+	return nil, errors.New("ciphertext is not a multiple of the block size")
+}
+out := make([]byte, len(data))
+cipher.NewCBCDecrypter(block, {{bytes (.Key "iv")}}).CryptBlocks(out, data)
+{{if eq (lower (.Opt "padding")) "none" "no"}}return out, nil
+{{else}}{{import "bytes"}}if len(out) == 0 {
+	return nil, errors.New("invalid padding")
+}
+n := int(out[len(out)-1])
+if n == 0 || n > aes.BlockSize || !bytes.Equal(out[len(out)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+	return nil, errors.New("invalid padding")
+}
+return out[:len(out)-n], nil
+{{end}}`
+	}
+	return `{{import "crypto/aes"}}{{import "crypto/cipher"}}
+{{- $mode := lower (.Opt "mode")}}
+{{- if .Bool "skip-aead-verify"}}{{fail "-skip-aead-verify has no code template"}}{{end}}
+block, err := aes.NewCipher({{bytes (.Key "key")}})
+if err != nil {
+	return nil, err
+}
+{{if eq $mode "" "gcm"}}aead, err := cipher.NewGCMWithTagSize(block, {{.Int "tag-len"}})
+if err != nil {
+	return nil, err
+}
+return ` + gcm + `
+{{else if eq $mode "cbc"}}` + cbc + `
+{{else if eq $mode "ctr"}}out := make([]byte, len(data))
+cipher.NewCTR(block, {{bytes (.Key "iv")}}).XORKeyStream(out, data)
+return out, nil
+{{else}}{{fail (print "unsupported AES mode " $mode)}}{{end}}`
+}
+
+func goChaCha(decrypt bool) string {
+	call := `aead.Seal(nil, {{bytes (.Key "nonce")}}, data, {{bytes (.Opt "aad")}}), nil`
+	if decrypt {
+		call = `aead.Open(nil, {{bytes (.Key "nonce")}}, data, {{bytes (.Opt "aad")}})`
+	}
+	return `{{import "golang.org/x/crypto/chacha20poly1305"}}
+aead, err := chacha20poly1305.New({{bytes (.Key "key")}})
+if err != nil {
+	return nil, err
+}
+return ` + call
+}
+
+// pythonBase64Decode makes the Python decoder accept both alphabets and
+// missing padding, as the base64 plugin does by default.
+const pythonBase64Decode = `data = data.replace(b"-", b"+").replace(b"_", b"/")
+return base64.b64decode(data + b"=" * (-len(data) % 4), validate=True)`
+
+var pythonTemplates = map[string]string{
+	"base64": `{{import "import base64"}}
+{{if .Bool "url"}}out = base64.urlsafe_b64encode(data){{else}}out = base64.b64encode(data){{end}}
+{{if .Bool "raw"}}out = out.rstrip(b"=")
+{{end}}return out`,
+	".base64": `{{import "import base64"}}
+data = data.strip()
+{{if .Bool "strict"}}return base64.b64decode(data, validate=True)
+{{else if .Bool "url"}}return base64.urlsafe_b64decode(data + b"=" * (-len(data) % 4))
+{{else}}` + pythonBase64Decode + `
+{{end}}`,
+	"base32": `{{import "import base64"}}
+out = base64.{{if .Bool "hex"}}b32hexencode{{else}}b32encode{{end}}(data)
+{{if .Bool "no-pad"}}out = out.rstrip(b"=")
+{{end}}return out`,
+	".base32": `{{import "import base64"}}
+data = data.strip()
+return base64.{{if .Bool "hex"}}b32hexdecode{{else}}b32decode{{end}}(data{{if .Bool "no-pad"}} + b"=" * (-len(data) % 8){{end}})`,
+	"hex":  `return data.hex().encode()`,
+	".hex": `return bytes.fromhex(data.strip().decode())`,
+	"url": `{{import "import urllib.parse"}}
+return urllib.parse.quote_plus(data, safe="").encode()`,
+	".url": `{{import "import urllib.parse"}}
+return urllib.parse.unquote_to_bytes(data.replace(b"+", b" "))`,
+	"html": `for old, new in ((b"&", b"&amp;"), (b"'", b"&#39;"), (b"<", b"&lt;"), (b">", b"&gt;"), (b'"', b"&#34;")):
+    data = data.replace(old, new)
+return data`,
+	".html": `{{import "import html"}}
+return html.unescape(data.decode("utf-8", "surrogateescape")).encode("utf-8", "surrogateescape")`,
+	"rot13":  pythonROT13,
+	".rot13": pythonROT13,
+	"gzip": `{{import "import gzip"}}
+return gzip.compress(data, compresslevel={{.Int "level"}}, mtime=0)`,
+	"zlib": `{{import "import zlib"}}
+return zlib.compress(data, {{.Int "level"}})`,
+	"flate": `{{import "import zlib"}}
+c = zlib.compressobj({{.Int "level"}}, zlib.DEFLATED, -15)
+return c.compress(data) + c.flush()`,
+	"bzip2": `{{import "import bz2"}}
+return bz2.compress(data{{with .Int "level"}}{{if gt . 0}}, {{.}}{{end}}{{end}})`,
+	".gzip": `{{import "import gzip"}}
+return gzip.decompress(data)`,
+	".zlib": `{{import "import zlib"}}
+return zlib.decompress(data)`,
+	".flate": `{{import "import zlib"}}
+return zlib.decompress(data, -15)`,
+	".bzip2": `{{import "import bz2"}}
+return bz2.decompress(data)`,
+	"json": `{{import "import json"}}
+return (json.dumps(json.loads(data), indent=4, sort_keys=True, ensure_ascii=False) + "\n").encode()`,
+	".json": `{{import "import json"}}
+return json.dumps(json.loads(data), separators=(",", ":"), ensure_ascii=False).encode()`,
+	"xor":  `return bytes(b ^ {{.Byte "value"}} for b in data)`,
+	".xor": `return bytes(b ^ {{.Byte "value"}} for b in data)`,
+	"add":  `return bytes((b + {{.Byte "value"}}) & 0xff for b in data)`,
+	".add": `return bytes((b - {{.Byte "value"}}) & 0xff for b in data)`,
+	"sub":  `return bytes((b - {{.Byte "value"}}) & 0xff for b in data)`,
+	".sub": `return bytes((b + {{.Byte "value"}}) & 0xff for b in data)`,
+	"not":  `return bytes(b ^ 0xff for b in data)`,
+	".not": `return bytes(b ^ 0xff for b in data)`,
+	"adler32": `{{import "import zlib"}}
+return b"%08x" % zlib.adler32(data)`,
+	"crc32": `{{import "import zlib"}}
+return b"%08x" % zlib.crc32(data)`,
+	"hmac": `{{import "import hmac"}}
+return hmac.new({{bytes (.Opt "key")}}, data, {{hashFunc (.Opt "alg")}}).hexdigest().encode()`,
+	"aes":               pythonAES(false),
+	".aes":              pythonAES(true),
+	"chacha20poly1305":  pythonChaCha(false),
+	".chacha20poly1305": pythonChaCha(true),
+}
+
+const pythonROT13 = `table = bytes.maketrans(
+    b"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+    b"NOPQRSTUVWXYZABCDEFGHIJKLMnopqrstuvwxyzabcdefghijklm",
+)
+return data.translate(table)`
+
+func pythonAES(decrypt bool) string {
+	gcm := `return AESGCM({{bytes (.Key "key")}}).encrypt({{bytes (.Key "iv")}}, data, {{bytes (.Opt "aad")}})`
+	cbc := `{{if eq (lower (.Opt "padding")) "none" "no"}}{{else}}{{import "from cryptography.hazmat.primitives import padding"}}padder = padding.PKCS7(128).padder()
+data = padder.update(data) + padder.finalize()
+{{end}}encryptor = Cipher(algorithms.AES({{bytes (.Key "key")}}), modes.CBC({{bytes (.Key "iv")}})).encryptor()
+return encryptor.update(data) + encryptor.finalize()`
+	ctr := `encryptor = Cipher(algorithms.AES({{bytes (.Key "key")}}), modes.CTR({{bytes (.Key "iv")}})).encryptor()
+return encryptor.update(data) + encryptor.finalize()`
+	if decrypt {
+		gcm = `return AESGCM({{bytes (.Key "key")}}).decrypt({{bytes (.Key "iv")}}, data, {{bytes (.Opt "aad")}})`
+		cbc = `decryptor = Cipher(algorithms.AES({{bytes (.Key "key")}}), modes.CBC({{bytes (.Key "iv")}})).decryptor()
+data = decryptor.update(data) + decryptor.finalize()
+{{if eq (lower (.Opt "padding")) "none" "no"}}return data
+{{else}}{{import "from cryptography.hazmat.primitives import padding"}}unpadder = padding.PKCS7(128).unpadder()
+return unpadder.update(data) + unpadder.finalize()
+{{end}}`
+		ctr = strings.ReplaceAll(ctr, "encryptor", "decryptor")
+	}
+	return `{{- $mode := lower (.Opt "mode")}}
+{{- if .Bool "skip-aead-verify"}}{{fail "-skip-aead-verify has no code template"}}{{end}}
+{{- if eq $mode "" "gcm"}}{{if ne (.Opt "tag-len") "16"}}{{fail "AESGCM in Python only uses 16 byte tags"}}{{end -}}
+{{import "from cryptography.hazmat.primitives.ciphers.aead import AESGCM"}}
+` + gcm + `
+{{else if eq $mode "cbc" "ctr"}}{{import "from cryptography.hazmat.primitives.ciphers import Cipher, algorithms, modes"}}
+{{- if eq $mode "cbc"}}
+` + cbc + `
+{{else}}
+` + ctr + `
+{{end}}{{else}}{{fail (print "unsupported AES mode " $mode)}}{{end}}`
+}
+
+func pythonChaCha(decrypt bool) string {
+	call := "encrypt"
+	if decrypt {
+		call = "decrypt"
+	}
+	return `{{import "from cryptography.hazmat.primitives.ciphers.aead import ChaCha20Poly1305"}}
+return ChaCha20Poly1305({{bytes (.Key "key")}}).` + call + `({{bytes (.Key "nonce")}}, data, {{bytes (.Opt "aad")}})`
+}
+
+func init() {
+	hashTemplates := map[string]string{
+		EmitGo: `{{import "encoding/hex"}}
+h := {{hashNew "%s"}}()
+h.Write(data)
+return []byte(hex.EncodeToString(h.Sum(nil))), nil`,
+		EmitPython: `return {{hashNew "%s"}}(data).hexdigest().encode()`,
+	}
+	sources := map[string]map[string]string{EmitGo: goTemplates, EmitPython: pythonTemplates}
+	for lang, templates := range sources {
+		for name := range emitHashes[lang] {
+			if _, ok := templates[name]; !ok {
+				templates[name] = strings.ReplaceAll(hashTemplates[lang], "%s", name)
+			}
+		}
+		funcs := (&emitter{lang: lang}).funcs()
+		for name, code := range templates {
+			emitTemplates[lang][name] = template.Must(template.New(name).Funcs(funcs).Parse(code))
+		}
+	}
+}
@@ -21,11 +21,11 @@ import (
 
 // Example is a runnable sample that loads both input data and a transform chain.
 type Example struct {
-	Name         string
-	Description  string
-	Source       []byte
-	Steps        []PresetStep
-	WantContains string
+	Name        string
+	Description string
+	Source      []byte
+	Steps       []PresetStep
+	Want        Expectation // checked against the result of Source
 }
 
 // BuiltinExamples returns runnable samples with concrete input data.
@@ -44,7 +44,7 @@ func BuiltinExamples() []Example {
 				{Plugin: "jwt", Options: map[string]string{"r": "true"}},
 				{Plugin: "jwt", Unprocess: true},
 			},
-			WantContains: `"name": "Max Power"`,
+			Want: Expectation{Contains: `"name": "Max Power"`},
 		},
 		{
 			Name:        "Compressed webhook payload",
@@ -59,7 +59,7 @@ func BuiltinExamples() []Example {
 					"no-color": "true",
 				}},
 			},
-			WantContains: `"actor": "user_123"`,
+			Want: Expectation{Contains: `"actor": "user_123"`},
 		},
 		{
 			Name:        "SAML redirect request",
@@ -68,7 +68,7 @@ func BuiltinExamples() []Example {
 			Steps: []PresetStep{
 				{Plugin: "saml", Options: map[string]string{"url": "true", "deflate": "true"}},
 			},
-			WantContains: "https://idp.example.test",
+			Want: Expectation{Contains: "https://idp.example.test"},
 		},
 		{
 			Name:        "Protobuf login request",
@@ -78,7 +78,7 @@ func BuiltinExamples() []Example {
 				{Plugin: "base64", Unprocess: true},
 				{Plugin: "protobuf"},
 			},
-			WantContains: `2: string "correct-horse-battery-staple"`,
+			Want: Expectation{Contains: `2: string "correct-horse-battery-staple"`},
 		},
 		{
 			Name:        "URL token policy extraction",
@@ -92,7 +92,7 @@ func BuiltinExamples() []Example {
 					"no-color": "true",
 				}},
 			},
-			WantContains: `"principal": "svc-billing"`,
+			Want: Expectation{Contains: `"principal": "svc-billing"`},
 		},
 		{
 			Name:        "MessagePack event from logs",
@@ -106,7 +106,7 @@ func BuiltinExamples() []Example {
 					"no-color": "true",
 				}},
 			},
-			WantContains: `"type": "api.denied"`,
+			Want: Expectation{Contains: `"type": "api.denied"`},
 		},
 		{
 			Name:        "CBOR device attestation",
@@ -120,7 +120,7 @@ func BuiltinExamples() []Example {
 					"no-color": "true",
 				}},
 			},
-			WantContains: `"status": "jailbreak"`,
+			Want: Expectation{Contains: `"status": "jailbreak"`},
 		},
 		{
 			Name:        "DNS name from packet bytes",
@@ -130,7 +130,7 @@ func BuiltinExamples() []Example {
 				{Plugin: "hex", Unprocess: true},
 				{Plugin: "dns", Unprocess: true},
 			},
-			WantContains: "admin-auth-service-prod-edge-001x.example.com.",
+			Want: Expectation{Contains: "admin-auth-service-prod-edge-001x.example.com."},
 		},
 		{
 			Name:        "AES-GCM API secret",
@@ -146,7 +146,7 @@ func BuiltinExamples() []Example {
 				}},
 				{Plugin: "json"},
 			},
-			WantContains: `"api_key"`,
+			Want: Expectation{Contains: `"api_key"`},
 		},
 		{
 			Name:        "Create encrypted support fixture",
@@ -166,18 +166,18 @@ func BuiltinExamples() []Example {
 			},
 		},
 		{
-			Name:         "Print X.509 certificate details",
-			Description:  "Inspect a PEM certificate and print the subject, issuer, validity, public key, extensions, and signature material.",
-			Source:       sampleCertificatePEM(),
-			Steps:        []PresetStep{{Plugin: "certPrinter"}},
-			WantContains: "www.example.test",
+			Name:        "Print X.509 certificate details",
+			Description: "Inspect a PEM certificate and print the subject, issuer, validity, public key, extensions, and signature material.",
+			Source:      sampleCertificatePEM(),
+			Steps:       []PresetStep{{Plugin: "certPrinter"}},
+			Want:        Expectation{Contains: "www.example.test"},
 		},
 		{
-			Name:         "Clone an X.509 certificate shape",
-			Description:  "Clone a PEM certificate into a new self-signed certificate with a freshly generated matching private key.",
-			Source:       sampleCertificatePEM(),
-			Steps:        []PresetStep{{Plugin: "certCloner"}},
-			WantContains: "BEGIN PRIVATE KEY",
+			Name:        "Clone an X.509 certificate shape",
+			Description: "Clone a PEM certificate into a new self-signed certificate with a freshly generated matching private key.",
+			Source:      sampleCertificatePEM(),
+			Steps:       []PresetStep{{Plugin: "certCloner"}},
+			Want:        Expectation{Contains: "BEGIN PRIVATE KEY"},
 		},
 		{
 			Name:        "Print X.509 certificate ASN.1",
@@ -187,7 +187,7 @@ func BuiltinExamples() []Example {
 				{Plugin: "pem", Unprocess: true},
 				{Plugin: "asn1"},
 			},
-			WantContains: "SEQUENCE",
+			Want: Expectation{Contains: "SEQUENCE"},
 		},
 		{
 			Name:        "QR payload fixture",
@@ -306,6 +306,15 @@ func appendProtoVarint(out []byte, value uint64) []byte {
 	return append(out, byte(value))
 }
 
+// Test returns the example as a test case over its source.
+func (example Example) Test() TestCase {
+	return TestCase{
+		Name:        example.Name,
+		InputBase64: base64.StdEncoding.EncodeToString(example.Source),
+		Expectation: example.Want,
+	}
+}
+
 // ExampleResult runs an example in an isolated pipeline and returns its result.
 func ExampleResult(example Example) ([]byte, error) {
 	p := New()
@@ -325,7 +334,7 @@ func ExampleMatches(example Example, query string) bool {
 		return true
 	}
 	var parts []string
-	parts = append(parts, example.Name, example.Description, string(example.Source), example.WantContains)
+	parts = append(parts, example.Name, example.Description, string(example.Source), example.Want.Contains)
 	for _, step := range example.Steps {
 		parts = append(parts, step.Plugin)
 		if step.Unprocess {
@@ -344,8 +353,10 @@ func ExampleMatches(example Example, query string) bool {
 // The previous pipeline state remains undoable.
 func (p *Pipeline) ApplyExample(example Example) {
 	p.record()
-	p.source = append([]byte(nil), example.Source...)
+	p.replaceSource(append([]byte(nil), example.Source...))
 	p.steps = presetSteps(example.Steps)
+	p.branches = nil
+	p.tests = []TestCase{example.Test()}
 	p.Compute()
 }
 
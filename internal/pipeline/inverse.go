@@ -0,0 +1,218 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/takeshixx/deen/internal/plugins"
+)
+
+// nonInverse lists the plugins whose decode direction exists but does not
+// undo the encode direction, with the reason Inverse reports for them.
+var nonInverse = map[string]string{
+	"json2xml":  "XML has no number, boolean or array types",
+	"toml":      "TOML and JSON documents have different type systems",
+	"jwt":       "signing tokens needs keys and claims",
+	"jwk":       "it generates and inspects keys",
+	"csv":       "it renders tables",
+	"qr":        "it renders images",
+	"saml":      "it only decodes SAML messages",
+	"timestamp": "it formats timestamps at the resolution of the layout",
+	"dns":       "it decodes wire-format names",
+	"uuid":      "it inspects UUIDs",
+	"sign":      "verifying needs a signature and public key",
+}
+
+// layoutPlugins only change the layout of a document, not its content.
+// Inverse drops them, or compacts the document again when that restores the
+// original input.
+var layoutPlugins = map[string]bool{"json": true, "xml": true}
+
+// encodeOptions derive the options of an encode step that reproduce the
+// encoded input a decode step of the same plugin saw.
+var encodeOptions = map[string]func(in []byte, opts map[string]string){
+	"base64": base64Options,
+	"gzip":   gzipOptions,
+	"zlib":   zlibOptions,
+}
+
+// Inverse returns the chain that turns the result of the pipeline back into
+// its source, e.g. ".url | .base64 | .gzip | json" becomes
+// "gzip | base64 | url" to re-encode an edited JSON document. The enabled
+// steps are reversed and their directions flipped, and layout-only formatters
+// such as json and xml pretty-printing are dropped. Where the encoded data
+// allows it, the options of the inverse steps are picked to reproduce the
+// original encoding, such as the Base64 alphabet and padding or the gzip
+// compression level. Steps without an inverse, such as hashes, are reported
+// as a *StepError.
+func (p *Pipeline) Inverse() ([]Step, error) {
+	p.Compute()
+	data := make([]stepData, len(p.steps))
+	for i, s := range p.steps {
+		if s.err == nil {
+			data[i] = stepData{in: p.Input(i), out: s.effective}
+		}
+	}
+	ctx, cancel := p.limits.chainContext(context.Background())
+	defer cancel()
+	return invertSteps(ctx, p.limits, p.steps, data)
+}
+
+// Invert replaces the chain with its Inverse and the source with the current
+// result, so the result can be edited and re-encoded. Branches and test
+// cases describe the original chain and are dropped; parameters are kept.
+func (p *Pipeline) Invert() error {
+	steps, err := p.Inverse()
+	if err != nil {
+		return err
+	}
+	ss, err := checkedSteps(steps)
+	if err != nil {
+		return err
+	}
+	p.record()
+	p.load(snapshot{Source: bytes.Clone(p.Result()), Steps: ss, Params: p.params})
+	p.Compute()
+	return nil
+}
+
+// stepData is the data a step read and wrote, nil where it is unknown.
+type stepData struct{ in, out []byte }
+
+// invertSteps returns the inverse of steps. data holds what each step read
+// and wrote; where it is missing, options are copied unchanged.
+func invertSteps(ctx context.Context, l Limits, steps []*Step, data []stepData) ([]Step, error) {
+	var inv []Step
+	for i := len(steps) - 1; i >= 0; i-- {
+		s := steps[i]
+		if s.Disabled {
+			continue
+		}
+		var d stepData
+		if i < len(data) {
+			d = data[i]
+		}
+		step, keep, err := invertStep(ctx, l, s, d)
+		if err != nil {
+			return nil, &StepError{Index: i, Plugin: s.Plugin, Err: err}
+		}
+		if keep {
+			inv = append(inv, step)
+		}
+	}
+	return inv, nil
+}
+
+// invertStep returns the inverse of s and whether the chain needs it at all.
+func invertStep(ctx context.Context, l Limits, s *Step, d stepData) (Step, bool, error) {
+	if s.Region != "" {
+		r, err := parseRegion(s.Region)
+		if err != nil {
+			return Step{}, false, err
+		}
+		if r.re == nil && r.jq == nil {
+			return Step{}, false, errors.New("byte range regions cannot be inverted because the step changes the length of the data")
+		}
+	}
+	if s.Map != nil {
+		if s.Map.Split == SplitRegex {
+			return Step{}, false, errors.New("map steps that split on a regex cannot be inverted because the encoded pieces may not match it")
+		}
+		nested := make([]*Step, len(s.Map.Steps))
+		for i := range s.Map.Steps {
+			nested[i] = &s.Map.Steps[i]
+		}
+		steps, err := invertSteps(ctx, l, nested, nil)
+		if err != nil {
+			return Step{}, false, err
+		}
+		return Step{Plugin: MapPlugin, Map: &Map{Split: s.Map.Split, Pattern: s.Map.Pattern, Steps: steps}, Region: s.Region}, true, nil
+	}
+	if reason, ok := nonInverse[s.Plugin]; ok {
+		return Step{}, false, fmt.Errorf("%s has no inverse: %s", s.Plugin, reason)
+	}
+	if !plugins.CanDecode(s.Plugin) {
+		return Step{}, false, fmt.Errorf("%s is one-way and has no inverse", s.Plugin)
+	}
+	if layoutPlugins[s.Plugin] {
+		if s.Unprocess || d.in == nil {
+			return Step{}, false, nil
+		}
+		// Compacting is only worth a step if it restores the original input.
+		compact := Step{Plugin: s.Plugin, Unprocess: true, Region: s.Region}
+		out, err := runStep(ctx, l, &compact, d.out)
+		return compact, err == nil && bytes.Equal(out, d.in), nil
+	}
+	inv := Step{Plugin: s.Plugin, Unprocess: !s.Unprocess, Options: maps.Clone(s.Options), Region: s.Region}
+	if inv.Options == nil {
+		inv.Options = map[string]string{}
+	}
+	// The plugin only sees the region of the input, so the input tells nothing
+	// about the encoding of a step with a region.
+	if derive := encodeOptions[s.Plugin]; derive != nil && s.Unprocess && d.in != nil && s.Region == "" {
+		derive(d.in, inv.Options)
+	}
+	return inv, true, nil
+}
+
+// base64Options selects the alphabet and padding the Base64 decoder found in
+// in, trying them in the order the decoder does. Input whose length needs no
+// padding decodes with either padding; URL-safe Base64 is then taken to be
+// unpadded, as padding would need escaping in URLs.
+func base64Options(in []byte, opts map[string]string) {
+	in = bytes.TrimSpace(in)
+	delete(opts, "strict")
+	delete(opts, "url")
+	delete(opts, "raw")
+	for _, c := range []struct {
+		enc      *base64.Encoding
+		url, raw bool
+	}{
+		{base64.StdEncoding, false, false},
+		{base64.RawStdEncoding, false, true},
+		{base64.URLEncoding, true, false},
+		{base64.RawURLEncoding, true, true},
+	} {
+		if _, err := c.enc.DecodeString(string(in)); err == nil {
+			if c.url {
+				opts["url"] = "true"
+			}
+			if c.raw || c.url && !bytes.HasSuffix(in, []byte("=")) {
+				opts["raw"] = "true"
+			}
+			return
+		}
+	}
+}
+
+// gzipOptions reads the compression level from the XFL header byte, which
+// marks the fastest and the best compression.
+func gzipOptions(in []byte, opts map[string]string) {
+	if len(in) < 10 {
+		return
+	}
+	switch in[8] {
+	case 2:
+		opts["level"] = "9"
+	case 4:
+		opts["level"] = "1"
+	}
+}
+
+// zlibOptions reads the compression level from the FLEVEL bits of the zlib
+// header, which mark the fastest and the best compression.
+func zlibOptions(in []byte, opts map[string]string) {
+	if len(in) < 2 {
+		return
+	}
+	switch in[1] >> 6 {
+	case 0:
+		opts["level"] = "1"
+	case 3:
+		opts["level"] = "9"
+	}
+}
@@ -0,0 +1,134 @@
+package pipeline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, data string, level int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestInverseReencodesCookie(t *testing.T) {
+	doc := `{"admin":false,"user":"alice"}`
+	cookie := url.QueryEscape(base64.RawURLEncoding.EncodeToString(gzipped(t, doc, gzip.BestCompression)))
+	p := New()
+	if err := p.LoadCommandLine(".url | .base64 | .gzip | json -no-color"); err != nil {
+		t.Fatalf("LoadCommandLine: %v", err)
+	}
+	p.SetSource([]byte(cookie))
+	if err := p.Err(3); err != nil {
+		t.Fatalf("Err = %v", err)
+	}
+
+	steps, err := p.Inverse()
+	if err != nil {
+		t.Fatalf("Inverse: %v", err)
+	}
+	if got := MapChainString(steps); got != ".json | gzip -level 9 | base64 -raw -url | url" {
+		t.Fatalf("Inverse = %q", got)
+	}
+	if err := p.Invert(); err != nil {
+		t.Fatalf("Invert: %v", err)
+	}
+	if got := string(p.Result()); got != cookie {
+		t.Fatalf("re-encoded Result = %q, want %q", got, cookie)
+	}
+
+	// The edited document is re-encoded the same way.
+	p.SetSource([]byte(strings.Replace(doc, "false", "true", 1)))
+	q := New()
+	q.LoadCommandLine(".url | .base64 | .gzip")
+	q.SetSource(p.Result())
+	if got := string(q.Result()); got != `{"admin":true,"user":"alice"}` {
+		t.Fatalf("decoded edit = %q", got)
+	}
+	if got := string(p.Result()); strings.Contains(got, "%3D") {
+		t.Fatalf("re-encoded edit %q is padded", got)
+	}
+	if !p.Undo() || !p.Undo() || p.Len() != 4 || string(p.Source()) != cookie {
+		t.Fatalf("Undo did not restore the decode chain: %s", p.CommandLine())
+	}
+}
+
+func TestInverseDropsLayoutSteps(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("eyJhIjogMX0="))
+	p.AddStep("base64", true)
+	p.AddStepWithOptions("json", false, map[string]string{"no-color": "true"})
+	steps, err := p.Inverse()
+	if err != nil {
+		t.Fatalf("Inverse: %v", err)
+	}
+	// The original document was not compact, so it is not compacted again.
+	if got := MapChainString(steps); got != "base64" {
+		t.Fatalf("Inverse = %q", got)
+	}
+}
+
+func TestInverseSkipsDisabledAndFlipsEncoders(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("hi"))
+	p.AddStep("hex", false)
+	p.AddStep("url", false)
+	p.SetStepDisabled(1, true)
+	p.AddStepWithOptions("xor", false, map[string]string{"value": "0x20"})
+	if err := p.Invert(); err != nil {
+		t.Fatalf("Invert: %v", err)
+	}
+	if got := p.CommandLine(); got != "deen .xor -value 0x20 | deen .hex" {
+		t.Fatalf("CommandLine = %q", got)
+	}
+	if got := string(p.Result()); got != "hi" {
+		t.Fatalf("Result = %q", got)
+	}
+}
+
+func TestInverseMapAndRegion(t *testing.T) {
+	p := New()
+	if err := p.LoadCommandLine(`.base64 -region 're:"v": "([^"]+)' | map -split json -pattern '$.l[*]' .hex`); err != nil {
+		t.Fatalf("LoadCommandLine: %v", err)
+	}
+	src := `{"v": "aGk=", "l": ["6869", "627965"]}`
+	p.SetSource([]byte(src))
+	if err := p.Invert(); err != nil {
+		t.Fatalf("Invert: %v", err)
+	}
+	if got := string(p.Result()); got != src {
+		t.Fatalf("Result = %q, want %q (chain %s)", got, src, p.CommandLine())
+	}
+}
+
+func TestInverseRefusesOneWaySteps(t *testing.T) {
+	for _, tt := range []struct {
+		chain, want string
+	}{
+		{".base64 | sha256", "step 2 (sha256): sha256 is one-way"},
+		{"jwt", "step 1 (jwt): jwt has no inverse"},
+		{".hex -region 0:4", "byte range regions cannot be inverted"},
+		{"map -split regex -pattern [0-9]+ hex", "split on a regex"},
+	} {
+		p := New()
+		if err := p.LoadCommandLine(tt.chain); err != nil {
+			t.Fatalf("LoadCommandLine(%q): %v", tt.chain, err)
+		}
+		if _, err := p.Inverse(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Inverse(%q) = %v, want %q", tt.chain, err, tt.want)
+		}
+		if err := p.Invert(); err == nil || p.CanUndo() {
+			t.Errorf("Invert(%q) = %v, CanUndo %v", tt.chain, err, p.CanUndo())
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// Limits bounds the time and output a transform may use. Zero values disable
+// the corresponding limit.
+//
+// Plugins do not observe a context, so a timed-out step is abandoned rather
+// than stopped: its error is reported and any further output is discarded,
+// while the plugin goroutine finishes in the background. In WebAssembly builds
+// goroutines are cooperative, so a timeout fires only once the plugin yields.
+type Limits struct {
+	StepTimeout  time.Duration // wall-clock budget for one step
+	ChainTimeout time.Duration // wall-clock budget for all steps together
+	MaxOutput    int64         // bytes a single step may write
+	MaxExpansion float64       // output bytes per input byte a step may write
+}
+
+// expansionFloor is the output size below which MaxExpansion is not enforced,
+// so small outputs of tiny inputs (hashes, UUIDs, encodings) are not flagged.
+const expansionFloor = 1 << 20
+
+// DefaultLimits returns limits for interactive use in the GUI, web UI and MCP
+// server, where the whole chain is held in memory.
+func DefaultLimits() Limits {
+	return Limits{
+		StepTimeout:  30 * time.Second,
+		ChainTimeout: 2 * time.Minute,
+		MaxOutput:    256 << 20,
+		MaxExpansion: 1000,
+	}
+}
+
+var (
+	// ErrOutputLimit is reported when a step writes more than MaxOutput bytes.
+	ErrOutputLimit = errors.New("output size limit exceeded")
+	// ErrExpansionLimit is reported when a step writes more than MaxExpansion
+	// bytes per input byte, e.g. for a decompression bomb.
+	ErrExpansionLimit = errors.New("output expansion limit exceeded")
+)
+
+// Limits returns the limits applied to step execution.
+func (p *Pipeline) Limits() Limits { return p.limits }
+
+// SetLimits changes the limits applied to step execution. It does not
+// recompute the pipeline.
+func (p *Pipeline) SetLimits(l Limits) { p.limits = l }
+
+type limitsJSON struct {
+	StepTimeout  string  `json:"stepTimeout,omitempty"`
+	ChainTimeout string  `json:"chainTimeout,omitempty"`
+	MaxOutput    int64   `json:"maxOutput,omitempty"`
+	MaxExpansion float64 `json:"maxExpansion,omitempty"`
+}
+
+// MarshalJSON encodes durations in time.Duration string form, e.g. "30s".
+func (l Limits) MarshalJSON() ([]byte, error) {
+	v := limitsJSON{MaxOutput: l.MaxOutput, MaxExpansion: l.MaxExpansion}
+	if l.StepTimeout > 0 {
+		v.StepTimeout = l.StepTimeout.String()
+	}
+	if l.ChainTimeout > 0 {
+		v.ChainTimeout = l.ChainTimeout.String()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes the form written by MarshalJSON.
+func (l *Limits) UnmarshalJSON(data []byte) error {
+	var v limitsJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	out := Limits{MaxOutput: v.MaxOutput, MaxExpansion: v.MaxExpansion}
+	var err error
+	if v.StepTimeout != "" {
+		if out.StepTimeout, err = time.ParseDuration(v.StepTimeout); err != nil {
+			return fmt.Errorf("stepTimeout: %w", err)
+		}
+	}
+	if v.ChainTimeout != "" {
+		if out.ChainTimeout, err = time.ParseDuration(v.ChainTimeout); err != nil {
+			return fmt.Errorf("chainTimeout: %w", err)
+		}
+	}
+	*l = out
+	return nil
+}
+
+// chainContext applies the chain budget of l to ctx.
+func (l Limits) chainContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.ChainTimeout > 0 {
+		return context.WithTimeoutCause(ctx, l.ChainTimeout,
+			fmt.Errorf("chain timed out after %s: %w", l.ChainTimeout, context.DeadlineExceeded))
+	}
+	return context.WithCancel(ctx)
+}
+
+// runTransform runs fn from r to w within ctx and the step limits of l.
+// Panics in the plugin are recovered and returned as errors.
+//
+// When ctx ends first, runTransform returns without waiting for fn: Go cannot
+// stop the goroutine running an in-process plugin, so it lingers until fn
+// returns. Its reads and writes fail from then on, which ends most plugins
+// soon, but one stuck in a computation keeps its CPU and memory until done.
+// External plugins receive the step context and are killed with it.
+func runTransform(ctx context.Context, l Limits, fn types.ContextTransformFunc, fs *flag.FlagSet, r io.Reader, w io.Writer) error {
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+	var cancel context.CancelFunc
+	if l.StepTimeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, l.StepTimeout,
+			fmt.Errorf("step timed out after %s: %w", l.StepTimeout, context.DeadlineExceeded))
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	in := &countingReader{ctx: ctx, r: r}
+	out := &limitedWriter{ctx: ctx, w: w, in: in, limits: l}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- fmt.Errorf("plugin panic: %v", v)
+			}
+		}()
+		done <- fn(ctx, in, out, fs)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = context.Cause(ctx)
+	}
+	if limitErr := out.close(); limitErr != nil {
+		// The plugin usually wraps or replaces the write error; report the
+		// limit itself.
+		return limitErr
+	}
+	return err
+}
+
+// countingReader counts consumed input and stops reading once ctx is done.
+type countingReader struct {
+	ctx context.Context
+	r   io.Reader
+
+	mu sync.Mutex
+	n  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if err := context.Cause(c.ctx); err != nil {
+		return 0, err
+	}
+	n, err := c.r.Read(p)
+	c.mu.Lock()
+	c.n += int64(n)
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *countingReader) count() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// limitedWriter enforces output limits. Once closed it discards writes, so an
+// abandoned plugin cannot touch the destination after runTransform returns.
+type limitedWriter struct {
+	ctx    context.Context
+	w      io.Writer
+	in     *countingReader
+	limits Limits
+
+	mu     sync.Mutex
+	n      int64
+	err    error
+	closed bool
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	if err := context.Cause(l.ctx); err != nil {
+		return 0, err
+	}
+	total := l.n + int64(len(p))
+	if l.limits.MaxOutput > 0 && total > l.limits.MaxOutput {
+		l.err = fmt.Errorf("%w (%d bytes)", ErrOutputLimit, l.limits.MaxOutput)
+		return 0, l.err
+	}
+	if l.limits.MaxExpansion > 0 && total > expansionFloor &&
+		float64(total) > l.limits.MaxExpansion*float64(l.in.count()) {
+		l.err = fmt.Errorf("%w (%gx)", ErrExpansionLimit, l.limits.MaxExpansion)
+		return 0, l.err
+	}
+	n, err := l.w.Write(p)
+	l.n += int64(n)
+	return n, err
+}
+
+// close stops further writes and returns the limit error, if any.
+func (l *limitedWriter) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	return l.err
+}
@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunTransformRecoversPanic(t *testing.T) {
+	fn := func(context.Context, io.Reader, io.Writer, *flag.FlagSet) error { panic("boom") }
+	err := runTransform(context.Background(), Limits{}, fn, nil, strings.NewReader(""), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "plugin panic: boom") {
+		t.Fatalf("err = %v, want plugin panic", err)
+	}
+}
+
+func TestRunTransformStepTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	fn := func(context.Context, io.Reader, io.Writer, *flag.FlagSet) error {
+		<-release
+		return nil
+	}
+	l := Limits{StepTimeout: 20 * time.Millisecond}
+	err := runTransform(context.Background(), l, fn, nil, strings.NewReader(""), io.Discard)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "step timed out after 20ms") {
+		t.Fatalf("err = %v, want step timeout", err)
+	}
+}
+
+func TestRunTransformExpansionLimit(t *testing.T) {
+	fn := func(_ context.Context, r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		if _, err := io.ReadAll(r); err != nil {
+			return err
+		}
+		_, err := w.Write(make([]byte, 2*expansionFloor))
+		return err
+	}
+	l := Limits{MaxExpansion: 100}
+	var out bytes.Buffer
+	err := runTransform(context.Background(), l, fn, nil, strings.NewReader("x"), &out)
+	if !errors.Is(err, ErrExpansionLimit) {
+		t.Fatalf("err = %v, want ErrExpansionLimit", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("wrote %d bytes past the limit", out.Len())
+	}
+
+	// Small outputs are below the floor and pass regardless of the ratio.
+	small := func(_ context.Context, _ io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		_, err := w.Write(make([]byte, 64))
+		return err
+	}
+	if err := runTransform(context.Background(), l, small, nil, strings.NewReader(""), io.Discard); err != nil {
+		t.Fatalf("small output: %v", err)
+	}
+}
+
+func TestComputeOutputLimit(t *testing.T) {
+	p := New()
+	p.SetLimits(Limits{MaxOutput: 4})
+	p.SetSource([]byte("hello world"))
+	p.AddStep("base64", false)
+	if err := p.Err(0); !errors.Is(err, ErrOutputLimit) {
+		t.Fatalf("Err(0) = %v, want ErrOutputLimit", err)
+	}
+
+	var buf bytes.Buffer
+	err := p.StreamFrom(context.Background(), strings.NewReader("hello world"), &buf)
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || !errors.Is(err, ErrOutputLimit) {
+		t.Fatalf("StreamFrom err = %v, want output limit step error", err)
+	}
+}
+
+func TestStreamChainTimeout(t *testing.T) {
+	p := New()
+	p.SetLimits(Limits{ChainTimeout: 20 * time.Millisecond})
+	p.AddStep("base64", false)
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	err := p.StreamFrom(context.Background(), pr, io.Discard)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "chain timed out") {
+		t.Fatalf("err = %v, want chain timeout", err)
+	}
+}
+
+func TestLimitsJSONRoundTrip(t *testing.T) {
+	want := DefaultLimits()
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"stepTimeout":"30s"`) {
+		t.Fatalf("json = %s", data)
+	}
+	var got Limits
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("round trip = %+v, want %+v", got, want)
+	}
+	if err := json.Unmarshal([]byte(`{"stepTimeout":"soon"}`), &got); err == nil {
+		t.Fatal("expected error for invalid duration")
+	}
+}
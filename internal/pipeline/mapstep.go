@@ -0,0 +1,608 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/takeshixx/deen/internal/plugins"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// MapPlugin is the Plugin name of map steps. It is not a registered plugin:
+// a map step runs the nested chain of its Map on each piece of its input.
+const MapPlugin = "map"
+
+// Split modes of a map step.
+const (
+	SplitLines     = "lines"
+	SplitDelimiter = "delimiter"
+	SplitRegex     = "regex"
+	SplitJSON      = "json"
+)
+
+// SplitModes lists the split modes in the order editors offer them.
+var SplitModes = []string{SplitLines, SplitDelimiter, SplitRegex, SplitJSON}
+
+// Map configures a map step. The step splits its input into pieces, runs
+// Steps on each piece and writes every result back where its piece was, so
+// everything between the pieces is kept as it is. Pattern depends on Split:
+//
+//   - lines: unused; each line without its line ending is a piece.
+//   - delimiter: the separator; escapes such as \t and \n are interpreted.
+//   - regex: each match is a piece, or its first capture group if it has one.
+//   - json: a JSONPath selecting values of a JSON document, "$[*]" (the
+//     elements of a top-level array) by default. String values are
+//     transformed without their quotes.
+//
+// Empty pieces are left unchanged.
+type Map struct {
+	Split   string
+	Pattern string
+	Steps   []Step
+}
+
+// MapPieceError reports which piece of a map step failed.
+type MapPieceError struct {
+	Piece int // one-based piece number
+	Err   error
+}
+
+func (e *MapPieceError) Error() string { return fmt.Sprintf("piece %d: %s", e.Piece, e.Err) }
+
+func (e *MapPieceError) Unwrap() error { return e.Err }
+
+func (m *Map) clone() *Map {
+	if m == nil {
+		return nil
+	}
+	c := &Map{Split: m.Split, Pattern: m.Pattern, Steps: make([]Step, 0, len(m.Steps))}
+	for i := range m.Steps {
+		c.Steps = append(c.Steps, *cloneStep(&m.Steps[i]))
+	}
+	return c
+}
+
+// check validates the split mode and pattern of m, not its steps.
+func (m *Map) check() error {
+	switch m.Split {
+	case SplitLines:
+	case SplitDelimiter:
+		if m.Pattern == "" {
+			return errors.New("delimiter split needs a delimiter pattern")
+		}
+	case SplitRegex:
+		if _, err := regexp.Compile(m.Pattern); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	case SplitJSON:
+		if _, err := parseJSONPath(m.jsonPath()); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown split mode %q", m.Split)
+	}
+	return nil
+}
+
+func (m *Map) jsonPath() string {
+	if m.Pattern == "" {
+		return "$[*]"
+	}
+	return m.Pattern
+}
+
+// resolveSteps resolves the plugin names of steps in place and normalizes
+// their options, descending into map steps. Directions a plugin cannot run
+// are dropped like in chain files; with strict set they are rejected along
+// with unknown options and invalid option values, as LoadSteps does.
+func resolveSteps(steps []Step, strict bool) error {
+	for i := range steps {
+		if err := resolveStep(i, &steps[i], strict); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveStep(i int, s *Step, strict bool) error {
+	if s.Region != "" {
+		if err := CheckRegion(s.Region); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	if s.Map != nil || s.Plugin == MapPlugin {
+		s.Plugin, s.Unprocess, s.Options = MapPlugin, false, nil
+		if s.Map == nil {
+			return fmt.Errorf("step %d (%s): missing map settings", i+1, MapPlugin)
+		}
+		err := s.Map.check()
+		if err == nil {
+			err = resolveSteps(s.Map.Steps, strict)
+		}
+		if err != nil {
+			return fmt.Errorf("step %d (%s): %w", i+1, MapPlugin, err)
+		}
+		return nil
+	}
+	if s.Plugin == "" && !strict {
+		return nil
+	}
+	plugin, _, ok := plugins.Resolve(s.Plugin)
+	if !ok {
+		return fmt.Errorf("step %d: unknown plugin %q", i+1, s.Plugin)
+	}
+	s.Plugin = plugin.Name
+	s.Options = normalizeStepOptions(s.Plugin, s.Options)
+	if !strict {
+		s.Unprocess = s.Unprocess && plugins.CanDecode(s.Plugin)
+		return nil
+	}
+	if err := checkStepOptions(s); err != nil {
+		return fmt.Errorf("step %d (%s): %w", i+1, s.Plugin, err)
+	}
+	return nil
+}
+
+// checkStepOptions rejects unsupported directions, unknown options and
+// invalid option values of a plugin step.
+func checkStepOptions(s *Step) error {
+	_, fs, err := stepTransform(Limits{}, s)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedOptionNames(s.Options) {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown option %q", name)
+		}
+	}
+	return nil
+}
+
+// AddMapStep appends a map step with a copy of m and returns its index.
+func (p *Pipeline) AddMapStep(m Map) int {
+	p.record()
+	p.steps = append(p.steps, &Step{Plugin: MapPlugin, Map: m.clone()})
+	p.Compute()
+	return len(p.steps) - 1
+}
+
+// SetMap replaces the split settings and nested chain of step i with a copy
+// of m, turning it into a map step if it was not one. This clears manual
+// edits from i onward.
+func (p *Pipeline) SetMap(i int, m Map) {
+	if i < 0 || i >= len(p.steps) {
+		return
+	}
+	p.record()
+	s := p.steps[i]
+	s.Plugin, s.Unprocess, s.Options = MapPlugin, false, nil
+	s.Map = m.clone()
+	s.dirty = true
+	p.clearOverrides(i)
+	p.Compute()
+}
+
+// ParseMapChain parses the nested chain of a map step from a chain expression
+// such as ".base64 | gzip -level 9", as map step editors show it. A blank
+// expression is an empty chain, which leaves every piece unchanged.
+func ParseMapChain(expr string) ([]Step, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	s, err := parseCommandLine(expr)
+	if err != nil {
+		return nil, err
+	}
+	return snapshotSteps(s.Steps), nil
+}
+
+// MapChainString formats the nested chain of a map step as the expression
+// ParseMapChain reads. Disabled steps are left out.
+func MapChainString(steps []Step) string {
+	var parts []string
+	for i := range steps {
+		if steps[i].Disabled || steps[i].Plugin == "" {
+			continue
+		}
+		parts = append(parts, stepCommand(&steps[i]))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// Summary describes m in one line for step cards and chain views, such as
+// "regex token=(\S+): .base64 | .gzip".
+func (m *Map) Summary() string {
+	split := m.Split
+	if m.Pattern != "" {
+		split += " " + m.Pattern
+	}
+	chain := MapChainString(m.Steps)
+	if chain == "" {
+		chain = "(no steps)"
+	}
+	return split + ": " + chain
+}
+
+// mapTransform returns the transform of a map step. Nested steps run within
+// ctx and the limits l, one piece after another.
+func mapTransform(l Limits, m *Map) types.ContextTransformFunc {
+	return func(ctx context.Context, r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		in, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		out, err := m.apply(in, func(piece []byte) ([]byte, error) {
+			return runSteps(ctx, l, m.Steps, piece)
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}
+}
+
+// runSteps runs the enabled steps one after another over in.
+func runSteps(ctx context.Context, l Limits, steps []Step, in []byte) ([]byte, error) {
+	data := in
+	for i := range steps {
+		s := &steps[i]
+		if s.Disabled || s.Plugin == "" {
+			continue
+		}
+		out, err := runStep(ctx, l, s, data)
+		if err != nil {
+			return nil, &StepError{Index: i, Plugin: s.Plugin, Err: err}
+		}
+		data = out
+	}
+	return data, nil
+}
+
+// apply splits in according to m, replaces every non-empty piece with the
+// result of fn and returns the reassembled data.
+func (m *Map) apply(in []byte, fn func([]byte) ([]byte, error)) ([]byte, error) {
+	var spans [][2]int
+	switch m.Split {
+	case SplitLines:
+		spans = lineSpans(in)
+	case SplitDelimiter:
+		if m.Pattern == "" {
+			return nil, errors.New("delimiter split needs a delimiter pattern")
+		}
+		spans = delimiterSpans(in, []byte(unescapePattern(m.Pattern)))
+	case SplitRegex:
+		re, err := regexp.Compile(m.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		spans = regexSpans(in, re)
+	case SplitJSON:
+		path, err := parseJSONPath(m.jsonPath())
+		if err != nil {
+			return nil, err
+		}
+		if spans, err = selectJSON(in, path); err != nil {
+			return nil, err
+		}
+		inner := fn
+		fn = func(raw []byte) ([]byte, error) { return transformJSONValue(raw, inner) }
+	default:
+		return nil, fmt.Errorf("unknown split mode %q", m.Split)
+	}
+
+	return splice(in, spans, func(n int, piece []byte) ([]byte, error) {
+		if len(piece) == 0 {
+			return piece, nil
+		}
+		res, err := fn(piece)
+		if err != nil {
+			return nil, &MapPieceError{Piece: n + 1, Err: err}
+		}
+		return res, nil
+	})
+}
+
+// splice returns in with each of the ordered, non-overlapping spans replaced
+// by the result of fn for its zero-based index and content.
+func splice(in []byte, spans [][2]int, fn func(int, []byte) ([]byte, error)) ([]byte, error) {
+	var out bytes.Buffer
+	out.Grow(len(in))
+	last := 0
+	for n, sp := range spans {
+		out.Write(in[last:sp[0]])
+		last = sp[1]
+		res, err := fn(n, in[sp[0]:sp[1]])
+		if err != nil {
+			return nil, err
+		}
+		out.Write(res)
+	}
+	out.Write(in[last:])
+	return out.Bytes(), nil
+}
+
+// lineSpans returns the lines of in without "\n" or "\r\n" endings.
+func lineSpans(in []byte) [][2]int {
+	var spans [][2]int
+	for start := 0; start < len(in); {
+		end := len(in)
+		next := end
+		if i := bytes.IndexByte(in[start:], '\n'); i >= 0 {
+			end, next = start+i, start+i+1
+		}
+		if end > start && in[end-1] == '\r' && next > end {
+			end--
+		}
+		spans = append(spans, [2]int{start, end})
+		start = next
+	}
+	return spans
+}
+
+func delimiterSpans(in, delim []byte) [][2]int {
+	var spans [][2]int
+	start := 0
+	for {
+		i := bytes.Index(in[start:], delim)
+		if i < 0 {
+			return append(spans, [2]int{start, len(in)})
+		}
+		spans = append(spans, [2]int{start, start + i})
+		start += i + len(delim)
+	}
+}
+
+// regexSpans returns the matches of re in in, or the first capture group of
+// each match when re has one.
+func regexSpans(in []byte, re *regexp.Regexp) [][2]int {
+	group := 0
+	if re.NumSubexp() > 0 {
+		group = 1
+	}
+	var spans [][2]int
+	for _, m := range re.FindAllSubmatchIndex(in, -1) {
+		if m[2*group] < 0 {
+			continue
+		}
+		spans = append(spans, [2]int{m[2*group], m[2*group+1]})
+	}
+	return spans
+}
+
+// unescapePattern interprets Go escape sequences such as \t in a delimiter,
+// falling back to the literal text when it is not a valid quoted string.
+func unescapePattern(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	if u, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return u
+	}
+	return s
+}
+
+// transformJSONValue runs fn on a selected JSON value. Strings are passed
+// without quotes and the result is written back as a string; other values are
+// passed as JSON text and the result is kept as JSON if it is valid JSON.
+func transformJSONValue(raw []byte, fn func([]byte) ([]byte, error)) ([]byte, error) {
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		if s == "" {
+			return raw, nil
+		}
+		out, err := fn([]byte(s))
+		if err != nil {
+			return nil, err
+		}
+		return jsonString(out)
+	}
+	out, err := fn(raw)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(out); json.Valid(trimmed) {
+		return trimmed, nil
+	}
+	return jsonString(out)
+}
+
+func jsonString(b []byte) ([]byte, error) {
+	if !utf8.Valid(b) {
+		return nil, errors.New("result is not valid UTF-8 and cannot be stored in a JSON string")
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(string(b)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonPathSeg is one step of a JSONPath: a member name, an array index or a
+// wildcard, optionally applied at any depth (..).
+type jsonPathSeg struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+	descend  bool
+}
+
+func (s jsonPathSeg) matches(key string, index, n int, inArray bool) bool {
+	switch {
+	case s.wildcard:
+		return true
+	case s.isIndex:
+		i := s.index
+		if i < 0 {
+			i += n
+		}
+		return inArray && i == index
+	default:
+		return !inArray && s.key == key
+	}
+}
+
+// parseJSONPath parses the JSONPath subset map steps support: $, .name, .*,
+// ..name, ..*, [n], [-n], [*] and ['name'].
+func parseJSONPath(path string) ([]jsonPathSeg, error) {
+	bad := func(format string, args ...any) error {
+		return fmt.Errorf("invalid JSONPath %q: %s", path, fmt.Sprintf(format, args...))
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, bad("must start with $")
+	}
+	var segs []jsonPathSeg
+	rest := path[1:]
+	for rest != "" {
+		var seg jsonPathSeg
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			seg.descend = true
+			rest = rest[2:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] == '[':
+		default:
+			return nil, bad("unexpected %q", rest[0])
+		}
+		switch {
+		case rest == "":
+			return nil, bad("missing name after dot")
+		case rest[0] == '*':
+			seg.wildcard = true
+			rest = rest[1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, bad("unterminated [")
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				seg.wildcard = true
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				seg.key = inner[1 : len(inner)-1]
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, bad("unsupported selector [%s]", inner)
+				}
+				seg.index, seg.isIndex = n, true
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			seg.key = rest[:end]
+			rest = rest[end:]
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+// selectJSON returns the byte ranges of the values path selects in doc, in
+// document order. Values nested inside an already selected value are skipped.
+func selectJSON(doc []byte, path []jsonPathSeg) ([][2]int, error) {
+	trimmed := bytes.TrimSpace(doc)
+	if !json.Valid(trimmed) {
+		return nil, errors.New("input is not valid JSON")
+	}
+	off := bytes.Index(doc, trimmed[:1])
+	var spans [][2]int
+	if err := walkJSON(trimmed, off, path, &spans); err != nil {
+		return nil, err
+	}
+	return disjointSpans(spans), nil
+}
+
+// disjointSpans sorts spans by start and drops spans that overlap an earlier
+// one, such as values nested in an already selected value.
+func disjointSpans(spans [][2]int) [][2]int {
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	out := spans[:0]
+	for _, sp := range spans {
+		if len(out) > 0 && sp[0] < out[len(out)-1][1] {
+			continue
+		}
+		out = append(out, sp)
+	}
+	return out
+}
+
+func walkJSON(raw []byte, off int, path []jsonPathSeg, spans *[][2]int) error {
+	if len(path) == 0 {
+		*spans = append(*spans, [2]int{off, off + len(raw)})
+		return nil
+	}
+	seg := path[0]
+	if raw[0] != '{' && raw[0] != '[' {
+		return nil
+	}
+	inArray := raw[0] == '['
+	n := 0
+	if inArray && seg.isIndex && seg.index < 0 {
+		if err := eachJSONChild(raw, off, func(string, int, []byte, int) error { n++; return nil }); err != nil {
+			return err
+		}
+	}
+	return eachJSONChild(raw, off, func(key string, index int, child []byte, childOff int) error {
+		if seg.matches(key, index, n, inArray) {
+			if err := walkJSON(child, childOff, path[1:], spans); err != nil {
+				return err
+			}
+		}
+		if seg.descend {
+			return walkJSON(child, childOff, path, spans)
+		}
+		return nil
+	})
+}
+
+// eachJSONChild calls fn for every member or element of the object or array
+// raw, which starts at offset off of the document, with the child's offset.
+func eachJSONChild(raw []byte, off int, fn func(key string, index int, child []byte, childOff int) error) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, _ := tok.(json.Delim)
+	for i := 0; dec.More(); i++ {
+		var key string
+		if delim == '{' {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ = tok.(string)
+		}
+		var child json.RawMessage
+		if err := dec.Decode(&child); err != nil {
+			return err
+		}
+		end := int(dec.InputOffset())
+		if err := fn(key, i, child, off+end-len(child)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
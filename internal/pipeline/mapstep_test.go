@@ -0,0 +1,201 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func mapStep(t *testing.T, split, pattern, chain string) Step {
+	t.Helper()
+	steps, err := ParseMapChain(chain)
+	if err != nil {
+		t.Fatalf("ParseMapChain(%q): %v", chain, err)
+	}
+	return Step{Plugin: MapPlugin, Map: &Map{Split: split, Pattern: pattern, Steps: steps}}
+}
+
+func TestMapStepSplits(t *testing.T) {
+	tests := []struct {
+		name    string
+		split   string
+		pattern string
+		in      string
+		want    string
+	}{
+		{"lines", SplitLines, "", "aGVsbG8=\r\n\nd29ybGQ=", "hello\r\n\nworld"},
+		{"lines trailing newline", SplitLines, "", "aGk=\nYnll\n", "hi\nbye\n"},
+		{"delimiter", SplitDelimiter, ",", "aGk=,,Ynll", "hi,,bye"},
+		{"delimiter escape", SplitDelimiter, `\t`, "aGk=\tYnll", "hi\tbye"},
+		{"regex match", SplitRegex, `[A-Za-z0-9+/]{3,}=*`, "a=aGk= b=Ynll", "a=hi b=bye"},
+		{"regex group", SplitRegex, `token=(\S+)`, "token=aGk= x token=Ynll", "token=hi x token=bye"},
+		{"json array", SplitJSON, "", `["aGk=", "", "Ynll"]`, `["hi", "", "bye"]`},
+		{"json path", SplitJSON, "$.items[*].v", `{"items": [{"v": "aGk="}, {"w": "x"}, {"v": "Ynll"}], "v": "keep"}`, `{"items": [{"v": "hi"}, {"w": "x"}, {"v": "bye"}], "v": "keep"}`},
+		{"json descend", SplitJSON, "$..v", `{"v": "aGk=", "a": [{"v": "Ynll"}]}`, `{"v": "hi", "a": [{"v": "bye"}]}`},
+		{"json index", SplitJSON, "$[-1]", `["aGk=", "Ynll"]`, `["aGk=", "bye"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New()
+			if err := p.LoadSteps([]Step{mapStep(t, tt.split, tt.pattern, ".base64")}); err != nil {
+				t.Fatalf("LoadSteps: %v", err)
+			}
+			p.SetSource([]byte(tt.in))
+			if err := p.Err(0); err != nil {
+				t.Fatalf("Err = %v", err)
+			}
+			if got := string(p.Result()); got != tt.want {
+				t.Fatalf("Result = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapStepJSONKeepsNonStringResultsAsJSON(t *testing.T) {
+	p := New()
+	p.SetSource([]byte(`{"n": 12, "s": "abc"}`))
+	p.AddMapStep(Map{Split: SplitJSON, Pattern: "$.*", Steps: []Step{{Plugin: "hex"}}})
+	if err := p.Err(0); err != nil {
+		t.Fatalf("Err = %v", err)
+	}
+	// 12 hex-encodes to "3132", which is a JSON number again; "abc" stays a string.
+	if got, want := string(p.Result()), `{"n": 3132, "s": "616263"}`; got != want {
+		t.Fatalf("Result = %q, want %q", got, want)
+	}
+}
+
+func TestMapStepReportsFailingPiece(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("aGk=\n!!!\n"))
+	p.AddMapStep(Map{Split: SplitLines, Steps: []Step{{Plugin: "base64", Unprocess: true}}})
+	var pieceErr *MapPieceError
+	if err := p.Err(0); !errors.As(err, &pieceErr) || pieceErr.Piece != 2 {
+		t.Fatalf("Err = %v, want failure of piece 2", err)
+	}
+}
+
+func TestMapStepStreamTraceAndCache(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("aGk=\nYnll\n"))
+	p.AddMapStep(Map{Split: SplitLines, Steps: []Step{{Plugin: "base64", Unprocess: true}, {Plugin: "hex"}}})
+	p.AddStep("base64", false)
+	want := p.Result()
+
+	var buf bytes.Buffer
+	if err := p.Stream(context.Background(), &buf); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("Stream = %q, Compute = %q", buf.Bytes(), want)
+	}
+	buf.Reset()
+	var plugins []string
+	err := p.Trace(context.Background(), &buf, func(st StepTrace, _ []byte) error {
+		plugins = append(plugins, st.Plugin)
+		return nil
+	})
+	if err != nil || !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("Trace = %q, %v", buf.Bytes(), err)
+	}
+	if strings.Join(plugins, ",") != "map,base64" {
+		t.Fatalf("traced plugins = %v", plugins)
+	}
+
+	// Changing the nested chain must not hit the cache entry of the old one.
+	m := *p.Steps()[0].Map
+	m.Steps = []Step{{Plugin: "base64", Unprocess: true}}
+	p.SetMap(0, m)
+	if got := string(p.Output(0)); got != "hi\nbye\n" {
+		t.Fatalf("Output(0) after SetMap = %q", got)
+	}
+}
+
+func TestMapStepChainJSONRoundTrip(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("a,b"))
+	p.AddMapStep(Map{Split: SplitDelimiter, Pattern: ",", Steps: []Step{
+		{Plugin: "hex"},
+		{Plugin: MapPlugin, Map: &Map{Split: SplitRegex, Pattern: "6", Steps: []Step{{Plugin: "base64"}}}},
+	}})
+	want := string(p.Result())
+	data, err := p.ExportJSON()
+	if err != nil {
+		t.Fatalf("ExportJSON: %v", err)
+	}
+	if !strings.Contains(string(data), `"split": "regex"`) {
+		t.Fatalf("nested map missing from chain JSON:\n%s", data)
+	}
+
+	q := New()
+	if err := q.ImportJSON(data); err != nil {
+		t.Fatalf("ImportJSON: %v", err)
+	}
+	if got := string(q.Result()); got != want {
+		t.Fatalf("imported Result = %q, want %q", got, want)
+	}
+
+	q.SetMap(0, Map{Split: SplitLines})
+	if !q.Undo() || q.Steps()[0].Map.Split != SplitDelimiter || len(q.Steps()[0].Map.Steps) != 2 {
+		t.Fatalf("Undo did not restore the map settings: %+v", q.Steps()[0].Map)
+	}
+}
+
+func TestMapStepCommandLineRoundTrip(t *testing.T) {
+	p := New()
+	if err := p.LoadCommandLine(`.url | map -split regex -pattern 'v=(\S+)' '.base64 | map -split delimiter -pattern : hex' | json`); err != nil {
+		t.Fatalf("LoadCommandLine: %v", err)
+	}
+	m := p.Steps()[1].Map
+	if m == nil || m.Split != SplitRegex || len(m.Steps) != 2 || m.Steps[1].Map == nil || m.Steps[1].Map.Pattern != ":" {
+		t.Fatalf("parsed map step = %+v", m)
+	}
+	cmd := p.CommandLine()
+	q := New()
+	if err := q.LoadCommandLine(cmd); err != nil {
+		t.Fatalf("LoadCommandLine(%q): %v", cmd, err)
+	}
+	if got := q.CommandLine(); got != cmd {
+		t.Fatalf("CommandLine round trip = %q, want %q", got, cmd)
+	}
+}
+
+func TestMapStepValidation(t *testing.T) {
+	for _, tt := range []struct {
+		steps []Step
+		want  string
+	}{
+		{[]Step{{Plugin: MapPlugin}}, "missing map settings"},
+		{[]Step{{Map: &Map{Split: "words"}}}, `unknown split mode "words"`},
+		{[]Step{{Map: &Map{Split: SplitRegex, Pattern: "("}}}, "invalid regex"},
+		{[]Step{{Map: &Map{Split: SplitJSON, Pattern: "items"}}}, "must start with $"},
+		{[]Step{{Map: &Map{Split: SplitLines, Steps: []Step{{Plugin: "base64", Options: map[string]string{"nope": "1"}}}}}}, `step 1 (map): step 1 (base64): unknown option "nope"`},
+	} {
+		if err := New().LoadSteps(tt.steps); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadSteps(%+v) = %v, want %q", tt.steps, err, tt.want)
+		}
+	}
+}
+
+func TestMapStepBindsNestedParams(t *testing.T) {
+	p := New()
+	data := []byte(`{"version": 1, "params": [{"name": "alg"}], "steps": [
+		{"plugin": "map", "map": {"split": "lines", "steps": [{"plugin": "hmac", "options": {"key": "k", "alg": "${alg}"}}]}}]}`)
+	if err := p.LoadJSON(data); err != nil {
+		t.Fatalf("LoadJSON: %v", err)
+	}
+	if refs := p.ParamRefs(0); len(refs) != 1 || refs[0] != "alg" {
+		t.Fatalf("ParamRefs = %v", refs)
+	}
+	if err := p.SetParam("alg", "sha256"); err != nil {
+		t.Fatalf("SetParam: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := p.StreamFrom(context.Background(), strings.NewReader("a\nb"), &buf); err != nil {
+		t.Fatalf("StreamFrom: %v", err)
+	}
+	if lines := strings.Split(buf.String(), "\n"); len(lines) != 2 || len(lines[0]) != 64 {
+		t.Fatalf("StreamFrom = %q, want two SHA-256 HMACs", buf.String())
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"strings"
 
 	"github.com/takeshixx/deen/internal/plugins"
+	"github.com/takeshixx/deen/pkg/types"
 )
 
-// Option describes a single configurable plugin flag for UI rendering.
+// Option describes a single configurable plugin flag for UI rendering. It is
+// built from the flag registered by the plugin and its types.OptionSpec.
 type Option struct {
 	Name        string
 	Label       string
@@ -18,15 +20,25 @@ type Option struct {
 	IsBool      bool
 	Kind        string
 	Choices     []string
+	Open        bool // Choices are suggestions; other values are accepted
+	Range       *types.OptionRange
 	Secret      bool
 	Multiline   bool
 	HelpLabel   string
 	HelpURL     string
+
+	spec types.OptionSpec
+}
+
+// Check reports whether value is acceptable for the option, so editors can
+// flag invalid input before the step runs.
+func (o Option) Check(value string) error {
+	return o.spec.Check(value)
 }
 
 // PluginOptions returns the configurable options (flags) of a plugin, or nil if
 // it has none. Bool flags are reported with IsBool so the UI can render a
-// checkbox instead of a text entry.
+// checkbox instead of a text entry. Options marked hidden are omitted.
 func PluginOptions(name string) []Option {
 	p, _, ok := plugins.Resolve(name)
 	if !ok || p.RegisterFlags == nil {
@@ -37,315 +49,44 @@ func PluginOptions(name string) []Option {
 
 	var opts []Option
 	fs.VisitAll(func(f *flag.Flag) {
-		if p.Name == "aes" && f.Name == "nonce" {
+		spec, _ := p.OptionSpec(f.Name)
+		if spec.Hidden {
 			return
 		}
+		spec.Name = f.Name
 		_, isBool := f.Value.(interface{ IsBoolFlag() bool })
-		kind := optionKind(p.Name, f.Name, f.DefValue, isBool)
+		if spec.Type == "" {
+			spec.Type = optionType(spec, f.DefValue, isBool)
+		}
+		label := spec.Label
+		if label == "" {
+			label = prettyOptionLabel(f.Name)
+		}
+		description := spec.Description
+		if description == "" {
+			description = f.Usage
+		}
 		opts = append(opts, Option{
 			Name:        f.Name,
-			Label:       optionLabel(p.Name, f.Name),
+			Label:       label,
 			Usage:       f.Usage,
-			Description: optionDescription(p.Name, f.Name, f.Usage),
+			Description: description,
 			Default:     f.DefValue,
 			IsBool:      isBool,
-			Kind:        kind,
-			Choices:     optionChoices(p.Name, f.Name),
-			Secret:      isSecretOption(p.Name, f.Name),
-			Multiline:   optionMultiline(p.Name, f.Name),
-			HelpLabel:   optionHelpLabel(p.Name, f.Name),
-			HelpURL:     optionHelpURL(p.Name, f.Name),
+			Kind:        optionKind(spec),
+			Choices:     spec.Choices,
+			Open:        spec.Open,
+			Range:       spec.Range,
+			Secret:      spec.Secret,
+			Multiline:   spec.Multiline,
+			HelpLabel:   spec.HelpLabel,
+			HelpURL:     spec.HelpURL,
+			spec:        spec,
 		})
 	})
 	return opts
 }
 
-func optionLabel(plugin, name string) string {
-	switch plugin + ":" + name {
-	case "base32:no-pad":
-		return "No padding"
-	case "base64:url":
-		return "URL-safe alphabet"
-	case "base64:raw":
-		return "Raw output"
-	case "base64:strict":
-		return "Strict decoding"
-	case "base32:hex":
-		return "Extended hex alphabet"
-	case "blake3:derive-key":
-		return "Derive key"
-	case "brotli:lgwin":
-		return "Window size"
-	case "certCloner:ca-cert":
-		return "CA certificate file"
-	case "certCloner:ca-key":
-		return "CA private key file"
-	case "certCloner:o":
-		return "Output prefix"
-	case "certCloner:sig-alg":
-		return "Signature algorithm"
-	case "csv:in":
-		return "Input format"
-	case "csv:out":
-		return "Output format"
-	case "jq:no-color", "json:no-color":
-		return "No color"
-	case "jq:q":
-		return "Query"
-	case "jwt:decrypt":
-		return "Decrypt JWE"
-	case "jwt:enc-alg":
-		return "Encryption algorithm"
-	case "jwt:enc-keyfile":
-		return "Encryption key file"
-	case "jwt:enc-secret":
-		return "Encryption secret"
-	case "jwt:header":
-		return "Token header"
-	case "jwt:key":
-		return "Verification key file"
-	case "jwt:key-alg":
-		return "Key management algorithm"
-	case "jwt:list":
-		return "List algorithms"
-	case "jwt:r":
-		return "Recreate token, keep signature"
-	case "jwt:secret":
-		return "Verification secret"
-	case "jwt:sign-alg":
-		return "Signing algorithm"
-	case "jwt:sign-keyfile":
-		return "Signing key file"
-	case "jwt:sign-secret":
-		return "Signing secret"
-	case "jwt:verify":
-		return "Verify signature"
-	case "lzw:lit-width":
-		return "Literal width"
-	case "pem:cert":
-		return "Certificate"
-	case "pem:type":
-		return "PEM type"
-	case "qr:size":
-		return "Image size"
-	case "regex:re":
-		return "Regular expression"
-	case "saml:url":
-		return "URL encoding"
-	case "scrypt:len":
-		return "Output length"
-	case "scrypt:p":
-		return "Parallelization"
-	case "scrypt:r":
-		return "Block size"
-	case "sign:pub":
-		return "Public key"
-	case "sign:sig":
-		return "Signature"
-	case "strconv:ctrl":
-		return "Control characters only"
-	case "timestamp:utc":
-		return "UTC output"
-	case "uuid:gen":
-		return "Generate UUID"
-	case "unicode:big":
-		return "Big endian"
-	case "unicode:bom":
-		return "BOM handling"
-	case "unicode:encoding":
-		return "Text encoding"
-	case "unicode-normalize:form":
-		return "Normalization form"
-	case "aes:iv":
-		return "Nonce / IV"
-	case "aes:skip-aead-verify":
-		return "Skip AEAD verify"
-	case "ascii:mode":
-		return "Non-ASCII handling"
-	case "add:value":
-		return "Add value"
-	case "sub:value":
-		return "Subtract value"
-	case "xor:value":
-		return "XOR value"
-	default:
-		return prettyOptionLabel(name)
-	}
-}
-
-func optionDescription(plugin, name, usage string) string {
-	switch plugin + ":" + name {
-	case "add:value", "sub:value", "xor:value":
-		return "Byte value as decimal, hex such as 0x2a, or a single character."
-	case "aes:aad", "chacha20poly1305:aad":
-		return "Additional authenticated data required for GCM or AEAD verification."
-	case "aes:iv":
-		return "Nonce or initialization vector as hex or Base64. GCM expects 12 bytes; CBC and CTR expect 16 bytes."
-	case "aes:key":
-		return "AES key as hex or Base64. Must be 16, 24, or 32 bytes."
-	case "aes:mode":
-		return "AES mode to use: GCM, CBC, or CTR."
-	case "aes:nonce":
-		return "Alias for nonce or initialization vector as hex or Base64."
-	case "aes:padding":
-		return "CBC padding mode: PKCS#7 compatible padding or no padding for block-aligned data."
-	case "aes:skip-aead-verify":
-		return "Dangerous GCM decrypt mode: output unauthenticated plaintext even when tag verification fails."
-	case "aes:tag-len":
-		return "GCM authentication tag length in bytes. Must be between 12 and 16."
-	case "ascii:mode":
-		return "How to handle non-ASCII bytes."
-	case "base32:hex":
-		return "Use the RFC 4648 extended hex alphabet."
-	case "base32:no-pad":
-		return "Omit Base32 padding characters."
-	case "base64:raw":
-		return "Encode without Base64 padding."
-	case "base64:strict":
-		return "Decode only standard Base64."
-	case "base64:url":
-		return "Use the URL-safe Base64 alphabet."
-	case "bcrypt:cost":
-		return "bcrypt work factor."
-	case "blake2b:key", "blake2s:key", "blake2x:key", "blake3:key", "hmac:key":
-		return "Key for MAC or keyed hashing."
-	case "blake2b:len", "blake2s:len", "blake2x:len", "blake3:length":
-		return "Number of digest bytes to output."
-	case "blake3:context":
-		return "Context string for BLAKE3 key derivation."
-	case "blake3:derive-key":
-		return "Key material for BLAKE3 key derivation."
-	case "brotli:level", "bzip2:level", "flate:level", "gzip:level", "zlib:level":
-		return "Compression level."
-	case "brotli:lgwin":
-		return "Brotli sliding window size."
-	case "certCloner:ca-cert":
-		return "Path to a PEM CA certificate file. The file may also contain the CA private key."
-	case "certCloner:ca-key":
-		return "Path to a PEM CA private key file, if it is not bundled with the CA certificate."
-	case "certCloner:o":
-		return "Write cloned certificate files using this path prefix."
-	case "certCloner:sig-alg":
-		return "Signature hash for the cloned certificate."
-	case "chacha20poly1305:key":
-		return "ChaCha20-Poly1305 key as hex or Base64. Must be 32 bytes."
-	case "chacha20poly1305:nonce":
-		return "ChaCha20-Poly1305 nonce as hex or Base64. Must be 12 bytes."
-	case "csv:in":
-		return "Input delimiter or format."
-	case "csv:out":
-		return "Output delimiter or format."
-	case "hmac:alg":
-		return "Hash algorithm for HMAC."
-	case "jq:no-color", "json:no-color":
-		return "Disable ANSI color in formatted output."
-	case "jq:q":
-		return "jq filter expression to run against the JSON input."
-	case "jq:plain":
-		return "Print compact JSON instead of formatted output."
-	case "jwk:public":
-		return "Output public JWK material when possible."
-	case "jwk:thumbprint":
-		return "Output RFC 7638 SHA-256 thumbprints."
-	case "jwt:decrypt":
-		return "Decrypt a JWE token."
-	case "jwt:enc-alg":
-		return "Content encryption algorithm for creating JWE tokens."
-	case "jwt:enc-keyfile":
-		return "Encryption key file for creating JWE tokens."
-	case "jwt:enc-secret":
-		return "Encryption secret for creating JWE tokens."
-	case "jwt:header":
-		return "JSON header to use when creating a token."
-	case "jwt:key":
-		return "Public key file used when verifying a token."
-	case "jwt:key-alg":
-		return "Key management algorithm for creating JWE tokens."
-	case "jwt:list":
-		return "Show supported JWT algorithms."
-	case "jwt:r":
-		return "Recreate the token with modified JSON while keeping the original signature."
-	case "jwt:secret":
-		return "Secret used when verifying a token."
-	case "jwt:sign-alg":
-		return "Signature algorithm for creating JWS tokens."
-	case "jwt:sign-keyfile":
-		return "Private key file used to sign a token."
-	case "jwt:sign-secret":
-		return "Shared secret used to sign a token."
-	case "jwt:verify":
-		return "Verify the token signature while decoding."
-	case "lzw:lit-width":
-		return "Number of bits used for literal codes."
-	case "lzw:order":
-		return "Bit order for LZW data."
-	case "pem:cert":
-		return "Create PEM output from certificate bytes."
-	case "pem:headers":
-		return "PEM headers as a JSON object."
-	case "pem:type":
-		return "PEM block type."
-	case "qr:size":
-		return "Generated QR image size in pixels."
-	case "regex:all":
-		return "Return all matches instead of the first match."
-	case "regex:group":
-		return "Capture group to extract."
-	case "regex:re":
-		return "Regular expression to match."
-	case "regex:replace":
-		return "Replacement text. When set, matching text is replaced."
-	case "saml:deflate":
-		return "Use raw DEFLATE compression for SAML redirect payloads."
-	case "saml:plain":
-		return "Skip DEFLATE detection when decoding."
-	case "saml:url":
-		return "URL-escape encoded output or URL-unescape input."
-	case "scrypt:cost":
-		return "CPU and memory cost parameter."
-	case "scrypt:len":
-		return "Number of key bytes to output."
-	case "scrypt:p":
-		return "scrypt parallelization parameter."
-	case "scrypt:r":
-		return "scrypt block size parameter."
-	case "scrypt:salt":
-		return "Salt as a hex string."
-	case "sign:alg":
-		return "Signature algorithm."
-	case "sign:key":
-		return "Private key for signing. Accepts PEM path or hex/Base64 Ed25519 key material."
-	case "sign:pub":
-		return "Public key for verification. Accepts PEM path or hex/Base64 Ed25519 key material."
-	case "sign:sig":
-		return "Signature to verify, as hex, Base64, or a file path."
-	case "strconv:ctrl":
-		return "Escape only control characters."
-	case "strconv:graph":
-		return "Escape printable characters using Go graph escapes."
-	case "timestamp:layout":
-		return "Go time layout for formatting or parsing time strings."
-	case "timestamp:unit":
-		return "Timestamp unit."
-	case "timestamp:utc":
-		return "Format parsed times in UTC."
-	case "unicode-normalize:form":
-		return "Unicode normalization form."
-	case "unicode:big":
-		return "Use big-endian byte order."
-	case "unicode:bom":
-		return "How to handle byte order marks."
-	case "unicode:encoding":
-		return "Text encoding for conversion."
-	case "uuid:gen":
-		return "Generate a random UUID v4."
-	case "uuid:info":
-		return "Show UUID version, variant, and bytes."
-	default:
-		return usage
-	}
-}
-
 func prettyOptionLabel(name string) string {
 	parts := strings.FieldsFunc(name, func(r rune) bool {
 		return r == '-' || r == '_'
@@ -402,118 +143,33 @@ func prettyOptionPart(part string) string {
 	}
 }
 
-func optionMultiline(plugin, name string) bool {
-	switch plugin + ":" + name {
-	case "jq:q", "jwt:header", "pem:headers":
-		return true
-	default:
-		return false
-	}
-}
-
-func optionHelpLabel(plugin, name string) string {
-	switch plugin + ":" + name {
-	case "jq:q":
-		return "jq syntax"
-	default:
-		return ""
-	}
-}
-
-func optionHelpURL(plugin, name string) string {
-	switch plugin + ":" + name {
-	case "jq:q":
-		return "https://jqlang.github.io/jq/manual/"
+// optionType infers the value type of a flag whose spec does not declare one.
+func optionType(spec types.OptionSpec, def string, isBool bool) types.OptionType {
+	switch {
+	case isBool:
+		return types.OptionBool
+	case len(spec.Choices) > 0 && !spec.Open:
+		return types.OptionSelect
+	case spec.Range != nil || isNumberDefault(def):
+		return types.OptionNumber
 	default:
-		return ""
+		return types.OptionText
 	}
 }
 
-func optionKind(plugin, name, def string, isBool bool) string {
+// optionKind maps an option type to the editor the UIs render. Secret text
+// options get a masked entry.
+func optionKind(spec types.OptionSpec) string {
 	switch {
-	case isBool:
+	case spec.Type == types.OptionBool:
 		return "bool"
-	case len(optionChoices(plugin, name)) > 0:
+	case len(spec.Choices) > 0 && !spec.Open:
 		return "select"
-	case isSecretOption(plugin, name):
+	case spec.Secret:
 		return "secret"
-	case isNumberDefault(def):
-		return "number"
-	default:
-		return "text"
-	}
-}
-
-func optionChoices(plugin, name string) []string {
-	switch plugin + ":" + name {
-	case "timestamp:unit":
-		return []string{"auto", "s", "ms", "us", "ns"}
-	case "unicode:bom":
-		return []string{"ignore", "use", "expect"}
-	case "unicode:encoding":
-		return []string{
-			"utf8",
-			"utf16le",
-			"utf16be",
-			"utf32le",
-			"utf32be",
-			"latin1",
-			"windows1252",
-			"shiftjis",
-			"eucjp",
-			"gbk",
-			"gb18030",
-			"big5",
-			"euckr",
-			"koi8r",
-		}
-	case "unicode-normalize:form":
-		return []string{"nfc", "nfd", "nfkc", "nfkd"}
-	case "ascii:mode":
-		return []string{"strict", "replace", "strip", "escape"}
-	case "hmac:alg":
-		return []string{"md5", "sha1", "sha224", "sha256", "sha384", "sha512", "sha3-256", "sha3-512"}
-	case "lzw:order":
-		return []string{"0", "1"}
-	case "lzw:lit-width":
-		return []string{"2", "3", "4", "5", "6", "7", "8"}
-	case "csv:in":
-		return []string{"csv", "tsv", "semicolon"}
-	case "csv:out":
-		return []string{"table", "csv", "tsv", "semicolon"}
-	case "aes:mode":
-		return []string{"gcm", "cbc", "ctr"}
-	case "aes:padding":
-		return []string{"pkcs7", "none"}
-	case "aes:tag-len":
-		return []string{"16", "15", "14", "13", "12"}
-	case "sign:alg":
-		return []string{"ed25519", "rsa-pss", "ecdsa"}
-	case "certCloner:sig-alg":
-		return []string{"", "sha256", "sha384", "sha512"}
-	case "jwt:sign-alg":
-		return []string{"", "EdDSA", "HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "PS384", "PS512"}
-	case "jwt:enc-alg":
-		return []string{"", "A128CBC-HS256", "A192CBC-HS384", "A256CBC-HS512", "A128GCM", "A192GCM", "A256GCM"}
-	case "jwt:key-alg":
-		return []string{"", "dir", "RSA1_5", "RSA-OAEP", "RSA-OAEP-256", "A128KW", "A192KW", "A256KW", "ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A192KW", "ECDH-ES+A256KW", "A128GCMKW", "A192GCMKW", "A256GCMKW", "PBES2-HS256+A128KW", "PBES2-HS384+A192KW", "PBES2-HS512+A256KW"}
 	default:
-		return nil
-	}
-}
-
-func isSecretOption(plugin, name string) bool {
-	switch plugin + ":" + name {
-	case "jwt:key", "sign:pub", "scrypt:salt":
-		return false
-	case "certCloner:ca-key", "jwt:enc-keyfile", "jwt:sign-keyfile":
-		return true
+		return string(spec.Type)
 	}
-	name = strings.ToLower(name)
-	return strings.Contains(name, "key") ||
-		strings.Contains(name, "secret") ||
-		strings.Contains(name, "pass") ||
-		strings.Contains(name, "token")
 }
 
 func isNumberDefault(def string) bool {
@@ -0,0 +1,345 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Param declares a named chain parameter. Step options reference it as
+// ${name}; the value is bound when the chain runs, so one chain file can be
+// reused with different keys, secrets or filters.
+type Param struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"` // "text" (default), "number" or "bool"
+	Default     string `json:"default,omitempty"`
+	Secret      bool   `json:"secret,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Parameter types.
+const (
+	ParamText   = "text"
+	ParamNumber = "number"
+	ParamBool   = "bool"
+)
+
+// ParamEnvPrefix prefixes the environment variables that supply parameter
+// values, e.g. DEEN_PARAM_KEY for a parameter named "key".
+const ParamEnvPrefix = "DEEN_PARAM_"
+
+var (
+	paramNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	paramRefRe  = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// Check reports whether value is acceptable for the parameter type.
+func (pr Param) Check(value string) error {
+	switch pr.Type {
+	case ParamNumber:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("must be a number")
+		}
+	case ParamBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+	}
+	return nil
+}
+
+// EnvName returns the environment variable that supplies the parameter.
+func (pr Param) EnvName() string {
+	return ParamEnvPrefix + strings.ToUpper(pr.Name)
+}
+
+// paramState is the parameter part of a pipeline snapshot.
+type paramState struct {
+	decls  []Param
+	values map[string]string // bound values by name
+}
+
+func (ps paramState) clone() paramState {
+	c := paramState{decls: append([]Param(nil), ps.decls...)}
+	if len(ps.values) > 0 {
+		c.values = make(map[string]string, len(ps.values))
+		for k, v := range ps.values {
+			c.values[k] = v
+		}
+	}
+	return c
+}
+
+func (ps paramState) lookup(name string) (Param, bool) {
+	for _, pr := range ps.decls {
+		if pr.Name == name {
+			return pr, true
+		}
+	}
+	return Param{}, false
+}
+
+// value returns the bound value of a parameter, or its default.
+func (ps paramState) value(pr Param) (string, bool) {
+	if v, ok := ps.values[pr.Name]; ok {
+		return v, true
+	}
+	return pr.Default, pr.Default != ""
+}
+
+func checkParams(decls []Param) error {
+	seen := map[string]bool{}
+	for _, pr := range decls {
+		if !paramNameRe.MatchString(pr.Name) {
+			return fmt.Errorf("invalid parameter name %q", pr.Name)
+		}
+		if seen[pr.Name] {
+			return fmt.Errorf("duplicate parameter %q", pr.Name)
+		}
+		seen[pr.Name] = true
+		switch pr.Type {
+		case "", ParamText, ParamNumber, ParamBool:
+		default:
+			return fmt.Errorf("parameter %s: unknown type %q", pr.Name, pr.Type)
+		}
+		if pr.Default != "" {
+			if err := pr.Check(pr.Default); err != nil {
+				return fmt.Errorf("parameter %s: default %w", pr.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Params returns the parameters declared by the chain.
+func (p *Pipeline) Params() []Param {
+	return append([]Param(nil), p.params.decls...)
+}
+
+// ParamValue returns the value a parameter runs with: the bound value, or the
+// default. ok is false when the parameter has neither.
+func (p *Pipeline) ParamValue(name string) (value string, ok bool) {
+	pr, declared := p.params.lookup(name)
+	if !declared {
+		return "", false
+	}
+	return p.params.value(pr)
+}
+
+// SetParam binds a value to a declared parameter and recomputes the steps that
+// reference it. Like SetOption, it clears manual edits from the first of those
+// steps onward.
+func (p *Pipeline) SetParam(name, value string) error {
+	return p.SetParams(map[string]string{name: value})
+}
+
+// SetParams binds several parameter values as one undoable change. Nothing is
+// bound when one of the values is invalid.
+func (p *Pipeline) SetParams(values map[string]string) error {
+	for _, name := range sortedOptionNames(values) {
+		if err := p.checkParam(name, values[name]); err != nil {
+			return err
+		}
+	}
+	p.record()
+	for name, value := range values {
+		p.bindParam(name, value)
+	}
+	for i := range p.steps {
+		if slices.ContainsFunc(p.ParamRefs(i), func(name string) bool { _, ok := values[name]; return ok }) {
+			p.clearOverrides(i)
+			break
+		}
+	}
+	p.Compute()
+	return nil
+}
+
+func (p *Pipeline) checkParam(name, value string) error {
+	pr, ok := p.params.lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown parameter %q", name)
+	}
+	if err := pr.Check(value); err != nil {
+		return fmt.Errorf("parameter %s: %w", name, err)
+	}
+	return nil
+}
+
+func (p *Pipeline) bindParam(name, value string) {
+	if p.params.values == nil {
+		p.params.values = map[string]string{}
+	}
+	p.params.values[name] = value
+}
+
+// BindParams binds parameter values for a headless run: values given
+// explicitly as name=value pairs take precedence over DEEN_PARAM_<NAME>
+// environment variables, which take precedence over defaults. Naming an
+// undeclared parameter is an error.
+func (p *Pipeline) BindParams(pairs []string) error {
+	explicit := map[string]string{}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("parameter %q: want name=value", pair)
+		}
+		if _, declared := p.params.lookup(name); !declared {
+			return fmt.Errorf("unknown parameter %q", name)
+		}
+		explicit[name] = value
+	}
+	for _, pr := range p.params.decls {
+		value, ok := explicit[pr.Name]
+		if !ok {
+			value, ok = os.LookupEnv(pr.EnvName())
+		}
+		if !ok {
+			continue
+		}
+		if err := p.checkParam(pr.Name, value); err != nil {
+			return err
+		}
+		p.bindParam(pr.Name, value)
+	}
+	return nil
+}
+
+// BindParamValues binds values for a headless run, like BindParams but without
+// consulting the environment. Nothing is bound when one of the values is
+// invalid.
+func (p *Pipeline) BindParamValues(values map[string]string) error {
+	for _, name := range sortedOptionNames(values) {
+		if err := p.checkParam(name, values[name]); err != nil {
+			return err
+		}
+	}
+	for name, value := range values {
+		p.bindParam(name, value)
+	}
+	return nil
+}
+
+// MissingParams returns the declared parameters that have neither a bound
+// value nor a default.
+func (p *Pipeline) MissingParams() []Param {
+	var missing []Param
+	for _, pr := range p.params.decls {
+		if _, ok := p.params.value(pr); !ok {
+			missing = append(missing, pr)
+		}
+	}
+	return missing
+}
+
+// HasParamRef reports whether an option value references a parameter as
+// ${name}.
+func HasParamRef(value string) bool { return paramRefRe.MatchString(value) }
+
+// ParamRefs returns the names of the declared parameters referenced by the
+// options of step i, including the nested steps of a map step.
+func (p *Pipeline) ParamRefs(i int) []string {
+	if i < 0 || i >= len(p.steps) {
+		return nil
+	}
+	var names []string
+	var visit func(s *Step)
+	visit = func(s *Step) {
+		for _, name := range sortedOptionNames(s.Options) {
+			for _, m := range paramRefRe.FindAllStringSubmatch(s.Options[name], -1) {
+				if _, ok := p.params.lookup(m[1]); ok && !slices.Contains(names, m[1]) {
+					names = append(names, m[1])
+				}
+			}
+		}
+		if s.Map != nil {
+			for j := range s.Map.Steps {
+				visit(&s.Map.Steps[j])
+			}
+		}
+	}
+	visit(p.steps[i])
+	return names
+}
+
+// bindStep returns s with ${name} references to declared parameters replaced
+// by their values, in the nested steps of a map step too. References to
+// undeclared names are left as they are, so chains without parameters run
+// unchanged. s itself is returned when nothing needs replacing.
+func (p *Pipeline) bindStep(s *Step) (*Step, error) {
+	if len(p.params.decls) == 0 {
+		return s, nil
+	}
+	opts, err := p.bindOptions(s.Options)
+	if err != nil {
+		return nil, err
+	}
+	var m *Map
+	if s.Map != nil {
+		for i := range s.Map.Steps {
+			bound, err := p.bindStep(&s.Map.Steps[i])
+			if err != nil {
+				return nil, err
+			}
+			if bound == &s.Map.Steps[i] {
+				continue
+			}
+			if m == nil {
+				m = &Map{Split: s.Map.Split, Pattern: s.Map.Pattern, Steps: slices.Clone(s.Map.Steps)}
+			}
+			m.Steps[i] = *bound
+		}
+	}
+	if opts == nil && m == nil {
+		return s, nil
+	}
+	bound := *s
+	if opts != nil {
+		bound.Options = opts
+	}
+	if m != nil {
+		bound.Map = m
+	}
+	return &bound, nil
+}
+
+// bindOptions returns a copy of options with parameter references replaced,
+// or nil when none of the values reference a declared parameter.
+func (p *Pipeline) bindOptions(options map[string]string) (map[string]string, error) {
+	var opts map[string]string
+	for _, name := range sortedOptionNames(options) {
+		value := options[name]
+		if !strings.Contains(value, "${") {
+			continue
+		}
+		var err error
+		bound := paramRefRe.ReplaceAllStringFunc(value, func(ref string) string {
+			pr, ok := p.params.lookup(ref[2 : len(ref)-1])
+			if !ok {
+				return ref
+			}
+			v, ok := p.params.value(pr)
+			if !ok && err == nil {
+				err = fmt.Errorf("missing value for parameter %q", pr.Name)
+			}
+			return v
+		})
+		if err != nil {
+			return nil, err
+		}
+		if bound == value {
+			continue
+		}
+		if opts == nil {
+			opts = make(map[string]string, len(options))
+			for k, v := range options {
+				opts[k] = v
+			}
+		}
+		opts[name] = bound
+	}
+	return opts, nil
+}
@@ -0,0 +1,165 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const paramChain = `{
+  "version": 1,
+  "params": [
+    {"name": "key", "secret": true, "default": "s3cret", "description": "HMAC key"},
+    {"name": "alg", "default": "sha256"},
+    {"name": "n", "type": "number"}
+  ],
+  "steps": [
+    {"plugin": "hmac", "options": {"key": "${key}", "alg": "${alg}"}},
+    {"plugin": "hex", "options": {}}
+  ]
+}`
+
+func TestParamsBindStep(t *testing.T) {
+	p := New()
+	if err := p.LoadJSON([]byte(`{"version":1,"params":[{"name":"who","default":"world"}],"steps":[{"plugin":"base64"}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	s := &Step{Plugin: "aes", Options: map[string]string{"key": "${who}-${who}", "iv": "${other}", "mode": "gcm"}}
+	bound, err := p.bindStep(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bound.Options["key"] != "world-world" || bound.Options["iv"] != "${other}" || bound.Options["mode"] != "gcm" {
+		t.Fatalf("bound options = %v", bound.Options)
+	}
+	if s.Options["key"] != "${who}-${who}" {
+		t.Fatal("bindStep modified the step")
+	}
+	plain := &Step{Plugin: "hex", Options: map[string]string{"x": "y"}}
+	if got, _ := p.bindStep(plain); got != plain {
+		t.Fatal("bindStep copied a step without references")
+	}
+}
+
+func TestParamsRunAndExport(t *testing.T) {
+	p := New()
+	if err := p.LoadJSON([]byte(paramChain)); err != nil {
+		t.Fatal(err)
+	}
+	if missing := p.MissingParams(); len(missing) != 1 || missing[0].Name != "n" {
+		t.Fatalf("MissingParams = %v", missing)
+	}
+	if refs := p.ParamRefs(0); strings.Join(refs, ",") != "alg,key" {
+		t.Fatalf("ParamRefs = %v", refs)
+	}
+
+	run := func() string {
+		var out bytes.Buffer
+		if err := p.StreamFrom(context.Background(), strings.NewReader("deen"), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+	withDefault := run()
+	if err := p.BindParams([]string{"key=other"}); err != nil {
+		t.Fatal(err)
+	}
+	if run() == withDefault {
+		t.Fatal("binding key did not change the output")
+	}
+
+	p.SetSource([]byte("deen"))
+	before := p.Result()
+	if err := p.SetParam("key", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(before, p.Result()) || string(p.Result()) != withDefault {
+		t.Fatal("SetParam did not recompute the referencing step")
+	}
+	if !p.Undo() || !bytes.Equal(p.Result(), before) {
+		t.Fatal("SetParam is not undoable")
+	}
+
+	for name, export := range map[string]func() ([]byte, error){"ExportJSON": p.ExportJSON, "ExportJSONWithoutSource": p.ExportJSONWithoutSource} {
+		data, err := export()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("s3cret")) || bytes.Contains(data, []byte("other")) {
+			t.Fatalf("%s leaks a secret parameter value:\n%s", name, data)
+		}
+		var cf chainFile
+		if err := json.Unmarshal(data, &cf); err != nil {
+			t.Fatal(err)
+		}
+		if len(cf.Params) != 3 || cf.Params[1].Default != "sha256" || cf.Steps[0].Options["key"] != "${key}" {
+			t.Fatalf("%s params = %+v, steps = %+v", name, cf.Params, cf.Steps)
+		}
+	}
+
+	if err := p.SetParam("n", "7"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := p.ExportJSON()
+	if !bytes.Contains(data, []byte(`"value": "7"`)) {
+		t.Fatalf("ExportJSON dropped a bound value:\n%s", data)
+	}
+	data, _ = p.ExportJSONWithoutSource()
+	if bytes.Contains(data, []byte(`"value"`)) {
+		t.Fatalf("ExportJSONWithoutSource wrote a bound value:\n%s", data)
+	}
+}
+
+func TestParamsErrors(t *testing.T) {
+	p := New()
+	if err := p.LoadJSON([]byte(paramChain)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetParam("n", "seven"); err == nil || !strings.Contains(err.Error(), "must be a number") {
+		t.Fatalf("SetParam err = %v", err)
+	}
+	if err := p.BindParams([]string{"nope=1"}); err == nil || !strings.Contains(err.Error(), `unknown parameter "nope"`) {
+		t.Fatalf("BindParams err = %v", err)
+	}
+	if err := p.BindParams([]string{"key"}); err == nil {
+		t.Fatal("BindParams accepted a pair without =")
+	}
+
+	t.Setenv("DEEN_PARAM_N", "3")
+	if err := p.BindParams(nil); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := p.ParamValue("n"); v != "3" {
+		t.Fatalf("n = %q, want value from environment", v)
+	}
+	if err := p.BindParams([]string{"n=4"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := p.ParamValue("n"); v != "4" {
+		t.Fatalf("n = %q, want explicit value over environment", v)
+	}
+
+	q := New()
+	q.LoadJSON([]byte(`{"version":1,"params":[{"name":"k"}],"steps":[{"plugin":"hmac","options":{"key":"${k}"}}]}`))
+	err := q.StreamFrom(context.Background(), strings.NewReader("x"), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), `step 1 (hmac): missing value for parameter "k"`) {
+		t.Fatalf("StreamFrom err = %v", err)
+	}
+	q.SetSource([]byte("x"))
+	if q.Err(0) == nil {
+		t.Fatal("Compute ran a step with an unbound parameter")
+	}
+
+	for _, bad := range []string{
+		`{"version":1,"params":[{"name":"a b"}],"steps":[]}`,
+		`{"version":1,"params":[{"name":"a"},{"name":"a"}],"steps":[]}`,
+		`{"version":1,"params":[{"name":"a","type":"date"}],"steps":[]}`,
+		`{"version":1,"params":[{"name":"a","type":"bool","default":"maybe"}],"steps":[]}`,
+	} {
+		if err := New().LoadJSON([]byte(bad)); err == nil {
+			t.Errorf("LoadJSON(%s) succeeded", bad)
+		}
+	}
+}
@@ -6,11 +6,15 @@ package pipeline
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"slices"
 
 	"github.com/takeshixx/deen/internal/plugins"
+	"github.com/takeshixx/deen/pkg/types"
 )
 
 // Step is a single transform in the pipeline.
@@ -19,25 +23,47 @@ type Step struct {
 	Unprocess bool              // run the decode direction
 	Options   map[string]string // flag name -> value (string form)
 	Disabled  bool              // bypass this step without removing it
+	Map       *Map              // map step settings; Plugin is MapPlugin when set
+	Region    string            // part of the input to transform, see CheckRegion
 
 	override    []byte // user-edited content that replaces the computed output
 	hasOverride bool
 	output      []byte
 	effective   []byte
 	err         error
+
+	// Incremental recomputation state, see Compute.
+	dirty    bool   // settings changed in a way the cache key does not cover
+	computed bool   // output, effective and the digests below are valid
+	keyed    bool   // output was produced by the plugin for key
+	key      digest // cache key of the last plugin run
+	inSum    digest // digest of the input the step was computed from
+	outSum   digest // digest of output
+	effSum   digest // digest of effective
 }
 
 // Pipeline holds the source input and the chain of steps.
 type Pipeline struct {
-	source []byte
-	steps  []*Step
+	source   []byte
+	steps    []*Step
+	branches []*Branch
 
 	undo []snapshot
 	redo []snapshot
+
+	limits Limits
+	params paramState
+	tests  []TestCase
+
+	cache    *stepCache
+	srcSum   digest
+	srcSumOK bool
 }
 
-// New returns an empty pipeline.
-func New() *Pipeline { return &Pipeline{} }
+// New returns an empty pipeline with DefaultLimits.
+func New() *Pipeline {
+	return &Pipeline{limits: DefaultLimits(), cache: newStepCache(defaultCacheSize)}
+}
 
 const maxHistory = 100
 
@@ -46,19 +72,34 @@ type stepSnapshot struct {
 	Unprocess   bool
 	Options     map[string]string
 	Disabled    bool
+	Map         *Map
+	Region      string
 	Override    []byte
 	HasOverride bool
 }
 
 type snapshot struct {
-	Source []byte
-	Steps  []stepSnapshot
+	Source   []byte
+	Steps    []stepSnapshot
+	Branches []branchSnapshot
+	Params   paramState
+	Tests    []TestCase
 }
 
+// chainFile is the saved form of a pipeline. Version 2 adds branches; chains
+// without branches are still written as version 1.
 type chainFile struct {
-	Version int             `json:"version"`
-	Source  []byte          `json:"source,omitempty"`
-	Steps   []chainFileStep `json:"steps"`
+	Version  int               `json:"version"`
+	Source   []byte            `json:"source,omitempty"`
+	Params   []chainFileParam  `json:"params,omitempty"`
+	Steps    []chainFileStep   `json:"steps"`
+	Branches []chainFileBranch `json:"branches,omitempty"`
+	Tests    []TestCase        `json:"tests,omitempty"`
+}
+
+type chainFileParam struct {
+	Param
+	Value string `json:"value,omitempty"`
 }
 
 type chainFileStep struct {
@@ -66,10 +107,20 @@ type chainFileStep struct {
 	Unprocess   bool              `json:"unprocess,omitempty"`
 	Options     map[string]string `json:"options,omitempty"`
 	Disabled    bool              `json:"disabled,omitempty"`
+	Map         *chainFileMap     `json:"map,omitempty"`
+	Region      string            `json:"region,omitempty"`
 	Override    []byte            `json:"override,omitempty"`
 	HasOverride bool              `json:"hasOverride,omitempty"`
 }
 
+// chainFileMap holds the settings and nested chain of a map step. Nested
+// steps have no overrides.
+type chainFileMap struct {
+	Split   string          `json:"split"`
+	Pattern string          `json:"pattern,omitempty"`
+	Steps   []chainFileStep `json:"steps"`
+}
+
 // Steps returns the current steps.
 func (p *Pipeline) Steps() []*Step { return p.steps }
 
@@ -79,11 +130,13 @@ func (p *Pipeline) Len() int { return len(p.steps) }
 // Source returns the current source input.
 func (p *Pipeline) Source() []byte { return p.source }
 
-// Clear resets the source and all steps while keeping undo history available.
+// Clear resets the source, all steps and branches while keeping undo history
+// available.
 func (p *Pipeline) Clear() {
 	p.record()
-	p.source = nil
+	p.replaceSource(nil)
 	p.steps = nil
+	p.branches = nil
 	p.Compute()
 }
 
@@ -119,9 +172,10 @@ func (p *Pipeline) ExportJSON() ([]byte, error) {
 	return p.exportJSON(true)
 }
 
-// ExportJSONWithoutSource serializes the transform chain without source input
-// or manual output overrides. It is intended for shareable recipes that should
-// not leak the user's data.
+// ExportJSONWithoutSource serializes the transform chain without source input,
+// manual output overrides, bound parameter values or secret parameters of
+// test cases. It is intended for shareable recipes that should not leak the
+// user's data.
 func (p *Pipeline) ExportJSONWithoutSource() ([]byte, error) {
 	return p.exportJSON(false)
 }
@@ -134,65 +188,223 @@ func (p *Pipeline) exportJSON(includeSource bool) ([]byte, error) {
 	if includeSource {
 		cf.Source = append([]byte(nil), p.source...)
 	}
-	for _, step := range p.steps {
-		opts := make(map[string]string, len(step.Options))
-		for k, v := range step.Options {
-			opts[k] = v
-		}
-		cfs := chainFileStep{
-			Plugin:    step.Plugin,
-			Unprocess: step.Unprocess,
-			Options:   opts,
-			Disabled:  step.Disabled,
+	// Secret parameter values are never written, not even as defaults.
+	for _, pr := range p.params.decls {
+		cfp := chainFileParam{Param: pr}
+		if pr.Secret {
+			cfp.Default = ""
+		} else if v, ok := p.params.values[pr.Name]; ok && includeSource {
+			cfp.Value = v
 		}
+		cf.Params = append(cf.Params, cfp)
+	}
+	for _, step := range p.steps {
+		cfs := chainFileStepOf(step)
 		if includeSource {
 			cfs.Override = append([]byte(nil), step.override...)
 			cfs.HasOverride = step.hasOverride
 		}
 		cf.Steps = append(cf.Steps, cfs)
 	}
+	for _, b := range p.branches {
+		cf.Version = 2
+		cfb := chainFileBranch{Name: b.Name, After: b.After, Steps: make([]chainFileStep, 0, len(b.steps))}
+		for _, step := range b.steps {
+			cfb.Steps = append(cfb.Steps, chainFileStepOf(step))
+		}
+		cf.Branches = append(cf.Branches, cfb)
+	}
+	if len(p.tests) > 0 {
+		cf.Tests = cloneTests(p.tests)
+		if !includeSource {
+			// Test cases may bind secret parameters to real values.
+			for _, tc := range cf.Tests {
+				for name := range tc.Params {
+					if pr, ok := p.params.lookup(name); ok && pr.Secret {
+						delete(tc.Params, name)
+					}
+				}
+			}
+		}
+	}
 	return json.MarshalIndent(cf, "", "  ")
 }
 
+// chainFileStepOf returns the chain file form of s without its override.
+func chainFileStepOf(s *Step) chainFileStep {
+	opts := make(map[string]string, len(s.Options))
+	for k, v := range s.Options {
+		opts[k] = v
+	}
+	cfs := chainFileStep{
+		Plugin:    s.Plugin,
+		Unprocess: s.Unprocess,
+		Options:   opts,
+		Disabled:  s.Disabled,
+		Region:    s.Region,
+	}
+	if s.Map != nil {
+		cfs.Map = &chainFileMap{Split: s.Map.Split, Pattern: s.Map.Pattern, Steps: make([]chainFileStep, 0, len(s.Map.Steps))}
+		for i := range s.Map.Steps {
+			cfs.Map.Steps = append(cfs.Map.Steps, chainFileStepOf(&s.Map.Steps[i]))
+		}
+	}
+	return cfs
+}
+
+// stepOf returns the step a chain file step describes, without its override
+// and with plugin names left unresolved.
+func (cfs chainFileStep) stepOf() Step {
+	s := Step{Plugin: cfs.Plugin, Unprocess: cfs.Unprocess, Options: cfs.Options, Disabled: cfs.Disabled, Region: cfs.Region}
+	if cfs.Map != nil {
+		s.Map = &Map{Split: cfs.Map.Split, Pattern: cfs.Map.Pattern, Steps: make([]Step, 0, len(cfs.Map.Steps))}
+		for _, nested := range cfs.Map.Steps {
+			s.Map.Steps = append(s.Map.Steps, nested.stepOf())
+		}
+	}
+	return s
+}
+
 // ImportJSON replaces the pipeline with a serialized chain. The previous state
 // is retained in undo history so a user can recover from an accidental import.
 func (p *Pipeline) ImportJSON(data []byte) error {
+	s, err := parseChainJSON(data)
+	if err != nil {
+		return err
+	}
+	p.record()
+	p.restore(s)
+	return nil
+}
+
+// LoadJSON replaces the pipeline with a serialized chain like ImportJSON, but
+// neither records undo history nor computes step outputs. It is meant for
+// headless runners that execute the chain with Stream or StreamFrom.
+func (p *Pipeline) LoadJSON(data []byte) error {
+	s, err := parseChainJSON(data)
+	if err != nil {
+		return err
+	}
+	p.load(s)
+	return nil
+}
+
+// LoadSteps replaces the pipeline with copies of steps like LoadJSON, without a
+// source. Unlike chain files, unknown plugins, unsupported decode directions
+// and invalid option values are rejected up front.
+func (p *Pipeline) LoadSteps(steps []Step) error {
+	ss, err := checkedSteps(steps)
+	if err != nil {
+		return err
+	}
+	p.load(snapshot{Steps: ss})
+	return nil
+}
+
+// checkedSteps resolves and checks copies of steps like LoadSteps.
+func checkedSteps(steps []Step) ([]stepSnapshot, error) {
+	ss := make([]stepSnapshot, 0, len(steps))
+	for i := range steps {
+		step := Step{
+			Plugin:    steps[i].Plugin,
+			Unprocess: steps[i].Unprocess,
+			Options:   steps[i].Options,
+			Disabled:  steps[i].Disabled,
+			Map:       steps[i].Map.clone(),
+			Region:    steps[i].Region,
+		}
+		if err := resolveStep(i, &step, true); err != nil {
+			return nil, err
+		}
+		ss = append(ss, stepSnapshot{
+			Plugin:    step.Plugin,
+			Unprocess: step.Unprocess,
+			Options:   step.Options,
+			Disabled:  step.Disabled,
+			Map:       step.Map,
+			Region:    step.Region,
+		})
+	}
+	return ss, nil
+}
+
+func parseChainJSON(data []byte) (snapshot, error) {
 	var cf chainFile
 	if err := json.Unmarshal(data, &cf); err != nil {
-		return err
+		return snapshot{}, err
+	}
+	if cf.Version != 1 && cf.Version != 2 {
+		return snapshot{}, fmt.Errorf("unsupported chain version %d", cf.Version)
 	}
-	if cf.Version != 1 {
-		return fmt.Errorf("unsupported chain version %d", cf.Version)
+	if cf.Version < 2 && len(cf.Branches) > 0 {
+		return snapshot{}, errors.New("branches need chain version 2")
 	}
 
 	s := snapshot{
 		Source: append([]byte(nil), cf.Source...),
 		Steps:  make([]stepSnapshot, 0, len(cf.Steps)),
 	}
-	for i, step := range cf.Steps {
-		pluginName := step.Plugin
-		if step.Plugin != "" {
-			plugin, _, ok := plugins.Resolve(step.Plugin)
-			if !ok {
-				return fmt.Errorf("step %d: unknown plugin %q", i+1, step.Plugin)
+	for _, cfp := range cf.Params {
+		s.Params.decls = append(s.Params.decls, cfp.Param)
+	}
+	if err := checkParams(s.Params.decls); err != nil {
+		return snapshot{}, err
+	}
+	for _, cfp := range cf.Params {
+		if cfp.Value == "" {
+			continue
+		}
+		if err := cfp.Check(cfp.Value); err != nil {
+			return snapshot{}, fmt.Errorf("parameter %s: %w", cfp.Name, err)
+		}
+		if s.Params.values == nil {
+			s.Params.values = map[string]string{}
+		}
+		s.Params.values[cfp.Name] = cfp.Value
+	}
+	for i, tc := range cf.Tests {
+		if _, err := tc.InputBytes(); err != nil {
+			return snapshot{}, fmt.Errorf("test %d: %w", i+1, err)
+		}
+		for name := range tc.Params {
+			if _, ok := s.Params.lookup(name); !ok {
+				return snapshot{}, fmt.Errorf("test %d: unknown parameter %q", i+1, name)
 			}
-			pluginName = plugin.Name
 		}
-		opts := normalizeStepOptions(pluginName, step.Options)
-		unprocess := step.Unprocess && plugins.CanDecode(pluginName)
+	}
+	s.Tests = cloneTests(cf.Tests)
+	for i, cfs := range cf.Steps {
+		step := cfs.stepOf()
+		if err := resolveStep(i, &step, false); err != nil {
+			return snapshot{}, err
+		}
 		s.Steps = append(s.Steps, stepSnapshot{
-			Plugin:      pluginName,
-			Unprocess:   unprocess,
-			Options:     opts,
+			Plugin:      step.Plugin,
+			Unprocess:   step.Unprocess,
+			Options:     normalizeStepOptions(step.Plugin, step.Options),
 			Disabled:    step.Disabled,
-			Override:    append([]byte(nil), step.Override...),
-			HasOverride: step.HasOverride,
+			Map:         step.Map,
+			Region:      step.Region,
+			Override:    append([]byte(nil), cfs.Override...),
+			HasOverride: cfs.HasOverride,
 		})
 	}
-
-	p.record()
-	p.restore(s)
-	return nil
+	for _, cfb := range cf.Branches {
+		bs := branchSnapshot{Name: cfb.Name, After: cfb.After, Steps: make([]stepSnapshot, 0, len(cfb.Steps))}
+		for i, cfs := range cfb.Steps {
+			step := cfs.stepOf()
+			if err := resolveStep(i, &step, false); err != nil {
+				return snapshot{}, fmt.Errorf("branch %s: %w", cfb.Name, err)
+			}
+			step.Options = normalizeStepOptions(step.Plugin, step.Options)
+			bs.Steps = append(bs.Steps, stepSnapshotOf(&step))
+		}
+		s.Branches = append(s.Branches, bs)
+	}
+	if err := checkBranches(s.Branches, len(s.Steps)); err != nil {
+		return snapshot{}, err
+	}
+	return s, nil
 }
 
 // SetSource sets the source input. Editing the source invalidates any
@@ -209,7 +421,7 @@ func (p *Pipeline) SetSourceOwned(b []byte) {
 
 func (p *Pipeline) setSource(b []byte) {
 	p.record()
-	p.source = b
+	p.replaceSource(b)
 	p.clearOverrides(0)
 	p.Compute()
 }
@@ -288,6 +500,12 @@ func (p *Pipeline) RemoveStep(i int) {
 	}
 	p.record()
 	p.steps = append(p.steps[:i], p.steps[i+1:]...)
+	// Branches forking off the removed step fork off its input instead.
+	for _, b := range p.branches {
+		if b.After > i {
+			b.After--
+		}
+	}
 	p.Compute()
 }
 
@@ -297,9 +515,21 @@ func (p *Pipeline) MoveStep(from, to int) {
 		return
 	}
 	p.record()
+	// Branches keep forking off the same step.
+	forks := make([]*Step, len(p.branches))
+	for j, b := range p.branches {
+		if b.After > 0 {
+			forks[j] = p.steps[b.After-1]
+		}
+	}
 	step := p.steps[from]
 	p.steps = append(p.steps[:from], p.steps[from+1:]...)
 	p.steps = append(p.steps[:to], append([]*Step{step}, p.steps[to:]...)...)
+	for j, b := range p.branches {
+		if forks[j] != nil {
+			b.After = slices.Index(p.steps, forks[j]) + 1
+		}
+	}
 	p.clearOverrides(min(from, to))
 	p.Compute()
 }
@@ -311,6 +541,11 @@ func (p *Pipeline) DuplicateStep(i int) {
 	}
 	p.record()
 	p.steps = append(p.steps[:i+1], append([]*Step{cloneStep(p.steps[i])}, p.steps[i+1:]...)...)
+	for _, b := range p.branches {
+		if b.After > i+1 {
+			b.After++
+		}
+	}
 	p.clearOverrides(i + 1)
 	p.Compute()
 }
@@ -324,6 +559,7 @@ func (p *Pipeline) SetStepDisabled(i int, disabled bool) {
 	}
 	p.record()
 	p.steps[i].Disabled = disabled
+	p.steps[i].dirty = true
 	p.clearOverrides(i + 1)
 	p.Compute()
 }
@@ -340,6 +576,8 @@ func (p *Pipeline) SetPlugin(i int, plugin string, unprocess bool) {
 	p.record()
 	p.steps[i].Plugin = plugin
 	p.steps[i].Unprocess = unprocess
+	p.steps[i].Map = nil
+	p.steps[i].dirty = true
 	p.clearOverrides(i)
 	p.Compute()
 }
@@ -354,6 +592,20 @@ func (p *Pipeline) SetOption(i int, name, value string) {
 		p.steps[i].Options = map[string]string{}
 	}
 	p.steps[i].Options[name] = value
+	p.steps[i].dirty = true
+	p.clearOverrides(i)
+	p.Compute()
+}
+
+// SetRegion restricts step i to a region of its input, or to all of it when
+// spec is empty. See CheckRegion for the forms of spec.
+func (p *Pipeline) SetRegion(i int, spec string) {
+	if i < 0 || i >= len(p.steps) {
+		return
+	}
+	p.record()
+	p.steps[i].Region = spec
+	p.steps[i].dirty = true
 	p.clearOverrides(i)
 	p.Compute()
 }
@@ -368,6 +620,7 @@ func (p *Pipeline) EditOutput(i int, data []byte) {
 	p.clearOverrides(i + 1)
 	p.steps[i].override = data
 	p.steps[i].hasOverride = true
+	p.steps[i].dirty = true
 	p.Compute()
 }
 
@@ -421,73 +674,182 @@ func appendLimited(history []snapshot, s snapshot) []snapshot {
 
 func (p *Pipeline) snapshot() snapshot {
 	s := snapshot{
-		Source: p.source,
-		Steps:  make([]stepSnapshot, 0, len(p.steps)),
+		Source:   p.source,
+		Steps:    make([]stepSnapshot, 0, len(p.steps)),
+		Branches: p.branchSnapshots(),
+		Params:   p.params.clone(),
+		Tests:    cloneTests(p.tests),
 	}
 	for _, step := range p.steps {
-		opts := make(map[string]string, len(step.Options))
-		for k, v := range step.Options {
-			opts[k] = v
-		}
-		s.Steps = append(s.Steps, stepSnapshot{
-			Plugin:      step.Plugin,
-			Unprocess:   step.Unprocess,
-			Options:     opts,
-			Disabled:    step.Disabled,
-			Override:    append([]byte(nil), step.override...),
-			HasOverride: step.hasOverride,
-		})
+		s.Steps = append(s.Steps, stepSnapshotOf(step))
 	}
 	return s
 }
 
-func (p *Pipeline) restore(s snapshot) {
-	p.source = s.Source
-	p.steps = make([]*Step, 0, len(s.Steps))
-	for _, ss := range s.Steps {
-		opts := make(map[string]string, len(ss.Options))
-		for k, v := range ss.Options {
-			opts[k] = v
-		}
-		p.steps = append(p.steps, &Step{
-			Plugin:      ss.Plugin,
-			Unprocess:   ss.Unprocess,
-			Options:     opts,
-			Disabled:    ss.Disabled,
-			override:    append([]byte(nil), ss.Override...),
-			hasOverride: ss.HasOverride,
-		})
+// stepSnapshotOf returns a snapshot of the settings and override of step.
+func stepSnapshotOf(step *Step) stepSnapshot {
+	opts := make(map[string]string, len(step.Options))
+	for k, v := range step.Options {
+		opts[k] = v
+	}
+	return stepSnapshot{
+		Plugin:      step.Plugin,
+		Unprocess:   step.Unprocess,
+		Options:     opts,
+		Disabled:    step.Disabled,
+		Map:         step.Map.clone(),
+		Region:      step.Region,
+		Override:    append([]byte(nil), step.override...),
+		HasOverride: step.hasOverride,
 	}
+}
+
+func (p *Pipeline) restore(s snapshot) {
+	p.load(s)
 	p.Compute()
 }
 
+// load replaces the source and steps with a snapshot without computing.
+func (p *Pipeline) load(s snapshot) {
+	p.replaceSource(s.Source)
+	p.params = s.Params.clone()
+	p.tests = cloneTests(s.Tests)
+	p.steps = newSteps(s.Steps)
+	p.branches = make([]*Branch, 0, len(s.Branches))
+	for _, bs := range s.Branches {
+		p.branches = append(p.branches, bs.branch())
+	}
+}
+
+// newSteps returns new steps with copies of the snapshot settings.
+func newSteps(ss []stepSnapshot) []*Step {
+	steps := make([]*Step, 0, len(ss))
+	for _, s := range ss {
+		steps = append(steps, s.step())
+	}
+	return steps
+}
+
+// step returns a new step with copies of the snapshot settings.
+func (ss stepSnapshot) step() *Step {
+	opts := make(map[string]string, len(ss.Options))
+	for k, v := range ss.Options {
+		opts[k] = v
+	}
+	return &Step{
+		Plugin:      ss.Plugin,
+		Unprocess:   ss.Unprocess,
+		Options:     opts,
+		Disabled:    ss.Disabled,
+		Map:         ss.Map.clone(),
+		Region:      ss.Region,
+		override:    append([]byte(nil), ss.Override...),
+		hasOverride: ss.HasOverride,
+	}
+}
+
+// snapshotSteps returns copies of the snapshot steps without overrides.
+func snapshotSteps(ss []stepSnapshot) []Step {
+	steps := make([]Step, 0, len(ss))
+	for _, s := range ss {
+		step := s.step()
+		step.override, step.hasOverride = nil, false
+		steps = append(steps, *step)
+	}
+	return steps
+}
+
 // clearOverrides drops manual edits for all steps with index >= from.
 func (p *Pipeline) clearOverrides(from int) {
 	for j := from; j < len(p.steps); j++ {
+		if p.steps[j].hasOverride {
+			p.steps[j].dirty = true
+		}
 		p.steps[j].hasOverride = false
 		p.steps[j].override = nil
 	}
 }
 
-// Compute (re)evaluates every step's output from the source down.
+// replaceSource sets the source without recording or computing.
+func (p *Pipeline) replaceSource(b []byte) {
+	p.source = b
+	p.srcSumOK = false
+}
+
+func (p *Pipeline) sourceDigest() digest {
+	if !p.srcSumOK {
+		p.srcSum = digestOf(p.source)
+		p.srcSumOK = true
+	}
+	return p.srcSum
+}
+
+// Compute (re)evaluates step outputs from the source down. It is incremental:
+// a step whose input and settings are unchanged since the last computation
+// keeps its output, and plugin runs are looked up by input digest, plugin,
+// direction and options in a content-addressed cache first, so undo, redo and
+// toggling steps reuse earlier results. Steps of non-deterministic plugins
+// such as uuid run on every call instead. Steps must be changed through the
+// Pipeline methods for this to see the change.
 func (p *Pipeline) Compute() {
-	prev := p.source
+	ctx, cancel := p.limits.chainContext(context.Background())
+	defer cancel()
+	prev, prevSum := p.source, p.sourceDigest()
 	for _, s := range p.steps {
-		switch {
-		case s.hasOverride:
-			s.output, s.err = s.override, nil
-		case s.Plugin == "":
-			s.output, s.err = prev, nil // passthrough until a transform is chosen
-		default:
-			s.output, s.err = runStep(s, prev)
+		p.computeStep(ctx, s, prev, prevSum)
+		prev, prevSum = s.effective, s.effSum
+	}
+	p.computeBranches(ctx)
+}
+
+// computeStep brings s up to date for the input in with digest inSum.
+func (p *Pipeline) computeStep(ctx context.Context, s *Step, in []byte, inSum digest) {
+	var key digest
+	bound, bindErr := s, error(nil)
+	plugin := !s.hasOverride && s.Plugin != ""
+	// Steps with a non-deterministic plugin run again on every computation.
+	fresh := plugin && volatile(s)
+	if plugin {
+		if bound, bindErr = p.bindStep(s); bindErr == nil {
+			key = stepKey(inSum, bound)
 		}
-		if s.Disabled {
-			s.effective = prev
+	}
+	if s.computed && !s.dirty && !fresh && bindErr == nil && s.inSum == inSum && (!plugin || (s.keyed && s.key == key)) {
+		return
+	}
+
+	switch {
+	case s.hasOverride:
+		s.output, s.err, s.outSum = s.override, nil, digestOf(s.override)
+		s.keyed = false
+	case s.Plugin == "":
+		s.output, s.err, s.outSum = in, nil, inSum // passthrough until a transform is chosen
+		s.keyed = false
+	case bindErr != nil:
+		s.output, s.err, s.outSum = nil, bindErr, digestOf(nil)
+		s.keyed = false
+	case s.computed && s.keyed && s.key == key && s.err == nil && !fresh:
+		// Only the bypass or an override of this step changed.
+	default:
+		if e, ok := p.cache.get(key); ok && !fresh {
+			s.output, s.err, s.outSum = e.output, nil, e.sum
 		} else {
-			s.effective = s.output
+			s.output, s.err = runStep(ctx, p.limits, bound, in)
+			s.outSum = digestOf(s.output)
+			if s.err == nil && !fresh {
+				p.cache.put(&cacheEntry{key: key, output: s.output, sum: s.outSum})
+			}
 		}
-		prev = s.effective
+		s.key, s.keyed = key, true
+	}
+	if s.Disabled {
+		s.effective, s.effSum = in, inSum
+	} else {
+		s.effective, s.effSum = s.output, s.outSum
 	}
+	s.inSum = inSum
+	s.computed = true
+	s.dirty = false
 }
 
 func cloneStep(s *Step) *Step {
@@ -500,42 +862,81 @@ func cloneStep(s *Step) *Step {
 		Unprocess:   s.Unprocess,
 		Options:     opts,
 		Disabled:    s.Disabled,
+		Map:         s.Map.clone(),
+		Region:      s.Region,
 		override:    append([]byte(nil), s.override...),
 		hasOverride: s.hasOverride,
 	}
 }
 
-// runStep executes a single plugin transform.
-func runStep(s *Step, in []byte) ([]byte, error) {
+// runStep executes a single plugin transform within ctx and the limits l.
+func runStep(ctx context.Context, l Limits, s *Step, in []byte) ([]byte, error) {
+	fn, fs, err := stepTransform(l, s)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := runTransform(ctx, l, fn, fs, bytes.NewReader(in), &buf); err != nil {
+		return buf.Bytes(), err
+	}
+	return buf.Bytes(), nil
+}
+
+// stepTransform resolves the plugin of s and returns the transform for its
+// direction together with a flag set holding the step options. The transform
+// of a map step runs its nested steps within the limits l, and a step with a
+// region transforms only that part of its input.
+func stepTransform(l Limits, s *Step) (types.ContextTransformFunc, *flag.FlagSet, error) {
+	fn, fs, err := pluginTransform(l, s)
+	if err != nil || s.Region == "" {
+		return fn, fs, err
+	}
+	r, err := parseRegion(s.Region)
+	if err != nil {
+		return nil, nil, err
+	}
+	return regionTransform(r, fn), fs, nil
+}
+
+func pluginTransform(l Limits, s *Step) (types.ContextTransformFunc, *flag.FlagSet, error) {
+	if s.Map != nil {
+		if err := s.Map.check(); err != nil {
+			return nil, nil, err
+		}
+		return mapTransform(l, s.Map), flag.NewFlagSet(MapPlugin, flag.ContinueOnError), nil
+	}
 	cmd := s.Plugin
 	if s.Unprocess {
 		cmd = "." + s.Plugin
 	}
 	plugin, unprocess, ok := plugins.Resolve(cmd)
 	if !ok {
-		return nil, fmt.Errorf("unknown plugin %q", s.Plugin)
+		return nil, nil, fmt.Errorf("unknown plugin %q", s.Plugin)
 	}
-	fn := plugin.Process
-	if unprocess {
-		if plugin.Unprocess == nil {
-			return nil, fmt.Errorf("%s does not support decoding", s.Plugin)
-		}
-		fn = plugin.Unprocess
+	fn := plugin.Transform(unprocess)
+	if fn == nil {
+		return nil, nil, fmt.Errorf("%s does not support decoding", s.Plugin)
 	}
 
 	fs := flag.NewFlagSet(s.Plugin, flag.ContinueOnError)
 	if plugin.RegisterFlags != nil {
 		plugin.RegisterFlags(fs)
 	}
-	for name, value := range s.Options {
-		if fs.Lookup(name) != nil {
-			_ = fs.Set(name, value)
+	for _, name := range sortedOptionNames(s.Options) {
+		if fs.Lookup(name) == nil {
+			continue
+		}
+		value := s.Options[name]
+		if err := fs.Set(name, value); err != nil {
+			if value == "" {
+				// A cleared entry falls back to the flag default.
+				continue
+			}
+			return nil, nil, fmt.Errorf("invalid value for -%s: %w", name, err)
 		}
 	}
-
-	var buf bytes.Buffer
-	if err := fn(bytes.NewReader(in), &buf, fs); err != nil {
-		return buf.Bytes(), err
+	if err := plugin.ValidateFlags(fs); err != nil {
+		return nil, nil, err
 	}
-	return buf.Bytes(), nil
+	return fn, fs, nil
 }
@@ -419,8 +419,6 @@ func TestPluginOptionsMetadata(t *testing.T) {
 	}{
 		{"aes", "mode"},
 		{"aes", "padding"},
-		{"csv", "in"},
-		{"csv", "out"},
 		{"hmac", "alg"},
 		{"jwt", "sign-alg"},
 		{"jwt", "enc-alg"},
@@ -435,6 +433,12 @@ func TestPluginOptionsMetadata(t *testing.T) {
 			t.Fatalf("%s %s metadata = %#v, want select choices", tc.plugin, tc.name, opt)
 		}
 	}
+	// Delimiters are open-ended: the choices are suggestions for a text entry.
+	for _, name := range []string{"in", "out"} {
+		if opt := find("csv", name); opt.Kind != "text" || !opt.Open || len(opt.Choices) == 0 {
+			t.Fatalf("csv %s metadata = %#v, want open text with suggestions", name, opt)
+		}
+	}
 	for _, tc := range []struct {
 		plugin string
 		name   string
@@ -629,3 +633,77 @@ func TestImportJSONRejectsUnknownPlugin(t *testing.T) {
 		t.Fatal("expected unknown plugin error")
 	}
 }
+
+func TestPluginOptionsFromSchema(t *testing.T) {
+	var level Option
+	for _, opt := range PluginOptions("gzip") {
+		if opt.Name == "level" {
+			level = opt
+		}
+	}
+	if level.Kind != "number" || level.Range == nil || level.Range.Max != 9 {
+		t.Fatalf("gzip level metadata = %#v, want ranged number", level)
+	}
+	if err := level.Check("10"); err == nil {
+		t.Fatal("gzip level 10 accepted")
+	}
+	if err := level.Check("5"); err != nil {
+		t.Fatalf("gzip level 5 rejected: %v", err)
+	}
+	for _, opt := range PluginOptions("aes") {
+		if opt.Name == "nonce" {
+			t.Fatal("hidden aes nonce option is listed")
+		}
+	}
+}
+
+func TestInvalidOptionRejectedBeforeStepRuns(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("test"))
+	p.AddStepWithOptions("aes", false, map[string]string{"mode": "ecb", "key": "000102030405060708090a0b0c0d0e0f"})
+	err := p.Err(0)
+	if err == nil || !strings.Contains(err.Error(), `invalid value "ecb" for -mode`) {
+		t.Fatalf("step error = %v, want invalid mode", err)
+	}
+	if len(p.Output(0)) != 0 {
+		t.Fatalf("step produced output %q despite invalid option", p.Output(0))
+	}
+
+	p.SetOption(0, "mode", "gcm")
+	p.SetOption(0, "tag-len", "abc")
+	if err := p.Err(0); err == nil || !strings.Contains(err.Error(), "-tag-len") {
+		t.Fatalf("step error = %v, want invalid tag-len", err)
+	}
+}
+
+func TestLoadSteps(t *testing.T) {
+	p := New()
+	err := p.LoadSteps([]Step{
+		{Plugin: "b64", Unprocess: true},
+		{Plugin: "base32", Options: map[string]string{"hex": "true"}, Disabled: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 2 || p.Steps()[0].Plugin != "base64" || !p.Steps()[1].Disabled {
+		t.Fatalf("steps = %+v", p.Steps())
+	}
+	if p.Output(0) != nil {
+		t.Fatal("LoadSteps computed outputs")
+	}
+
+	for _, tc := range []struct {
+		step Step
+		want string
+	}{
+		{Step{Plugin: "nope"}, `step 1: unknown plugin "nope"`},
+		{Step{Plugin: "sha256", Unprocess: true}, "step 1 (sha256): sha256 does not support decoding"},
+		{Step{Plugin: "bcrypt", Options: map[string]string{"cost": "99"}}, "step 1 (bcrypt): invalid value"},
+		{Step{Plugin: "hex", Options: map[string]string{"bogus": "1"}}, `step 1 (hex): unknown option "bogus"`},
+	} {
+		err := New().LoadSteps([]Step{tc.step})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("LoadSteps(%+v) = %v, want %q", tc.step, err, tc.want)
+		}
+	}
+}
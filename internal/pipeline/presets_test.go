@@ -1,7 +1,7 @@
 package pipeline
 
 import (
-	"strings"
+	"context"
 	"testing"
 )
 
@@ -92,8 +92,8 @@ func TestBuiltinExamplesAreRunnable(t *testing.T) {
 					t.Fatalf("step %d failed: %v", i+1, step.err)
 				}
 			}
-			if example.WantContains != "" && !strings.Contains(string(p.Result()), example.WantContains) {
-				t.Fatalf("result %q does not contain %q", string(p.Result()), example.WantContains)
+			if err := example.Want.Check(p.Result(), nil); err != nil {
+				t.Fatal(err)
 			}
 		})
 	}
@@ -125,3 +125,18 @@ func TestApplyExampleIsUndoable(t *testing.T) {
 		t.Fatalf("undo did not restore previous chain: %#v", p.Steps())
 	}
 }
+
+func TestBuiltinPresetTests(t *testing.T) {
+	for _, preset := range BuiltinPresets() {
+		if len(preset.Tests) == 0 {
+			t.Errorf("%s has no test cases", preset.Name)
+		}
+		p := New()
+		p.ApplyPreset(preset)
+		for i, tc := range p.Tests() {
+			if err := p.RunTest(context.Background(), tc); err != nil {
+				t.Errorf("%s test %d: %v", preset.Name, i+1, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/itchyny/gojq"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// region is a parsed Step.Region: a byte range, a regex or a jq path.
+type region struct {
+	spec string
+
+	offset int64
+	length int64 // -1 reaches the end of the input
+
+	re *regexp.Regexp
+	jq *gojq.Code
+}
+
+// CheckRegion reports whether spec is a valid step region, so editors can flag
+// invalid input before the step runs. The forms are:
+//
+//   - OFFSET:LENGTH selects LENGTH bytes from OFFSET. An empty LENGTH reaches
+//     the end of the input, a negative OFFSET counts from the end, and both
+//     accept 0x prefixes.
+//   - re:REGEX selects the first match of REGEX, or its first capture group if
+//     it has one.
+//   - jq:PATH selects every value the jq path expression PATH, such as
+//     .session.token or .items[].data, selects in JSON input. Strings are
+//     transformed without their quotes.
+//
+// The step transforms only the selected bytes and splices the result back
+// into the surrounding data.
+func CheckRegion(spec string) error {
+	_, err := parseRegion(spec)
+	return err
+}
+
+func parseRegion(spec string) (*region, error) {
+	r := &region{spec: spec}
+	switch {
+	case strings.HasPrefix(spec, "re:"):
+		re, err := regexp.Compile(spec[3:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid region regex: %w", err)
+		}
+		r.re = re
+	case strings.HasPrefix(spec, "jq:"):
+		query, err := gojq.Parse("path(" + spec[3:] + ")")
+		if err != nil {
+			return nil, fmt.Errorf("invalid region jq path: %w", err)
+		}
+		if r.jq, err = gojq.Compile(query); err != nil {
+			return nil, fmt.Errorf("invalid region jq path: %w", err)
+		}
+	default:
+		offset, length, ok := strings.Cut(spec, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid region %q: want OFFSET:LENGTH, re:REGEX or jq:PATH", spec)
+		}
+		var err error
+		if r.offset, err = strconv.ParseInt(offset, 0, 64); err != nil {
+			return nil, fmt.Errorf("invalid region offset %q", offset)
+		}
+		r.length = -1
+		if length != "" {
+			if r.length, err = strconv.ParseInt(length, 0, 64); err != nil || r.length < 0 {
+				return nil, fmt.Errorf("invalid region length %q", length)
+			}
+		}
+	}
+	return r, nil
+}
+
+// spans returns the byte ranges of in the region selects.
+func (r *region) spans(in []byte) ([][2]int, error) {
+	switch {
+	case r.re != nil:
+		spans := regexSpans(in, r.re)
+		if len(spans) == 0 {
+			return nil, fmt.Errorf("region %s does not match the input", r.spec)
+		}
+		return spans[:1], nil
+	case r.jq != nil:
+		return r.jsonSpans(in)
+	}
+	start := r.offset
+	if start < 0 {
+		start += int64(len(in))
+	}
+	size := int64(len(in))
+	// Compare the length with the bytes left so a huge length cannot overflow.
+	if start < 0 || start > size || r.length > size-start {
+		return nil, fmt.Errorf("region %s is outside the %d-byte input", r.spec, len(in))
+	}
+	end := size
+	if r.length >= 0 {
+		end = start + r.length
+	}
+	return [][2]int{{int(start), int(end)}}, nil
+}
+
+// jsonSpans runs the jq path over in and locates every resulting path in the
+// JSON text, so the document keeps its formatting and member order.
+func (r *region) jsonSpans(in []byte) ([][2]int, error) {
+	doc := bytes.TrimSpace(in)
+	var v any
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return nil, errors.New("region needs JSON input: " + err.Error())
+	}
+	off := bytes.Index(in, doc[:1])
+	var spans [][2]int
+	iter := r.jq.Run(v)
+	for {
+		p, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := p.(error); ok {
+			return nil, fmt.Errorf("region %s: %w", r.spec, err)
+		}
+		path, _ := p.([]any)
+		sp, found, err := locateJSON(doc, off, path)
+		if err != nil {
+			return nil, fmt.Errorf("region %s: %w", r.spec, err)
+		}
+		if found {
+			spans = append(spans, sp)
+		}
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("region %s selects nothing in the input", r.spec)
+	}
+	return disjointSpans(spans), nil
+}
+
+// locateJSON returns the byte range of the value at path, a list of member
+// names and array indices, in the JSON value raw starting at offset off.
+func locateJSON(raw []byte, off int, path []any) ([2]int, bool, error) {
+	for _, component := range path {
+		key, isKey := component.(string)
+		index, isIndex := component.(int)
+		if !isKey && !isIndex {
+			return [2]int{}, false, fmt.Errorf("unsupported path component %v", component)
+		}
+		if isIndex && index < 0 && raw[0] == '[' {
+			n := 0
+			if err := eachJSONChild(raw, off, func(string, int, []byte, int) error { n++; return nil }); err != nil {
+				return [2]int{}, false, err
+			}
+			index += n
+		}
+		var next []byte
+		nextOff := 0
+		if raw[0] == '{' && isKey || raw[0] == '[' && isIndex {
+			err := eachJSONChild(raw, off, func(k string, i int, child []byte, childOff int) error {
+				if (isKey && k == key) || (isIndex && i == index) {
+					next, nextOff = child, childOff
+				}
+				return nil
+			})
+			if err != nil {
+				return [2]int{}, false, err
+			}
+		}
+		if next == nil {
+			return [2]int{}, false, nil
+		}
+		raw, off = next, nextOff
+	}
+	return [2]int{off, off + len(raw)}, true, nil
+}
+
+// regionTransform wraps fn so that it runs only on the region of its input
+// and the rest of the input is copied around its output.
+func regionTransform(r *region, fn types.ContextTransformFunc) types.ContextTransformFunc {
+	return func(ctx context.Context, in io.Reader, w io.Writer, fs *flag.FlagSet) error {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return err
+		}
+		spans, err := r.spans(data)
+		if err != nil {
+			return err
+		}
+		call := func(part []byte) ([]byte, error) {
+			var buf bytes.Buffer
+			if err := fn(ctx, bytes.NewReader(part), &buf, fs); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}
+		run := func(_ int, part []byte) ([]byte, error) { return call(part) }
+		if r.jq != nil {
+			run = func(_ int, raw []byte) ([]byte, error) { return transformJSONValue(raw, call) }
+		}
+		out, err := splice(data, spans, run)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}
+}
+
+// RegionTransform returns fn restricted to the region spec, as described by
+// CheckRegion. An empty spec returns fn unchanged.
+func RegionTransform(spec string, fn types.TransformFunc) (types.TransformFunc, error) {
+	if spec == "" {
+		return fn, nil
+	}
+	r, err := parseRegion(spec)
+	if err != nil {
+		return nil, err
+	}
+	region := regionTransform(r, func(_ context.Context, in io.Reader, w io.Writer, fs *flag.FlagSet) error {
+		return fn(in, w, fs)
+	})
+	return func(in io.Reader, w io.Writer, fs *flag.FlagSet) error {
+		return region(context.Background(), in, w, fs)
+	}, nil
+}
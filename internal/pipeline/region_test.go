@@ -0,0 +1,153 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRegionTransformsOnlyRegion(t *testing.T) {
+	tests := []struct {
+		name   string
+		region string
+		in     string
+		want   string
+	}{
+		{"offset length", "4:8", "key=aGVsbG8=;rest", "key=hello;rest"},
+		{"offset to end", "0x4:", "key=aGVsbG8=", "key=hello"},
+		{"negative offset", "-8:", "key=aGVsbG8=", "key=hello"},
+		{"regex match", `re:[A-Za-z0-9]+=`, "?aGk= and Ynll", "?hi and Ynll"},
+		{"regex group", `re:session=([^;]+)`, "Cookie: a=1; session=aGVsbG8=; b=2", "Cookie: a=1; session=hello; b=2"},
+		{"jq member", "jq:.user.token", `{"user": {"id": 7, "token": "aGVsbG8="}, "token": "keep"}`, `{"user": {"id": 7, "token": "hello"}, "token": "keep"}`},
+		{"jq iterate", "jq:.items[].v", `{"items": [{"v": "aGk="}, {"v": "Ynll"}]}`, `{"items": [{"v": "hi"}, {"v": "bye"}]}`},
+		{"jq negative index", "jq:.[-1]", `["aGk=", "Ynll"]`, `["aGk=", "bye"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New()
+			if err := p.LoadSteps([]Step{{Plugin: "base64", Unprocess: true, Region: tt.region}}); err != nil {
+				t.Fatalf("LoadSteps: %v", err)
+			}
+			p.SetSource([]byte(tt.in))
+			if err := p.Err(0); err != nil {
+				t.Fatalf("Err = %v", err)
+			}
+			if got := string(p.Result()); got != tt.want {
+				t.Fatalf("Result = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionErrors(t *testing.T) {
+	for _, tt := range []struct {
+		region string
+		in     string
+		want   string
+	}{
+		{"4:100", "short", "outside the 5-byte input"},
+		{"-9:", "short", "outside the 5-byte input"},
+		{"10:", "short", "outside the 5-byte input"},
+		{"1:9223372036854775807", "short", "outside the 5-byte input"},
+		{"re:x=(\\d+)", "y=1", "does not match the input"},
+		{"jq:.a", "not json", "needs JSON input"},
+		{"jq:.missing", `{"a": "aGk="}`, "selects nothing"},
+	} {
+		p := New()
+		p.SetSource([]byte(tt.in))
+		p.AddStep("base64", true)
+		p.SetRegion(0, tt.region)
+		if err := p.Err(0); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("region %q on %q: Err = %v, want %q", tt.region, tt.in, err, tt.want)
+		}
+	}
+	for _, spec := range []string{"10", "a:1", "1:-2", "re:(", "jq:.[", "jq:.a |"} {
+		if err := CheckRegion(spec); err == nil {
+			t.Errorf("CheckRegion(%q) = nil, want error", spec)
+		}
+		if err := New().LoadSteps([]Step{{Plugin: "hex", Region: spec}}); err == nil || !strings.Contains(err.Error(), "step 1:") {
+			t.Errorf("LoadSteps with region %q = %v, want step error", spec, err)
+		}
+	}
+}
+
+func TestRegionChainJSONAndUndo(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("id=1 v=aGk="))
+	p.AddStep("base64", true)
+	p.SetRegion(0, "re:v=(\\S+)")
+	want := string(p.Result())
+	if want != "id=1 v=hi" {
+		t.Fatalf("Result = %q", want)
+	}
+	data, err := p.ExportJSON()
+	if err != nil {
+		t.Fatalf("ExportJSON: %v", err)
+	}
+	if !strings.Contains(string(data), `"region": "re:v=(\\S+)"`) {
+		t.Fatalf("region missing from chain JSON:\n%s", data)
+	}
+	q := New()
+	if err := q.ImportJSON(data); err != nil {
+		t.Fatalf("ImportJSON: %v", err)
+	}
+	if got := string(q.Result()); got != want {
+		t.Fatalf("imported Result = %q, want %q", got, want)
+	}
+
+	// The cache must not return the output of the whole-input step.
+	q.SetRegion(0, "")
+	if err := q.Err(0); err == nil {
+		t.Fatal("Err = nil after clearing the region, want base64 error")
+	}
+	if !q.Undo() || q.Steps()[0].Region != "re:v=(\\S+)" || string(q.Result()) != want {
+		t.Fatalf("Undo did not restore the region: %+v", q.Steps()[0])
+	}
+}
+
+func TestRegionCommandLineRoundTrip(t *testing.T) {
+	p := New()
+	expr := `.url -region 're:q=([^&]+)' | map -split delimiter -pattern , -region=jq:.list .base64 | hex --region 0:4`
+	if err := p.LoadCommandLine(expr); err != nil {
+		t.Fatalf("LoadCommandLine: %v", err)
+	}
+	steps := p.Steps()
+	if steps[0].Region != "re:q=([^&]+)" || steps[1].Region != "jq:.list" || steps[2].Region != "0:4" {
+		t.Fatalf("parsed regions = %q, %q, %q", steps[0].Region, steps[1].Region, steps[2].Region)
+	}
+	cmd := p.CommandLine()
+	if !strings.Contains(cmd, "-region") {
+		t.Fatalf("CommandLine = %q, want region flags", cmd)
+	}
+	q := New()
+	if err := q.LoadCommandLine(cmd); err != nil {
+		t.Fatalf("LoadCommandLine(%q): %v", cmd, err)
+	}
+	if got := q.CommandLine(); got != cmd {
+		t.Fatalf("CommandLine round trip = %q, want %q", got, cmd)
+	}
+	if err := New().LoadCommandLine("hex -region nope"); err == nil || !strings.Contains(err.Error(), "step 1 (hex)") {
+		t.Fatalf("LoadCommandLine with bad region = %v", err)
+	}
+}
+
+func TestRegionStreams(t *testing.T) {
+	p := New()
+	p.SetSource([]byte(`{"a": "aGk=", "b": 1}`))
+	p.AddStep("base64", true)
+	p.SetRegion(0, "jq:.a")
+	p.AddStep("hex", false)
+	p.SetRegion(1, "0:2")
+	want := p.Result()
+	if string(want) != `7b22a": "hi", "b": 1}` {
+		t.Fatalf("Result = %q", want)
+	}
+	var buf bytes.Buffer
+	if err := p.Stream(context.Background(), &buf); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("Stream = %q, Compute = %q", buf.Bytes(), want)
+	}
+}
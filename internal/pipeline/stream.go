@@ -0,0 +1,139 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// StepError reports which step of a streamed chain failed.
+type StepError struct {
+	Index  int // zero-based step index
+	Plugin string
+	Err    error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %d (%s): %s", e.Index+1, e.Plugin, e.Err)
+}
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// errStreamAborted is handed to upstream writers when a downstream step has
+// stopped reading because it failed.
+var errStreamAborted = errors.New("downstream step failed")
+
+// Stream runs the chain over the pipeline source and writes the final result
+// to w, within ctx and the pipeline limits. Manual output overrides are
+// honoured: the chain resumes after the last enabled step that has one.
+func (p *Pipeline) Stream(ctx context.Context, w io.Writer) error {
+	start := 0
+	var r io.Reader = bytes.NewReader(p.source)
+	for i, s := range p.steps {
+		if s.hasOverride && !s.Disabled {
+			start = i + 1
+			r = bytes.NewReader(s.override)
+		}
+	}
+	return p.stream(ctx, r, w, start)
+}
+
+// StreamFrom runs the chain over r instead of the pipeline source and writes
+// the final result to w, within ctx and the pipeline limits. Manual output
+// overrides belong to the source they were made against, so they are ignored.
+func (p *Pipeline) StreamFrom(ctx context.Context, r io.Reader, w io.Writer) error {
+	return p.stream(ctx, r, w, 0)
+}
+
+// stream connects the enabled steps from index start onward with io.Pipe and
+// runs each one in its own goroutine, so data flows through the chain without
+// buffering whole intermediate outputs. Step outputs, errors and undo history
+// of the pipeline are left untouched.
+func (p *Pipeline) stream(ctx context.Context, r io.Reader, w io.Writer, start int) error {
+	type stage struct {
+		index int
+		step  *Step
+		fn    types.ContextTransformFunc
+		fs    *flag.FlagSet
+	}
+	ctx, cancel := p.limits.chainContext(ctx)
+	defer cancel()
+
+	var stages []stage
+	for i := start; i < len(p.steps); i++ {
+		s := p.steps[i]
+		if s.Disabled || s.Plugin == "" {
+			continue
+		}
+		bound, err := p.bindStep(s)
+		if err != nil {
+			return &StepError{Index: i, Plugin: s.Plugin, Err: err}
+		}
+		fn, fs, err := stepTransform(p.limits, bound)
+		if err != nil {
+			return &StepError{Index: i, Plugin: s.Plugin, Err: err}
+		}
+		stages = append(stages, stage{index: i, step: s, fn: fn, fs: fs})
+	}
+	if len(stages) == 0 {
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	// A failing step closes the pipes on both sides of it, which fails its
+	// neighbours too, possibly with errors of their own making. The step
+	// that failed first, before any pipe was closed, is the one to report.
+	var mu sync.Mutex
+	first, firstErr := -1, error(nil)
+	var wg sync.WaitGroup
+	in := r
+	for n, st := range stages {
+		var out io.Writer = w
+		var pw *io.PipeWriter
+		var next *io.PipeReader
+		if n < len(stages)-1 {
+			next, pw = io.Pipe()
+			out = pw
+		}
+		src, _ := in.(*io.PipeReader)
+		wg.Add(1)
+		go func(n int, st stage, in io.Reader) {
+			defer wg.Done()
+			err := runTransform(ctx, p.limits, st.fn, st.fs, in, out)
+			if err != nil {
+				mu.Lock()
+				if first < 0 {
+					first, firstErr = n, err
+				}
+				mu.Unlock()
+			}
+			if src != nil {
+				if err != nil {
+					src.CloseWithError(errStreamAborted)
+				} else {
+					// Let the upstream step finish even if this one did
+					// not consume all of its input.
+					_, _ = io.Copy(io.Discard, src)
+				}
+			}
+			if pw != nil {
+				pw.CloseWithError(err)
+			}
+		}(n, st, in)
+		if next != nil {
+			in = next
+		}
+	}
+	wg.Wait()
+
+	if first >= 0 {
+		return &StepError{Index: stages[first].index, Plugin: stages[first].step.Plugin, Err: firstErr}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/takeshixx/deen/internal/plugins"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// test-copy copies its input but, like many plugins, reports a failed write
+// with an error of its own. test-reject fails without reading its input.
+func init() {
+	for name, fn := range map[string]types.TransformFunc{
+		"test-copy": func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+			if _, err := io.Copy(w, r); err != nil {
+				return errors.New("copy failed")
+			}
+			return nil
+		},
+		"test-reject": func(io.Reader, io.Writer, *flag.FlagSet) error {
+			return errors.New("rejected")
+		},
+	} {
+		err := plugins.Register(func() *types.DeenPlugin {
+			p := types.NewPlugin()
+			p.Name = name
+			p.Category = "misc"
+			p.Process = fn
+			return p
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestStreamMatchesCompute(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("hello streaming world"))
+	p.AddStep("gzip", false)
+	p.AddStep("base64", false)
+	p.AddStep("base64", true)
+	p.AddStep("gzip", true)
+	p.AddStep("hex", false)
+
+	var buf bytes.Buffer
+	if err := p.Stream(context.Background(), &buf); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), p.Result()) {
+		t.Fatalf("Stream = %q, Compute = %q", buf.Bytes(), p.Result())
+	}
+}
+
+func TestStreamFromSkipsDisabledAndIgnoresOverrides(t *testing.T) {
+	p := New()
+	p.SetSource([]byte("saved"))
+	p.AddStep("base64", false)
+	p.AddStep("hex", false)
+	p.SetStepDisabled(1, true)
+	p.EditOutput(0, []byte("edited"))
+
+	var buf bytes.Buffer
+	if err := p.StreamFrom(context.Background(), strings.NewReader("test"), &buf); err != nil {
+		t.Fatalf("StreamFrom: %v", err)
+	}
+	if got := buf.String(); got != "dGVzdA==" {
+		t.Fatalf("StreamFrom = %q, want dGVzdA==", got)
+	}
+
+	buf.Reset()
+	if err := p.Stream(context.Background(), &buf); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if got := buf.String(); got != "edited" {
+		t.Fatalf("Stream = %q, want override", got)
+	}
+}
+
+func TestStreamReportsFailingStep(t *testing.T) {
+	p := New()
+	p.AddStep("base64", false)
+	p.AddStep("hex", true)
+	p.AddStep("hex", false)
+
+	err := p.StreamFrom(context.Background(), strings.NewReader("test"), &bytes.Buffer{})
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) {
+		t.Fatalf("StreamFrom err = %v, want *StepError", err)
+	}
+	if stepErr.Index != 1 || stepErr.Plugin != "hex" {
+		t.Fatalf("StepError = %d (%s), want 1 (hex)", stepErr.Index, stepErr.Plugin)
+	}
+	if !strings.HasPrefix(err.Error(), "step 2 (hex): ") {
+		t.Fatalf("error = %q", err)
+	}
+}
+
+func TestStreamReportsStepThatClosedThePipe(t *testing.T) {
+	// test-reject closes its input, which fails the copy upstream with an
+	// unrelated error.
+	p := New()
+	p.AddStep("test-copy", false)
+	p.AddStep("test-reject", false)
+
+	input := strings.NewReader(strings.Repeat("x", 1<<20))
+	err := p.StreamFrom(context.Background(), input, &bytes.Buffer{})
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.Index != 1 || stepErr.Err.Error() != "rejected" {
+		t.Fatalf("StreamFrom err = %v, want step 2 (test-reject)", err)
+	}
+}
+
+func TestStreamDoesNotBlockOnUnreadInput(t *testing.T) {
+	// magic only reads a short prefix; upstream steps must still finish.
+	p := New()
+	p.AddStep("hex", false)
+	p.AddStep("magic", false)
+
+	input := strings.NewReader(strings.Repeat("x", 1<<20))
+	var buf bytes.Buffer
+	if err := p.StreamFrom(context.Background(), input, &buf); err != nil {
+		t.Fatalf("StreamFrom: %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("expected magic output")
+	}
+}
+
+func TestLoadJSONDoesNotCompute(t *testing.T) {
+	p := New()
+	if err := p.LoadJSON([]byte(`{"version":1,"source":"ZEdWemRBPT0=","steps":[{"plugin":"b64","unprocess":true}]}`)); err != nil {
+		t.Fatalf("LoadJSON: %v", err)
+	}
+	if p.Len() != 1 || p.Steps()[0].Plugin != "base64" {
+		t.Fatalf("steps = %+v", p.Steps())
+	}
+	if p.Output(0) != nil {
+		t.Fatalf("LoadJSON computed output %q", p.Output(0))
+	}
+	var buf bytes.Buffer
+	if err := p.Stream(context.Background(), &buf); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if got := buf.String(); got != "test" {
+		t.Fatalf("Stream = %q, want test", got)
+	}
+}
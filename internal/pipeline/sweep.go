@@ -0,0 +1,314 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Score modes of a sweep.
+const (
+	ScoreAuto      = "auto"
+	ScorePrintable = "printable"
+	ScoreEntropy   = "entropy"
+	ScoreMagic     = "magic"
+	ScoreCrib      = "crib"
+)
+
+// ScoreModes lists the score modes in the order editors offer them.
+var ScoreModes = []string{ScoreAuto, ScorePrintable, ScoreEntropy, ScoreMagic, ScoreCrib}
+
+// MaxSweepRuns bounds the option combinations of one sweep.
+const MaxSweepRuns = 4096
+
+// Sweep runs one plugin over every combination of a set of option values and
+// ranks the outputs, e.g. to recover an unknown XOR byte or LZW bit order.
+type Sweep struct {
+	Plugin    string
+	Unprocess bool
+	Options   map[string]string // options every run uses
+	Params    []SweepParam      // options varied between runs
+	Score     string            // one of ScoreModes; ScoreAuto when empty
+	Crib      string            // text a correct output is expected to contain
+}
+
+// SweepParam is an option a sweep varies and the values it takes.
+type SweepParam struct {
+	Name   string
+	Values []string
+}
+
+// SweepResult is the output of one sweep run, scored from 0 to 100.
+type SweepResult struct {
+	Options   map[string]string `json:"options"` // values of the varied options
+	Score     int               `json:"score"`
+	Printable int               `json:"printable"` // percentage of printable bytes
+	Letters   int               `json:"letters"`   // percentage of ASCII letters and spaces
+	Entropy   float64           `json:"entropy"`   // Shannon entropy in bits per byte
+	Magic     string            `json:"magic,omitempty"`
+	Crib      bool              `json:"crib,omitempty"`
+	Bytes     int               `json:"bytes"`
+	Preview   string            `json:"preview"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// ParseSweepParam parses NAME=VALUES, where VALUES is a comma separated list
+// of values and integer ranges such as 0x00..0xff or 1..8. Values of a range
+// keep the base of its start, so 0x00..0xff yields 0x00, 0x01 and so on. A
+// VALUES of * stands for every choice of the option, or true and false for
+// switches.
+func ParseSweepParam(spec string) (SweepParam, error) {
+	name, values, ok := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.TrimSpace(values) == "" {
+		return SweepParam{}, fmt.Errorf("invalid sweep %q: want NAME=VALUES", spec)
+	}
+	param := SweepParam{Name: name}
+	for _, v := range strings.Split(values, ",") {
+		v = strings.TrimSpace(v)
+		from, to, isRange := strings.Cut(v, "..")
+		if !isRange {
+			param.Values = append(param.Values, v)
+			continue
+		}
+		expanded, err := sweepRange(from, to)
+		if err != nil {
+			return SweepParam{}, fmt.Errorf("invalid sweep %q: %w", spec, err)
+		}
+		param.Values = append(param.Values, expanded...)
+	}
+	if len(param.Values) > MaxSweepRuns {
+		return SweepParam{}, fmt.Errorf("invalid sweep %q: more than %d values", spec, MaxSweepRuns)
+	}
+	return param, nil
+}
+
+func sweepRange(from, to string) ([]string, error) {
+	lo, err := strconv.ParseInt(from, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range start %q", from)
+	}
+	hi, err := strconv.ParseInt(to, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range end %q", to)
+	}
+	if hi < lo {
+		return nil, fmt.Errorf("range %s..%s ends before it starts", from, to)
+	}
+	// The distance of wide ranges does not fit in an int64, but it does in a
+	// uint64.
+	span := uint64(hi) - uint64(lo)
+	if span >= MaxSweepRuns {
+		return nil, fmt.Errorf("range %s..%s has more than %d values", from, to, MaxSweepRuns)
+	}
+	hexDigits := 0
+	if strings.HasPrefix(from, "0x") || strings.HasPrefix(from, "0X") {
+		hexDigits = len(from) - 2
+	}
+	values := make([]string, 0, span+1)
+	// Count instead of comparing n with hi, which would wrap at MaxInt64.
+	for i, n := uint64(0), lo; i <= span; i, n = i+1, n+1 {
+		if hexDigits > 0 {
+			values = append(values, fmt.Sprintf("0x%0*x", hexDigits, n))
+		} else {
+			values = append(values, strconv.FormatInt(n, 10))
+		}
+	}
+	return values, nil
+}
+
+// String formats a parameter in the form ParseSweepParam reads. Runs of
+// consecutive values are not folded back into ranges.
+func (sp SweepParam) String() string {
+	return sp.Name + "=" + strings.Join(sp.Values, ",")
+}
+
+// Step returns the step that produces r, with the fixed and varied options.
+func (s Sweep) Step(r SweepResult) Step {
+	opts := maps.Clone(s.Options)
+	if opts == nil {
+		opts = map[string]string{}
+	}
+	maps.Copy(opts, r.Options)
+	return Step{Plugin: s.Plugin, Unprocess: s.Unprocess, Options: opts}
+}
+
+// Runs checks the sweep and returns the varied options of every run, in the
+// order Run tries them.
+func (s Sweep) Runs() ([]map[string]string, error) {
+	if !slices.Contains(ScoreModes, s.score()) {
+		return nil, fmt.Errorf("unknown score mode %q (modes: %s)", s.Score, strings.Join(ScoreModes, ", "))
+	}
+	if s.score() == ScoreCrib && s.Crib == "" {
+		return nil, errors.New("crib scoring needs a crib")
+	}
+	if len(s.Params) == 0 {
+		return nil, errors.New("sweep needs at least one option to vary")
+	}
+	if _, err := checkedSteps([]Step{{Plugin: s.Plugin, Unprocess: s.Unprocess, Options: s.Options}}); err != nil {
+		return nil, err
+	}
+	options := map[string]Option{}
+	for _, opt := range PluginOptions(s.Plugin) {
+		options[opt.Name] = opt
+	}
+	runs := []map[string]string{{}}
+	for _, param := range s.Params {
+		opt, ok := options[param.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s has no option %q", s.Plugin, param.Name)
+		}
+		values := param.Values
+		if len(values) == 1 && values[0] == "*" {
+			switch {
+			case opt.IsBool:
+				values = []string{"false", "true"}
+			case len(opt.Choices) > 0:
+				values = opt.Choices
+			default:
+				return nil, fmt.Errorf("option %s has no fixed set of values for *", param.Name)
+			}
+		}
+		for _, v := range values {
+			if err := opt.Check(v); err != nil {
+				return nil, fmt.Errorf("option %s: %w", param.Name, err)
+			}
+		}
+		if len(runs)*len(values) > MaxSweepRuns {
+			return nil, fmt.Errorf("sweep has more than %d runs", MaxSweepRuns)
+		}
+		next := make([]map[string]string, 0, len(runs)*len(values))
+		for _, run := range runs {
+			for _, v := range values {
+				opts := maps.Clone(run)
+				opts[param.Name] = v
+				next = append(next, opts)
+			}
+		}
+		runs = next
+	}
+	return runs, nil
+}
+
+// Run runs the sweep on in within the limits l and returns the results best
+// first. Runs that fail are ranked last with their error. The chain timeout
+// of l bounds the whole sweep.
+func (s Sweep) Run(ctx context.Context, l Limits, in []byte) ([]SweepResult, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := l.chainContext(ctx)
+	defer cancel()
+	results := make([]SweepResult, 0, len(runs))
+	for _, opts := range runs {
+		r := SweepResult{Options: opts}
+		step := s.Step(r)
+		out, err := runStep(ctx, l, &step, in)
+		if err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				return nil, cause
+			}
+			r.Error = err.Error()
+		} else {
+			s.rate(&r, out)
+		}
+		results = append(results, r)
+	}
+	slices.SortStableFunc(results, func(a, b SweepResult) int {
+		if (a.Error == "") != (b.Error == "") {
+			if a.Error == "" {
+				return -1
+			}
+			return 1
+		}
+		return b.Score - a.Score
+	})
+	return results, nil
+}
+
+func (s Sweep) score() string {
+	if s.Score == "" {
+		return ScoreAuto
+	}
+	return s.Score
+}
+
+// rate fills in the measures and score of r from the output out. Auto
+// scoring ranks crib hits first, then outputs with known magic bytes, then
+// text by printability and the share of letters, and to a lesser extent by
+// low entropy.
+func (s Sweep) rate(r *SweepResult, out []byte) {
+	r.Bytes = len(out)
+	r.Printable = printablePercent(out)
+	r.Entropy = float64(int(entropy(out)*100)) / 100
+	r.Letters = lettersPercent(out)
+	r.Magic = magicType(out)
+	r.Crib = s.Crib != "" && bytes.Contains(out, []byte(s.Crib))
+	r.Preview = sweepPreview(out)
+
+	low := int((8 - entropy(out)) * 100 / 8)
+	switch s.score() {
+	case ScorePrintable:
+		r.Score = r.Printable
+	case ScoreEntropy:
+		r.Score = low
+	case ScoreMagic:
+		r.Score = boolScore(r.Magic != "")
+	case ScoreCrib:
+		r.Score = boolScore(r.Crib)
+	default:
+		switch {
+		case r.Crib:
+			r.Score = 100
+		case r.Magic != "":
+			r.Score = 95
+		default:
+			r.Score = (2*r.Printable + 2*r.Letters + low) * 90 / 500
+		}
+	}
+}
+
+func lettersPercent(data []byte) int {
+	if len(data) == 0 {
+		return 0
+	}
+	var letters int
+	for _, b := range data {
+		if b == ' ' || (b|0x20 >= 'a' && b|0x20 <= 'z') {
+			letters++
+		}
+	}
+	return letters * 100 / len(data)
+}
+
+func boolScore(hit bool) int {
+	if hit {
+		return 100
+	}
+	return 0
+}
+
+// sweepPreview returns the start of out on one line, with bytes that are not
+// printable ASCII shown as dots.
+func sweepPreview(out []byte) string {
+	const limit = 48
+	var b strings.Builder
+	for i, c := range out {
+		if i == limit {
+			b.WriteString("...")
+			break
+		}
+		if c >= 0x20 && c < 0x7f {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package pipeline
+
+import (
+	"bytes"
+	"compress/lzw"
+	"context"
+	"strings"
+	"testing"
+)
+
+func xorBytes(data string, key byte) []byte {
+	out := []byte(data)
+	for i := range out {
+		out[i] ^= key
+	}
+	return out
+}
+
+func TestParseSweepParam(t *testing.T) {
+	for _, tt := range []struct{ spec, want string }{
+		{"value=0x00..0x03", "value=0x00,0x01,0x02,0x03"},
+		{"order=0,1", "order=0,1"},
+		{"lit-width=6..8,2", "lit-width=6,7,8,2"},
+		{"unit=*", "unit=*"},
+		{"value=9223372036854775806..9223372036854775807", "value=9223372036854775806,9223372036854775807"},
+	} {
+		p, err := ParseSweepParam(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseSweepParam(%q): %v", tt.spec, err)
+		}
+		if got := p.String(); got != tt.want {
+			t.Errorf("ParseSweepParam(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+	for _, spec := range []string{"value", "=1", "value=", "value=3..1", "value=a..b", "value=0..100000",
+		"value=-0x7fffffffffffffff..0x7fffffffffffffff", "value=-9223372036854775808..9223372036854775807"} {
+		if _, err := ParseSweepParam(spec); err == nil {
+			t.Errorf("ParseSweepParam(%q) = nil error", spec)
+		}
+	}
+}
+
+func TestSweepRecoversXORKey(t *testing.T) {
+	in := xorBytes("attack at dawn, bring the flag", 0x5a)
+	for _, tt := range []struct {
+		score, crib string
+	}{
+		{ScoreAuto, ""},
+		{ScoreCrib, "flag"},
+	} {
+		s := Sweep{Plugin: "xor", Params: []SweepParam{mustSweepParam(t, "value=0x00..0xff")}, Score: tt.score, Crib: tt.crib}
+		results, err := s.Run(context.Background(), DefaultLimits(), in)
+		if err != nil {
+			t.Fatalf("%s: Run: %v", tt.score, err)
+		}
+		if len(results) != 256 {
+			t.Fatalf("%s: %d results", tt.score, len(results))
+		}
+		best := results[0]
+		if v := best.Options["value"]; v != "0x5a" {
+			t.Fatalf("%s: best value = %s (score %d, %q)", tt.score, v, best.Score, best.Preview)
+		}
+		if got := s.Step(best); got.Plugin != "xor" || got.Options["value"] != best.Options["value"] {
+			t.Fatalf("%s: Step = %+v", tt.score, got)
+		}
+	}
+}
+
+func TestSweepScores(t *testing.T) {
+	s := Sweep{Crib: "flag"}
+	for _, tt := range []struct {
+		score, out string
+		want       int
+	}{
+		{ScorePrintable, "ab\x00\x01", 50},
+		{ScoreEntropy, "aaaa", 100},
+		{ScoreEntropy, "\x00\x01", 87},
+		{ScoreMagic, "%PDF-1.7", 100},
+		{ScoreCrib, "the flag", 100},
+		{ScoreCrib, "the FLAG", 0},
+		{ScoreAuto, "the flag", 100},
+		{ScoreAuto, "GIF89a", 95},
+		{ScoreAuto, "plain text", 82},
+		{ScoreAuto, ";..;91z;.z>;", 54},
+	} {
+		s.Score = tt.score
+		var r SweepResult
+		s.rate(&r, []byte(tt.out))
+		if r.Score != tt.want {
+			t.Errorf("%s score of %q = %d, want %d (%+v)", tt.score, tt.out, r.Score, tt.want, r)
+		}
+	}
+}
+
+func TestSweepRanksMagicAndErrors(t *testing.T) {
+	var buf bytes.Buffer
+	w := lzw.NewWriter(&buf, lzw.MSB, 8)
+	w.Write([]byte("\x1f\x8b\x08 looks like gzip inside lzw"))
+	w.Close()
+	s := Sweep{Plugin: "lzw", Unprocess: true, Params: []SweepParam{mustSweepParam(t, "order=0,1")}, Score: ScoreMagic}
+	results, err := s.Run(context.Background(), DefaultLimits(), buf.Bytes())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if results[0].Options["order"] != "1" || results[0].Magic != "gzip data" || results[0].Score != 100 {
+		t.Fatalf("best = %+v", results[0])
+	}
+
+	s = Sweep{Plugin: "base64", Unprocess: true, Params: []SweepParam{mustSweepParam(t, "strict=*")}}
+	results, err = s.Run(context.Background(), DefaultLimits(), []byte("aGk"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if results[0].Options["strict"] != "false" || results[0].Preview != "hi" || results[1].Error == "" {
+		t.Fatalf("results = %+v", results)
+	}
+}
+
+func TestSweepValidation(t *testing.T) {
+	for _, tt := range []struct {
+		sweep Sweep
+		want  string
+	}{
+		{Sweep{Plugin: "xor"}, "at least one option"},
+		{Sweep{Plugin: "nope", Params: []SweepParam{{"value", []string{"1"}}}}, `unknown plugin "nope"`},
+		{Sweep{Plugin: "xor", Params: []SweepParam{{"key", []string{"1"}}}}, `xor has no option "key"`},
+		{Sweep{Plugin: "xor", Params: []SweepParam{{"value", []string{"300"}}}}, "option value"},
+		{Sweep{Plugin: "xor", Params: []SweepParam{{"value", []string{"*"}}}}, "no fixed set"},
+		{Sweep{Plugin: "xor", Params: []SweepParam{{"value", []string{"1"}}}, Score: "nope"}, "unknown score mode"},
+		{Sweep{Plugin: "xor", Params: []SweepParam{{"value", []string{"1"}}}, Score: ScoreCrib}, "needs a crib"},
+		{Sweep{Plugin: "aes", Params: []SweepParam{mustSweepParam(t, "key=0..99"), mustSweepParam(t, "iv=0..99")}}, "more than 4096 runs"},
+	} {
+		if _, err := tt.sweep.Runs(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Runs(%+v) = %v, want %q", tt.sweep, err, tt.want)
+		}
+	}
+}
+
+func mustSweepParam(t *testing.T, spec string) SweepParam {
+	t.Helper()
+	p, err := ParseSweepParam(spec)
+	if err != nil {
+		t.Fatalf("ParseSweepParam(%q): %v", spec, err)
+	}
+	return p
+}
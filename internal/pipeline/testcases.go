@@ -0,0 +1,135 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Expectation describes the result a chain should produce. Every field that
+// is set must hold; an empty expectation only requires the chain to succeed.
+type Expectation struct {
+	Output       string `json:"output,omitempty"`       // exact result as text
+	OutputBase64 string `json:"outputBase64,omitempty"` // exact result as Base64
+	Contains     string `json:"contains,omitempty"`     // text the result contains
+	SHA256       string `json:"sha256,omitempty"`       // hex digest of the result
+	Error        string `json:"error,omitempty"`        // text the expected error contains
+}
+
+// TestCase is a regression test carried by a chain file, a preset or an
+// example: an input, optional parameter values and the expected result.
+type TestCase struct {
+	Name        string            `json:"name,omitempty"`
+	Input       string            `json:"input,omitempty"`
+	InputBase64 string            `json:"inputBase64,omitempty"`
+	Params      map[string]string `json:"params,omitempty"`
+	Expectation
+}
+
+// InputBytes returns the decoded test input.
+func (tc TestCase) InputBytes() ([]byte, error) {
+	if tc.InputBase64 == "" {
+		return []byte(tc.Input), nil
+	}
+	if tc.Input != "" {
+		return nil, fmt.Errorf("input and inputBase64 are both set")
+	}
+	b, err := base64.StdEncoding.DecodeString(tc.InputBase64)
+	if err != nil {
+		return nil, fmt.Errorf("inputBase64: %w", err)
+	}
+	return b, nil
+}
+
+// Check compares the outcome of a chain run with the expectation and returns
+// an error describing the first mismatch.
+func (e Expectation) Check(out []byte, err error) error {
+	if e.Error != "" {
+		if err == nil {
+			return fmt.Errorf("succeeded, want error containing %q", e.Error)
+		}
+		if !strings.Contains(err.Error(), e.Error) {
+			return fmt.Errorf("error %q does not contain %q", err, e.Error)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if e.Output != "" && string(out) != e.Output {
+		return fmt.Errorf("output %s, want %q", excerpt(out), e.Output)
+	}
+	if e.OutputBase64 != "" {
+		want, derr := base64.StdEncoding.DecodeString(e.OutputBase64)
+		if derr != nil {
+			return fmt.Errorf("outputBase64: %w", derr)
+		}
+		if !bytes.Equal(out, want) {
+			return fmt.Errorf("output %s, want %s", base64.StdEncoding.EncodeToString(out), e.OutputBase64)
+		}
+	}
+	if e.Contains != "" && !bytes.Contains(out, []byte(e.Contains)) {
+		return fmt.Errorf("output %s does not contain %q", excerpt(out), e.Contains)
+	}
+	if e.SHA256 != "" {
+		sum := sha256.Sum256(out)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, e.SHA256) {
+			return fmt.Errorf("output SHA-256 %s, want %s", got, strings.ToLower(e.SHA256))
+		}
+	}
+	return nil
+}
+
+// excerpt quotes the start of out for failure messages.
+func excerpt(out []byte) string {
+	const excerptLen = 120
+	if len(out) > excerptLen {
+		return fmt.Sprintf("%q... (%d bytes)", out[:excerptLen], len(out))
+	}
+	return fmt.Sprintf("%q", out)
+}
+
+// Tests returns the test cases of the chain.
+func (p *Pipeline) Tests() []TestCase {
+	return append([]TestCase(nil), p.tests...)
+}
+
+// RunTest streams the input of tc through the chain with the parameter values
+// of tc and checks the result. It returns nil when the test passes. The
+// pipeline's own parameter values are left untouched.
+func (p *Pipeline) RunTest(ctx context.Context, tc TestCase) error {
+	in, err := tc.InputBytes()
+	if err != nil {
+		return err
+	}
+	saved := p.params.clone()
+	defer func() { p.params = saved }()
+	for _, name := range sortedOptionNames(tc.Params) {
+		if err := p.checkParam(name, tc.Params[name]); err != nil {
+			return err
+		}
+		p.bindParam(name, tc.Params[name])
+	}
+	var out bytes.Buffer
+	err = p.StreamFrom(ctx, bytes.NewReader(in), &out)
+	return tc.Check(out.Bytes(), err)
+}
+
+func cloneTests(tests []TestCase) []TestCase {
+	out := make([]TestCase, 0, len(tests))
+	for _, tc := range tests {
+		if tc.Params != nil {
+			params := make(map[string]string, len(tc.Params))
+			for k, v := range tc.Params {
+				params[k] = v
+			}
+			tc.Params = params
+		}
+		out = append(out, tc)
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpectationCheck(t *testing.T) {
+	for _, tc := range []struct {
+		want Expectation
+		out  string
+		err  error
+		fail string
+	}{
+		{Expectation{}, "x", nil, ""},
+		{Expectation{}, "", errors.New("boom"), "boom"},
+		{Expectation{Output: "deen"}, "deen", nil, ""},
+		{Expectation{Output: "deen"}, "deen\n", nil, `output "deen\n", want "deen"`},
+		{Expectation{OutputBase64: "ZGVlbg=="}, "deen", nil, ""},
+		{Expectation{OutputBase64: "ZGVlbg=="}, "dee", nil, "want ZGVlbg=="},
+		{Expectation{Contains: "ee"}, "deen", nil, ""},
+		{Expectation{Contains: "x"}, "deen", nil, `does not contain "x"`},
+		{Expectation{SHA256: "2A2E6B16B0E2D0A5A2D0D9A3AE4D5AA4AB9B5EA1C15A5C3A5A69C5F6CF1BBA3E"}, "deen", nil, "output SHA-256"},
+		{Expectation{Error: "step 2"}, "", errors.New("step 2 (gzip): bad"), ""},
+		{Expectation{Error: "step 2"}, "", errors.New("step 1 (hex): bad"), `does not contain "step 2"`},
+		{Expectation{Error: "step 2"}, "ok", nil, "succeeded"},
+	} {
+		err := tc.want.Check([]byte(tc.out), tc.err)
+		switch {
+		case tc.fail == "" && err != nil:
+			t.Errorf("%+v.Check(%q, %v) = %v", tc.want, tc.out, tc.err, err)
+		case tc.fail != "" && (err == nil || !strings.Contains(err.Error(), tc.fail)):
+			t.Errorf("%+v.Check(%q, %v) = %v, want %q", tc.want, tc.out, tc.err, err, tc.fail)
+		}
+	}
+}
+
+func TestChainFileTests(t *testing.T) {
+	p := New()
+	err := p.LoadJSON([]byte(`{"version":1,"params":[{"name":"upper","type":"bool","default":"false"}],
+		"steps":[{"plugin":"base32","options":{"hex":"${upper}"}}],
+		"tests":[{"name":"std","input":"f","output":"MY======"},{"name":"hex","inputBase64":"Zg==","params":{"upper":"true"},"output":"CO======"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := p.Tests()
+	if len(tests) != 2 {
+		t.Fatalf("tests = %+v", tests)
+	}
+	for _, tc := range tests {
+		if err := p.RunTest(context.Background(), tc); err != nil {
+			t.Errorf("%s: %v", tc.Name, err)
+		}
+	}
+	if v, _ := p.ParamValue("upper"); v != "false" {
+		t.Fatalf("RunTest left upper = %q bound", v)
+	}
+
+	data, err := p.ExportJSONWithoutSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := New()
+	if err := q.LoadJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := q.Tests(); len(got) != 2 || got[1].Params["upper"] != "true" || got[1].Output != "CO======" {
+		t.Fatalf("round trip tests = %+v", got)
+	}
+
+	for _, bad := range []string{
+		`{"version":1,"steps":[],"tests":[{"input":"a","inputBase64":"YQ=="}]}`,
+		`{"version":1,"steps":[],"tests":[{"inputBase64":"!"}]}`,
+		`{"version":1,"steps":[],"tests":[{"params":{"nope":"1"}}]}`,
+	} {
+		if err := New().LoadJSON([]byte(bad)); err == nil {
+			t.Errorf("LoadJSON(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestExportWithoutSourceDropsSecretTestParams(t *testing.T) {
+	p := New()
+	err := p.LoadJSON([]byte(`{"version":1,"params":[{"name":"key","secret":true},{"name":"alg","default":"sha256"}],
+		"steps":[{"plugin":"hmac","options":{"key":"${key}","alg":"${alg}"}}],
+		"tests":[{"name":"known","input":"a","params":{"key":"hunter2","alg":"sha1"},"output":"x"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := p.ExportJSONWithoutSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Fatalf("export leaks a secret test parameter:\n%s", data)
+	}
+	q := New()
+	if err := q.LoadJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := q.Tests(); len(got) != 1 || got[0].Params["alg"] != "sha1" {
+		t.Fatalf("round trip tests = %+v", got)
+	}
+	if got := p.Tests()[0].Params["key"]; got != "hunter2" {
+		t.Fatalf("export changed the pipeline's test params: key = %q", got)
+	}
+	if data, _ := p.ExportJSON(); !bytes.Contains(data, []byte("hunter2")) {
+		t.Fatalf("ExportJSON dropped a test parameter:\n%s", data)
+	}
+}
+
+func TestApplyExampleCarriesTest(t *testing.T) {
+	example := BuiltinExamples()[0]
+	p := New()
+	p.ApplyExample(example)
+	tests := p.Tests()
+	if len(tests) != 1 || tests[0].Contains != example.Want.Contains {
+		t.Fatalf("tests = %+v", tests)
+	}
+	if err := p.RunTest(context.Background(), tests[0]); err != nil {
+		t.Fatal(err)
+	}
+}
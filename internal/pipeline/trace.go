@@ -0,0 +1,158 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"io"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/takeshixx/deen/internal/plugins"
+)
+
+// redacted replaces secret option values in traces.
+const redacted = "[redacted]"
+
+// StepTrace records what one step of a traced chain run did.
+type StepTrace struct {
+	Step         int               `json:"step"` // one-based index in the chain
+	Plugin       string            `json:"plugin"`
+	Direction    string            `json:"direction"` // "process" or "unprocess"
+	Options      map[string]string `json:"options,omitempty"`
+	InputBytes   int64             `json:"input_bytes"`
+	OutputBytes  int64             `json:"output_bytes"`
+	InputSHA256  string            `json:"input_sha256"`
+	OutputSHA256 string            `json:"output_sha256,omitempty"`
+	WallTime     time.Duration     `json:"wall_time_ns"`
+	AllocBytes   uint64            `json:"alloc_bytes"` // heap allocated while the step ran
+	Error        string            `json:"error,omitempty"`
+}
+
+// Trace runs the chain over the pipeline source like Stream and writes the
+// final result to w, but runs the steps one after another with every
+// intermediate output held in memory, so each step can be measured on its
+// own. fn is called after each enabled step with its trace and output;
+// returning an error stops the run.
+//
+// AllocBytes is an estimate: it is the growth of the process-wide allocation
+// counter and includes whatever else the process allocated meanwhile.
+func (p *Pipeline) Trace(ctx context.Context, w io.Writer, fn func(StepTrace, []byte) error) error {
+	start := 0
+	data := p.source
+	for i, s := range p.steps {
+		if s.hasOverride && !s.Disabled {
+			start = i + 1
+			data = s.override
+		}
+	}
+	return p.trace(ctx, data, w, start, fn)
+}
+
+// TraceFrom is Trace over r instead of the pipeline source. Like StreamFrom,
+// it ignores manual output overrides.
+func (p *Pipeline) TraceFrom(ctx context.Context, r io.Reader, w io.Writer, fn func(StepTrace, []byte) error) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return p.trace(ctx, data, w, 0, fn)
+}
+
+func (p *Pipeline) trace(ctx context.Context, data []byte, w io.Writer, start int, fn func(StepTrace, []byte) error) error {
+	ctx, cancel := p.limits.chainContext(ctx)
+	defer cancel()
+
+	for i := start; i < len(p.steps); i++ {
+		s := p.steps[i]
+		if s.Disabled || s.Plugin == "" {
+			continue
+		}
+		t := StepTrace{
+			Step:        i + 1,
+			Plugin:      s.Plugin,
+			Direction:   "process",
+			InputBytes:  int64(len(data)),
+			InputSHA256: sha256Hex(data),
+		}
+		if s.Unprocess {
+			t.Direction = "unprocess"
+		}
+		var out []byte
+		err := p.traceStep(ctx, s, &t, data, &out)
+		if err != nil {
+			t.Error = err.Error()
+		} else {
+			t.OutputBytes = int64(len(out))
+			t.OutputSHA256 = sha256Hex(out)
+		}
+		if ferr := fn(t, out); ferr != nil {
+			return ferr
+		}
+		if err != nil {
+			return &StepError{Index: i, Plugin: s.Plugin, Err: err}
+		}
+		data = out
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// traceStep runs s over in, storing its output in out and its effective
+// options, wall time and allocations in t.
+func (p *Pipeline) traceStep(ctx context.Context, s *Step, t *StepTrace, in []byte, out *[]byte) error {
+	bound, err := p.bindStep(s)
+	if err != nil {
+		return err
+	}
+	fn, fs, err := stepTransform(p.limits, bound)
+	if err != nil {
+		return err
+	}
+	t.Options = p.traceOptions(s, fs)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	var buf bytes.Buffer
+	err = runTransform(ctx, p.limits, fn, fs, bytes.NewReader(in), &buf)
+	t.WallTime = time.Since(start)
+	runtime.ReadMemStats(&after)
+	t.AllocBytes = after.TotalAlloc - before.TotalAlloc
+	*out = buf.Bytes()
+	return err
+}
+
+// traceOptions returns the values every option of s runs with, defaults
+// included. Secret options and options that reference a secret parameter are
+// redacted.
+func (p *Pipeline) traceOptions(s *Step, fs *flag.FlagSet) map[string]string {
+	plugin, _, _ := plugins.Resolve(s.Plugin)
+	opts := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if spec, _ := plugin.OptionSpec(f.Name); spec.Secret || p.refsSecretParam(s.Options[f.Name]) {
+			value = redacted
+		}
+		opts[f.Name] = value
+	})
+	if len(opts) == 0 {
+		return nil
+	}
+	return opts
+}
+
+func (p *Pipeline) refsSecretParam(value string) bool {
+	return slices.ContainsFunc(paramRefRe.FindAllStringSubmatch(value, -1), func(m []string) bool {
+		pr, ok := p.params.lookup(m[1])
+		return ok && pr.Secret
+	})
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTraceMatchesStream(t *testing.T) {
+	p := New()
+	p.AddStep("base64", false)
+	p.AddStep("hex", false)
+	p.SetStepDisabled(1, true)
+	p.AddStep("base64", true)
+
+	var traces []StepTrace
+	var outputs []string
+	var buf bytes.Buffer
+	err := p.TraceFrom(context.Background(), strings.NewReader("deen"), &buf, func(st StepTrace, out []byte) error {
+		traces = append(traces, st)
+		outputs = append(outputs, string(out))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TraceFrom: %v", err)
+	}
+	if got := buf.String(); got != "deen" {
+		t.Fatalf("output = %q, want deen", got)
+	}
+	if len(traces) != 2 || traces[0].Step != 1 || traces[1].Step != 3 {
+		t.Fatalf("traces = %+v, want steps 1 and 3", traces)
+	}
+	first := traces[0]
+	if first.Direction != "process" || first.InputBytes != 4 || first.OutputBytes != 8 || outputs[0] != "ZGVlbg==" {
+		t.Fatalf("first trace = %+v, output %q", first, outputs[0])
+	}
+	if first.InputSHA256 != sha256Hex([]byte("deen")) || first.OutputSHA256 != traces[1].InputSHA256 {
+		t.Fatalf("digests do not chain: %+v", traces)
+	}
+	if traces[1].Direction != "unprocess" {
+		t.Fatalf("direction = %q, want unprocess", traces[1].Direction)
+	}
+}
+
+func TestTraceRedactsSecrets(t *testing.T) {
+	p := New()
+	if err := p.ImportJSON([]byte(`{"version":1,"params":[{"name":"key","secret":true,"default":"k1"}],"steps":[{"plugin":"hmac","options":{"key":"${key}"}},{"plugin":"aes","options":{"key":"000102030405060708090a0b0c0d0e0f","mode":"ctr","iv":"000102030405060708090a0b0c0d0e0f"}}]}`)); err != nil {
+		t.Fatalf("ImportJSON: %v", err)
+	}
+	var traces []StepTrace
+	err := p.TraceFrom(context.Background(), strings.NewReader("deen"), &bytes.Buffer{}, func(st StepTrace, _ []byte) error {
+		traces = append(traces, st)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TraceFrom: %v", err)
+	}
+	if got := traces[0].Options["key"]; got != redacted {
+		t.Fatalf("hmac key = %q, want redacted", got)
+	}
+	if got := traces[0].Options["alg"]; got != "sha256" {
+		t.Fatalf("hmac alg = %q, want the default", got)
+	}
+	if got := traces[1].Options["key"]; got != redacted {
+		t.Fatalf("aes key = %q, want redacted", got)
+	}
+}
+
+func TestTraceReportsFailingStep(t *testing.T) {
+	p := New()
+	p.AddStep("base64", false)
+	p.AddStep("hex", true)
+	p.AddStep("hex", false)
+
+	var traces []StepTrace
+	err := p.TraceFrom(context.Background(), strings.NewReader("test"), &bytes.Buffer{}, func(st StepTrace, _ []byte) error {
+		traces = append(traces, st)
+		return nil
+	})
+	var se *StepError
+	if !errors.As(err, &se) || se.Index != 1 {
+		t.Fatalf("err = %v, want step 2 failure", err)
+	}
+	if len(traces) != 2 || traces[1].Error == "" || traces[1].OutputSHA256 != "" {
+		t.Fatalf("traces = %+v, want the failing step last with its error", traces)
+	}
+}
@@ -91,6 +91,8 @@ var pluginLabels = map[string]string{
 	"unicode":           "Unicode",
 	"unicode-inspect":   "Unicode Inspect",
 	"unicode-normalize": "Unicode Normalize",
+	"idna":              "IDNA / Punycode",
+	"bech32":            "Bech32",
 	"ascii":             "ASCII",
 	"pem":               "PEM",
 	"quoted-printable":  "Quoted-Printable",
@@ -322,9 +324,63 @@ var catalogCopyByName = map[string]catalogCopy{
 		[]Example{{"Encode text", "test", "dGVzdA=="}},
 	},
 	"base85": {
-		"Encodes bytes using the denser Ascii85/Base85 representation and decodes it again.",
-		"Use it when you see compact printable data from PostScript/PDF-style tools or need less overhead than Base64.",
-		[]Reference{{"Ascii85 overview", "https://en.wikipedia.org/wiki/Ascii85"}},
+		"Encodes bytes using the denser Ascii85/Base85 representation and decodes it again, as plain Ascii85, Adobe <~ ~> delimited, btoa or ZeroMQ Z85.",
+		"Use it when you see compact printable data from PostScript/PDF streams, btoa mail archives or ZeroMQ keys; decoding detects the variant by default.",
+		[]Reference{{"Ascii85 overview", "https://en.wikipedia.org/wiki/Ascii85"}, {"ZeroMQ Z85", "https://rfc.zeromq.org/spec/32/"}},
+		[]Example{{"Encode with Adobe delimiters", "Hello", "<~87cURDZ~>"}},
+	},
+	"base58": {
+		"Encodes bytes as Base58 text with the Bitcoin/IPFS, Ripple or Flickr alphabet and decodes it again.",
+		"Use it for Bitcoin addresses and keys, IPFS CIDv0 hashes, Solana keys and other identifiers that avoid look-alike characters.",
+		[]Reference{{"Base58 draft", "https://datatracker.ietf.org/doc/html/draft-msporny-base58"}},
+		[]Example{{"Encode text", "Hello World!", "2NEpo7TZRRrLZSi2U"}},
+	},
+	"base58check": {
+		"Encodes bytes as Base58 with a four byte double SHA-256 checksum and verifies the checksum when decoding.",
+		"Use it to decode Bitcoin and Ripple addresses or WIF keys into version byte and payload, or to spot a mistyped address.",
+		[]Reference{{"Base58Check encoding", "https://en.bitcoin.it/wiki/Base58Check_encoding"}},
+		nil,
+	},
+	"base62": {
+		"Encodes bytes as a big number in 0-9, A-Z and a-z and decodes it again.",
+		"Use it for short IDs, URL shortener slugs and tokens that must stay alphanumeric.",
+		[]Reference{{"Base62 overview", "https://en.wikipedia.org/wiki/Base62"}},
+		nil,
+	},
+	"base36": {
+		"Encodes bytes as a big number in 0-9 and a-z and decodes it case-insensitively.",
+		"Use it for case-insensitive identifiers such as Reddit IDs, TinyURL-style slugs or CIDv1 base36 strings.",
+		[]Reference{{"Base36 overview", "https://en.wikipedia.org/wiki/Base36"}},
+		nil,
+	},
+	"base45": {
+		"Encodes bytes with the QR-code friendly Base45 alphabet and decodes it again, optionally behind a prefix such as HC1:.",
+		"Use it for EU Digital COVID Certificates and other QR payloads in alphanumeric mode; follow with zlib and CBOR decoding.",
+		[]Reference{{"RFC 9285", "https://www.rfc-editor.org/rfc/rfc9285"}},
+		[]Example{{"Encode text", "AB", "BB8"}},
+	},
+	"base91": {
+		"Encodes bytes with the 91 character basE91 alphabet and decodes it again.",
+		"Use it when you meet compact basE91 text in CTFs or tools that need less overhead than Base64.",
+		[]Reference{{"basE91", "https://base91.sourceforge.net/"}},
+		nil,
+	},
+	"uuencode": {
+		"Encodes bytes as uuencoded lines between begin and end lines that carry the file mode and name, and decodes them again.",
+		"Use it for attachments in old mail spools, Usenet dumps and droppers; text between the parts of a multi-part message is skipped.",
+		[]Reference{{"uuencoding overview", "https://en.wikipedia.org/wiki/Uuencoding"}},
+		[]Example{{"Encode text", "Cat", "begin 644 data\n#0V%T\n`\nend"}},
+	},
+	"xxencode": {
+		"Encodes bytes like uuencode but with an alphanumeric alphabet, and decodes it again.",
+		"Use it for xxencoded attachments that had to pass EBCDIC gateways.",
+		[]Reference{{"xxencoding overview", "https://en.wikipedia.org/wiki/Xxencoding"}},
+		nil,
+	},
+	"yenc": {
+		"Encodes bytes as yEnc with =ybegin and =yend lines and decodes single or multi-part yEnc, verifying sizes and CRC32 checksums.",
+		"Use it for Usenet binaries and NZB downloads; paste all parts, in any order and with their headers, to reassemble the file.",
+		[]Reference{{"yEnc 1.3 specification", "http://www.yenc.org/yenc-draft.1.3.txt"}},
 		nil,
 	},
 	"ascii": {
@@ -359,7 +415,7 @@ var catalogCopyByName = map[string]catalogCopy{
 	},
 	"unicode-inspect": {
 		"Reports Unicode and text-encoding clues without changing the input bytes.",
-		"Use it to identify BOMs, UTF-8 validity, likely UTF-16/UTF-32 byte order, and suspicious control characters.",
+		"Use it to identify BOMs, UTF-8 validity, likely UTF-16/UTF-32 byte order, suspicious control characters, mixed scripts, and characters that look like ASCII.",
 		referenceSets["unicode"],
 		nil,
 	},
@@ -369,6 +425,18 @@ var catalogCopyByName = map[string]catalogCopy{
 		referenceSets["unicode"],
 		[]Example{{"Compose accent marks", "Cafe\u0301", "Caf\u00e9"}},
 	},
+	"bech32": {
+		"Encodes data as Bech32 or Bech32m with a human-readable part and decodes strings to JSON with the HRP, checksum variant, 5-bit words, bytes and SegWit witness program.",
+		"Use it for Bitcoin SegWit and Taproot addresses, age keys, and Lightning invoices; a checksum error names the character that is most likely mistyped.",
+		[]Reference{{"BIP 173 Bech32", "https://github.com/bitcoin/bips/blob/master/bip-0173.mediawiki"}, {"BIP 350 Bech32m", "https://github.com/bitcoin/bips/blob/master/bip-0350.mediawiki"}},
+		[]Example{{"Decode a BIP 173 test vector", "a12uel5l", `{"hrp":"a","variant":"bech32","words":[],"data":""}`}},
+	},
+	"idna": {
+		"Converts hostnames label by label between Unicode and Punycode (xn--) using the IDNA2008/UTS #46 mapping, and reports mixed scripts and confusable characters with -inspect.",
+		"Use it to read xn-- domains from logs, certificates, and phishing reports, and to check whether a domain imitates another one.",
+		[]Reference{{"RFC 3492 Punycode", "https://www.rfc-editor.org/rfc/rfc3492"}, {"UTS #46 IDNA compatibility processing", "https://www.unicode.org/reports/tr46/"}, {"UTS #39 Unicode security mechanisms", "https://www.unicode.org/reports/tr39/"}},
+		[]Example{{"Decode a homograph domain", "xn--80ak6aa92e.com", "аррӏе.com"}},
+	},
 	"strconv": {
 		"Quotes and unquotes strings using Go-style escape sequences.",
 		"Use it for debugging string literals, control characters, and copied Go or JSON-like escaped text.",
@@ -642,6 +710,9 @@ func UICatalog() []UIPluginInfo {
 				continue
 			}
 			copy := copyForPlugin(p.Name, p.Category)
+			if IsExternal(p.Name) && p.Description != "" {
+				copy.Description = p.Description
+			}
 			infos = append(infos, UIPluginInfo{
 				Name:        p.Name,
 				Label:       PluginLabel(p.Name),
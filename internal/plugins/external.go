@@ -0,0 +1,371 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// External plugins are executables named deen-plugin-<name> that speak a small
+// JSON/stdio protocol:
+//
+//	deen-plugin-<name> describe
+//
+// prints an ExternalDescription as JSON on stdout and exits 0.
+//
+//	deen-plugin-<name> run
+//
+// reads one line of JSON (an ExternalRequest) from stdin, followed by the raw
+// input data until EOF, and writes the raw output to stdout as it goes. A
+// non-zero exit status fails the transform; the plugin's stderr becomes the
+// error message.
+
+// ExternalPrefix is the file name prefix of external plugin executables.
+const ExternalPrefix = "deen-plugin-"
+
+// ExternalProtocol is the protocol version spoken with external plugins.
+const ExternalProtocol = 1
+
+// PluginPathEnv names the environment variable holding extra directories to
+// search for external plugins, separated like PATH.
+const PluginPathEnv = "DEEN_PLUGIN_PATH"
+
+// SearchPathEnv names the environment variable that, set to a true value such
+// as 1, adds the PATH directories to the external plugin search. It is off by
+// default because every deen-plugin-* executable found is run to describe
+// itself at start-up.
+const SearchPathEnv = "DEEN_PLUGIN_SEARCH_PATH"
+
+// stderrLimit bounds the stderr kept from an external plugin. Only its last
+// line is used, for error messages.
+const stderrLimit = 64 << 10
+
+// describeTimeout bounds how long an external plugin may take to describe
+// itself, so a broken executable cannot hang startup.
+const describeTimeout = 5 * time.Second
+
+// waitDelay bounds how long a killed external plugin may keep its pipes
+// open, for example through a child process that inherited them, before
+// Wait gives up on them.
+const waitDelay = 2 * time.Second
+
+// ExternalDescription is the reply of an external plugin to "describe".
+type ExternalDescription struct {
+	Protocol    int              `json:"protocol"`
+	Name        string           `json:"name"`
+	Aliases     []string         `json:"aliases,omitempty"`
+	Category    string           `json:"category,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Decode      bool             `json:"decode,omitempty"` // supports the unprocess direction
+	Options     []ExternalOption `json:"options,omitempty"`
+}
+
+// ExternalOption describes one flag of an external plugin. Type is one of
+// "text" (the default), "number", "bool" or "select".
+type ExternalOption struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Label       string   `json:"label,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Choices     []string `json:"choices,omitempty"`
+	Min         *int     `json:"min,omitempty"`
+	Max         *int     `json:"max,omitempty"`
+	Secret      bool     `json:"secret,omitempty"`
+	Multiline   bool     `json:"multiline,omitempty"`
+}
+
+// ExternalRequest is the header line sent to an external plugin on "run".
+// Options holds the value of every declared option, defaults included.
+type ExternalRequest struct {
+	Protocol  int               `json:"protocol"`
+	Direction string            `json:"direction"` // "process" or "unprocess"
+	Options   map[string]string `json:"options"`
+}
+
+// externalPaths maps external plugin names to their executables or
+// WebAssembly modules.
+var externalPaths = map[string]string{}
+
+// IsExternal reports whether the named plugin is provided by an external
+// executable or WebAssembly module.
+func IsExternal(name string) bool {
+	_, ok := externalPaths[name]
+	return ok
+}
+
+// ExternalDirs returns the directories searched for external plugins: dirs,
+// then the entries of DEEN_PLUGIN_PATH, then PATH if DEEN_PLUGIN_SEARCH_PATH
+// asks for it.
+func ExternalDirs(dirs ...string) []string {
+	lists := []string{os.Getenv(PluginPathEnv)}
+	if search, _ := strconv.ParseBool(os.Getenv(SearchPathEnv)); search {
+		lists = append(lists, os.Getenv("PATH"))
+	}
+	var out []string
+	for _, list := range lists {
+		dirs = append(dirs, filepath.SplitList(list)...)
+	}
+	for _, dir := range dirs {
+		if dir != "" && !slices.Contains(out, dir) {
+			out = append(out, dir)
+		}
+	}
+	return out
+}
+
+// LoadExternal discovers deen-plugin-* executables in dirs and registers the
+// plugins they describe. Like PATH lookups, the first executable with a given
+// file name wins. Plugins that fail to describe themselves or clash with an
+// existing plugin are skipped and reported in the returned errors.
+func LoadExternal(dirs []string) []error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // PATH commonly lists directories that do not exist
+		}
+		for _, entry := range entries {
+			file := externalFileName(entry.Name())
+			if !strings.HasPrefix(file, ExternalPrefix) || seen[file] {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			seen[file] = true
+			if err := loadExternalPlugin(path); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			}
+		}
+	}
+	return errs
+}
+
+func loadExternalPlugin(path string) error {
+	desc, err := describeExternal(path)
+	if err != nil {
+		return err
+	}
+	transform := func(direction string) types.ContextTransformFunc { return externalTransform(path, direction) }
+	constructor := func() *types.DeenPlugin { return newExternalPlugin(desc, transform) }
+	if err := Register(constructor); err != nil {
+		return err
+	}
+	externalPaths[desc.Name] = path
+	return nil
+}
+
+func describeExternal(path string) (ExternalDescription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+	var stdout bytes.Buffer
+	var stderr stderrTail
+	cmd := exec.CommandContext(ctx, path, "describe")
+	cmd.WaitDelay = waitDelay
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return ExternalDescription{}, externalError("describe", err, &stderr)
+	}
+	return parseExternalDescription(stdout.Bytes())
+}
+
+// parseExternalDescription decodes and checks the reply to "describe".
+func parseExternalDescription(data []byte) (ExternalDescription, error) {
+	var desc ExternalDescription
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return desc, fmt.Errorf("invalid description: %w", err)
+	}
+	switch {
+	case desc.Protocol != ExternalProtocol:
+		return desc, fmt.Errorf("unsupported protocol version %d", desc.Protocol)
+	case desc.Name == "" || strings.ContainsAny(desc.Name, ". \t|"):
+		return desc, fmt.Errorf("invalid plugin name %q", desc.Name)
+	}
+	if !slices.Contains(PluginCategories, desc.Category) {
+		desc.Category = "misc"
+	}
+	return desc, nil
+}
+
+// newExternalPlugin builds the plugin for an external description, running
+// each direction with the function returned by transform. Process and
+// Unprocess run it without a deadline.
+func newExternalPlugin(desc ExternalDescription, transform func(direction string) types.ContextTransformFunc) *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = desc.Name
+	p.Category = desc.Category
+	p.Description = desc.Description
+	// Nothing tells what an external plugin depends on, so never cache it.
+	p.NonDeterministic = true
+	if desc.Decode {
+		p.Aliases = append(p.Aliases, "."+desc.Name)
+	}
+	for _, alias := range desc.Aliases {
+		p.Aliases = append(p.Aliases, alias)
+		if desc.Decode {
+			p.Aliases = append(p.Aliases, "."+alias)
+		}
+	}
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		for _, o := range desc.Options {
+			if o.Type == "bool" {
+				flags.Bool(o.Name, o.Default == "true", o.Description)
+			} else {
+				flags.String(o.Name, o.Default, o.Description)
+			}
+		}
+	}
+	for _, o := range desc.Options {
+		spec := types.OptionSpec{
+			Name:        o.Name,
+			Label:       o.Label,
+			Description: o.Description,
+			Type:        externalOptionType(o.Type),
+			Choices:     o.Choices,
+			Secret:      o.Secret,
+			Multiline:   o.Multiline,
+		}
+		if o.Min != nil && o.Max != nil {
+			spec.Range = &types.OptionRange{Min: *o.Min, Max: *o.Max}
+		}
+		p.Options = append(p.Options, spec)
+	}
+	p.ProcessContext = transform("process")
+	p.Process = withoutContext(p.ProcessContext)
+	if desc.Decode {
+		p.UnprocessContext = transform("unprocess")
+		p.Unprocess = withoutContext(p.UnprocessContext)
+	}
+	return p
+}
+
+// withoutContext adapts fn for callers that have no context.
+func withoutContext(fn types.ContextTransformFunc) types.TransformFunc {
+	return func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		return fn(context.Background(), r, w, flags)
+	}
+}
+
+func externalOptionType(t string) types.OptionType {
+	switch t {
+	case "number":
+		return types.OptionNumber
+	case "bool":
+		return types.OptionBool
+	case "select":
+		return types.OptionSelect
+	default:
+		return types.OptionText
+	}
+}
+
+// externalTransform streams r through the executable at path and its output
+// to w.
+func externalTransform(path, direction string) types.ContextTransformFunc {
+	return func(ctx context.Context, r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		header, err := json.Marshal(externalRequest(direction, flags))
+		if err != nil {
+			return err
+		}
+		var stderr stderrTail
+		// The context ends the process when the step times out.
+		cmd := exec.CommandContext(ctx, path, "run")
+		cmd.WaitDelay = waitDelay
+		cmd.Stdin = io.MultiReader(bytes.NewReader(append(header, '\n')), r)
+		cmd.Stdout = w
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return externalError(direction, err, &stderr)
+		}
+		return nil
+	}
+}
+
+// externalRequest builds the run header carrying every flag value.
+func externalRequest(direction string, flags *flag.FlagSet) ExternalRequest {
+	req := ExternalRequest{Protocol: ExternalProtocol, Direction: direction, Options: map[string]string{}}
+	if flags != nil {
+		flags.VisitAll(func(f *flag.Flag) { req.Options[f.Name] = f.Value.String() })
+	}
+	return req
+}
+
+// externalError prefers the message a failed plugin printed on stderr over
+// the bare exit status.
+func externalError(op string, err error, stderr *stderrTail) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	if msg := lastLine(stderr.String()); msg != "" {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// stderrTail keeps the last stderrLimit bytes written to it, so a plugin
+// flooding stderr cannot exhaust memory.
+type stderrTail struct {
+	buf []byte
+}
+
+func (t *stderrTail) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) >= stderrLimit {
+		p = p[len(p)-stderrLimit:]
+		t.buf = t.buf[:0]
+	}
+	if over := len(t.buf) + len(p) - stderrLimit; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	t.buf = append(t.buf, p...)
+	return n, nil
+}
+
+func (t *stderrTail) String() string { return string(t.buf) }
+
+// lastLine returns the last non-empty line of a plugin's stderr.
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[i+1:])
+	}
+	return s
+}
+
+// externalFileName strips the executable extension on Windows.
+func externalFileName(name string) string {
+	if runtime.GOOS == "windows" {
+		return strings.TrimSuffix(strings.ToLower(name), ".exe")
+	}
+	return name
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
@@ -0,0 +1,220 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestExternalPluginProcess is not a real test: it is the external plugin
+// executed by the deen-plugin-* scripts written by writeExternalPlugin.
+func TestExternalPluginProcess(t *testing.T) {
+	if os.Getenv("DEEN_TEST_EXTERNAL_PLUGIN") != "1" {
+		t.Skip("helper process")
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		os.Exit(2)
+	}
+	switch args[1] {
+	case "describe":
+		min, max := 0, 25
+		json.NewEncoder(os.Stdout).Encode(ExternalDescription{
+			Protocol:    ExternalProtocol,
+			Name:        os.Getenv("DEEN_TEST_EXTERNAL_NAME"),
+			Aliases:     []string{"trot"},
+			Category:    "codecs",
+			Description: "Rotates letters for tests.",
+			Decode:      true,
+			Options: []ExternalOption{
+				{Name: "shift", Type: "number", Default: "13", Min: &min, Max: &max},
+				{Name: "fail", Type: "bool"},
+			},
+		})
+	case "run":
+		in := bufio.NewReader(os.Stdin)
+		line, err := in.ReadBytes('\n')
+		var req ExternalRequest
+		if err != nil || json.Unmarshal(line, &req) != nil {
+			fmt.Fprintln(os.Stderr, "bad header")
+			os.Exit(1)
+		}
+		if req.Options["fail"] == "true" {
+			fmt.Fprintln(os.Stderr, "debug noise\nbad things happened")
+			os.Exit(3)
+		}
+		shift, _ := strconv.Atoi(req.Options["shift"])
+		if req.Direction == "unprocess" {
+			shift = 26 - shift
+		}
+		data, _ := io.ReadAll(in)
+		for i, c := range data {
+			if c >= 'a' && c <= 'z' {
+				data[i] = 'a' + (c-'a'+byte(shift))%26
+			}
+		}
+		os.Stdout.Write(data)
+	default:
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+// writeExternalPlugin writes a deen-plugin-<file> script into dir that runs
+// the test binary as an external plugin named name.
+func writeExternalPlugin(t *testing.T, dir, file, name string) {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := fmt.Sprintf("#!/bin/sh\nDEEN_TEST_EXTERNAL_PLUGIN=1 DEEN_TEST_EXTERNAL_NAME=%s exec %q -test.run='^TestExternalPluginProcess$' -- \"$@\"\n", name, exe)
+	if err := os.WriteFile(filepath.Join(dir, ExternalPrefix+file), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// keepRegistry restores the plugin registry when the test ends.
+func keepRegistry(t *testing.T) {
+	md, keys, paths := slices.Clone(metadata), maps.Clone(constructorByKey), maps.Clone(externalPaths)
+	t.Cleanup(func() { metadata, constructorByKey, externalPaths = md, keys, paths })
+}
+
+func runExternal(t *testing.T, cmd, input string, opts map[string]string) (string, error) {
+	t.Helper()
+	p, unprocess, ok := Resolve(cmd)
+	if !ok {
+		t.Fatalf("Resolve(%q) failed", cmd)
+	}
+	fs := flag.NewFlagSet(p.Name, flag.ContinueOnError)
+	p.RegisterFlags(fs)
+	for k, v := range opts {
+		if err := fs.Set(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fn := p.Process
+	if unprocess {
+		fn = p.Unprocess
+	}
+	var out bytes.Buffer
+	err := fn(strings.NewReader(input), &out, fs)
+	return out.String(), err
+}
+
+func TestLoadExternal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	keepRegistry(t)
+	dir, later := t.TempDir(), t.TempDir()
+	writeExternalPlugin(t, dir, "testrot", "testrot")
+	writeExternalPlugin(t, later, "testrot", "shadowed")
+	writeExternalPlugin(t, later, "clash", "base64")
+	if err := os.WriteFile(filepath.Join(dir, ExternalPrefix+"noexec"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := LoadExternal([]string{dir, filepath.Join(dir, "missing"), later})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `name "base64" is already taken`) {
+		t.Fatalf("errs = %v, want one base64 clash", errs)
+	}
+	if CmdAvailable("shadowed") || CmdAvailable("noexec") {
+		t.Fatal("loaded a shadowed or non-executable plugin")
+	}
+	if !IsExternal("testrot") || !CanDecode("testrot") || !CmdAvailable(".trot") {
+		t.Fatal("testrot not registered with decode support and aliases")
+	}
+	if CategoryOf("testrot") != "codecs" {
+		t.Fatalf("category = %q", CategoryOf("testrot"))
+	}
+
+	if got, err := runExternal(t, "trot", "abc-xyz", map[string]string{"shift": "1"}); err != nil || got != "bcd-yza" {
+		t.Fatalf("process = %q, %v", got, err)
+	}
+	if got, err := runExternal(t, ".testrot", "bcd", map[string]string{"shift": "1"}); err != nil || got != "abc" {
+		t.Fatalf("unprocess = %q, %v", got, err)
+	}
+	if _, err := runExternal(t, "testrot", "abc", map[string]string{"fail": "true"}); err == nil || err.Error() != "bad things happened" {
+		t.Fatalf("err = %v, want last stderr line", err)
+	}
+
+	p, _, _ := Resolve("testrot")
+	fs := flag.NewFlagSet("testrot", flag.ContinueOnError)
+	p.RegisterFlags(fs)
+	fs.Set("shift", "40")
+	if err := p.ValidateFlags(fs); err == nil {
+		t.Fatal("out-of-range shift accepted")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.ProcessContext(ctx, strings.NewReader("abc"), io.Discard, fs); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want the plugin stopped with its context", err)
+	}
+
+	var found bool
+	for _, info := range UICatalog() {
+		if info.Name == "testrot" {
+			found = info.Description == "Rotates letters for tests." && info.CanDecode
+		}
+	}
+	if !found {
+		t.Fatal("testrot missing from the UI catalog")
+	}
+}
+
+func TestExternalDirs(t *testing.T) {
+	t.Setenv(PluginPathEnv, "/plugins"+string(os.PathListSeparator)+"/opt")
+	t.Setenv("PATH", "/opt"+string(os.PathListSeparator)+"/bin")
+	got := ExternalDirs("/mine", "")
+	want := []string{"/mine", "/plugins", "/opt"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ExternalDirs = %v, want %v", got, want)
+	}
+	t.Setenv(SearchPathEnv, "1")
+	got = ExternalDirs("/mine", "")
+	want = []string{"/mine", "/plugins", "/opt", "/bin"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ExternalDirs with %s = %v, want %v", SearchPathEnv, got, want)
+	}
+}
+
+func TestStderrTail(t *testing.T) {
+	var tail stderrTail
+	noise := strings.Repeat("x", stderrLimit-3) + "\n"
+	for range 3 {
+		fmt.Fprint(&tail, noise)
+	}
+	fmt.Fprint(&tail, "the error\n")
+	if len(tail.buf) != stderrLimit || lastLine(tail.String()) != "the error" {
+		t.Fatalf("kept %d bytes ending in %q", len(tail.buf), lastLine(tail.String()))
+	}
+	tail.Write([]byte(strings.Repeat("y", 2*stderrLimit)))
+	if len(tail.buf) != stderrLimit {
+		t.Fatalf("kept %d bytes of a large write", len(tail.buf))
+	}
+}
+
+func TestRegisterRejectsTakenNames(t *testing.T) {
+	keepRegistry(t)
+	constructor := constructorByKey["base64"]
+	if err := Register(constructor); err == nil {
+		t.Fatal("registered base64 twice")
+	}
+}
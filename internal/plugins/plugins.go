@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 	"text/tabwriter"
@@ -21,6 +22,17 @@ var pluginConstructors = []func() *types.DeenPlugin{
 	codecs.NewPluginBase32,
 	codecs.NewPluginBase64,
 	codecs.NewPluginBase85,
+	codecs.NewPluginBase58,
+	codecs.NewPluginBase58Check,
+	codecs.NewPluginBase62,
+	codecs.NewPluginBase36,
+	codecs.NewPluginBase45,
+	codecs.NewPluginBase91,
+	codecs.NewPluginUUEncode,
+	codecs.NewPluginXXEncode,
+	codecs.NewPluginYEnc,
+	codecs.NewPluginBech32,
+	codecs.NewPluginIDNA,
 	codecs.NewPluginASCII,
 	codecs.NewPluginHex,
 	codecs.NewPluginURL,
@@ -123,15 +135,38 @@ var metadata []*types.DeenPlugin
 
 func init() {
 	for _, constructor := range pluginConstructors {
-		p := constructor()
-		metadata = append(metadata, p)
-		constructorByKey[lookupKey(p.Name)] = constructor
-		for _, alias := range p.Aliases {
-			constructorByKey[lookupKey(alias)] = constructor
+		if err := Register(constructor); err != nil {
+			panic(err)
 		}
 	}
 }
 
+// Register adds a plugin to the registry next to the built-in ones. It fails
+// when the plugin name or one of its aliases is already taken. Register is
+// meant for program start-up and must not race with lookups.
+func Register(constructor func() *types.DeenPlugin) error {
+	p := constructor()
+	if p.Name == "" {
+		return fmt.Errorf("plugin has no name")
+	}
+	keys := []string{lookupKey(p.Name)}
+	for _, alias := range p.Aliases {
+		if key := lookupKey(alias); !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		if _, taken := constructorByKey[key]; taken {
+			return fmt.Errorf("plugin %s: name %q is already taken", p.Name, key)
+		}
+	}
+	metadata = append(metadata, p)
+	for _, key := range keys {
+		constructorByKey[key] = constructor
+	}
+	return nil
+}
+
 // lookupKey normalises a command or alias to its canonical lookup form by
 // dropping the leading "." that marks the unprocess (decode) direction.
 func lookupKey(cmd string) string {
@@ -228,6 +263,16 @@ func Names() []string {
 	return names
 }
 
+// Builtin returns the names of the plugins compiled into deen, in
+// registration order. Plugins added through Register are not included.
+func Builtin() []string {
+	names := make([]string, 0, len(pluginConstructors))
+	for _, constructor := range pluginConstructors {
+		names = append(names, constructor().Name)
+	}
+	return names
+}
+
 // PluginInfo describes a plugin for catalogs and UIs.
 type PluginInfo struct {
 	Name        string
@@ -276,6 +321,21 @@ func CategoryOf(name string) string {
 	return ""
 }
 
+// Detect runs the Detect hook of every plugin that has one and returns the
+// matches by plugin name, in registration order.
+func Detect(data []byte) (names []string, detections []types.Detection) {
+	for _, p := range metadata {
+		if p.Detect == nil {
+			continue
+		}
+		if d, ok := p.Detect(data); ok {
+			names = append(names, p.Name)
+			detections = append(detections, d)
+		}
+	}
+	return names, detections
+}
+
 // CanDecode reports whether the named plugin supports the reverse operation.
 func CanDecode(name string) bool {
 	p, _, ok := Resolve(name)
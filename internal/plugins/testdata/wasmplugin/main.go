@@ -0,0 +1,58 @@
+// Command wasmplugin is a WASI plugin used by the WebAssembly loader tests.
+// Build it with GOOS=wasip1 GOARCH=wasm.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "describe":
+		fmt.Print(`{"protocol":1,"name":"wasmrev","category":"misc","description":"Reverses its input in a sandbox.",` +
+			`"options":[{"name":"mode","type":"select","default":"reverse","choices":["reverse","readfile","spin","alloc","env"]}]}`)
+	case "run":
+		in := bufio.NewReader(os.Stdin)
+		var req struct {
+			Options map[string]string `json:"options"`
+		}
+		line, err := in.ReadBytes('\n')
+		if err != nil || json.Unmarshal(line, &req) != nil {
+			fmt.Fprintln(os.Stderr, "bad header")
+			os.Exit(1)
+		}
+		switch req.Options["mode"] {
+		case "readfile":
+			if _, err := os.ReadFile("/etc/hostname"); err != nil {
+				fmt.Fprintln(os.Stderr, "no filesystem")
+				os.Exit(1)
+			}
+			fmt.Print("filesystem visible")
+		case "env":
+			fmt.Print(len(os.Environ()))
+		case "spin":
+			for {
+			}
+		case "alloc":
+			var keep [][]byte
+			for {
+				keep = append(keep, make([]byte, 16<<20))
+			}
+		default:
+			data, _ := io.ReadAll(in)
+			for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+				data[i], data[j] = data[j], data[i]
+			}
+			os.Stdout.Write(data)
+		}
+	default:
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,204 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// WebAssembly plugins are WASI (preview 1) modules stored as *.wasm files in a
+// plugin directory. They speak the same protocol as external executables, with
+// the command passed as the second program argument, but run inside a pure-Go
+// runtime without filesystem, network or environment access and with bounded
+// memory and run time.
+
+// WasmConfig bounds the resources of WebAssembly plugins.
+type WasmConfig struct {
+	MemoryLimitPages uint32        // 64 KiB pages of linear memory per instance
+	Timeout          time.Duration // wall-clock budget of a single run
+	CacheDir         string        // compilation cache; empty disables it
+}
+
+// DefaultWasmConfig returns a 256 MiB memory limit, a two minute run time and
+// a compilation cache in the user cache directory.
+func DefaultWasmConfig() WasmConfig {
+	cfg := WasmConfig{MemoryLimitPages: 4096, Timeout: 2 * time.Minute}
+	if dir, err := os.UserCacheDir(); err == nil {
+		cfg.CacheDir = filepath.Join(dir, "deen", "wasm")
+	}
+	return cfg
+}
+
+// WasmDirs returns the directories searched for WebAssembly plugins: dirs,
+// then the entries of DEEN_PLUGIN_PATH. Unlike executables, modules are not
+// looked up on PATH.
+func WasmDirs(dirs ...string) []string {
+	var out []string
+	for _, dir := range append(dirs, filepath.SplitList(os.Getenv(PluginPathEnv))...) {
+		if dir != "" && !slices.Contains(out, dir) {
+			out = append(out, dir)
+		}
+	}
+	return out
+}
+
+// LoadWasm compiles the *.wasm modules in dirs and registers the plugins they
+// describe. Modules that fail to compile or describe themselves, or clash
+// with an existing plugin, are skipped and reported in the returned errors.
+func LoadWasm(dirs []string, cfg WasmConfig) []error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".wasm") || seen[entry.Name()] {
+				continue
+			}
+			seen[entry.Name()] = true
+			path := filepath.Join(dir, entry.Name())
+			if err := loadWasmPlugin(path, cfg); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			}
+		}
+	}
+	return errs
+}
+
+func loadWasmPlugin(path string, cfg WasmConfig) error {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m, err := compileWasm(code, cfg)
+	if err != nil {
+		return err
+	}
+	var stdout bytes.Buffer
+	if err := m.run(context.Background(), "describe", nil, &stdout); err != nil {
+		m.close()
+		return err
+	}
+	desc, err := parseExternalDescription(stdout.Bytes())
+	if err != nil {
+		m.close()
+		return err
+	}
+	constructor := func() *types.DeenPlugin { return newExternalPlugin(desc, m.transform) }
+	if err := Register(constructor); err != nil {
+		m.close()
+		return err
+	}
+	externalPaths[desc.Name] = path
+	return nil
+}
+
+// wasmModule is a compiled plugin module. Every run gets a fresh instance.
+type wasmModule struct {
+	rt       wazero.Runtime
+	compiled wazero.CompiledModule
+	timeout  time.Duration
+}
+
+func compileWasm(code []byte, cfg WasmConfig) (*wasmModule, error) {
+	ctx := context.Background()
+	rc := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if cfg.MemoryLimitPages > 0 {
+		rc = rc.WithMemoryLimitPages(cfg.MemoryLimitPages)
+	}
+	if cfg.CacheDir != "" {
+		if cache, err := wazero.NewCompilationCacheWithDir(cfg.CacheDir); err == nil {
+			rc = rc.WithCompilationCache(cache)
+		}
+	}
+	rt := wazero.NewRuntimeWithConfig(ctx, rc)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		rt.Close(ctx)
+		return nil, err
+	}
+	compiled, err := rt.CompileModule(ctx, code)
+	if err != nil {
+		rt.Close(ctx)
+		return nil, fmt.Errorf("compile: %w", err)
+	}
+	return &wasmModule{rt: rt, compiled: compiled, timeout: cfg.Timeout}, nil
+}
+
+func (m *wasmModule) close() { m.rt.Close(context.Background()) }
+
+// run instantiates the module with command as its argument, stdin as input
+// and stdout as output until parent ends. The instance sees no files, network
+// or environment.
+func (m *wasmModule) run(parent context.Context, command string, stdin io.Reader, stdout io.Writer) error {
+	ctx := parent
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+	if stdin == nil {
+		stdin = bytes.NewReader(nil)
+	}
+	var stderr stderrTail
+	mc := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs("deen-plugin", command).
+		WithStdin(stdin).
+		WithStdout(stdout).
+		WithStderr(&stderr).
+		WithRandSource(rand.Reader).
+		WithSysWalltime().
+		WithSysNanotime()
+	mod, err := m.rt.InstantiateModule(ctx, m.compiled, mc)
+	if mod != nil {
+		mod.Close(ctx)
+	}
+	var exitErr *sys.ExitError
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		if err := context.Cause(parent); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s: timed out after %s", command, m.timeout)
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 0:
+		return nil
+	case errors.As(err, &exitErr):
+		if msg := lastLine(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("%s: exit code %d", command, exitErr.ExitCode())
+	default:
+		return err
+	}
+}
+
+// transform runs the module over the external plugin protocol.
+func (m *wasmModule) transform(direction string) types.ContextTransformFunc {
+	return func(ctx context.Context, r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		header, err := json.Marshal(externalRequest(direction, flags))
+		if err != nil {
+			return err
+		}
+		return m.run(ctx, "run", io.MultiReader(bytes.NewReader(append(header, '\n')), r), w)
+	}
+}
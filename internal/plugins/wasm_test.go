@@ -0,0 +1,74 @@
+package plugins
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildWasmPlugin compiles testdata/wasmplugin for WASI into dir.
+func buildWasmPlugin(t *testing.T, dir string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds a WASI module")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not available")
+	}
+	cmd := exec.Command(gobin, "build", "-o", filepath.Join(dir, "wasmrev.wasm"), "./testdata/wasmplugin")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm", "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building the WASI plugin: %v\n%s", err, out)
+	}
+}
+
+func TestLoadWasm(t *testing.T) {
+	keepRegistry(t)
+	dir := t.TempDir()
+	buildWasmPlugin(t, dir)
+	if err := os.WriteFile(filepath.Join(dir, "broken.wasm"), []byte("not wasm"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := WasmConfig{MemoryLimitPages: 1024, Timeout: 2 * time.Second}
+	errs := LoadWasm([]string{dir}, cfg)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.wasm") {
+		t.Fatalf("errs = %v, want one error for broken.wasm", errs)
+	}
+	if !IsExternal("wasmrev") || CanDecode("wasmrev") || CategoryOf("wasmrev") != "misc" {
+		t.Fatal("wasmrev not registered as a one-way misc plugin")
+	}
+
+	if got, err := runExternal(t, "wasmrev", "deen", nil); err != nil || got != "need" {
+		t.Fatalf("process = %q, %v", got, err)
+	}
+	if _, err := runExternal(t, "wasmrev", "", map[string]string{"mode": "readfile"}); err == nil || err.Error() != "no filesystem" {
+		t.Fatalf("readfile err = %v, want no filesystem", err)
+	}
+	if got, err := runExternal(t, "wasmrev", "", map[string]string{"mode": "env"}); err != nil || got != "0" {
+		t.Fatalf("environment = %q, %v; want none", got, err)
+	}
+	if _, err := runExternal(t, "wasmrev", "", map[string]string{"mode": "alloc"}); err == nil {
+		t.Fatal("allocation past the memory limit succeeded")
+	}
+	start := time.Now()
+	if _, err := runExternal(t, "wasmrev", "", map[string]string{"mode": "spin"}); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("spin err = %v, want timeout", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatalf("spinning plugin ran for %s", d)
+	}
+}
+
+func TestWasmDirs(t *testing.T) {
+	t.Setenv(PluginPathEnv, "/plugins"+string(os.PathListSeparator)+"/mine")
+	t.Setenv("PATH", "/bin")
+	got := WasmDirs("/mine")
+	if strings.Join(got, ",") != "/mine,/plugins" {
+		t.Fatalf("WasmDirs = %v", got)
+	}
+}
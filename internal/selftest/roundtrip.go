@@ -0,0 +1,433 @@
+package selftest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/takeshixx/deen/internal/plugins"
+)
+
+// inverse declares how the Unprocess direction of a plugin undoes Process.
+// Every built-in plugin with an Unprocess function needs an entry, so a new
+// decoder cannot be added without deciding what it must round-trip.
+type inverse struct {
+	domain domain
+	// fixed holds options every run needs, e.g. keys and nonces.
+	fixed map[string]string
+	// values lists the values tried for options that are neither bools nor
+	// choices. Options without values keep their default.
+	values map[string][]string
+	// skip lists options that are not varied because they change the output
+	// format rather than the encoding.
+	skip []string
+	// complete adds the options that depend on others to an option set, e.g.
+	// the IV of a cipher mode. It may be nil.
+	complete func(opts map[string]string)
+	// adapt restricts an input to what an option set can encode, e.g. to the
+	// byte range of an LZW literal width. It may be nil.
+	adapt func(opts map[string]string, in []byte) []byte
+	// equal compares the round-tripped result with the input. nil compares
+	// the bytes.
+	equal func(in, out []byte) bool
+	// none explains why Unprocess is not an inverse of Process; the plugin
+	// then only gets the decoder checks.
+	none string
+}
+
+const key128 = "000102030405060708090a0b0c0d0e0f"
+
+var inverses = map[string]inverse{
+	"base32":      {domain: binary},
+	"base64":      {domain: binary},
+	"base85":      {domain: binary},
+	"base58":      {domain: binary},
+	"base58check": {domain: binary},
+	"base62":      {domain: binary},
+	"base36":      {domain: binary},
+	"base45":      {domain: binary},
+	"base91":      {domain: binary},
+	"uuencode":    {domain: binary},
+	"xxencode":    {domain: binary},
+	"yenc":        {domain: binary},
+	"hex":         {domain: binary},
+	"url":         {domain: binary},
+	"html":        {domain: text},
+	"unicode": {
+		domain: text,
+		adapt:  representable,
+	},
+	"bech32":           {none: "decodes to a JSON report of the HRP, words and bytes"},
+	"idna":             {none: "maps hostnames to a canonical form, e.g. lower case; decoding cannot restore the original"},
+	"strconv":          {domain: binary},
+	"pem":              {domain: binary, values: map[string][]string{"type": {"MESSAGE", "CERTIFICATE"}}},
+	"quoted-printable": {domain: binary},
+	"rot13":            {domain: binary},
+
+	"flate":  {domain: binary, values: map[string][]string{"level": {"-1", "0", "1", "9"}}},
+	"lzma":   {domain: binary},
+	"lzma2":  {domain: binary},
+	"lzw":    {domain: binary, adapt: lzwLiterals},
+	"gzip":   {domain: binary, values: map[string][]string{"level": {"-1", "0", "1", "9"}}},
+	"zlib":   {domain: binary, values: map[string][]string{"level": {"-1", "0", "1", "9"}}},
+	"bzip2":  {domain: binary, values: map[string][]string{"level": {"1", "9"}}},
+	"brotli": {domain: binary, values: map[string][]string{"level": {"0", "6", "11"}, "lgwin": {"0", "10", "24"}}},
+	"zstd":   {domain: binary},
+
+	"json":     {domain: jsonDoc, skip: []string{"no-color"}, equal: jsonEqual},
+	"yaml":     {domain: jsonDoc, equal: jsonEqual},
+	"msgpack":  {domain: jsonDoc, equal: jsonEqual},
+	"cbor":     {domain: jsonDoc, equal: jsonEqual},
+	"xml":      {none: "pretty-prints XML; decoding compacts it"},
+	"json2xml": {none: "XML has no number, boolean or array types"},
+	"toml":     {none: "converts between TOML and JSON documents with different type systems"},
+	"jwt":      {none: "decodes tokens; signing needs keys and claims"},
+	"jwk":      {none: "generates and inspects keys"},
+	"csv":      {none: "renders tables"},
+	"qr":       {none: "renders images; the decoder reads them back only for suitable sizes"},
+	"saml":     {none: "decodes SAML messages"},
+	"timestamp": {
+		none: "formats timestamps at the resolution of the layout",
+	},
+	"dns":  {none: "encodes names; decoding reads wire-format names"},
+	"uuid": {none: "inspects UUIDs"},
+	"sign": {none: "verification needs a signature and public key"},
+
+	"aes": {
+		domain: binary,
+		fixed:  map[string]string{"key": key128},
+		values: map[string][]string{"aad": {"header"}},
+		skip:   []string{"iv", "skip-aead-verify"},
+		complete: func(opts map[string]string) {
+			if opts["mode"] == "cbc" || opts["mode"] == "ctr" {
+				opts["iv"] = key128
+			} else {
+				opts["iv"] = "000102030405060708090a0b"
+			}
+		},
+		adapt: func(opts map[string]string, in []byte) []byte {
+			if opts["mode"] == "cbc" && opts["padding"] == "none" {
+				return in[:len(in)/16*16]
+			}
+			return in
+		},
+	},
+	"chacha20poly1305": {
+		domain: binary,
+		fixed:  map[string]string{"key": key128 + key128, "nonce": "000102030405060708090a0b"},
+		values: map[string][]string{"aad": {"", "header"}},
+	},
+
+	"xor": {domain: binary, values: map[string][]string{"value": {"0x00", "0xff", "0x5a"}}},
+	"add": {domain: binary, values: map[string][]string{"value": {"1", "255"}}},
+	"sub": {domain: binary, values: map[string][]string{"value": {"1", "255"}}},
+	"not": {domain: binary},
+}
+
+// domain generates the inputs Process accepts.
+type domain struct {
+	edges [][]byte
+	gen   func(*rand.Rand) []byte
+}
+
+var binary = domain{
+	edges: [][]byte{nil, {0}, allBytes(), bytes.Repeat([]byte{0}, 4096), []byte("deen")},
+	gen:   randomBytes,
+}
+
+var text = domain{
+	edges: [][]byte{nil, []byte("a"), []byte("héllo wörld"), []byte("日本語のテキスト"), []byte("emoji 😀 \x00\t\r\n")},
+	gen:   randomText,
+}
+
+var jsonDoc = domain{
+	edges: [][]byte{[]byte(`{}`), []byte(`[]`), []byte(`{"a":[1,"x",true,null],"b":{"c":-2}}`)},
+	gen:   randomJSON,
+}
+
+func allBytes() []byte {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func randomBytes(rng *rand.Rand) []byte {
+	b := make([]byte, rng.IntN(2048))
+	switch rng.IntN(3) {
+	case 0: // noise
+		for i := range b {
+			b[i] = byte(rng.Uint32())
+		}
+	case 1: // runs, which compressors encode differently
+		for i := 0; i < len(b); {
+			n := min(1+rng.IntN(64), len(b)-i)
+			c := byte(rng.Uint32())
+			for j := 0; j < n; j++ {
+				b[i+j] = c
+			}
+			i += n
+		}
+	default: // printable ASCII
+		for i := range b {
+			b[i] = byte(0x20 + rng.IntN(0x5f))
+		}
+	}
+	return b
+}
+
+// textRanges are the rune ranges random text is drawn from.
+var textRanges = [][2]rune{{0x20, 0x7e}, {0xa0, 0xff}, {0x100, 0x17f}, {0x391, 0x3c9}, {0x410, 0x44f}, {0x3041, 0x3096}, {0x4e00, 0x4fff}, {0xac00, 0xad00}, {0x1f600, 0x1f64f}}
+
+func randomText(rng *rand.Rand) []byte {
+	var b []byte
+	n := rng.IntN(512)
+	for i := 0; i < n; i++ {
+		r := textRanges[rng.IntN(len(textRanges))]
+		b = utf8.AppendRune(b, r[0]+rune(rng.IntN(int(r[1]-r[0]+1))))
+	}
+	return b
+}
+
+func randomJSON(rng *rand.Rand) []byte {
+	b, _ := json.Marshal(randomValue(rng, 3))
+	return b
+}
+
+func randomValue(rng *rand.Rand, depth int) any {
+	kind := rng.IntN(6)
+	if depth == 0 {
+		kind = rng.IntN(4)
+	}
+	switch kind {
+	case 0:
+		return rng.IntN(1<<20) - 1<<19
+	case 1:
+		return string(randomText(rng))
+	case 2:
+		return rng.IntN(2) == 0
+	case 3:
+		return nil
+	case 4:
+		list := make([]any, rng.IntN(5))
+		for i := range list {
+			list[i] = randomValue(rng, depth-1)
+		}
+		return list
+	default:
+		obj := map[string]any{}
+		for i := rng.IntN(5); i > 0; i-- {
+			obj["k"+strconv.Itoa(rng.IntN(100))] = randomValue(rng, depth-1)
+		}
+		return obj
+	}
+}
+
+func jsonEqual(in, out []byte) bool {
+	var a, b any
+	if json.Unmarshal(in, &a) != nil || json.Unmarshal(out, &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// lzwLiterals masks input bytes to the literal width of the option set.
+func lzwLiterals(opts map[string]string, in []byte) []byte {
+	width, err := strconv.Atoi(opts["lit-width"])
+	if err != nil || width >= 8 {
+		return in
+	}
+	out := make([]byte, len(in))
+	for i, c := range in {
+		out[i] = c & (1<<width - 1)
+	}
+	return out
+}
+
+// representable drops the runes the target encoding of the option set cannot
+// represent.
+func representable(opts map[string]string, in []byte) []byte {
+	var limit rune
+	switch opts["encoding"] {
+	case "latin1":
+		limit = 0xff
+	case "windows1252", "koi8r":
+		limit = 0x7f
+	case "shiftjis", "eucjp", "gbk", "big5", "euckr":
+		limit = 0x7f
+	default:
+		return in
+	}
+	var out []byte
+	for _, r := range string(in) {
+		if r <= limit {
+			out = utf8.AppendRune(out, r)
+		}
+	}
+	return out
+}
+
+// optionSets returns the option sets a plugin is tested with: the defaults,
+// then every non-default value of one option at a time.
+func optionSets(name string, inv inverse) []map[string]string {
+	p, _, _ := plugins.Resolve(name)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if p.RegisterFlags != nil {
+		p.RegisterFlags(fs)
+	}
+	base := map[string]string{}
+	for k, v := range inv.fixed {
+		base[k] = v
+	}
+	sets := []map[string]string{base}
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := inv.fixed[f.Name]; ok || slices.Contains(inv.skip, f.Name) {
+			return
+		}
+		spec, _ := p.OptionSpec(f.Name)
+		if spec.Hidden {
+			return
+		}
+		values := inv.values[f.Name]
+		switch {
+		case values != nil:
+		case len(spec.Choices) > 0:
+			values = spec.Choices
+		case isBoolFlag(f):
+			values = []string{"true", "false"}
+		}
+		for _, v := range values {
+			if v == f.DefValue {
+				continue
+			}
+			set := map[string]string{f.Name: v}
+			for k, v := range base {
+				set[k] = v
+			}
+			sets = append(sets, set)
+		}
+	})
+	if inv.complete != nil {
+		for _, set := range sets {
+			inv.complete(set)
+		}
+	}
+	return sets
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// roundTrips checks that Unprocess undoes Process for every option set of the
+// plugin, over the edge cases of its domain and generated inputs.
+func (r *runner) roundTrips(name string) {
+	inv, ok := inverses[name]
+	if !ok {
+		r.add(Result{Plugin: name, Check: CheckSpec, Err: fmt.Errorf("decoder has no round-trip declaration")})
+		return
+	}
+	if inv.none != "" {
+		r.add(Result{Plugin: name, Check: CheckRoundTrip, Skip: inv.none})
+		return
+	}
+	for _, opts := range optionSets(name, inv) {
+		rng := r.rand(name, CheckRoundTrip+formatOptions(opts))
+		inputs := append([][]byte(nil), inv.domain.edges...)
+		for i := 0; i < r.opts.Iterations; i++ {
+			inputs = append(inputs, inv.domain.gen(rng))
+		}
+		res := Result{Plugin: name, Check: CheckRoundTrip, Options: opts}
+		for _, in := range inputs {
+			if inv.adapt != nil {
+				in = inv.adapt(opts, in)
+			}
+			if err := r.roundTrip(name, inv, opts, in); err != nil {
+				res.Case = "input " + excerpt(in)
+				res.Err = err
+				break
+			}
+		}
+		r.add(res)
+	}
+}
+
+func (r *runner) roundTrip(name string, inv inverse, opts map[string]string, in []byte) error {
+	enc, err := r.transform(name, opts, in)
+	if err != nil {
+		return fmt.Errorf("process: %w", err)
+	}
+	out, err := r.transform("."+name, opts, enc)
+	if err != nil {
+		return fmt.Errorf("unprocess of %s: %w", excerpt(enc), err)
+	}
+	equal := inv.equal
+	if equal == nil {
+		equal = bytes.Equal
+	}
+	if !equal(in, out) {
+		return fmt.Errorf("unprocess returned %s", excerpt(out))
+	}
+	return nil
+}
+
+// decoders feeds the Unprocess direction random bytes and mutated encodings.
+// Errors are expected; panics and hangs are failures.
+func (r *runner) decoders(name string) {
+	inv := inverses[name]
+	opts := optionSets(name, inv)[0]
+	rng := r.rand(name, CheckDecode)
+	var valid [][]byte
+	if inv.none == "" {
+		for _, in := range inv.domain.edges {
+			if enc, err := r.transform(name, opts, in); err == nil {
+				valid = append(valid, enc)
+			}
+		}
+	}
+	res := Result{Plugin: name, Check: CheckDecode, Options: opts}
+	for i := 0; i < r.opts.Iterations*4 && res.Err == nil; i++ {
+		var in []byte
+		if len(valid) > 0 && i%2 == 0 {
+			in = mutate(rng, valid[rng.IntN(len(valid))])
+		} else if i%4 == 1 {
+			in = randomText(rng)
+		} else {
+			in = randomBytes(rng)
+		}
+		if _, err := r.transform("."+name, opts, in); isFailure(err) {
+			res.Case = "input " + excerpt(in)
+			res.Err = err
+		}
+	}
+	r.add(res)
+}
+
+// mutate flips, drops, inserts or truncates bytes of a valid encoding.
+func mutate(rng *rand.Rand, in []byte) []byte {
+	b := append([]byte(nil), in...)
+	for n := 1 + rng.IntN(4); n > 0 && len(b) > 0; n-- {
+		i := rng.IntN(len(b))
+		switch rng.IntN(4) {
+		case 0:
+			b[i] ^= byte(1 << rng.IntN(8))
+		case 1:
+			b = append(b[:i], b[i+1:]...)
+		case 2:
+			b = append(b[:i], append([]byte{byte(rng.Uint32())}, b[i:]...)...)
+		default:
+			b = b[:i]
+		}
+	}
+	return b
+}
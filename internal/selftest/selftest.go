@@ -0,0 +1,220 @@
+// Package selftest checks the built-in plugins against each other and against
+// published test vectors: Unprocess must undo Process across the declared
+// option space, decoders must reject malformed input without panicking, and
+// hashes and ciphers must reproduce their NIST and RFC known answers.
+package selftest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/takeshixx/deen/internal/plugins"
+)
+
+// Check kinds.
+const (
+	CheckRoundTrip = "roundtrip"
+	CheckDecode    = "decode"
+	CheckVector    = "vector"
+	CheckSpec      = "spec"
+)
+
+// Options configures a self-test run.
+type Options struct {
+	Seed       uint64   // seed of the generated and fuzzed inputs
+	Iterations int      // random inputs per option set and direction
+	Plugins    []string // plugins to test; all built-in plugins when empty
+	Timeout    time.Duration
+}
+
+// DefaultOptions returns the options used by `deen selftest`.
+func DefaultOptions() Options {
+	return Options{Seed: 1, Iterations: 50, Timeout: 10 * time.Second}
+}
+
+// Result is the outcome of one check. Err is nil when the check passed.
+type Result struct {
+	Plugin  string            `json:"plugin"`
+	Check   string            `json:"check"`
+	Options map[string]string `json:"options,omitempty"`
+	Case    string            `json:"case,omitempty"`
+	Skip    string            `json:"skip,omitempty"` // why the check did not run
+	Err     error             `json:"-"`
+}
+
+// String formats the result as "plugin check [options] case".
+func (r Result) String() string {
+	var b strings.Builder
+	b.WriteString(r.Plugin + " " + r.Check)
+	if len(r.Options) > 0 {
+		b.WriteString(" [" + formatOptions(r.Options) + "]")
+	}
+	if r.Case != "" {
+		b.WriteString(" " + r.Case)
+	}
+	return b.String()
+}
+
+var (
+	// errPanic marks transform errors that were recovered panics.
+	errPanic = errors.New("panic")
+	// errTimeout marks transforms that did not finish in time.
+	errTimeout = errors.New("timed out")
+)
+
+// isFailure reports whether a decoder error is a defect rather than a
+// rejection of malformed input.
+func isFailure(err error) bool {
+	return errors.Is(err, errPanic) || errors.Is(err, errTimeout)
+}
+
+// maxOutput bounds the output of a single transform, so a decoder that
+// expands fuzzed input without limit fails instead of exhausting memory.
+const maxOutput = 64 << 20
+
+// Run runs every check for the selected plugins and returns one result per
+// check, failures included. It fails with an error only for unknown plugin
+// names.
+func Run(opts Options) ([]Result, error) {
+	if opts.Iterations <= 0 {
+		opts.Iterations = DefaultOptions().Iterations
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultOptions().Timeout
+	}
+	builtin := plugins.Builtin()
+	names := builtin
+	if len(opts.Plugins) > 0 {
+		names = nil
+		for _, name := range opts.Plugins {
+			p, _, ok := plugins.Resolve(name)
+			if !ok || !slices.Contains(builtin, p.Name) {
+				return nil, fmt.Errorf("unknown built-in plugin %q", name)
+			}
+			if !slices.Contains(names, p.Name) {
+				names = append(names, p.Name)
+			}
+		}
+	}
+
+	r := &runner{opts: opts}
+	for _, name := range names {
+		p, _, _ := plugins.Resolve(name)
+		if p.Unprocess != nil {
+			r.roundTrips(name)
+			r.decoders(name)
+		}
+		r.vectors(name)
+	}
+	return r.results, nil
+}
+
+type runner struct {
+	opts    Options
+	results []Result
+}
+
+func (r *runner) add(res Result) { r.results = append(r.results, res) }
+
+// rand returns a generator seeded for one plugin and check, so the inputs of
+// a plugin do not change when other plugins are added or filtered out.
+func (r *runner) rand(name, check string) *rand.Rand {
+	var h uint64 = 14695981039346656037
+	for _, c := range []byte(name + "/" + check) {
+		h = (h ^ uint64(c)) * 1099511628211
+	}
+	return rand.New(rand.NewPCG(r.opts.Seed, h))
+}
+
+// transform runs one direction of a fresh plugin instance with the given
+// options. Panics are recovered and reported as errPanic; a transform that
+// does not finish within the timeout is abandoned.
+func (r *runner) transform(cmd string, opts map[string]string, in []byte) ([]byte, error) {
+	p, unprocess, ok := plugins.Resolve(cmd)
+	if !ok {
+		return nil, fmt.Errorf("unknown plugin %q", cmd)
+	}
+	fn := p.Process
+	if unprocess {
+		fn = p.Unprocess
+	}
+	fs := flag.NewFlagSet(p.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if p.RegisterFlags != nil {
+		p.RegisterFlags(fs)
+	}
+	for _, name := range sortedKeys(opts) {
+		if err := fs.Set(name, opts[name]); err != nil {
+			return nil, fmt.Errorf("invalid value for -%s: %w", name, err)
+		}
+	}
+	if err := p.ValidateFlags(fs); err != nil {
+		return nil, err
+	}
+
+	type outcome struct {
+		out []byte
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		var out limitedBuffer
+		defer func() {
+			if v := recover(); v != nil {
+				done <- outcome{err: fmt.Errorf("%w: %v", errPanic, v)}
+			}
+		}()
+		err := fn(bytes.NewReader(in), &out, fs)
+		done <- outcome{out.Bytes(), err}
+	}()
+	select {
+	case o := <-done:
+		return o.out, o.err
+	case <-time.After(r.opts.Timeout):
+		return nil, fmt.Errorf("%w after %s", errTimeout, r.opts.Timeout)
+	}
+}
+
+// limitedBuffer is a bytes.Buffer that fails writes beyond maxOutput.
+type limitedBuffer struct{ bytes.Buffer }
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > maxOutput {
+		return 0, fmt.Errorf("output exceeds %d bytes", maxOutput)
+	}
+	return b.Buffer.Write(p)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatOptions(opts map[string]string) string {
+	parts := make([]string, 0, len(opts))
+	for _, k := range sortedKeys(opts) {
+		parts = append(parts, k+"="+opts[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// excerpt quotes the start of data for failure messages.
+func excerpt(data []byte) string {
+	const excerptLen = 48
+	if len(data) > excerptLen {
+		return fmt.Sprintf("%q... (%d bytes)", data[:excerptLen], len(data))
+	}
+	return fmt.Sprintf("%q", data)
+}
@@ -0,0 +1,88 @@
+package selftest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/takeshixx/deen/internal/plugins"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+func TestBuiltinPluginsPass(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Iterations = 10
+	results, err := Run(opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	checked := map[string]bool{}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Errorf("%s: %v", r, r.Err)
+		}
+		checked[r.Plugin] = true
+	}
+	for _, name := range plugins.Builtin() {
+		if p, _, _ := plugins.Resolve(name); p.Unprocess != nil && !checked[name] {
+			t.Errorf("reversible plugin %s was not checked", name)
+		}
+	}
+}
+
+func TestRunRejectsUnknownPlugin(t *testing.T) {
+	if _, err := Run(Options{Plugins: []string{"nope"}}); err == nil {
+		t.Fatal("Run succeeded for an unknown plugin")
+	}
+}
+
+// brokenPlugin drops the last byte when decoding and panics on "boom".
+func brokenPlugin() *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = "selftest-broken"
+	p.Process = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(data, []byte("boom")) {
+			panic("boom")
+		}
+		if len(data) > 0 {
+			data = data[:len(data)-1]
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	return p
+}
+
+func TestRunnerReportsDefects(t *testing.T) {
+	if err := plugins.Register(brokenPlugin); err != nil {
+		t.Fatal(err)
+	}
+	r := &runner{opts: DefaultOptions()}
+	r.roundTrips("selftest-broken")
+	if len(r.results) != 1 || r.results[0].Check != CheckSpec {
+		t.Fatalf("results = %+v, want a missing declaration", r.results)
+	}
+
+	inverses["selftest-broken"] = inverse{domain: text}
+	defer delete(inverses, "selftest-broken")
+	r.results = nil
+	r.roundTrips("selftest-broken")
+	if len(r.results) != 1 || r.results[0].Err == nil || !strings.Contains(r.results[0].Err.Error(), "unprocess returned") {
+		t.Fatalf("results = %+v, want a round-trip failure", r.results)
+	}
+
+	if _, err := r.transform(".selftest-broken", nil, []byte("boom")); !errors.Is(err, errPanic) || !isFailure(err) {
+		t.Fatalf("transform error = %v, want a recovered panic", err)
+	}
+}
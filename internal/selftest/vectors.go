@@ -0,0 +1,149 @@
+package selftest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/takeshixx/deen/internal/plugins"
+)
+
+// vector is a published known answer. Hashes write text, so their output is
+// the digest as hex (Base64 for scrypt); cipher outputs are raw bytes.
+type vector struct {
+	plugin  string
+	source  string
+	options map[string]string
+	input   []byte
+	output  []byte
+}
+
+// ciphers are the plugins outside the hashs category that need vectors.
+var ciphers = []string{"aes", "chacha20poly1305"}
+
+// noVectors explains why a hash has no known-answer vector.
+var noVectors = map[string]string{
+	"bcrypt":  "salted with random bytes",
+	"blake2x": "no published unkeyed vectors",
+}
+
+var abc = []byte("abc")
+
+var check = []byte("123456789")
+
+var vectors = []vector{
+	{plugin: "sha1", source: "FIPS 180-4 example", input: abc, output: []byte("a9993e364706816aba3e25717850c26c9cd0d89d")},
+	{plugin: "sha224", source: "FIPS 180-4 example", input: abc, output: []byte("23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7")},
+	{plugin: "sha256", source: "FIPS 180-4 example", input: abc, output: []byte("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")},
+	{plugin: "sha384", source: "FIPS 180-4 example", input: abc, output: []byte("cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed8086072ba1e7cc2358baeca134c825a7")},
+	{plugin: "sha512", source: "FIPS 180-4 example", input: abc, output: []byte("ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f")},
+	{plugin: "sha512-224", source: "FIPS 180-4 example", input: abc, output: []byte("4634270f707b6a54daae7530460842e20e37ed265ceee9a43e8924aa")},
+	{plugin: "sha512-256", source: "FIPS 180-4 example", input: abc, output: []byte("53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23")},
+	{plugin: "sha3-224", source: "FIPS 202 example", input: abc, output: []byte("e642824c3f8cf24ad09234ee7d3c766fc9a3a5168d0c94ad73b46fdf")},
+	{plugin: "sha3-256", source: "FIPS 202 example", input: abc, output: []byte("3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532")},
+	{plugin: "sha3-384", source: "FIPS 202 example", input: abc, output: []byte("ec01498288516fc926459f58e2c6ad8df9b473cb0fc08c2596da7cf0e49be4b298d88cea927ac7f539f1edf228376d25")},
+	{plugin: "sha3-512", source: "FIPS 202 example", input: abc, output: []byte("b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0")},
+	{plugin: "md4", source: "RFC 1320 A.5", input: abc, output: []byte("a448017aaf21d8525fc10ae87aa6729d")},
+	{plugin: "md5", source: "RFC 1321 A.5", input: abc, output: []byte("900150983cd24fb0d6963f7d28e17f72")},
+	{plugin: "ripemd160", source: "RIPEMD-160 reference", input: abc, output: []byte("8eb208f7e05d987a9b044a8e98c6b087f15a0bfc")},
+	{plugin: "blake2b", source: "RFC 7693 Appendix A", input: abc, output: []byte("ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923")},
+	{plugin: "blake2s", source: "RFC 7693 Appendix B", input: abc, output: []byte("508c5e8c327c14e2e1a72ba34eeb452f37458b209ed63a294d999b4c86675982")},
+	{plugin: "blake3", source: "BLAKE3 test vectors", input: nil, output: []byte("af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262")},
+	{plugin: "scrypt", source: "RFC 7914 section 12", options: map[string]string{"salt": "4e61436c", "cost": "1024", "r": "8", "p": "16", "len": "64"}, input: []byte("password"), output: []byte("/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWIurzDZLiKjiG/xCSedmDDaxyevuUqD7m2DYMvfoswGQA==")},
+	{plugin: "adler32", source: "RFC 1950", input: []byte("Wikipedia"), output: []byte("11e60398")},
+	{plugin: "crc32", source: "CRC-32/ISO-HDLC check value", input: check, output: []byte("cbf43926")},
+	{plugin: "crc32c", source: "CRC-32/ISCSI check value", input: check, output: []byte("e3069283")},
+	{plugin: "crc32k", source: "CRC-32/KOOPMAN check value", input: check, output: []byte("2d3dd0ae")},
+	{plugin: "crc64", source: "CRC-64/GO-ISO check value", input: check, output: []byte("b90956c775a41001")},
+	{plugin: "crc64-ecma", source: "CRC-64/XZ check value", input: check, output: []byte("995dc9bbdf1939fa")},
+	{plugin: "fnv32", source: "FNV reference test suite", input: []byte("a"), output: []byte("050c5d7e")},
+	{plugin: "fnv32a", source: "FNV reference test suite", input: []byte("a"), output: []byte("e40c292c")},
+	{plugin: "fnv64", source: "FNV reference test suite", input: []byte("a"), output: []byte("af63bd4c8601b7be")},
+	{plugin: "fnv64a", source: "FNV reference test suite", input: []byte("a"), output: []byte("af63dc4c8601ec8c")},
+	{plugin: "fnv128", source: "FNV-1 offset basis", input: nil, output: []byte("6c62272e07bb014262b821756295c58d")},
+	{plugin: "fnv128a", source: "FNV-1a offset basis", input: nil, output: []byte("6c62272e07bb014262b821756295c58d")},
+	{plugin: "hmac", source: "RFC 4231 test case 2", options: map[string]string{"key": "Jefe"}, input: []byte("what do ya want for nothing?"), output: []byte("5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843")},
+	{plugin: "hmac", source: "RFC 4231 test case 2", options: map[string]string{"key": "Jefe", "alg": "sha512"}, input: []byte("what do ya want for nothing?"), output: []byte("164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737")},
+
+	{
+		plugin: "aes", source: "GCM specification test case 2",
+		options: map[string]string{"key": "00000000000000000000000000000000", "nonce": "000000000000000000000000"},
+		input:   hexBytes("00000000000000000000000000000000"),
+		output:  hexBytes("0388dace60b6a392f328c2b971b2fe78ab6e47d42cec13bdf53a67b21257bddf"),
+	},
+	{
+		plugin: "aes", source: "GCM specification test case 3",
+		options: map[string]string{"key": "feffe9928665731c6d6a8f9467308308", "nonce": "cafebabefacedbaddecaf888"},
+		input:   hexBytes("d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b391aafd255"),
+		output:  hexBytes("42831ec2217774244b7221b784d0d49ce3aa212f2c02a4e035c17e2329aca12e21d514b25466931c7d8f6a5aac84aa051ba30b396a0aac973d58e091473f59854d5c2af327cd64a62cf35abd2ba6fab4"),
+	},
+	{
+		plugin: "aes", source: "SP 800-38A F.2.1",
+		options: map[string]string{"mode": "cbc", "padding": "none", "key": "2b7e151628aed2a6abf7158809cf4f3c", "iv": "000102030405060708090a0b0c0d0e0f"},
+		input:   hexBytes("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51"),
+		output:  hexBytes("7649abac8119b246cee98e9b12e9197d5086cb9b507219ee95db113a917678b2"),
+	},
+	{
+		plugin: "aes", source: "SP 800-38A F.5.1",
+		options: map[string]string{"mode": "ctr", "key": "2b7e151628aed2a6abf7158809cf4f3c", "iv": "f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"},
+		input:   hexBytes("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51"),
+		output:  hexBytes("874d6191b620e3261bef6864990db6ce9806f66b7970fdff8617187bb9fffdff"),
+	},
+	{
+		plugin: "chacha20poly1305", source: "RFC 8439 section 2.8.2",
+		options: map[string]string{
+			"key":   "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f",
+			"nonce": "070000004041424344454647",
+			"aad":   string(hexBytes("50515253c0c1c2c3c4c5c6c7")),
+		},
+		input:  []byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it."),
+		output: hexBytes("d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d63dbea45e8ca9671282fafb69da92728b1a71de0a9e060b2905d6a5b67ecd3b3692ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc3ff4def08e4b7a9de576d26586cec64b61161ae10b594f09e26a7e902ecbd0600691"),
+	},
+}
+
+func hexBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// vectors checks the known answers of a plugin in both directions. Hashes and
+// ciphers without a vector fail unless noVectors explains why.
+func (r *runner) vectors(name string) {
+	found := false
+	for _, v := range vectors {
+		if v.plugin != name {
+			continue
+		}
+		found = true
+		res := Result{Plugin: name, Check: CheckVector, Case: v.source}
+		out, err := r.transform(name, v.options, v.input)
+		switch {
+		case err != nil:
+			res.Err = fmt.Errorf("process: %w", err)
+		case !bytes.Equal(out, v.output):
+			res.Err = fmt.Errorf("process returned %x, want %x", out, v.output)
+		}
+		if p, _, _ := plugins.Resolve(name); res.Err == nil && p.Unprocess != nil {
+			out, err := r.transform("."+name, v.options, v.output)
+			switch {
+			case err != nil:
+				res.Err = fmt.Errorf("unprocess: %w", err)
+			case !bytes.Equal(out, v.input):
+				res.Err = fmt.Errorf("unprocess returned %x, want %x", out, v.input)
+			}
+		}
+		r.add(res)
+	}
+	if found || (plugins.CategoryOf(name) != "hashs" && !slices.Contains(ciphers, name)) {
+		return
+	}
+	if reason, ok := noVectors[name]; ok {
+		r.add(Result{Plugin: name, Check: CheckVector, Skip: reason})
+		return
+	}
+	r.add(Result{Plugin: name, Check: CheckSpec, Err: fmt.Errorf("no known-answer vector")})
+}
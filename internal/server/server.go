@@ -35,8 +35,14 @@ type Config struct {
 	AuthPass string
 	CSP      string // Content-Security-Policy; empty disables the header
 	Log      bool
+	// ClientConfig, when non-empty, is served as JSON at ClientConfigPath so
+	// the web UI can pick up server-side settings such as execution limits.
+	ClientConfig []byte
 }
 
+// ClientConfigPath is the URL path of the client configuration document.
+const ClientConfigPath = "/deen-config.json"
+
 // Handler builds the HTTP handler for the given configuration. It is separate
 // from Run so it can be exercised in tests.
 func Handler(cfg Config) (http.Handler, error) {
@@ -51,6 +57,9 @@ func Handler(cfg Config) (http.Handler, error) {
 	}
 
 	var h http.Handler = assetHandler(fsys)
+	if len(cfg.ClientConfig) > 0 {
+		h = clientConfig(h, cfg.ClientConfig)
+	}
 	h = securityHeaders(h, cfg.CSP, cfg.TLSCert != "")
 	if cfg.AuthUser != "" {
 		h = basicAuth(h, cfg.AuthUser, cfg.AuthPass)
@@ -149,6 +158,27 @@ func servePrecompressed(w http.ResponseWriter, r *http.Request, fsys fs.FS) bool
 	return false
 }
 
+// clientConfig serves the static client configuration document, taking
+// precedence over a file of the same name in the served directory.
+func clientConfig(next http.Handler, data []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Clean(r.URL.Path) != ClientConfigPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method Not Allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	})
+}
+
 // wasmContentType ensures .wasm responses carry the correct media type, which
 // browsers require for WebAssembly.instantiateStreaming.
 func wasmContentType(next http.Handler) http.Handler {
@@ -148,3 +148,30 @@ func TestPrecompressedServing(t *testing.T) {
 		t.Errorf("expected plain file, got encoding=%q body=%q", rec.Header().Get("Content-Encoding"), rec.Body.String())
 	}
 }
+
+func TestHandlerClientConfig(t *testing.T) {
+	h, err := Handler(Config{Root: tempRoot(t), ClientConfig: []byte(`{"limits":{}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ClientConfigPath, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"limits":{}}` {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ClientConfigPath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: got %d", rec.Code)
+	}
+
+	h, _ = Handler(Config{Root: tempRoot(t)})
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ClientConfigPath, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without config: got %d", rec.Code)
+	}
+}
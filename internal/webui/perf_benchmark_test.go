@@ -66,7 +66,7 @@ func BenchmarkWebExamplesDetailPreparation(b *testing.B) {
 			webBenchmarkSink = example.Description
 			webBenchmarkSink = exampleChainSummary(example.Steps)
 			webBenchmarkSink = pipeline.DataMetadata(example.Source, 0).Summary()
-			webBenchmarkSink = example.WantContains
+			webBenchmarkSink = example.Want.Contains
 		}
 	}
 }
@@ -6,7 +6,9 @@
 package webui
 
 import (
+	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"sort"
@@ -54,6 +56,7 @@ var (
 	sourceFullHex      bool
 	sourceFullStrings  bool
 	stepCollapsed      = map[int]bool{}
+	outputRefs         []*outputRef
 )
 
 type cardRef struct {
@@ -115,6 +118,7 @@ type webRoute struct {
 func Run() {
 	doc = js.Global().Get("document")
 	pipe = pipeline.New()
+	loadClientConfig()
 	currentRoute = parseRoute()
 	loadChainFromRoute(currentRoute)
 	if currentRoute.tab != "" {
@@ -125,6 +129,42 @@ func Run() {
 	select {}
 }
 
+// loadClientConfig applies the execution limits published by `deen serve` at
+// deen-config.json. Static hosting without the document keeps the defaults.
+func loadClientConfig() {
+	var onResponse, onText, onError js.Func
+	release := func() {
+		onResponse.Release()
+		onText.Release()
+		onError.Release()
+	}
+	onText = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		defer release()
+		var cfg struct {
+			Limits *pipeline.Limits `json:"limits"`
+		}
+		if len(args) == 0 || json.Unmarshal([]byte(args[0].String()), &cfg) != nil || cfg.Limits == nil {
+			return nil
+		}
+		pipe.SetLimits(*cfg.Limits)
+		return nil
+	})
+	onError = js.FuncOf(func(js.Value, []js.Value) any {
+		release()
+		return nil
+	})
+	onResponse = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) == 0 || !args[0].Get("ok").Bool() {
+			release()
+			return nil
+		}
+		args[0].Call("text").Call("then", onText, onError)
+		return nil
+	})
+	js.Global().Call("fetch", "deen-config.json", map[string]any{"cache": "no-cache"}).
+		Call("then", onResponse, onError)
+}
+
 // --- small DOM helpers ---
 
 func el(tag string) js.Value { return doc.Call("createElement", tag) }
@@ -405,6 +445,7 @@ var iconPaths = map[string][]string{
 	"upload":       {"M12 21V9", "M7 14l5-5 5 5", "M5 3h14"},
 	"link":         {"M10 13a5 5 0 0 0 7.1 0l2-2a5 5 0 0 0-7.1-7.1l-1.2 1.2", "M14 11a5 5 0 0 0-7.1 0l-2 2A5 5 0 0 0 12 20.1l1.2-1.2"},
 	"terminal":     {"M4 17l6-5-6-5", "M12 19h8"},
+	"code":         {"M8 6l-6 6 6 6", "M16 6l6 6-6 6"},
 	"star":         {"M12 3l2.7 5.5 6.1.9-4.4 4.3 1 6.1L12 17l-5.4 2.8 1-6.1-4.4-4.3 6.1-.9z"},
 	"compare":      {"M8 6h13", "M8 12h13", "M8 18h13", "M3 6h.01", "M3 12h.01", "M3 18h.01"},
 	"undo":         {"M9 14l-5-5 5-5", "M4 9h10a6 6 0 0 1 0 12h-1"},
@@ -423,6 +464,9 @@ var iconPaths = map[string][]string{
 	"examples":     {"M4 19.5A2.5 2.5 0 0 1 6.5 17H20", "M4 4.5A2.5 2.5 0 0 1 6.5 2H20v20H6.5A2.5 2.5 0 0 1 4 19.5z"},
 	"plugins":      {"M9 3v5", "M15 3v5", "M6 8h12", "M7 8v4a5 5 0 0 0 10 0V8", "M12 17v4"},
 	"info":         {"M12 22a10 10 0 1 0 0-20 10 10 0 0 0 0 20z", "M12 16v-4", "M12 8h.01"},
+	"params":       {"M4 6h16", "M4 12h16", "M4 18h16", "M9 4v4", "M15 10v4", "M7 16v4"},
+	"invert":       {"M7 4L3 8l4 4", "M3 8h14", "M17 20l4-4-4-4", "M21 16H7"},
+	"chef":         {"M6 14a4 4 0 1 1 2-7.5 4 4 0 0 1 8 0 4 4 0 1 1 2 7.5", "M6 14v6h12v-6", "M6 17h12"},
 }
 
 func toolbarGroup(kids ...js.Value) js.Value {
@@ -733,8 +777,13 @@ func menuBar() js.Value {
 		menu("Chain",
 			menuChainPicker("upload", "Import chain"),
 			menuItem("download", "Export chain", exportChain),
+			menuItem("params", "Parameters", showParams),
 			menuItem("link", "Copy link", copyShareLink),
 			menuItem("terminal", "Copy command", copyCommand),
+			menuItem("code", "Copy Go code", func() { copyCode(pipeline.EmitGo) }),
+			menuItem("code", "Copy Python code", func() { copyCode(pipeline.EmitPython) }),
+			menuItem("invert", "Invert chain", invertChain),
+			menuItem("chef", "CyberChef recipe", showCyberChef),
 		),
 		menu("Workflow",
 			menuItem("star", "Presets", showPresets),
@@ -896,6 +945,7 @@ func renderHome() {
 		stepsEl.Call("appendChild", stepCard(i))
 	}
 	stepsEl.Call("appendChild", addCard())
+	stepsEl.Call("appendChild", branchCard())
 
 	appendChildren(main, stepsEl)
 	appendChildren(app, main)
@@ -915,8 +965,11 @@ func commandBar() js.Value {
 		commandGroup("Chain",
 			chainPicker(),
 			iconButton("", "upload", "Export chain", exportChain),
+			iconButton("", "params", "Parameters", showParams),
 			iconButton("", "link", "Copy link", copyShareLink),
 			iconButton("", "terminal", "Copy command", copyCommand),
+			iconButton("", "invert", "Invert chain", invertChain),
+			iconButton("", "chef", "CyberChef recipe", showCyberChef),
 		),
 		commandGroup("Workflow",
 			iconButton("", "star", "Presets", showPresets),
@@ -1120,9 +1173,9 @@ func populateExampleDetails(card js.Value, example pipeline.Example) {
 	})
 	appendChildren(actions, preview, load, copyLink)
 	appendChildren(card, desc, source, chain)
-	if example.WantContains != "" {
+	if example.Want.Contains != "" {
 		want := div("plugin-meta")
-		want.Set("textContent", "Expected result contains: "+example.WantContains)
+		want.Set("textContent", "Expected result contains: "+example.Want.Contains)
 		card.Call("appendChild", want)
 	}
 	appendChildren(card, previewSlot, actions)
@@ -1420,6 +1473,103 @@ func copyResult() {
 	}
 }
 
+// invertChain replaces the chain with its inverse and the source with the
+// result, so an edited result can be re-encoded the way the source was.
+func invertChain() {
+	runBusy("Inverting", func() {
+		if err := pipe.Invert(); err != nil {
+			alert(err.Error())
+			return
+		}
+		sourceName = ""
+		clearSourceFullViews()
+		rebuild()
+	})
+}
+
+// showCyberChef opens the modal that imports a CyberChef recipe as the chain
+// or exports the chain as a recipe. Notes on operations that were left out or
+// translated with a difference are listed below the recipe.
+func showCyberChef() {
+	wrap := div("modal-list")
+	recipe := textarea("")
+	recipe.Set("placeholder", "Paste a CyberChef recipe: JSON, Chef format or a CyberChef URL")
+	notes := el("pre")
+	notes.Get("style").Set("display", "none")
+	showNotes := func(text string) {
+		notes.Set("textContent", text)
+		if text == "" {
+			notes.Get("style").Set("display", "none")
+		} else {
+			notes.Get("style").Set("display", "")
+		}
+	}
+	format := selectOptionsEl("Format", []selectOption{
+		{value: "json", label: "JSON"},
+		{value: "chef", label: "Chef format"},
+		{value: "url", label: "CyberChef URL"},
+	}, "json")
+
+	var close func()
+	importRecipe := button("primary", "Import recipe", func() {
+		text := recipe.Get("value").String()
+		runBusy("Importing recipe", func() {
+			result, err := pipe.ImportCyberChef(text)
+			showNotes(cyberChefNotesText(result))
+			if err != nil {
+				alert(err.Error())
+				return
+			}
+			clearSourceFullViews()
+			rebuild()
+			if len(result) == 0 {
+				close()
+			}
+		})
+	})
+	exportRecipe := button("", "Export chain", func() {
+		ops, result, err := pipe.ExportCyberChef()
+		if err != nil {
+			alert(err.Error())
+			return
+		}
+		var text string
+		switch format.Get("value").String() {
+		case "chef":
+			text, err = pipeline.FormatCyberChefChef(ops)
+		case "url":
+			text, err = pipeline.FormatCyberChefURL(ops)
+		default:
+			var data []byte
+			data, err = pipeline.FormatCyberChefJSON(ops)
+			text = string(data)
+		}
+		if err != nil {
+			alert(err.Error())
+			return
+		}
+		recipe.Set("value", text)
+		autoSizeTextarea(recipe)
+		showNotes(cyberChefNotesText(result))
+		if clipboard := js.Global().Get("navigator").Get("clipboard"); clipboard.Truthy() {
+			clipboard.Call("writeText", text)
+		}
+	})
+	actions := div("modal-actions")
+	appendChildren(actions, importRecipe, exportRecipe, format)
+	appendChildren(wrap, recipe, actions, notes)
+	close = showModal("CyberChef recipe", wrap)
+}
+
+// cyberChefNotesText lists notes one per line.
+func cyberChefNotesText(notes []pipeline.CyberChefNote) string {
+	lines := make([]string, len(notes))
+	for i, n := range notes {
+		lines[i] = n.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
 func copyCommand() {
 	command := pipe.CommandLine()
 	if command == "" {
@@ -1435,6 +1585,22 @@ func copyCommand() {
 	js.Global().Call("prompt", "Command line:", command)
 }
 
+// copyCode copies the chain as a standalone program in lang.
+func copyCode(lang string) {
+	program, err := pipe.Emit(lang)
+	if err != nil {
+		alert(err.Error())
+		return
+	}
+	clipboard := js.Global().Get("navigator").Get("clipboard")
+	if clipboard.Truthy() {
+		clipboard.Call("writeText", program)
+		alert("Code copied.")
+		return
+	}
+	js.Global().Call("prompt", "Code:", program)
+}
+
 func downloadResult() {
 	downloadBytes(resultDownloadName(), pipe.Result())
 }
@@ -1467,15 +1633,21 @@ func copyShareLink() {
 
 func showSuggestions() {
 	suggestions := pipeline.Suggestions(pipe.Result())
-	if len(suggestions) == 0 {
-		alert("No likely transforms detected.")
-		return
-	}
 	sort.SliceStable(suggestions, func(i, j int) bool {
 		return suggestions[i].Confidence > suggestions[j].Confidence
 	})
 	list := div("modal-list")
 	var close func()
+	hide := func() {
+		if close != nil {
+			close()
+		}
+	}
+	if len(suggestions) == 0 {
+		empty := div("empty")
+		empty.Set("textContent", "No likely transforms detected.")
+		list.Call("appendChild", empty)
+	}
 	for _, suggestion := range suggestions {
 		s := suggestion
 		item := div("modal-item")
@@ -1501,9 +1673,7 @@ func showSuggestions() {
 			action.Set("textContent", "Add")
 		}
 		on(action, "click", func() {
-			if close != nil {
-				close()
-			}
+			hide()
 			runBusy("Processing", func() {
 				pipe.AddSuggestion(s)
 				rebuild()
@@ -1512,9 +1682,107 @@ func showSuggestions() {
 		item.Call("appendChild", action)
 		list.Call("appendChild", item)
 	}
+	list.Call("appendChild", sweepForm(hide))
 	close = showModal("Suggested transforms", list)
 }
 
+// maxSweepRows bounds the ranked results listed in the suggestions modal.
+const maxSweepRows = 10
+
+// sweepForm builds the sweep section of the suggestions modal: it runs a
+// plugin over ranges of option values on the current result and lists the
+// best outputs, each of which can be added as a step. hide closes the modal.
+func sweepForm(hide func()) js.Value {
+	wrap := div("modal-item sweep")
+	title := el("strong")
+	title.Set("textContent", "Sweep options")
+	help := el("p")
+	help.Set("textContent", "Try every value of plugin options on the current result and rank the outputs. Separate options with spaces; values are lists and ranges like 0x00..0xff, or * for every choice.")
+
+	var names []selectOption
+	for _, name := range plugins.Names() {
+		if len(pipeline.PluginOptions(name)) > 0 {
+			names = append(names, selectOption{value: name, label: name})
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].value < names[j].value })
+	plugin := selectOptionsEl("Plugin", names, "xor")
+	decodeWrap, decode := checkbox("decode", false)
+	vary := textInput("order=0,1 lit-width=6..8", "value=0x00..0xff")
+	vary.Set("className", "branch-chain")
+	modes := make([]selectOption, 0, len(pipeline.ScoreModes))
+	for _, mode := range pipeline.ScoreModes {
+		modes = append(modes, selectOption{value: mode, label: "Score: " + mode})
+	}
+	score := selectOptionsEl("Score", modes, pipeline.ScoreAuto)
+	crib := textInput("crib, e.g. flag{", "")
+	results := div("modal-list")
+
+	run := button("", "Run sweep", func() {
+		sweep := pipeline.Sweep{
+			Plugin:    plugin.Get("value").String(),
+			Unprocess: decode.Get("checked").Bool(),
+			Score:     score.Get("value").String(),
+			Crib:      crib.Get("value").String(),
+		}
+		for _, spec := range strings.Fields(vary.Get("value").String()) {
+			p, err := pipeline.ParseSweepParam(spec)
+			if err != nil {
+				alert(err.Error())
+				return
+			}
+			sweep.Params = append(sweep.Params, p)
+		}
+		runBusy("Sweeping", func() {
+			ranked, err := sweep.Run(context.Background(), pipe.Limits(), pipe.Result())
+			if err != nil {
+				alert(err.Error())
+				return
+			}
+			showSweepResults(results, sweep, ranked, hide)
+		})
+	})
+	controls := div("branch-add")
+	appendChildren(controls, plugin, decodeWrap, vary, score, crib, run)
+	appendChildren(wrap, title, help, controls, results)
+	return wrap
+}
+
+// showSweepResults lists the best sweep results in box.
+func showSweepResults(box js.Value, sweep pipeline.Sweep, ranked []pipeline.SweepResult, hide func()) {
+	box.Set("innerHTML", "")
+	if len(ranked) > maxSweepRows {
+		ranked = ranked[:maxSweepRows]
+	}
+	for _, r := range ranked {
+		var varied []string
+		for _, p := range sweep.Params {
+			varied = append(varied, p.Name+"="+r.Options[p.Name])
+		}
+		item := div("modal-item")
+		title := el("strong")
+		title.Set("textContent", strconv.Itoa(r.Score)+" · "+strings.Join(varied, " "))
+		preview := el("pre")
+		if r.Error != "" {
+			preview.Set("textContent", "Error: "+r.Error)
+		} else {
+			preview.Set("textContent", r.Preview)
+		}
+		appendChildren(item, title, preview)
+		if r.Error == "" {
+			step := sweep.Step(r)
+			item.Call("appendChild", button("", "Add", func() {
+				hide()
+				runBusy("Processing", func() {
+					pipe.AddStepWithOptions(step.Plugin, step.Unprocess, step.Options)
+					rebuild()
+				})
+			}))
+		}
+		box.Call("appendChild", item)
+	}
+}
+
 func searchPlugins() {
 	wrap := div("search-panel")
 	query := textInput("Search transformers", "")
@@ -1624,6 +1892,80 @@ func showPresets() {
 	close = showModal("Presets", list)
 }
 
+// showParams prompts for the values of the parameters declared by the chain.
+// Secret parameters use password inputs; an empty input keeps the default.
+func showParams() {
+	params := pipe.Params()
+	if len(params) == 0 {
+		alert("This chain declares no parameters. Parameters are declared in the chain file and referenced from step options as ${name}.")
+		return
+	}
+	list := div("modal-list")
+	inputs := make([]js.Value, len(params))
+	for i, pr := range params {
+		item := div("modal-item option")
+		label := el("label")
+		label.Set("className", "option-label")
+		label.Set("textContent", pr.Name)
+		input := el("input")
+		input.Set("type", "text")
+		if pr.Secret {
+			input.Set("type", "password")
+		}
+		if pr.Default != "" {
+			input.Set("placeholder", "default: "+pr.Default)
+		}
+		if v, ok := pipe.ParamValue(pr.Name); ok && v != pr.Default {
+			input.Set("value", v)
+		}
+		input.Set("title", pr.Description)
+		on(input, "input", func() {
+			value := input.Get("value").String()
+			validity := ""
+			if value != "" || pr.Default == "" {
+				if err := pr.Check(value); err != nil {
+					validity = pr.Name + " " + err.Error()
+				}
+			}
+			input.Call("setCustomValidity", validity)
+		})
+		inputs[i] = input
+		appendChildren(item, label, input)
+		if pr.Description != "" {
+			desc := el("p")
+			desc.Set("textContent", pr.Description)
+			item.Call("appendChild", desc)
+		}
+		list.Call("appendChild", item)
+	}
+	actions := div("modal-actions")
+	apply := el("button")
+	apply.Set("type", "button")
+	apply.Set("className", "primary")
+	apply.Set("textContent", "Apply")
+	actions.Call("appendChild", apply)
+	list.Call("appendChild", actions)
+	close := showModal("Chain parameters", list)
+	on(apply, "click", func() {
+		values := map[string]string{}
+		for i, pr := range params {
+			value := inputs[i].Get("value").String()
+			if value == "" && pr.Default != "" {
+				value = pr.Default
+			}
+			values[pr.Name] = value
+		}
+		runBusy("Processing", func() {
+			if err := pipe.SetParams(values); err != nil {
+				alert(err.Error())
+				return
+			}
+			close()
+			rebuild()
+		})
+	})
+}
+
 type comparePoint struct {
 	label string
 	data  []byte
@@ -1637,6 +1979,9 @@ func comparePoints() []comparePoint {
 			data:  pipe.Output(i),
 		})
 	}
+	for _, b := range pipe.Branches() {
+		points = append(points, comparePoint{label: "Output " + b.Name, data: b.Result()})
+	}
 	return points
 }
 
@@ -1737,6 +2082,9 @@ func loadChainFromRoute(route webRoute) {
 		return
 	}
 	compactStepCollapseState()
+	if len(pipe.Params()) > 0 {
+		afterPaint(showParams)
+	}
 }
 
 func downloadBytes(name string, data []byte) {
@@ -1843,6 +2191,9 @@ func loadChainFile(file js.Value) {
 				clearSourceFullViews()
 				compactStepCollapseState()
 				rebuild()
+				if len(pipe.Params()) > 0 {
+					showParams()
+				}
 			}
 		})
 		loadCB.Release()
@@ -2212,26 +2563,30 @@ func stepCard(i int) js.Value {
 	styleStepToggle(decodeWrap, decodeInput, "mode-toggle", "Decode mode", step.Unprocess && canDecode)
 	decodeInput.Set("checked", step.Unprocess && canDecode)
 
-	// One dropdown per category.
+	// One dropdown per category, or the split settings of a map step.
 	selRow := div("selectors")
-	for _, cat := range plugins.PluginCategories {
-		selected := ""
-		if plugins.CategoryOf(step.Plugin) == cat {
-			selected = step.Plugin
-		}
-		sel := selectOptionsEl(plugins.CategoryLabel(cat), pluginSelectOptions(cat), selected)
-		on(sel, "change", func() {
-			name := sel.Get("value").String()
-			if name == "" {
-				return
+	if step.Map != nil {
+		selRow = mapEditor(i, summary)
+	} else {
+		for _, cat := range plugins.PluginCategories {
+			selected := ""
+			if plugins.CategoryOf(step.Plugin) == cat {
+				selected = step.Plugin
 			}
-			decode := decodeInput.Get("checked").Bool() && plugins.CanDecode(name)
-			runBusy("Processing", func() {
-				pipe.SetPlugin(i, name, decode)
-				rebuild()
+			sel := selectOptionsEl(plugins.CategoryLabel(cat), pluginSelectOptions(cat), selected)
+			on(sel, "change", func() {
+				name := sel.Get("value").String()
+				if name == "" {
+					return
+				}
+				decode := decodeInput.Get("checked").Bool() && plugins.CanDecode(name)
+				runBusy("Processing", func() {
+					pipe.SetPlugin(i, name, decode)
+					rebuild()
+				})
 			})
-		})
-		selRow.Call("appendChild", sel)
+			selRow.Call("appendChild", sel)
+		}
 	}
 
 	on(decodeInput, "change", func() {
@@ -2249,7 +2604,7 @@ func stepCard(i int) js.Value {
 	}
 
 	options := div("options")
-	buildOptions(options, i)
+	buildOptions(options, i, summary)
 
 	ref.output = textareaWithMax("", 960)
 	on(ref.output, "input", func() {
@@ -2425,12 +2780,9 @@ func autoSizeViewerPanel(panel js.Value) {
 	}
 }
 
-func buildOptions(container js.Value, i int) {
+func buildOptions(container js.Value, i int, summary js.Value) {
 	step := pipe.Steps()[i]
 	opts := pipeline.PluginOptions(step.Plugin)
-	if len(opts) == 0 {
-		return
-	}
 	checkGroup, checkBody := optionGroup("Checkboxes")
 	fieldGroup, fieldBody := optionGroup("Inputs")
 	var hasChecks, hasFields bool
@@ -2439,7 +2791,7 @@ func buildOptions(container js.Value, i int) {
 		row := div("option")
 		row.Set("title", opt.Usage)
 		row.Get("classList").Call("toggle", "option-multiline", opt.Multiline)
-		if opt.IsBool {
+		if opt.IsBool && !pipeline.HasParamRef(step.Options[opt.Name]) {
 			wrap, input := checkbox(opt.Label, step.Options[opt.Name] == "true")
 			on(input, "change", func() {
 				val := "false"
@@ -2463,7 +2815,9 @@ func buildOptions(container js.Value, i int) {
 			if v, ok := step.Options[opt.Name]; ok {
 				current = v
 			}
-			if opt.Kind == "select" {
+			// An option bound to a chain parameter is edited as text.
+			paramRef := pipeline.HasParamRef(current)
+			if opt.Kind == "select" && !paramRef {
 				input = selectEl("", opt.Choices, current)
 				input.Set("title", opt.Description)
 				on(input, "change", func() {
@@ -2482,13 +2836,17 @@ func buildOptions(container js.Value, i int) {
 				} else {
 					input = el("input")
 					inputType := "text"
-					if opt.Kind == "number" {
+					if opt.Kind == "number" && !paramRef {
 						inputType = "number"
 					}
 					if opt.Kind == "secret" || opt.Secret {
 						inputType = "password"
 					}
 					input.Set("type", inputType)
+					if opt.Range != nil {
+						input.Set("min", opt.Range.Min)
+						input.Set("max", opt.Range.Max)
+					}
 					input.Set("placeholder", optionPlaceholder(opt))
 					input.Set("title", opt.Description)
 					if v, ok := step.Options[opt.Name]; ok {
@@ -2496,7 +2854,15 @@ func buildOptions(container js.Value, i int) {
 					}
 				}
 				on(input, "input", func() {
-					pipe.SetOption(i, opt.Name, input.Get("value").String())
+					value := input.Get("value").String()
+					validity := ""
+					if value != "" && !pipeline.HasParamRef(value) {
+						if err := opt.Check(value); err != nil {
+							validity = opt.Label + " " + err.Error()
+						}
+					}
+					input.Call("setCustomValidity", validity)
+					pipe.SetOption(i, opt.Name, value)
 					refreshOutputs(i)
 				})
 			}
@@ -2511,6 +2877,101 @@ func buildOptions(container js.Value, i int) {
 	if hasFields {
 		container.Call("appendChild", fieldGroup)
 	}
+	if step.Plugin != "" {
+		container.Call("appendChild", regionGroup(i, step.Region, summary))
+	}
+}
+
+// regionGroup builds the input that restricts step i to a region of its
+// input. An invalid region keeps the last valid one.
+func regionGroup(i int, current string, summary js.Value) js.Value {
+	group, body := optionGroup("Region")
+	row := div("option")
+	input := el("input")
+	input.Set("type", "text")
+	input.Set("placeholder", "whole input, or 10:32, re:token=([^&]+), jq:.data")
+	input.Set("value", current)
+	help := div("option-help")
+	help.Set("textContent", "Transforms only this part of the input and splices the result back in: OFFSET:LENGTH, re:REGEX (first capture group) or jq:PATH for JSON.")
+	on(input, "input", func() {
+		value := input.Get("value").String()
+		validity := ""
+		if value != "" {
+			if err := pipeline.CheckRegion(value); err != nil {
+				validity = err.Error()
+			}
+		}
+		input.Call("setCustomValidity", validity)
+		if validity != "" {
+			return
+		}
+		pipe.SetRegion(i, value)
+		summary.Set("textContent", summaryText(i))
+		refreshOutputs(i)
+	})
+	appendChildren(row, input, help)
+	body.Call("appendChild", row)
+	return group
+}
+
+// mapEditor builds the split and nested chain controls of map step i. The
+// nested chain is edited as a chain expression; an unparsable chain keeps the
+// last valid one.
+func mapEditor(i int, summary js.Value) js.Value {
+	m := pipe.Steps()[i].Map
+	group, body := optionGroup("Map over pieces")
+	row := func(label string, input js.Value, help string) {
+		r := div("option")
+		l := el("label")
+		l.Set("className", "option-label")
+		l.Set("textContent", label)
+		h := div("option-help")
+		h.Set("textContent", help)
+		appendChildren(r, l, input, h)
+		body.Call("appendChild", r)
+	}
+	split := selectEl("", pipeline.SplitModes, m.Split)
+	pattern := el("input")
+	pattern.Set("type", "text")
+	pattern.Set("value", m.Pattern)
+	chain := el("input")
+	chain.Set("type", "text")
+	chain.Set("placeholder", ".base64 | .gzip")
+	chain.Set("value", pipeline.MapChainString(m.Steps))
+	placeholders := map[string]string{
+		pipeline.SplitLines:     "not used for lines",
+		pipeline.SplitDelimiter: `delimiter, e.g. , or \t`,
+		pipeline.SplitRegex:     "regex, e.g. token=([^&]+)",
+		pipeline.SplitJSON:      "JSONPath, default: $[*]",
+	}
+	pattern.Set("placeholder", placeholders[m.Split])
+	row("Split", split, "Pieces are lines, text between delimiters, regex matches (or their first group) or selected JSON values.")
+	row("Pattern", pattern, "")
+	row("Chain per piece", chain, "Runs on each piece; the results are put back in place.")
+
+	apply := func() {
+		steps, err := pipeline.ParseMapChain(chain.Get("value").String())
+		validity := ""
+		if err != nil {
+			validity = err.Error()
+		}
+		chain.Call("setCustomValidity", validity)
+		if err != nil || split.Get("value").String() == "" {
+			return
+		}
+		pattern.Set("placeholder", placeholders[split.Get("value").String()])
+		pipe.SetMap(i, pipeline.Map{
+			Split:   split.Get("value").String(),
+			Pattern: pattern.Get("value").String(),
+			Steps:   steps,
+		})
+		summary.Set("textContent", summaryText(i))
+		refreshOutputs(i)
+	}
+	on(split, "change", func() { runBusy("Processing", apply) })
+	on(pattern, "input", apply)
+	on(chain, "input", apply)
+	return group
 }
 
 func optionGroup(title string) (js.Value, js.Value) {
@@ -2523,10 +2984,14 @@ func optionGroup(title string) (js.Value, js.Value) {
 }
 
 func optionPlaceholder(opt pipeline.Option) string {
-	if opt.Default == "" {
-		return opt.Label
+	placeholder := opt.Label
+	if opt.Default != "" {
+		placeholder = "default: " + opt.Default
+	}
+	if opt.Open && len(opt.Choices) > 0 {
+		placeholder += " (e.g. " + strings.Join(opt.Choices, ", ") + ")"
 	}
-	return "default: " + opt.Default
+	return placeholder
 }
 
 func optionHelp(opt pipeline.Option) js.Value {
@@ -2565,6 +3030,12 @@ func addCard() js.Value {
 	appendChildren(actions,
 		button("", "Search transformers", searchPlugins),
 		button("", "Detect next", showSuggestions),
+		button("", "Map over pieces", func() {
+			runBusy("Processing", func() {
+				pipe.AddMapStep(pipeline.Map{Split: pipeline.SplitLines})
+				rebuild()
+			})
+		}),
 	)
 	appendChildren(header, titleBlock, actions)
 	selRow := div("add-selectors")
@@ -2590,6 +3061,184 @@ func addCard() js.Value {
 	return card
 }
 
+// outputRef holds the elements that show the result of one pipeline output
+// in the branch tree.
+type outputRef struct {
+	branch int // index in pipe.Branches(), -1 for the main chain
+	meta   js.Value
+	body   js.Value
+}
+
+// forkLabel names the data a branch forks off: the input or a step output.
+func forkLabel(after int) string {
+	if after == 0 {
+		return "Input"
+	}
+	step := pipe.Steps()[after-1]
+	name := plugins.PluginLabel(step.Plugin)
+	if step.Unprocess {
+		name = "." + name
+	}
+	return fmt.Sprintf("Step %d · %s", after, name)
+}
+
+// branchCard builds the outputs card: the main chain result and the branches
+// grouped under the input or step they fork off.
+func branchCard() js.Value {
+	outputRefs = outputRefs[:0]
+	card := div("card branches")
+	title := el("div")
+	title.Set("className", "card-title")
+	title.Set("textContent", "Outputs")
+	subtitle := div("add-subtitle")
+	subtitle.Set("textContent", "Branches fork off the input or a step and run their own chain on it. Each branch result is a named output next to the main result.")
+	tree := div("branch-tree")
+	tree.Call("appendChild", outputView(-1))
+	branches := pipe.Branches()
+	for after := 0; after <= pipe.Len(); after++ {
+		list := div("branch-list")
+		for b, branch := range branches {
+			if branch.After == after {
+				list.Call("appendChild", branchNode(b))
+			}
+		}
+		if list.Get("childElementCount").Int() == 0 {
+			continue
+		}
+		fork := div("branch-fork")
+		forkTitle := div("branch-fork-title")
+		forkTitle.Set("textContent", forkLabel(after))
+		appendChildren(fork, forkTitle, list)
+		tree.Call("appendChild", fork)
+	}
+	appendChildren(card, title, subtitle, tree, addBranchForm())
+	return card
+}
+
+// branchNode builds the editor of branch b: its name, chain and result.
+func branchNode(b int) js.Value {
+	branch := pipe.Branches()[b]
+	node := div("branch")
+	header := div("branch-header")
+	name := textInput("name", branch.Name)
+	name.Set("title", "Output name")
+	// Renaming keeps the last valid name while the input holds an invalid or
+	// taken one.
+	on(name, "input", func() {
+		validity := ""
+		if err := pipe.RenameBranch(b, name.Get("value").String()); err != nil {
+			validity = err.Error()
+		}
+		name.Call("setCustomValidity", validity)
+	})
+	chain := textInput("no steps: the data the branch forks off", branch.ChainString())
+	chain.Set("className", "branch-chain")
+	on(chain, "input", func() {
+		steps, err := pipeline.ParseMapChain(chain.Get("value").String())
+		if err == nil {
+			err = pipe.SetBranchSteps(b, steps)
+		}
+		validity := ""
+		if err != nil {
+			validity = err.Error()
+		}
+		chain.Call("setCustomValidity", validity)
+		if err == nil {
+			refreshOutputs(pipe.Len())
+		}
+	})
+	remove := iconOnlyButton("trash", "Delete branch", func() {
+		pipe.RemoveBranch(b)
+		rebuild()
+	})
+	appendChildren(header, name, chain, remove)
+	appendChildren(node, header, outputView(b))
+	return node
+}
+
+// outputView builds the result view of an output and registers it for
+// refreshOutputs.
+func outputView(branch int) js.Value {
+	view := div("branch-output")
+	header := div("branch-output-header")
+	ref := &outputRef{branch: branch, meta: div("meta"), body: textareaWithMax("", 240)}
+	ref.body.Set("readOnly", true)
+	if branch < 0 {
+		name := el("span")
+		name.Set("className", "chain-plugin")
+		name.Set("textContent", pipeline.MainOutput)
+		header.Call("appendChild", name)
+	}
+	copyButton := iconOnlyButton("copy", "Copy output", func() {
+		clipboard := js.Global().Get("navigator").Get("clipboard")
+		if clipboard.Truthy() {
+			clipboard.Call("writeText", string(branchOutputData(ref.branch)))
+		}
+	})
+	appendChildren(header, ref.meta, copyButton)
+	appendChildren(view, header, ref.body)
+	outputRefs = append(outputRefs, ref)
+	renderBranchOutput(ref)
+	return view
+}
+
+// branchOutputData returns the result of branch b, or of the main chain for
+// -1.
+func branchOutputData(b int) []byte {
+	if b < 0 {
+		return pipe.Result()
+	}
+	return pipe.Branches()[b].Result()
+}
+
+func renderBranchOutput(ref *outputRef) {
+	data := branchOutputData(ref.branch)
+	meta := pipeline.DataMetadata(data, 0).Summary()
+	failed := false
+	if ref.branch >= 0 {
+		if err := pipe.Branches()[ref.branch].Err(); err != nil {
+			meta, failed = "Error: "+err.Error(), true
+		}
+	}
+	ref.meta.Set("textContent", meta)
+	ref.meta.Get("classList").Call("toggle", "error", failed)
+	text, _ := pipeline.TextDisplay(data)
+	ref.body.Set("value", text)
+	autoSizeTextareaSoon(ref.body)
+}
+
+// addBranchForm builds the controls that add a branch off the input or a
+// step.
+func addBranchForm() js.Value {
+	form := div("branch-add")
+	forks := make([]selectOption, 0, pipe.Len()+1)
+	for after := 0; after <= pipe.Len(); after++ {
+		forks = append(forks, selectOption{value: strconv.Itoa(after), label: forkLabel(after)})
+	}
+	fork := selectOptionsEl("", forks, strconv.Itoa(pipe.Len()))
+	fork.Set("title", "Fork off")
+	name := textInput("name, e.g. zlib", "")
+	chain := textInput(".zlib | hex", "")
+	chain.Set("className", "branch-chain")
+	add := button("", "Add branch", func() {
+		after, _ := strconv.Atoi(fork.Get("value").String())
+		steps, err := pipeline.ParseMapChain(chain.Get("value").String())
+		if err != nil {
+			alert(err.Error())
+			return
+		}
+		runBusy("Processing", func() {
+			if _, err := pipe.AddBranch(name.Get("value").String(), after, steps); err != nil {
+				alert(err.Error())
+				return
+			}
+			rebuild()
+		})
+	})
+	appendChildren(form, fork, name, chain, add)
+	return form
+}
+
 func updateHistory() {
 	historyEl.Set("innerHTML", "")
 	historyEl.Get("style").Set("display", "none")
@@ -2634,8 +3283,14 @@ func liveChainStepPill(i int, step *pipeline.Step) js.Value {
 	for k, v := range step.Options {
 		metaParts = append(metaParts, k+"="+v)
 	}
+	sort.Strings(metaParts)
+	if step.Map != nil {
+		metaParts = append(metaParts, step.Map.Summary())
+	}
+	if step.Region != "" {
+		metaParts = append(metaParts, "region="+step.Region)
+	}
 	if len(metaParts) > 0 {
-		sort.Strings(metaParts)
 		meta := el("span")
 		meta.Set("className", "chain-options")
 		meta.Set("textContent", strings.Join(metaParts, ", "))
@@ -2650,6 +3305,9 @@ func refreshOutputs(from int) {
 			renderOutput(c)
 		}
 	}
+	for _, ref := range outputRefs {
+		renderBranchOutput(ref)
+	}
 	updateHistory()
 }
 
@@ -2883,12 +3541,21 @@ func summaryText(i int) string {
 	if step.Plugin == "" {
 		return "(no transform)"
 	}
-	dir := "encode"
-	if step.Unprocess {
-		dir = "decode"
+	var text string
+	if step.Map != nil {
+		text = "map · " + step.Map.Summary()
+	} else {
+		dir := "encode"
+		if step.Unprocess {
+			dir = "decode"
+		}
+		text = fmt.Sprintf("%s / %s · %s", plugins.CategoryLabel(plugins.CategoryOf(step.Plugin)), plugins.PluginLabel(step.Plugin), dir)
+	}
+	if step.Region != "" {
+		text += " · " + step.Region
 	}
 	if step.Disabled {
-		dir += " · disabled"
+		text += " · disabled"
 	}
-	return fmt.Sprintf("%s / %s · %s", plugins.CategoryLabel(plugins.CategoryOf(step.Plugin)), plugins.PluginLabel(step.Plugin), dir)
+	return text
 }
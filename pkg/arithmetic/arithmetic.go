@@ -11,17 +11,30 @@ import (
 )
 
 func byteValue(flags *flag.FlagSet, name string) (byte, error) {
-	raw := strings.TrimSpace(flags.Lookup(name).Value.String())
+	b, err := parseByteValue(flags.Lookup(name).Value.String())
+	if err != nil {
+		return 0, fmt.Errorf("%s %s", name, err)
+	}
+	return b, nil
+}
+
+func parseByteValue(value string) (byte, error) {
+	raw := strings.TrimSpace(value)
 	if len(raw) == 1 && (raw[0] < '0' || raw[0] > '9') {
 		return raw[0], nil
 	}
 	n, err := strconv.ParseUint(raw, 0, 8)
 	if err != nil {
-		return 0, fmt.Errorf("%s must be a byte value such as 42, 0x2a, or '*'", name)
+		return 0, fmt.Errorf("must be a byte value such as 42, 0x2a, or '*'")
 	}
 	return byte(n), nil
 }
 
+func validateByteValue(value string) error {
+	_, err := parseByteValue(value)
+	return err
+}
+
 func registerValueFlag(defaultValue, usage string) func(*flag.FlagSet) {
 	return func(flags *flag.FlagSet) {
 		flags.String("value", defaultValue, usage)
@@ -58,6 +71,9 @@ func NewPluginXOR() *types.DeenPlugin {
 	p.Category = "arithmetic"
 	p.Description = "XOR every input byte with a byte value."
 	p.RegisterFlags = registerValueFlag("0xff", "byte value to XOR with")
+	p.Options = []types.OptionSpec{
+		{Name: "value", Label: "XOR value", Description: "Byte value as decimal, hex such as 0x2a, or a single character.", Type: types.OptionText, Validate: validateByteValue},
+	}
 	transform := func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		value, err := byteValue(flags, "value")
 		if err != nil {
@@ -79,6 +95,9 @@ func NewPluginAdd() *types.DeenPlugin {
 	p.Category = "arithmetic"
 	p.Description = "Add a byte value to every input byte, wrapping at 255."
 	p.RegisterFlags = registerValueFlag("1", "byte value to add")
+	p.Options = []types.OptionSpec{
+		{Name: "value", Label: "Add value", Description: "Byte value as decimal, hex such as 0x2a, or a single character.", Type: types.OptionText, Validate: validateByteValue},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		value, err := byteValue(flags, "value")
 		if err != nil {
@@ -105,6 +124,9 @@ func NewPluginSub() *types.DeenPlugin {
 	p.Category = "arithmetic"
 	p.Description = "Subtract a byte value from every input byte, wrapping at 0."
 	p.RegisterFlags = registerValueFlag("1", "byte value to subtract")
+	p.Options = []types.OptionSpec{
+		{Name: "value", Label: "Subtract value", Description: "Byte value as decimal, hex such as 0x2a, or a single character.", Type: types.OptionText, Validate: validateByteValue},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		value, err := byteValue(flags, "value")
 		if err != nil {
@@ -60,6 +60,9 @@ func NewPluginASCII() *types.DeenPlugin {
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.String("mode", "strict", "non-ASCII handling mode (strict, replace, strip, escape)")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "mode", Label: "Non-ASCII handling", Description: "How to handle non-ASCII bytes.", Choices: []string{"strict", "replace", "strip", "escape"}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		data, err := io.ReadAll(r)
 		if err != nil {
@@ -31,6 +31,10 @@ func NewPluginBase32() *types.DeenPlugin {
 		flags.Bool("hex", false, "use \"Extended Hex Alphabet\" defined in RFC 4648")
 		flags.Bool("no-pad", false, "disable padding")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "hex", Label: "Extended hex alphabet", Description: "Use the RFC 4648 extended hex alphabet."},
+		{Name: "no-pad", Label: "No padding", Description: "Omit Base32 padding characters."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		enc := base32Encoding(flags)
 		return encodeStream(r, w, func(w io.Writer) io.WriteCloser { return base32.NewEncoder(enc, w) })
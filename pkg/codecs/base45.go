@@ -0,0 +1,162 @@
+package codecs
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/takeshixx/deen/pkg/helpers"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+const base45Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// base45Encoder encodes every two bytes written to it as three characters
+// (RFC 9285) and the final odd byte as two characters on Close.
+type base45Encoder struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (e *base45Encoder) Write(p []byte) (int, error) {
+	n := len(p)
+	data := append(e.pending, p...)
+	out := make([]byte, 0, len(data)/2*3)
+	for len(data) >= 2 {
+		v := int(data[0])<<8 | int(data[1])
+		out = append(out, base45Alphabet[v%45], base45Alphabet[v/45%45], base45Alphabet[v/2025])
+		data = data[2:]
+	}
+	e.pending = append(e.pending[:0], data...)
+	if _, err := e.w.Write(out); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (e *base45Encoder) Close() error {
+	if len(e.pending) == 0 {
+		return nil
+	}
+	v := int(e.pending[0])
+	_, err := e.w.Write([]byte{base45Alphabet[v%45], base45Alphabet[v/45]})
+	return err
+}
+
+// base45Decoder decodes three characters at a time as written to it. Line
+// breaks are skipped; spaces belong to the alphabet and are decoded.
+type base45Decoder struct {
+	w       io.Writer
+	pending []byte
+	offset  int
+}
+
+func (d *base45Decoder) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p)/3*2+2)
+	for _, ch := range p {
+		if ch == '\r' || ch == '\n' {
+			d.offset++
+			continue
+		}
+		i := bytes.IndexByte([]byte(base45Alphabet), ch)
+		if i < 0 {
+			return 0, fmt.Errorf("illegal base45 character %q at offset %d", ch, d.offset)
+		}
+		d.offset++
+		d.pending = append(d.pending, byte(i))
+		if len(d.pending) < 3 {
+			continue
+		}
+		v := int(d.pending[0]) + int(d.pending[1])*45 + int(d.pending[2])*2025
+		if v > 0xffff {
+			return 0, fmt.Errorf("base45 triplet ending at offset %d exceeds 65535", d.offset)
+		}
+		out = append(out, byte(v>>8), byte(v))
+		d.pending = d.pending[:0]
+	}
+	if _, err := d.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (d *base45Decoder) Close() error {
+	switch len(d.pending) {
+	case 0:
+		return nil
+	case 1:
+		return errors.New("base45 input ends with a single character")
+	}
+	v := int(d.pending[0]) + int(d.pending[1])*45
+	if v > 0xff {
+		return errors.New("final base45 pair exceeds 255")
+	}
+	_, err := d.w.Write([]byte{byte(v)})
+	return err
+}
+
+// NewPluginBase45 creates a base45 plugin (RFC 9285).
+func NewPluginBase45() *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = "base45"
+	p.Aliases = []string{".base45", "b45", ".b45"}
+	p.Category = "codecs"
+	p.Description = "Base45 encoding as specified by RFC 9285, used in QR codes such as EU\nDigital COVID Certificates (\"HC1:\" prefixed)."
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.String("prefix", "", "prefix added when encoding and required when decoding (e.g. HC1:)")
+	}
+	p.Options = []types.OptionSpec{
+		{Name: "prefix", Label: "Prefix", Description: "Text such as HC1: that precedes the Base45 data."},
+	}
+	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		if _, err := io.WriteString(w, helpers.StringFlag(flags, "prefix")); err != nil {
+			return err
+		}
+		return encodeStream(r, w, func(w io.Writer) io.WriteCloser { return &base45Encoder{w: w} })
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		if prefix := helpers.StringFlag(flags, "prefix"); prefix != "" {
+			head := make([]byte, len(prefix))
+			if _, err := io.ReadFull(r, head); err != nil || string(head) != prefix {
+				return fmt.Errorf("base45 input does not start with %q", prefix)
+			}
+		}
+		return encodeStream(r, w, func(w io.Writer) io.WriteCloser { return &base45Decoder{w: w} })
+	}
+	p.Detect = func(data []byte) (types.Detection, bool) {
+		data = bytes.TrimRight(data, "\r\n")
+		if bytes.HasPrefix(data, []byte("HC1:")) && base45Valid(data[4:]) {
+			return types.Detection{
+				Options:    map[string]string{"prefix": "HC1:"},
+				Label:      "Decode Base45",
+				Reason:     "input is an HC1: prefixed Base45 health certificate",
+				Confidence: 90,
+			}, true
+		}
+		// Base45 is upper case with a few symbols; require one of the symbols
+		// that set it apart from plain words and base32.
+		if len(data) < 12 || !base45Valid(data) || !bytes.ContainsAny(data, "$%*+-./:") {
+			return types.Detection{}, false
+		}
+		return types.Detection{
+			Label:      "Decode Base45",
+			Reason:     "input only uses the Base45 alphabet and decodes cleanly",
+			Confidence: 50,
+		}, true
+	}
+	return p
+}
+
+// base45Valid reports whether data is complete, decodable base45.
+func base45Valid(data []byte) bool {
+	if len(data) == 0 || len(data)%3 == 1 {
+		return false
+	}
+	d := &base45Decoder{w: io.Discard}
+	if _, err := d.Write(data); err != nil {
+		return false
+	}
+	return d.Close() == nil
+}
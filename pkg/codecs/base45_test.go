@@ -0,0 +1,41 @@
+package codecs
+
+import "testing"
+
+func TestPluginBase45(t *testing.T) {
+	p := NewPluginBase45()
+	for _, v := range []struct{ plain, encoded string }{
+		{"AB", "BB8"},
+		{"Hello!!", "%69 VD92EX0"},
+		{"base-45", "UJCLQE7W581"},
+		{"ietf!", "QED8WEX0"},
+	} {
+		assertCodec(t, p, p.Process, []byte(v.plain), []byte(v.encoded))
+		assertCodec(t, p, p.Unprocess, []byte(v.encoded+"\n"), []byte(v.plain))
+	}
+	assertCodec(t, p, p.Process, []byte("ietf!"), []byte("HC1:QED8WEX0"), "-prefix", "HC1:")
+	assertCodec(t, p, p.Unprocess, []byte("HC1:QED8WEX0"), []byte("ietf!"), "-prefix", "HC1:")
+
+	for _, bad := range []string{"GGW", "QED8WEX", "ietf", "QED8WEX0"} {
+		args := []string{}
+		if bad == "QED8WEX0" {
+			args = []string{"-prefix", "HC1:"}
+		}
+		if _, err := tryCodec(p.Unprocess, p.RegisterFlags, []byte(bad), args...); err == nil {
+			t.Errorf("decoding %q %v succeeded", bad, args)
+		}
+	}
+}
+
+func TestPluginBase45Detect(t *testing.T) {
+	p := NewPluginBase45()
+	if d, ok := p.Detect([]byte("HC1:QED8WEX0\n")); !ok || d.Options["prefix"] != "HC1:" {
+		t.Errorf("HC1 detection = %#v, %v", d, ok)
+	}
+	if _, ok := p.Detect([]byte("BB8%69 VD92EX0")); !ok {
+		t.Error("Base45 text not detected")
+	}
+	if _, ok := p.Detect([]byte("HELLO WORLD AGAIN")); ok {
+		t.Error("plain upper case words detected as Base45")
+	}
+}
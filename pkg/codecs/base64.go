@@ -39,6 +39,11 @@ func NewPluginBase64() *types.DeenPlugin {
 		flags.Bool("raw", false, "unpadded Base64 encoding (RFC 4648 section 3.2)")
 		flags.Bool("url", false, "URL-safe Base64 alphabet")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "raw", Label: "Raw output", Description: "Encode without Base64 padding."},
+		{Name: "strict", Label: "Strict decoding", Description: "Decode only standard Base64."},
+		{Name: "url", Label: "URL-safe alphabet", Description: "Use the URL-safe Base64 alphabet."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		enc := base64Encoding(flags)
 		return encodeStream(r, w, func(w io.Writer) io.WriteCloser { return base64.NewEncoder(enc, w) })
@@ -1,25 +1,341 @@
 package codecs
 
 import (
+	"bytes"
 	"encoding/ascii85"
+	"errors"
 	"flag"
+	"fmt"
 	"io"
 
+	"github.com/takeshixx/deen/pkg/helpers"
 	"github.com/takeshixx/deen/pkg/types"
 )
 
+// base85Variants are the flavours selectable with -variant. "auto" encodes
+// plain Ascii85 and detects the variant when decoding.
+var base85Variants = []string{"auto", "ascii85", "adobe", "btoa", "z85"}
+
+// base85Codec encodes four bytes as five digits of an 85 character alphabet.
+// zero and spaces are the single-character shortcuts for all-zero and
+// all-space groups, or 0 when the variant has none.
+type base85Codec struct {
+	alphabet string
+	digit    [256]byte // alphabet index + 1; 0 marks an invalid character
+	zero     byte
+	spaces   byte
+}
+
+func newBase85Codec(alphabet string, zero, spaces byte) *base85Codec {
+	c := &base85Codec{alphabet: alphabet, zero: zero, spaces: spaces}
+	for i := 0; i < len(alphabet); i++ {
+		c.digit[alphabet[i]] = byte(i + 1)
+	}
+	return c
+}
+
+func ascii85Alphabet() string {
+	b := make([]byte, 85)
+	for i := range b {
+		b[i] = byte('!' + i)
+	}
+	return string(b)
+}
+
+var (
+	z85Codec  = newBase85Codec("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.-:+=^!/*?&<>()[]{}@%$#", 0, 0)
+	btoaCodec = newBase85Codec(ascii85Alphabet(), 'z', 'y')
+)
+
+// encode encodes data. A final partial group of n bytes becomes n+1 digits,
+// unless pad is set: then it is padded with zero bytes to a full group, as
+// btoa does.
+func (c *base85Codec) encode(data []byte, pad bool) []byte {
+	out := make([]byte, 0, (len(data)+3)/4*5)
+	for len(data) > 0 {
+		var group [4]byte
+		n := copy(group[:], data)
+		data = data[n:]
+		v := uint32(group[0])<<24 | uint32(group[1])<<16 | uint32(group[2])<<8 | uint32(group[3])
+		if n == 4 || pad {
+			switch {
+			case v == 0 && c.zero != 0:
+				out = append(out, c.zero)
+				continue
+			case v == 0x20202020 && c.spaces != 0:
+				out = append(out, c.spaces)
+				continue
+			}
+			n = 4
+		}
+		var digits [5]byte
+		for i := 4; i >= 0; i-- {
+			digits[i] = c.alphabet[v%85]
+			v /= 85
+		}
+		out = append(out, digits[:n+1]...)
+	}
+	return out
+}
+
+// decode decodes text, skipping whitespace. A final partial group of n digits
+// is padded with the highest digit and yields n-1 bytes.
+func (c *base85Codec) decode(text []byte) ([]byte, error) {
+	out := make([]byte, 0, len(text)/5*4+4)
+	var group [5]byte
+	n := 0
+	for i, ch := range text {
+		switch {
+		case isSpace(ch):
+			continue
+		case n == 0 && ch == c.zero && c.zero != 0:
+			out = append(out, 0, 0, 0, 0)
+			continue
+		case n == 0 && ch == c.spaces && c.spaces != 0:
+			out = append(out, ' ', ' ', ' ', ' ')
+			continue
+		case c.digit[ch] == 0:
+			return nil, fmt.Errorf("illegal base85 character %q at offset %d", ch, i)
+		}
+		group[n] = c.digit[ch] - 1
+		if n++; n == 5 {
+			v, err := base85Group(group)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+			n = 0
+		}
+	}
+	switch n {
+	case 0:
+		return out, nil
+	case 1:
+		return nil, errors.New("base85 input ends with a single character")
+	}
+	for i := n; i < 5; i++ {
+		group[i] = 84
+	}
+	v, err := base85Group(group)
+	if err != nil {
+		return nil, err
+	}
+	full := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	return append(out, full[:n-1]...), nil
+}
+
+func base85Group(digits [5]byte) (uint32, error) {
+	var v uint64
+	for _, d := range digits {
+		v = v*85 + uint64(d)
+	}
+	if v > 0xffffffff {
+		return 0, errors.New("base85 group exceeds 32 bits")
+	}
+	return uint32(v), nil
+}
+
+func isSpace(ch byte) bool {
+	switch ch {
+	case ' ', '\t', '\n', '\r', '\v', '\f', 0:
+		return true
+	}
+	return false
+}
+
+// btoaChecksums are the running checksums of the btoa trailer.
+type btoaChecksums struct{ eor, sum, rot uint32 }
+
+func (s *btoaChecksums) add(data []byte) {
+	for _, b := range data {
+		s.eor ^= uint32(b)
+		s.sum += uint32(b) + 1
+		s.rot = s.rot<<1 | s.rot>>31
+		s.rot += uint32(b)
+	}
+}
+
+// btoaEncode produces btoa output: a header, 78 character lines with the z and
+// y shortcuts and a trailer with the length and checksums.
+func btoaEncode(data []byte) []byte {
+	var out bytes.Buffer
+	out.WriteString("xbtoa Begin\n")
+	body := btoaCodec.encode(data, true)
+	for len(body) > 0 {
+		n := min(len(body), 78)
+		out.Write(body[:n])
+		out.WriteByte('\n')
+		body = body[n:]
+	}
+	var s btoaChecksums
+	s.add(data)
+	fmt.Fprintf(&out, "xbtoa End N %d %x E %x S %x R %x\n", len(data), len(data), s.eor, s.sum, s.rot)
+	return out.Bytes()
+}
+
+// btoaDecode decodes btoa output. With a trailer the padding is cut off and
+// the checksums are verified; a bare body is decoded like Ascii85.
+func btoaDecode(text []byte) ([]byte, error) {
+	if i := bytes.Index(text, []byte("xbtoa Begin")); i >= 0 {
+		text = text[i:]
+		if j := bytes.IndexByte(text, '\n'); j >= 0 {
+			text = text[j+1:]
+		} else {
+			text = nil
+		}
+	}
+	var trailer []byte
+	if i := bytes.Index(text, []byte("xbtoa End")); i >= 0 {
+		text, trailer = text[:i], text[i:]
+	}
+	data, err := btoaCodec.decode(text)
+	if err != nil || trailer == nil {
+		return data, err
+	}
+	var n, nHex int
+	var want btoaChecksums
+	if _, err := fmt.Sscanf(string(trailer), "xbtoa End N %d %x E %x S %x R %x", &n, &nHex, &want.eor, &want.sum, &want.rot); err != nil {
+		return nil, fmt.Errorf("malformed btoa trailer: %w", err)
+	}
+	if n != nHex || n > len(data) || len(data)-n > 3 {
+		return nil, fmt.Errorf("btoa trailer length %d does not match %d decoded bytes", n, len(data))
+	}
+	data = data[:n]
+	var got btoaChecksums
+	got.add(data)
+	if got != want {
+		return nil, errors.New("btoa checksum mismatch")
+	}
+	return data, nil
+}
+
+// adobeDecode decodes Ascii85 between the <~ and ~> delimiters of PostScript
+// and PDF. The opening delimiter is optional.
+func adobeDecode(text []byte) ([]byte, error) {
+	text = bytes.TrimPrefix(text, []byte("<~"))
+	end := bytes.LastIndex(text, []byte("~>"))
+	if end < 0 {
+		return nil, errors.New("adobe ascii85 input lacks the ~> delimiter")
+	}
+	return io.ReadAll(ascii85.NewDecoder(bytes.NewReader(text[:end])))
+}
+
+// base85Candidates returns the variants trimmed base85 text may be in, most
+// likely first. Delimiters settle the variant; otherwise the characters only
+// one alphabet has decide the order in which the variants are tried.
+func base85Candidates(text []byte) []string {
+	switch {
+	case bytes.HasPrefix(text, []byte("<~")) || bytes.HasSuffix(text, []byte("~>")):
+		return []string{"adobe"}
+	case bytes.Contains(text, []byte("xbtoa Begin")):
+		return []string{"btoa"}
+	case bytes.ContainsAny(text, "vwx{}"):
+		return []string{"z85"}
+	case bytes.IndexByte(text, 'y') >= 0:
+		// y is a Z85 digit and the btoa four-space shortcut.
+		return []string{"btoa", "z85"}
+	}
+	return []string{"ascii85", "z85"}
+}
+
+// decodeBase85 decodes trimmed text in the given variant.
+func decodeBase85(variant string, text []byte) ([]byte, error) {
+	switch variant {
+	case "ascii85":
+		return io.ReadAll(ascii85.NewDecoder(bytes.NewReader(text)))
+	case "adobe":
+		return adobeDecode(text)
+	case "btoa":
+		return btoaDecode(text)
+	case "z85":
+		return z85Codec.decode(text)
+	}
+	return nil, fmt.Errorf("unsupported base85 variant %q", variant)
+}
+
 // NewPluginBase85 creates a new ascii85 plugin.
 func NewPluginBase85() *types.DeenPlugin {
 	p := types.NewPlugin()
 	p.Name = "base85"
 	p.Aliases = []string{".base85", "b85", ".b85", "ascii85", ".ascii85", "a85", ".a85"}
 	p.Category = "codecs"
-	p.Description = "Implements the ascii85 data encoding as used in the btoa tool and\nAdobe's PostScript and PDF document formats."
-	p.Process = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
-		return encodeStream(r, w, func(w io.Writer) io.WriteCloser { return ascii85.NewEncoder(w) })
+	p.Description = "Implements the ascii85 data encoding as used in the btoa tool and\nAdobe's PostScript and PDF document formats, and ZeroMQ's Z85."
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.String("variant", "auto", "variant (auto, ascii85, adobe, btoa, z85); auto encodes ascii85 and detects the variant when decoding")
+	}
+	p.Options = []types.OptionSpec{
+		{Name: "variant", Label: "Variant", Description: "Plain Ascii85, Adobe <~ ~> delimited, btoa with z/y shortcuts or ZeroMQ Z85. Auto encodes Ascii85 and detects the variant when decoding.", Choices: base85Variants},
+	}
+	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		switch variant := helpers.StringFlag(flags, "variant"); variant {
+		case "", "auto", "ascii85":
+			return encodeStream(r, w, func(w io.Writer) io.WriteCloser { return ascii85.NewEncoder(w) })
+		case "adobe":
+			if _, err := io.WriteString(w, "<~"); err != nil {
+				return err
+			}
+			if err := encodeStream(r, w, func(w io.Writer) io.WriteCloser { return ascii85.NewEncoder(w) }); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, "~>")
+			return err
+		case "btoa":
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(btoaEncode(data))
+			return err
+		case "z85":
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(z85Codec.encode(data, false))
+			return err
+		default:
+			return fmt.Errorf("unsupported base85 variant %q", variant)
+		}
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		variant := helpers.StringFlag(flags, "variant")
+		if variant == "" || variant == "ascii85" {
+			return decodeTrimmed(r, w, func(r io.Reader) io.Reader { return ascii85.NewDecoder(r) })
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		data = bytes.TrimSpace(data)
+		candidates := []string{variant}
+		if variant == "auto" {
+			candidates = base85Candidates(data)
+		}
+		var firstErr error
+		for _, variant := range candidates {
+			decoded, err := decodeBase85(variant, data)
+			if err == nil {
+				_, err = w.Write(decoded)
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
 	}
-	p.Unprocess = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
-		return decodeTrimmed(r, w, func(r io.Reader) io.Reader { return ascii85.NewDecoder(r) })
+	p.Detect = func(data []byte) (types.Detection, bool) {
+		data = bytes.TrimSpace(data)
+		var reason string
+		switch {
+		case bytes.HasPrefix(data, []byte("<~")) && bytes.HasSuffix(data, []byte("~>")):
+			reason = "input is enclosed in Adobe Ascii85 <~ ~> delimiters"
+		case bytes.HasPrefix(data, []byte("xbtoa Begin")):
+			reason = "input has a btoa header"
+		default:
+			return types.Detection{}, false
+		}
+		return types.Detection{Label: "Decode Ascii85", Reason: reason, Confidence: 90}, true
 	}
 	return p
 }
@@ -1,6 +1,10 @@
 package codecs
 
-import "testing"
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
 
 var b85InputData = []byte("asd1239999")
 var b85InputDataProcessed = []byte("@<5s61,CpN3B7")
@@ -8,9 +12,76 @@ var b85InputDataProcessed = []byte("@<5s61,CpN3B7")
 func TestPluginBase85Process(t *testing.T) {
 	p := NewPluginBase85()
 	assertCodec(t, p, p.Process, b85InputData, b85InputDataProcessed)
+	assertCodec(t, p, p.Process, b85InputData, b85InputDataProcessed, "-variant", "ascii85")
+	assertCodec(t, p, p.Process, []byte("Hello"), []byte("<~87cURDZ~>"), "-variant", "adobe")
+	assertCodec(t, p, p.Process, []byte{0x86, 0x4f, 0xd2, 0x6f, 0xb5, 0x59, 0xf7, 0x5b}, []byte("HelloWorld"), "-variant", "z85")
+	assertCodec(t, p, p.Process, []byte("Hello"), []byte("nm=QNzV"), "-variant", "z85")
 }
 
 func TestPluginBase85Unprocess(t *testing.T) {
 	p := NewPluginBase85()
 	assertCodec(t, p, p.Unprocess, b85InputDataProcessed, b85InputData)
+	for _, tt := range []struct {
+		in, want string
+		variant  string
+	}{
+		{"<~87cURDZ~>\n", "Hello", "adobe"},
+		{"<~87cU\n RDZ~>", "Hello", "auto"},
+		{"87cURDZ~>", "Hello", "auto"},
+		{"HelloWorld", "\x86\x4f\xd2\x6f\xb5\x59\xf7\x5b", "z85"},
+		{"nm=QN zV", "Hello", "z85"},
+		{"nm=QNzV", "Hello", "auto"},
+		{"yz@:E^", "    \x00\x00\x00\x00abc", "btoa"},
+		{"yz@:E^", "    \x00\x00\x00\x00abc", "auto"},
+	} {
+		assertCodec(t, p, p.Unprocess, []byte(tt.in), []byte(tt.want), "-variant", tt.variant)
+	}
+	for _, tt := range []struct{ in, variant string }{
+		{"<~87cURDZ", "adobe"},
+		{"HelloWorld~", "z85"},
+		{"ab{cd", "ascii85"},
+		{"%%%%%", "z85"},
+		{"n", "z85"},
+	} {
+		if _, err := tryCodec(p.Unprocess, p.RegisterFlags, []byte(tt.in), "-variant", tt.variant); err == nil {
+			t.Errorf("decoding %q as %s succeeded", tt.in, tt.variant)
+		}
+	}
+}
+
+func TestPluginBase85Btoa(t *testing.T) {
+	p := NewPluginBase85()
+	input := append([]byte("    \x00\x00\x00\x00"), bytes.Repeat([]byte("deen btoa "), 12)...)
+	encoded := runCodec(t, p.Process, p.RegisterFlags, input, "-variant", "btoa")
+	lines := strings.Split(string(encoded), "\n")
+	if lines[0] != "xbtoa Begin" || !strings.HasPrefix(lines[1], "yz") || len(lines[1]) != 78 {
+		t.Fatalf("btoa output lacks header, shortcuts or 78 character lines:\n%s", encoded)
+	}
+	if !strings.HasPrefix(lines[len(lines)-2], "xbtoa End N 128 80 E ") {
+		t.Fatalf("btoa trailer = %q", lines[len(lines)-2])
+	}
+	assertCodec(t, p, p.Unprocess, encoded, input, "-variant", "btoa")
+	assertCodec(t, p, p.Unprocess, encoded, input)
+
+	tampered := bytes.Replace(encoded, []byte(" S "), []byte(" S 1"), 1)
+	if _, err := tryCodec(p.Unprocess, p.RegisterFlags, tampered); err == nil || err.Error() != "btoa checksum mismatch" {
+		t.Errorf("decoding a tampered trailer: err = %v", err)
+	}
+	for _, n := range []int{0, 1, 2, 3, 5} {
+		in := bytes.Repeat([]byte{0xfe}, n)
+		out := runCodec(t, p.Process, p.RegisterFlags, in, "-variant", "btoa")
+		assertCodec(t, p, p.Unprocess, out, in, "-variant", "btoa")
+	}
+}
+
+func TestPluginBase85Detect(t *testing.T) {
+	p := NewPluginBase85()
+	for _, s := range []string{"<~87cURDZ~>", "xbtoa Begin\nxbtoa End N 0 0 E 0 S 0 R 0\n"} {
+		if _, ok := p.Detect([]byte(s)); !ok {
+			t.Errorf("%q not detected", s)
+		}
+	}
+	if _, ok := p.Detect(b85InputDataProcessed); ok {
+		t.Errorf("undelimited %q detected", b85InputDataProcessed)
+	}
 }
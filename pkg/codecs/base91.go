@@ -0,0 +1,158 @@
+package codecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+const base91Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#$%&()*+,./:;<=>?@[]^_`{|}~\""
+
+// base91Encoder implements the basE91 encoder: 13 or 14 bits at a time are
+// written as two characters, so output is produced as input arrives.
+type base91Encoder struct {
+	w     io.Writer
+	queue uint
+	nbits uint
+}
+
+func (e *base91Encoder) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p)*5/4+2)
+	for _, b := range p {
+		e.queue |= uint(b) << e.nbits
+		e.nbits += 8
+		if e.nbits > 13 {
+			v := e.queue & 8191
+			if v > 88 {
+				e.queue >>= 13
+				e.nbits -= 13
+			} else {
+				v = e.queue & 16383
+				e.queue >>= 14
+				e.nbits -= 14
+			}
+			out = append(out, base91Alphabet[v%91], base91Alphabet[v/91])
+		}
+	}
+	if _, err := e.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (e *base91Encoder) Close() error {
+	if e.nbits == 0 {
+		return nil
+	}
+	out := []byte{base91Alphabet[e.queue%91]}
+	if e.nbits > 7 || e.queue > 90 {
+		out = append(out, base91Alphabet[e.queue/91])
+	}
+	_, err := e.w.Write(out)
+	return err
+}
+
+// base91Decoder reverses base91Encoder. Whitespace is skipped.
+type base91Decoder struct {
+	w      io.Writer
+	queue  uint
+	nbits  uint
+	value  int
+	offset int
+}
+
+func (d *base91Decoder) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p))
+	for _, ch := range p {
+		d.offset++
+		if ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' {
+			continue
+		}
+		c := bytes.IndexByte([]byte(base91Alphabet), ch)
+		if c < 0 {
+			return 0, fmt.Errorf("illegal base91 character %q at offset %d", ch, d.offset-1)
+		}
+		if d.value < 0 {
+			d.value = c
+			continue
+		}
+		d.value += c * 91
+		d.queue |= uint(d.value) << d.nbits
+		if d.value&8191 > 88 {
+			d.nbits += 13
+		} else {
+			d.nbits += 14
+		}
+		for d.nbits > 7 {
+			out = append(out, byte(d.queue))
+			d.queue >>= 8
+			d.nbits -= 8
+		}
+		d.value = -1
+	}
+	if _, err := d.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (d *base91Decoder) Close() error {
+	if d.value < 0 {
+		return nil
+	}
+	_, err := d.w.Write([]byte{byte(d.queue | uint(d.value)<<d.nbits)})
+	return err
+}
+
+// NewPluginBase91 creates a basE91 plugin.
+func NewPluginBase91() *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = "base91"
+	p.Aliases = []string{".base91", "b91", ".b91"}
+	p.Category = "codecs"
+	p.Description = "basE91 encoding: 91 printable ASCII characters with about 23% overhead\ninstead of Base64's 33%."
+	p.Process = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		return encodeStream(r, w, func(w io.Writer) io.WriteCloser { return &base91Encoder{w: w} })
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		return encodeStream(r, w, func(w io.Writer) io.WriteCloser { return &base91Decoder{w: w, value: -1} })
+	}
+	p.Detect = func(data []byte) (types.Detection, bool) {
+		data = bytes.TrimSpace(data)
+		if len(data) < 16 || json.Valid(data) {
+			return types.Detection{}, false
+		}
+		// Every character class must appear, including punctuation that
+		// Base64 and the base-N alphabets never use.
+		var digit, lower, upper, symbol bool
+		for _, ch := range data {
+			switch {
+			case ch >= '0' && ch <= '9':
+				digit = true
+			case ch >= 'a' && ch <= 'z':
+				lower = true
+			case ch >= 'A' && ch <= 'Z':
+				upper = true
+			case bytes.IndexByte([]byte(base91Alphabet), ch) >= 0:
+				if ch != '+' && ch != '/' && ch != '=' {
+					symbol = true
+				}
+			default:
+				return types.Detection{}, false
+			}
+		}
+		if !digit || !lower || !upper || !symbol {
+			return types.Detection{}, false
+		}
+		return types.Detection{
+			Label:      "Decode basE91",
+			Reason:     "input mixes letters, digits and punctuation of the basE91 alphabet",
+			Confidence: 40,
+		}, true
+	}
+	return p
+}
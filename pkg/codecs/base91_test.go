@@ -0,0 +1,26 @@
+package codecs
+
+import "testing"
+
+func TestPluginBase91(t *testing.T) {
+	p := NewPluginBase91()
+	assertCodec(t, p, p.Process, []byte("Hello world"), []byte(">OwJh>Io2Tv!lE"))
+	assertCodec(t, p, p.Unprocess, []byte(">OwJh>Io2Tv!lE\n"), []byte("Hello world"))
+	assertCodec(t, p, p.Process, []byte("test"), []byte("fPNKd"))
+	assertCodec(t, p, p.Unprocess, []byte("fPNKd"), []byte("test"))
+	if _, err := tryCodec(p.Unprocess, p.RegisterFlags, []byte("abc-def")); err == nil {
+		t.Error("decoding a character outside the alphabet succeeded")
+	}
+}
+
+func TestPluginBase91Detect(t *testing.T) {
+	p := NewPluginBase91()
+	if _, ok := p.Detect([]byte(">OwJh>Io2Tv!lE>OwJh>Io2Tv!lE")); !ok {
+		t.Error("basE91 text not detected")
+	}
+	for _, s := range []string{`{"Name":"value1","x":2}`, "dGVzdCBzdHJpbmcgMTIz+/==", "Hello, world. 123"} {
+		if _, ok := p.Detect([]byte(s)); ok {
+			t.Errorf("%q detected as basE91", s)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package codecs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/takeshixx/deen/pkg/helpers"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// bigDigits are the digits math/big uses for bases up to 62. Radix codecs map
+// their own alphabet onto these by index.
+const bigDigits = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// base58Alphabets are the base58 alphabets selectable with -alphabet.
+var base58Alphabets = map[string]string{
+	"bitcoin": "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz",
+	"ripple":  "rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz",
+	"flickr":  "123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ",
+}
+
+// base62Alphabets are the base62 alphabets selectable with -alphabet.
+var base62Alphabets = map[string]string{
+	"standard": "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	"inverted": "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ",
+}
+
+// radixCodec converts between bytes and text in an arbitrary base by treating
+// the input as one big-endian integer, the scheme of Bitcoin's base58 and most
+// base62 and base36 libraries. Leading zero bytes become leading zero digits so
+// they survive a round trip. The conversion needs the whole input in memory.
+type radixCodec struct {
+	name     string
+	alphabet string
+	digit    [256]byte // alphabet index + 1; 0 marks an invalid character
+}
+
+func newRadixCodec(name, alphabet string, foldCase bool) *radixCodec {
+	c := &radixCodec{name: name, alphabet: alphabet}
+	for i := 0; i < len(alphabet); i++ {
+		c.digit[alphabet[i]] = byte(i + 1)
+		if foldCase {
+			c.digit[unicode.ToUpper(rune(alphabet[i]))] = byte(i + 1)
+		}
+	}
+	return c
+}
+
+func (c *radixCodec) encode(data []byte) []byte {
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+	out := bytes.Repeat([]byte{c.alphabet[0]}, zeros)
+	n := new(big.Int).SetBytes(data[zeros:])
+	if n.Sign() == 0 {
+		return out
+	}
+	for _, d := range []byte(n.Text(len(c.alphabet))) {
+		out = append(out, c.alphabet[strings.IndexByte(bigDigits, d)])
+	}
+	return out
+}
+
+func (c *radixCodec) decode(text []byte) ([]byte, error) {
+	zeros := 0
+	for zeros < len(text) && c.digit[text[zeros]] == 1 {
+		zeros++
+	}
+	digits := make([]byte, 0, len(text)-zeros)
+	for i, ch := range text[zeros:] {
+		d := c.digit[ch]
+		if d == 0 {
+			return nil, fmt.Errorf("illegal %s character %q at offset %d", c.name, ch, zeros+i)
+		}
+		digits = append(digits, bigDigits[d-1])
+	}
+	out := make([]byte, zeros, len(text))
+	if len(digits) == 0 {
+		return out, nil
+	}
+	n, ok := new(big.Int).SetString(string(digits), len(c.alphabet))
+	if !ok {
+		return nil, fmt.Errorf("could not decode %s input", c.name)
+	}
+	return append(out, n.Bytes()...), nil
+}
+
+// valid reports whether text consists only of alphabet characters.
+func (c *radixCodec) valid(text []byte) bool {
+	for _, ch := range text {
+		if c.digit[ch] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// radixAlphabet returns the codec for the alphabet selected with -alphabet.
+func radixAlphabet(name string, alphabets map[string]string, flags *flag.FlagSet) (*radixCodec, error) {
+	choice := helpers.StringFlag(flags, "alphabet")
+	alphabet, ok := alphabets[choice]
+	if !ok {
+		return nil, fmt.Errorf("unsupported %s alphabet %q", name, choice)
+	}
+	return newRadixCodec(name, alphabet, false), nil
+}
+
+// radixProcess and radixUnprocess adapt a radix codec to the plugin transforms.
+func radixProcess(r io.Reader, w io.Writer, c *radixCodec) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(c.encode(data))
+	return err
+}
+
+func radixUnprocess(r io.Reader, w io.Writer, c *radixCodec) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	decoded, err := c.decode(bytes.TrimSpace(data))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(decoded)
+	return err
+}
+
+// detectRadix reports whether data is a plausible radix-encoded token: long
+// enough, made of alphabet characters only and mixing digits with letters of
+// both cases, which plain words and numbers rarely do.
+func detectRadix(c *radixCodec, data []byte, minLen int) bool {
+	data = bytes.TrimSpace(data)
+	if len(data) < minLen || !c.valid(data) {
+		return false
+	}
+	var digit, lower, upper bool
+	for _, ch := range data {
+		switch {
+		case ch >= '0' && ch <= '9':
+			digit = true
+		case ch >= 'a' && ch <= 'z':
+			lower = true
+		case ch >= 'A' && ch <= 'Z':
+			upper = true
+		}
+	}
+	return digit && lower && upper
+}
+
+// base58Checksum is the Base58Check checksum: the first four bytes of a
+// double SHA-256.
+func base58Checksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
+
+func base58CheckDecode(c *radixCodec, text []byte) ([]byte, error) {
+	decoded, err := c.decode(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) < 4 {
+		return nil, errors.New("input is shorter than the checksum")
+	}
+	payload, sum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	if !bytes.Equal(sum, base58Checksum(payload)) {
+		return nil, errors.New("checksum mismatch")
+	}
+	return payload, nil
+}
+
+// base58CheckAlphabet returns the name of the alphabet under which data is
+// Base58Check with a valid checksum.
+func base58CheckAlphabet(data []byte) (string, bool) {
+	data = bytes.TrimSpace(data)
+	if len(data) < 8 {
+		return "", false
+	}
+	for _, name := range []string{"bitcoin", "ripple", "flickr"} {
+		c := newRadixCodec("base58check", base58Alphabets[name], false)
+		if !c.valid(data) {
+			continue
+		}
+		if _, err := base58CheckDecode(c, data); err == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func base58Options() []types.OptionSpec {
+	return []types.OptionSpec{
+		{Name: "alphabet", Label: "Alphabet", Description: "Base58 alphabet: Bitcoin/IPFS, Ripple or Flickr.", Choices: []string{"bitcoin", "ripple", "flickr"}},
+	}
+}
+
+// NewPluginBase58 creates a base58 plugin.
+func NewPluginBase58() *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = "base58"
+	p.Aliases = []string{".base58", "b58", ".b58"}
+	p.Category = "codecs"
+	p.Description = "Base58 encoding as used by Bitcoin addresses and IPFS content IDs,\nwith the Bitcoin, Ripple or Flickr alphabet."
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.String("alphabet", "bitcoin", "alphabet (bitcoin, ripple, flickr)")
+	}
+	p.Options = base58Options()
+	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		c, err := radixAlphabet("base58", base58Alphabets, flags)
+		if err != nil {
+			return err
+		}
+		return radixProcess(r, w, c)
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		c, err := radixAlphabet("base58", base58Alphabets, flags)
+		if err != nil {
+			return err
+		}
+		return radixUnprocess(r, w, c)
+	}
+	p.Detect = func(data []byte) (types.Detection, bool) {
+		// The three alphabets share one character set, so only the checksum
+		// of base58check can tell them apart.
+		c := newRadixCodec("base58", base58Alphabets["bitcoin"], false)
+		if _, ok := base58CheckAlphabet(data); ok || !detectRadix(c, data, 16) {
+			return types.Detection{}, false
+		}
+		return types.Detection{
+			Label:      "Decode Base58",
+			Reason:     "input is a mixed-case token in the Base58 alphabet",
+			Confidence: 55,
+		}, true
+	}
+	return p
+}
+
+// NewPluginBase58Check creates a Base58Check plugin: base58 with a four byte
+// double SHA-256 checksum that is verified on decode.
+func NewPluginBase58Check() *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = "base58check"
+	p.Aliases = []string{".base58check", "b58c", ".b58c"}
+	p.Category = "codecs"
+	p.Description = "Base58Check encoding: base58 with a double SHA-256 checksum, as used by\nBitcoin addresses and WIF keys. Decoding verifies the checksum; the\nversion byte stays part of the payload."
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.String("alphabet", "bitcoin", "alphabet (bitcoin, ripple, flickr)")
+	}
+	p.Options = base58Options()
+	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		c, err := radixAlphabet("base58check", base58Alphabets, flags)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(c.encode(append(data, base58Checksum(data)...)))
+		return err
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		c, err := radixAlphabet("base58check", base58Alphabets, flags)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		payload, err := base58CheckDecode(c, bytes.TrimSpace(data))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(payload)
+		return err
+	}
+	p.Detect = func(data []byte) (types.Detection, bool) {
+		name, ok := base58CheckAlphabet(data)
+		if !ok {
+			return types.Detection{}, false
+		}
+		return types.Detection{
+			Options:    map[string]string{"alphabet": name},
+			Label:      "Decode Base58Check",
+			Reason:     "input decodes as " + name + " Base58 with a valid checksum",
+			Confidence: 90,
+		}, true
+	}
+	return p
+}
+
+// NewPluginBase62 creates a base62 plugin.
+func NewPluginBase62() *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = "base62"
+	p.Aliases = []string{".base62", "b62", ".b62"}
+	p.Category = "codecs"
+	p.Description = "Base62 encoding over 0-9, A-Z and a-z, common for short IDs and URL\nshorteners. The inverted alphabet puts lower case letters first."
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.String("alphabet", "standard", "alphabet (standard: 0-9A-Za-z, inverted: 0-9a-zA-Z)")
+	}
+	p.Options = []types.OptionSpec{
+		{Name: "alphabet", Label: "Alphabet", Description: "Digit order: 0-9A-Za-z (standard) or 0-9a-zA-Z (inverted).", Choices: []string{"standard", "inverted"}},
+	}
+	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		c, err := radixAlphabet("base62", base62Alphabets, flags)
+		if err != nil {
+			return err
+		}
+		return radixProcess(r, w, c)
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		c, err := radixAlphabet("base62", base62Alphabets, flags)
+		if err != nil {
+			return err
+		}
+		return radixUnprocess(r, w, c)
+	}
+	p.Detect = func(data []byte) (types.Detection, bool) {
+		// Mixed-case alphanumerics are ambiguous (Base64 and base58 share
+		// them), so only propose base62 when base58 is ruled out.
+		c := newRadixCodec("base62", base62Alphabets["standard"], false)
+		if !detectRadix(c, data, 12) || !bytes.ContainsAny(bytes.TrimSpace(data), "0OIl") {
+			return types.Detection{}, false
+		}
+		return types.Detection{
+			Options:    map[string]string{"alphabet": "standard"},
+			Label:      "Decode Base62",
+			Reason:     "input is a mixed-case alphanumeric token outside the Base58 alphabet",
+			Confidence: 45,
+		}, true
+	}
+	return p
+}
+
+// NewPluginBase36 creates a base36 plugin.
+func NewPluginBase36() *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = "base36"
+	p.Aliases = []string{".base36", "b36", ".b36"}
+	p.Category = "codecs"
+	p.Description = "Base36 encoding over 0-9 and a-z. Decoding ignores letter case."
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.Bool("upper", false, "encode with upper case letters")
+	}
+	p.Options = []types.OptionSpec{
+		{Name: "upper", Label: "Upper case", Description: "Encode with A-Z instead of a-z."},
+	}
+	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		encoded := newRadixCodec("base36", bigDigits[:36], true).encode(data)
+		if helpers.IsBoolFlag(flags, "upper") {
+			encoded = bytes.ToUpper(encoded)
+		}
+		_, err = w.Write(encoded)
+		return err
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		return radixUnprocess(r, w, newRadixCodec("base36", bigDigits[:36], true))
+	}
+	return p
+}
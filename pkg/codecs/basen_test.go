@@ -0,0 +1,85 @@
+package codecs
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestPluginBase58(t *testing.T) {
+	p := NewPluginBase58()
+	assertCodec(t, p, p.Process, []byte("Hello World!"), []byte("2NEpo7TZRRrLZSi2U"))
+	assertCodec(t, p, p.Unprocess, []byte("2NEpo7TZRRrLZSi2U\n"), []byte("Hello World!"))
+	leading := []byte{0, 0, 0x28, 0x7f, 0xb4, 0xcd}
+	assertCodec(t, p, p.Process, leading, []byte("11233QC4"))
+	assertCodec(t, p, p.Unprocess, []byte("11233QC4"), leading)
+	assertCodec(t, p, p.Process, []byte{0, 0}, []byte("11"))
+	assertCodec(t, p, p.Unprocess, []byte("rr"), []byte{0, 0}, "-alphabet", "ripple")
+	flickr := runCodec(t, p.Process, p.RegisterFlags, []byte("Hello World!"), "-alphabet", "flickr")
+	assertCodec(t, p, p.Unprocess, flickr, []byte("Hello World!"), "-alphabet", "flickr")
+
+	if _, err := tryCodec(p.Unprocess, p.RegisterFlags, []byte("0OIl")); err == nil || !strings.Contains(err.Error(), "illegal base58 character '0'") {
+		t.Errorf("decoding outside the alphabet: err = %v", err)
+	}
+	if _, err := tryCodec(p.Process, p.RegisterFlags, nil, "-alphabet", "monero"); err == nil {
+		t.Error("unknown alphabet accepted")
+	}
+}
+
+func TestPluginBase58Check(t *testing.T) {
+	p := NewPluginBase58Check()
+	burn := make([]byte, 21)
+	assertCodec(t, p, p.Process, burn, []byte("1111111111111111111114oLvT2"))
+	assertCodec(t, p, p.Unprocess, []byte("1111111111111111111114oLvT2"), burn)
+	address, _ := hex.DecodeString("00f54a5851e9372b87810a8e60cdd2e7cfd80b6e31")
+	assertCodec(t, p, p.Unprocess, []byte("1PMycacnJaSqwwJqjawXBErnLsZ7RkXUAs"), address)
+
+	for _, bad := range []string{"1PMycacnJaSqwwJqjawXBErnLsZ7RkXUAt", "1"} {
+		if _, err := tryCodec(p.Unprocess, p.RegisterFlags, []byte(bad)); err == nil {
+			t.Errorf("decoding %q with a bad checksum succeeded", bad)
+		}
+	}
+}
+
+func TestPluginBase62(t *testing.T) {
+	p := NewPluginBase62()
+	assertCodec(t, p, p.Process, []byte{61}, []byte("z"))
+	assertCodec(t, p, p.Process, []byte{61}, []byte("Z"), "-alphabet", "inverted")
+	assertCodec(t, p, p.Process, []byte{0, 62}, []byte("010"))
+	assertCodec(t, p, p.Unprocess, []byte("010"), []byte{0, 62})
+	assertCodec(t, p, p.Unprocess, []byte("z"), []byte{61})
+}
+
+func TestPluginBase36(t *testing.T) {
+	p := NewPluginBase36()
+	assertCodec(t, p, p.Process, []byte{1, 2}, []byte("76"))
+	assertCodec(t, p, p.Process, []byte{0xff}, []byte("73"))
+	assertCodec(t, p, p.Process, []byte("deen"), []byte("ruttny"))
+	assertCodec(t, p, p.Process, []byte("deen"), []byte("RUTTNY"), "-upper")
+	assertCodec(t, p, p.Unprocess, []byte("RUTtny"), []byte("deen"))
+}
+
+func TestBaseNDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"base58check", "1PMycacnJaSqwwJqjawXBErnLsZ7RkXUAs", true},
+		{"base58", "2NEpo7TZRRrLZSi2U", true},
+		{"base58", "1PMycacnJaSqwwJqjawXBErnLsZ7RkXUAs", false},
+		{"base58", "HelloWorld", false},
+		{"base62", "7n42DGM5Tfak9n8mt7Fhc7", false},
+		{"base62", "0OIl7n42DGM5Tflk9n8mt7", true},
+	}
+	plugins := map[string]func([]byte) bool{
+		"base58check": func(b []byte) bool { _, ok := NewPluginBase58Check().Detect(b); return ok },
+		"base58":      func(b []byte) bool { _, ok := NewPluginBase58().Detect(b); return ok },
+		"base62":      func(b []byte) bool { _, ok := NewPluginBase62().Detect(b); return ok },
+	}
+	for _, tt := range tests {
+		if got := plugins[tt.name]([]byte(tt.input)); got != tt.want {
+			t.Errorf("%s detect %q = %v, want %v", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
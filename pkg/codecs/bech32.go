@@ -0,0 +1,358 @@
+package codecs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/takeshixx/deen/pkg/helpers"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// The checksum constants of BIP 173 and BIP 350.
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+var bech32Variants = []string{"auto", "bech32", "bech32m"}
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := range gen {
+			if top>>i&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// bech32Residue returns the checksum constant hrp and the words, including
+// the six checksum words, end with.
+func bech32Residue(hrp string, words []byte) uint32 {
+	return bech32Polymod(append(bech32HRPExpand(hrp), words...))
+}
+
+func bech32VariantOf(residue uint32) string {
+	switch residue {
+	case bech32Const:
+		return "bech32"
+	case bech32mConst:
+		return "bech32m"
+	}
+	return ""
+}
+
+// bech32Encode appends the checksum of variant to words and writes the string.
+func bech32Encode(hrp string, words []byte, variant string) (string, error) {
+	if err := validateBech32HRP(hrp); err != nil {
+		return "", err
+	}
+	hrp = strings.ToLower(hrp)
+	c := uint32(bech32Const)
+	if variant == "bech32m" {
+		c = bech32mConst
+	}
+	mod := bech32Residue(hrp, append(append([]byte(nil), words...), 0, 0, 0, 0, 0, 0)) ^ c
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, w := range words {
+		b.WriteByte(bech32Charset[w])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[mod>>(5*(5-i))&31])
+	}
+	return b.String(), nil
+}
+
+func validateBech32HRP(hrp string) error {
+	if len(hrp) == 0 || len(hrp) > 83 {
+		return errors.New("human-readable part must be 1 to 83 characters")
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return fmt.Errorf("invalid character %q in human-readable part", hrp[i])
+		}
+	}
+	return nil
+}
+
+// bech32String is a decoded Bech32 string.
+type bech32String struct {
+	hrp     string
+	words   []byte // without the checksum
+	variant string
+}
+
+// bech32Decode splits s at its last 1, checks the characters and verifies the
+// checksum. want limits the variant unless it is "auto". A checksum error
+// names the character that is most likely wrong.
+func bech32Decode(s, want string) (*bech32String, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return nil, errors.New("bech32 string mixes upper and lower case")
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	switch {
+	case sep < 0:
+		return nil, errors.New("bech32 string lacks the 1 separator")
+	case len(s)-sep-1 < 6:
+		return nil, errors.New("bech32 data part is shorter than the checksum")
+	}
+	hrp := s[:sep]
+	if err := validateBech32HRP(hrp); err != nil {
+		return nil, err
+	}
+	words := make([]byte, len(s)-sep-1)
+	for i := range words {
+		d := strings.IndexByte(bech32Charset, s[sep+1+i])
+		if d < 0 {
+			return nil, fmt.Errorf("invalid bech32 character %q at position %d", s[sep+1+i], sep+2+i)
+		}
+		words[i] = byte(d)
+	}
+	variant := bech32VariantOf(bech32Residue(hrp, words))
+	switch {
+	case variant == "":
+		return nil, bech32LocateError(s, hrp, words, want)
+	case want != "" && want != "auto" && variant != want:
+		return nil, fmt.Errorf("checksum is %s, not %s", variant, want)
+	}
+	return &bech32String{hrp: hrp, words: words[:len(words)-6], variant: variant}, nil
+}
+
+// bech32LocateError finds the character whose replacement makes the checksum
+// valid. A Bech32 checksum pins down a single wrong character; with more than
+// one there is no unique answer.
+func bech32LocateError(s, hrp string, words []byte, want string) error {
+	prefix := bech32HRPExpand(hrp)
+	values := append(prefix, words...)
+	for i := range words {
+		orig := values[len(prefix)+i]
+		for d := byte(0); d < 32; d++ {
+			if d == orig {
+				continue
+			}
+			values[len(prefix)+i] = d
+			variant := bech32VariantOf(bech32Polymod(values))
+			if variant != "" && (want == "" || want == "auto" || want == variant) {
+				pos := len(hrp) + 2 + i
+				return fmt.Errorf("bech32 checksum mismatch: character %d (%q) should probably be %q", pos, s[pos-1], bech32Charset[d])
+			}
+		}
+		values[len(prefix)+i] = orig
+	}
+	return errors.New("bech32 checksum mismatch: more than one character is wrong")
+}
+
+// convertBits regroups values of from bits into values of to bits. Encoding
+// pads the last group with zeros; decoding rejects padding of more than
+// from-1 bits or non-zero padding.
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	var acc, bits uint
+	out := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, v := range data {
+		if uint(v)>>from != 0 {
+			return nil, fmt.Errorf("value %d does not fit in %d bits", v, from)
+		}
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&(1<<to-1)))
+		}
+	}
+	switch {
+	case pad && bits > 0:
+		out = append(out, byte(acc<<(to-bits)&(1<<to-1)))
+	case !pad && (bits >= from || acc&(1<<bits-1) != 0):
+		return nil, errors.New("words do not convert to whole bytes")
+	}
+	return out, nil
+}
+
+// bech32Report is the JSON output of decoding.
+type bech32Report struct {
+	HRP     string `json:"hrp"`
+	Variant string `json:"variant"`
+	Words   []int  `json:"words"`
+	// Data holds the words converted to bytes, as hex.
+	Data      *string `json:"data,omitempty"`
+	DataError string  `json:"data_error,omitempty"`
+	// SegWit addresses carry a version word before the program.
+	WitnessVersion *int   `json:"witness_version,omitempty"`
+	WitnessProgram string `json:"witness_program,omitempty"`
+}
+
+func newBech32Report(b *bech32String) bech32Report {
+	r := bech32Report{HRP: b.hrp, Variant: b.variant, Words: make([]int, len(b.words))}
+	for i, w := range b.words {
+		r.Words[i] = int(w)
+	}
+	if data, err := convertBits(b.words, 5, 8, false); err != nil {
+		r.DataError = err.Error()
+	} else {
+		h := hex.EncodeToString(data)
+		r.Data = &h
+	}
+	switch b.hrp {
+	case "bc", "tb", "bcrt":
+		if len(b.words) == 0 || b.words[0] > 16 {
+			break
+		}
+		program, err := convertBits(b.words[1:], 5, 8, false)
+		if err != nil || len(program) < 2 || len(program) > 40 {
+			break
+		}
+		version := int(b.words[0])
+		r.WitnessVersion = &version
+		r.WitnessProgram = hex.EncodeToString(program)
+	}
+	return r
+}
+
+// bech32Variant returns the -variant option; the bech32m alias selects
+// Bech32m unless the option says otherwise.
+func bech32Variant(command string, flags *flag.FlagSet) (string, error) {
+	switch variant := helpers.StringFlag(flags, "variant"); variant {
+	case "", "auto":
+		if command == "bech32m" {
+			return "bech32m", nil
+		}
+		return "auto", nil
+	case "bech32", "bech32m":
+		return variant, nil
+	default:
+		return "", fmt.Errorf("unsupported bech32 variant %q", variant)
+	}
+}
+
+// NewPluginBech32 creates a Bech32/Bech32m plugin (BIP 173, BIP 350).
+func NewPluginBech32() *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = "bech32"
+	p.Aliases = []string{".bech32", "bech32m", ".bech32m"}
+	p.Category = "codecs"
+	p.Description = "Bech32 and Bech32m (BIP 173/350) as used by SegWit addresses, age keys\nand Lightning invoices. Encoding needs -hrp; decoding verifies the checksum,\npoints at the wrong character and prints the HRP, 5-bit words and bytes as\nJSON."
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.String("hrp", "", "human-readable part written before the 1 separator when encoding")
+		flags.String("variant", "auto", "checksum (auto, bech32, bech32m); auto encodes bech32, or bech32m for witness versions 1 and up and the bech32m alias, and accepts both when decoding")
+		flags.Bool("words", false, "input bytes are 5-bit words instead of data to convert")
+		flags.Int("witness-version", -1, "prepend a SegWit witness version word (0-16)")
+	}
+	p.Options = []types.OptionSpec{
+		{Name: "hrp", Label: "HRP", Description: "Human-readable part such as bc, tb or age.", Validate: func(v string) error {
+			if v == "" {
+				return nil
+			}
+			return validateBech32HRP(v)
+		}},
+		{Name: "variant", Label: "Variant", Description: "Bech32 or Bech32m checksum. Auto encodes Bech32, or Bech32m for witness versions 1 and up, and accepts both when decoding.", Choices: bech32Variants},
+		{Name: "words", Label: "Input is words", Description: "Take the input bytes as 5-bit words instead of converting 8-bit data."},
+		{Name: "witness-version", Label: "Witness version", Description: "SegWit witness version word to put before the program; -1 for none.", Range: &types.OptionRange{Min: -1, Max: 16}},
+	}
+	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		hrp := helpers.StringFlag(flags, "hrp")
+		if hrp == "" {
+			return errors.New("-hrp is required to encode bech32")
+		}
+		var words []byte
+		if helpers.IsBoolFlag(flags, "words") {
+			for i, b := range data {
+				if b > 31 {
+					return fmt.Errorf("byte %d is %d, not a 5-bit word", i, b)
+				}
+			}
+			words = data
+		} else if words, err = convertBits(data, 8, 5, true); err != nil {
+			return err
+		}
+		variant, err := bech32Variant(p.Command, flags)
+		if err != nil {
+			return err
+		}
+		switch version := helpers.IntFlag(flags, "witness-version", -1); {
+		case version > 16:
+			return fmt.Errorf("witness version %d is above 16", version)
+		case version >= 0:
+			words = append([]byte{byte(version)}, words...)
+			if variant == "auto" && version > 0 {
+				variant = "bech32m"
+			}
+		}
+		if variant == "auto" {
+			variant = "bech32"
+		}
+		out, err := bech32Encode(hrp, words, variant)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, out)
+		return err
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		variant, err := bech32Variant(p.Command, flags)
+		if err != nil {
+			return err
+		}
+		decoded, err := bech32Decode(string(bytes.TrimSpace(data)), variant)
+		if err != nil {
+			return err
+		}
+		out, err := json.Marshal(newBech32Report(decoded))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}
+	p.Detect = func(data []byte) (types.Detection, bool) {
+		data = bytes.TrimSpace(data)
+		if len(data) < 8 || bytes.ContainsAny(data, " \t\r\n") {
+			return types.Detection{}, false
+		}
+		decoded, err := bech32Decode(string(data), "auto")
+		if err != nil {
+			return types.Detection{}, false
+		}
+		return types.Detection{
+			Label:      "Decode " + decoded.variant,
+			Reason:     fmt.Sprintf("input is %s with a valid checksum and human-readable part %q", decoded.variant, decoded.hrp),
+			Confidence: 90,
+		}, true
+	}
+	return p
+}
@@ -0,0 +1,98 @@
+package codecs
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decodeBech32Report(t *testing.T, in string, args ...string) bech32Report {
+	t.Helper()
+	p := NewPluginBech32()
+	var r bech32Report
+	if err := json.Unmarshal(runCodec(t, p.Unprocess, p.RegisterFlags, []byte(in), args...), &r); err != nil {
+		t.Fatalf("decoding %s: %v", in, err)
+	}
+	return r
+}
+
+func TestPluginBech32(t *testing.T) {
+	p := NewPluginBech32()
+	assertCodec(t, p, p.Process, nil, []byte("a12uel5l"), "-hrp", "a")
+	assertCodec(t, p, p.Process, nil, []byte("a1lqfn3a"), "-hrp", "a", "-variant", "bech32m")
+	program, _ := hex.DecodeString("751e76e8199196d454941c45d1b3a323f1433bd6")
+	assertCodec(t, p, p.Process, program, []byte("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"), "-hrp", "bc", "-witness-version", "0")
+	assertCodec(t, p, p.Process, []byte{0, 1, 2, 31}, []byte("test1qpzl5pxhda"), "-hrp", "test", "-words")
+
+	if _, err := tryCodec(p.Process, p.RegisterFlags, []byte("x")); err == nil {
+		t.Error("encoding without -hrp succeeded")
+	}
+	if _, err := tryCodec(p.Process, p.RegisterFlags, []byte{32}, "-hrp", "a", "-words"); err == nil {
+		t.Error("encoding a word above 31 succeeded")
+	}
+
+	in := allBytes()
+	encoded := runCodec(t, p.Process, p.RegisterFlags, in, "-hrp", "deen")
+	r := decodeBech32Report(t, string(encoded))
+	if r.HRP != "deen" || r.Variant != "bech32" || r.Data == nil || *r.Data != hex.EncodeToString(in) {
+		t.Errorf("round trip = %+v", r)
+	}
+}
+
+func TestPluginBech32Decode(t *testing.T) {
+	r := decodeBech32Report(t, "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4")
+	if r.HRP != "bc" || r.Variant != "bech32" || r.WitnessVersion == nil || *r.WitnessVersion != 0 || r.WitnessProgram != "751e76e8199196d454941c45d1b3a323f1433bd6" {
+		t.Errorf("segwit v0 address = %+v", r)
+	}
+	r = decodeBech32Report(t, "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y")
+	if r.Variant != "bech32m" || r.WitnessVersion == nil || *r.WitnessVersion != 1 {
+		t.Errorf("segwit v1 address = %+v", r)
+	}
+	r = decodeBech32Report(t, "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw")
+	if len(r.Words) != 32 || r.Words[31] != 31 || r.Data == nil || *r.Data != "00443214c74254b635cf84653a56d7c675be77df" {
+		t.Errorf("words = %+v", r)
+	}
+	// Lightning invoices and other word data need not end on a byte.
+	r = decodeBech32Report(t, "test1qpzl5pxhda")
+	if r.Data != nil || r.DataError == "" || len(r.Words) != 4 {
+		t.Errorf("unaligned words = %+v", r)
+	}
+}
+
+func TestPluginBech32Errors(t *testing.T) {
+	p := NewPluginBech32()
+	tests := []struct {
+		in   string
+		args []string
+		want string
+	}{
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", nil, `character 42 ('5') should probably be '4'`},
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3tq", nil, `character 42 ('q') should probably be '4'`},
+		{"bc1qw508d6qejxtdg4y5r3zarvaryac5xw7kv8f3t5", nil, "more than one character is wrong"},
+		{"bc1qw508d6qejxtdg4y5r3zarvbry0c5xw7kv8f3t4", nil, "invalid bech32 character 'b' at position 27"},
+		{"A1G7SGD8", nil, "checksum"},
+		{"a12UEL5L", nil, "mixes upper and lower case"},
+		{"pzry9x0s0muk", nil, "lacks the 1 separator"},
+		{"1pzry9x0s0muk", nil, "human-readable part"},
+		{"a1lqfn3a", []string{"-variant", "bech32"}, "checksum is bech32m, not bech32"},
+	}
+	for _, tt := range tests {
+		_, err := tryCodec(p.Unprocess, p.RegisterFlags, []byte(tt.in), tt.args...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("decoding %s: err = %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestPluginBech32Detect(t *testing.T) {
+	p := NewPluginBech32()
+	if d, ok := p.Detect([]byte("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4\n")); !ok || d.Label != "Decode bech32" {
+		t.Errorf("segwit address detection = %+v, %t", d, ok)
+	}
+	for _, in := range []string{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", "hello1world", "a12uel5l a12uel5l"} {
+		if _, ok := p.Detect([]byte(in)); ok {
+			t.Errorf("%q detected as bech32", in)
+		}
+	}
+}
@@ -24,6 +24,15 @@ func TestCodecsBinarySafe(t *testing.T) {
 		"base32":           NewPluginBase32(),
 		"base64":           NewPluginBase64(),
 		"base85":           NewPluginBase85(),
+		"base58":           NewPluginBase58(),
+		"base58check":      NewPluginBase58Check(),
+		"base62":           NewPluginBase62(),
+		"base36":           NewPluginBase36(),
+		"base45":           NewPluginBase45(),
+		"base91":           NewPluginBase91(),
+		"uuencode":         NewPluginUUEncode(),
+		"xxencode":         NewPluginXXEncode(),
+		"yenc":             NewPluginYEnc(),
 		"hex":              NewPluginHex(),
 		"url":              NewPluginURL(),
 		"strconv":          NewPluginStrconv(),
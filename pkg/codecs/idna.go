@@ -0,0 +1,247 @@
+package codecs
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/takeshixx/deen/pkg/helpers"
+	"github.com/takeshixx/deen/pkg/types"
+	"golang.org/x/net/idna"
+)
+
+var idnaProfiles = []string{"lookup", "registration", "punycode"}
+
+// idnaProfile returns the conversion selected by -profile. lookup applies the
+// UTS #46 mapping browsers use, registration rejects everything a registry
+// would and punycode only converts the labels.
+func idnaProfile(flags *flag.FlagSet) (*idna.Profile, error) {
+	transitional := helpers.IsBoolFlag(flags, "transitional")
+	switch profile := helpers.StringFlag(flags, "profile"); profile {
+	case "", "lookup":
+		return idna.New(idna.MapForLookup(), idna.BidiRule(), idna.Transitional(transitional)), nil
+	case "registration":
+		return idna.New(idna.ValidateForRegistration(), idna.Transitional(transitional)), nil
+	case "punycode":
+		return idna.Punycode, nil
+	default:
+		return nil, fmt.Errorf("unsupported idna profile %q", profile)
+	}
+}
+
+// idnaLines converts every line of data on its own. Empty lines are kept so
+// lists of hostnames stay aligned with their input.
+func idnaLines(data []byte, w io.Writer, convert func(string) (string, error)) error {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		host := strings.TrimSpace(line)
+		if host != "" {
+			out, err := convert(host)
+			if err != nil {
+				// The idna errors carry their own package prefix.
+				msg := strings.TrimPrefix(err.Error(), "idna: ")
+				if len(lines) > 1 {
+					return fmt.Errorf("line %d: %s", i+1, msg)
+				}
+				return errors.New(msg)
+			}
+			line = out
+		}
+		if i < len(lines)-1 {
+			line += "\n"
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// idnaAllowedMixes are the script combinations UTS #39 allows within a
+// single label at the highly restrictive level.
+var idnaAllowedMixes = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// mixedScripts reports whether scripts mix in a way a single label should not.
+func mixedScripts(scripts []string) bool {
+	if len(scripts) < 2 {
+		return false
+	}
+	for _, allowed := range idnaAllowedMixes {
+		ok := true
+		for _, s := range scripts {
+			ok = ok && slices.Contains(allowed, s)
+		}
+		if ok {
+			return false
+		}
+	}
+	return true
+}
+
+// idnaInspect writes a report on host: its Unicode and ASCII forms and, per
+// label, the scripts and characters that make it look like something else.
+func idnaInspect(w io.Writer, profile *idna.Profile, host string) error {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "host: %s\n", host)
+	// Both conversions return their best effort alongside an error, which is
+	// still worth inspecting.
+	unicodeForm, err := profile.ToUnicode(host)
+	if err != nil {
+		fmt.Fprintf(&out, "error: %v\n", err)
+	}
+	asciiForm, err := profile.ToASCII(unicodeForm)
+	if err != nil {
+		fmt.Fprintf(&out, "error: %v\n", err)
+	}
+	fmt.Fprintf(&out, "unicode: %s\nascii: %s\n", unicodeForm, asciiForm)
+
+	var findings []string
+	asciiLabels := strings.Split(asciiForm, ".")
+	for i, label := range strings.Split(unicodeForm, ".") {
+		if label == "" {
+			continue
+		}
+		name := label
+		if i < len(asciiLabels) && asciiLabels[i] != label {
+			name = asciiLabels[i] + " (" + label + ")"
+		}
+		info := inspectUnicode([]byte(label))
+		scripts := "none"
+		if len(info.scripts) > 0 {
+			scripts = strings.Join(info.scripts, ", ")
+		}
+		fmt.Fprintf(&out, "label %s\n  scripts: %s\n", name, scripts)
+		if mixedScripts(info.scripts) {
+			fmt.Fprintf(&out, "  mixed scripts: yes\n")
+			findings = append(findings, fmt.Sprintf("%q mixes %s", label, scripts))
+		}
+		if len(info.confusables) == 0 {
+			continue
+		}
+		var skeleton strings.Builder
+		for _, r := range label {
+			like, ok := confusableWith(r)
+			if !ok {
+				like = string(r)
+			}
+			skeleton.WriteString(like)
+		}
+		for _, c := range info.confusables {
+			fmt.Fprintf(&out, "  confusable: %s looks like %q\n", c, c.like)
+		}
+		fmt.Fprintf(&out, "  looks like: %s\n", skeleton.String())
+		// Accented Latin letters are ordinary in IDNs; a label counts when it
+		// reads as ASCII without using Latin at all.
+		if asciiOnly([]byte(skeleton.String())) && !slices.Contains(info.scripts, "Latin") {
+			findings = append(findings, fmt.Sprintf("%q reads as %q", label, skeleton.String()))
+		}
+	}
+	if len(findings) == 0 {
+		out.WriteString("verdict: no homograph indicators\n")
+	} else {
+		fmt.Fprintf(&out, "verdict: possible homograph: %s\n", strings.Join(findings, "; "))
+	}
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+// idnaHostLike reports whether every line of data is an ASCII hostname with at
+// least one Punycode label between them.
+func idnaHostLike(data []byte) bool {
+	ace := false
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		for _, label := range bytes.Split(line, []byte(".")) {
+			if len(label) > 4 && bytes.EqualFold(label[:4], []byte("xn--")) {
+				ace = true
+			}
+		}
+		for _, ch := range line {
+			if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '.') {
+				return false
+			}
+		}
+	}
+	return ace
+}
+
+// NewPluginIDNA creates an IDNA plugin.
+func NewPluginIDNA() *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = "idna"
+	p.Aliases = []string{".idna", "punycode", ".punycode"}
+	p.Category = "codecs"
+	p.Description = "Internationalized domain names: converts hostnames label by label\nbetween Unicode and Punycode (xn--) with the IDNA2008/UTS #46 mapping,\none hostname per line. -inspect reports mixed scripts and confusable\ncharacters of each label."
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.String("profile", "lookup", "conversion profile (lookup, registration, punycode)")
+		flags.Bool("transitional", false, "use the transitional mapping (ß to ss, ς to σ) of IDNA2003")
+		flags.Bool("inspect", false, "report scripts and confusable characters instead of converting")
+	}
+	p.Options = []types.OptionSpec{
+		{Name: "profile", Label: "Profile", Description: "Lookup maps input as browsers do, registration rejects anything a registry would and punycode only converts labels.", Choices: idnaProfiles},
+		{Name: "transitional", Label: "Transitional", Description: "Map deviation characters such as ß and ς as IDNA2003 did."},
+		{Name: "inspect", Label: "Inspect", Description: "Report the Unicode and ASCII forms, scripts and confusable characters of each hostname."},
+	}
+	convert := func(r io.Reader, w io.Writer, flags *flag.FlagSet, toUnicode bool) error {
+		profile, err := idnaProfile(flags)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if helpers.IsBoolFlag(flags, "inspect") {
+			first := true
+			for _, line := range strings.Split(string(data), "\n") {
+				if host := strings.TrimSpace(line); host != "" {
+					if !first {
+						if _, err := io.WriteString(w, "\n"); err != nil {
+							return err
+						}
+					}
+					first = false
+					if err := idnaInspect(w, profile, host); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+		if toUnicode {
+			return idnaLines(data, w, profile.ToUnicode)
+		}
+		return idnaLines(data, w, profile.ToASCII)
+	}
+	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		return convert(r, w, flags, false)
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		return convert(r, w, flags, true)
+	}
+	p.Detect = func(data []byte) (types.Detection, bool) {
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 || !idnaHostLike(data) {
+			return types.Detection{}, false
+		}
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if _, err := idna.Punycode.ToUnicode(string(bytes.TrimSpace(line))); err != nil {
+				return types.Detection{}, false
+			}
+		}
+		return types.Detection{
+			Label:      "Decode Punycode hostname",
+			Reason:     "input is a hostname with xn-- labels",
+			Confidence: 85,
+		}, true
+	}
+	return p
+}
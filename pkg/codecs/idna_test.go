@@ -0,0 +1,67 @@
+package codecs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPluginIDNA(t *testing.T) {
+	p := NewPluginIDNA()
+	assertCodec(t, p, p.Process, []byte("münchen.de"), []byte("xn--mnchen-3ya.de"))
+	assertCodec(t, p, p.Process, []byte("Bücher.Example\n\nexample.com\n"), []byte("xn--bcher-kva.example\n\nexample.com\n"))
+	assertCodec(t, p, p.Unprocess, []byte("xn--80ak6aa92e.com\nxn--mnchen-3ya.de"), []byte("аррӏе.com\nmünchen.de"))
+	assertCodec(t, p, p.Process, []byte("straße.de"), []byte("xn--strae-oqa.de"))
+	assertCodec(t, p, p.Process, []byte("straße.de"), []byte("strasse.de"), "-transitional")
+	assertCodec(t, p, p.Process, []byte("Bücher"), []byte("xn--Bcher-kva"), "-profile", "punycode")
+
+	if _, err := tryCodec(p.Unprocess, p.RegisterFlags, []byte("example.com\nxn--zz.com")); err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+		t.Errorf("decoding an invalid label: err = %v, want a line 2 error", err)
+	}
+	if _, err := tryCodec(p.Process, p.RegisterFlags, []byte("EXAMPLE.com"), "-profile", "registration"); err == nil {
+		t.Error("registration profile accepted upper case")
+	}
+}
+
+func TestPluginIDNAInspect(t *testing.T) {
+	p := NewPluginIDNA()
+	tests := []struct {
+		host string
+		want []string
+	}{
+		{"xn--80ak6aa92e.com", []string{
+			"unicode: аррӏе.com",
+			"label xn--80ak6aa92e (аррӏе)",
+			"scripts: Cyrillic",
+			`confusable: U+04CF 'ӏ' (Cyrillic) looks like "l"`,
+			"looks like: apple",
+			`verdict: possible homograph: "аррӏе" reads as "apple"`,
+		}},
+		{"xn--pple-43d.com", []string{
+			"scripts: Cyrillic, Latin",
+			"mixed scripts: yes",
+			`verdict: possible homograph: "аpple" mixes Cyrillic, Latin`,
+		}},
+		{"münchen.de", []string{"ascii: xn--mnchen-3ya.de", "verdict: no homograph indicators"}},
+		{"日本語とカタカナ.jp", []string{"scripts: Han, Hiragana, Katakana", "verdict: no homograph indicators"}},
+	}
+	for _, tt := range tests {
+		got := string(runCodec(t, p.Process, p.RegisterFlags, []byte(tt.host), "-inspect"))
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("inspecting %s: output missing %q:\n%s", tt.host, want, got)
+			}
+		}
+	}
+}
+
+func TestPluginIDNADetect(t *testing.T) {
+	p := NewPluginIDNA()
+	if _, ok := p.Detect([]byte("xn--80ak6aa92e.com\n")); !ok {
+		t.Error("Punycode hostname not detected")
+	}
+	for _, in := range []string{"example.com", "see xn--80ak6aa92e.com", "xn--zz.com"} {
+		if _, ok := p.Detect([]byte(in)); ok {
+			t.Errorf("%q detected as a Punycode hostname", in)
+		}
+	}
+}
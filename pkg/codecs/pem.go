@@ -23,6 +23,11 @@ func NewPluginPEM() *types.DeenPlugin {
 		flags.String("headers", "", "message headers in JSON format")
 		flags.Bool("cert", false, "create a PEM encoded certificate")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "cert", Label: "Certificate", Description: "Create PEM output from certificate bytes."},
+		{Name: "headers", Description: "PEM headers as a JSON object.", Multiline: true},
+		{Name: "type", Label: "PEM type", Description: "PEM block type."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		headers := map[string]string{}
 		if raw := helpers.StringFlag(flags, "headers"); raw != "" {
@@ -21,6 +21,10 @@ func NewPluginStrconv() *types.DeenPlugin {
 		flags.Bool("ctrl", false, "only escape control sequences")
 		flags.Bool("graph", false, "escape to graphs")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "ctrl", Label: "Control characters only", Description: "Escape only control characters."},
+		{Name: "graph", Description: "Escape printable characters using Go graph escapes."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		data, err := io.ReadAll(r)
 		if err != nil {
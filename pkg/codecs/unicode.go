@@ -3,6 +3,7 @@ package codecs
 import (
 	"flag"
 	"io"
+	"strings"
 
 	"github.com/takeshixx/deen/pkg/helpers"
 	"github.com/takeshixx/deen/pkg/types"
@@ -35,7 +36,7 @@ func commandEndianness(command string, flags *flag.FlagSet) unicode.Endianness {
 }
 
 func readBOMPolicy(flags *flag.FlagSet) unicode.BOMPolicy {
-	switch helpers.StringFlag(flags, "bom") {
+	switch strings.ToLower(helpers.StringFlag(flags, "bom")) {
 	case "use":
 		return unicode.UseBOM
 	case "expect":
@@ -48,7 +49,7 @@ func readBOMPolicy(flags *flag.FlagSet) unicode.BOMPolicy {
 // unicodeEncoding maps a command and flags to a text encoding.
 func unicodeEncoding(command string, flags *flag.FlagSet) encoding.Encoding {
 	if command == "" || command == "unicode" {
-		command = helpers.StringFlag(flags, "encoding")
+		command = strings.ToLower(helpers.StringFlag(flags, "encoding"))
 	}
 	endianness := commandEndianness(command, flags)
 	bomPolicy := readBOMPolicy(flags)
@@ -103,6 +104,15 @@ func NewPluginUnicode() *types.DeenPlugin {
 		flags.String("bom", "ignore", "BOM mode (use, ignore, expect)")
 		flags.Bool("big", false, "use big endian (default: little)")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "big", Label: "Big endian", Description: "Use big-endian byte order."},
+		{Name: "bom", Label: "BOM handling", Description: "How to handle byte order marks.", Choices: []string{"ignore", "use", "expect"}},
+		{
+			Name: "encoding", Label: "Text encoding", Description: "Text encoding for conversion.",
+			Choices:       []string{"utf8", "utf16le", "utf16be", "utf32le", "utf32be", "latin1", "windows1252", "shiftjis", "eucjp", "gbk", "gb18030", "big5", "euckr", "koi8r"},
+			ChoiceAliases: []string{"utf-8", "utf16", "utf32", "iso88591", "iso-8859-1", "windows-1252", "cp1252", "shift-jis", "sjis", "euc-jp", "koi8-r"},
+		},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		enc := unicodeEncoding(p.Command, flags)
 		_, err := io.Copy(enc.NewEncoder().Writer(w), r)
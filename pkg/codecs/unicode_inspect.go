@@ -5,10 +5,13 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/takeshixx/deen/pkg/types"
+	"golang.org/x/text/unicode/norm"
 )
 
 type unicodeInspection struct {
@@ -22,6 +25,15 @@ type unicodeInspection struct {
 	nullEven    int
 	nullOdd     int
 	nullMod4    [4]int
+	scripts     []string // scripts of the text in order of appearance, without Common and Inherited
+	confusables []confusableRune
+}
+
+// confusableRune is a code point that looks like ASCII text.
+type confusableRune struct {
+	r      rune
+	script string
+	like   string
 }
 
 func inspectUnicode(data []byte) unicodeInspection {
@@ -43,6 +55,7 @@ func inspectUnicode(data []byte) unicodeInspection {
 			info.nullMod4[i%4]++
 		}
 	}
+	seen := map[rune]bool{}
 	for len(data) > 0 {
 		r, size := utf8.DecodeRune(data)
 		if r == utf8.RuneError && size == 1 {
@@ -53,10 +66,89 @@ func inspectUnicode(data []byte) unicodeInspection {
 		}
 		info.runes++
 		data = data[size:]
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		script := runeScript(r)
+		if script != "Common" && script != "Inherited" && !slices.Contains(info.scripts, script) {
+			info.scripts = append(info.scripts, script)
+		}
+		if like, ok := confusableWith(r); ok {
+			info.confusables = append(info.confusables, confusableRune{r: r, script: script, like: like})
+		}
 	}
 	return info
 }
 
+// scriptNames lists the Unicode scripts in the order runeScript tries them:
+// the ones domain names mostly use first, then the rest alphabetically.
+var scriptNames = func() []string {
+	names := []string{"Latin", "Cyrillic", "Greek", "Han", "Arabic", "Hebrew", "Hiragana", "Katakana", "Hangul", "Armenian", "Georgian", "Thai", "Devanagari", "Common", "Inherited"}
+	var rest []string
+	for name := range unicode.Scripts {
+		if !slices.Contains(names, name) {
+			rest = append(rest, name)
+		}
+	}
+	slices.Sort(rest)
+	return append(names, rest...)
+}()
+
+// runeScript returns the Unicode script of r, or "Unknown".
+func runeScript(r rune) string {
+	switch {
+	case r < utf8.RuneSelf && (r|0x20 >= 'a' && r|0x20 <= 'z'):
+		return "Latin"
+	case r < utf8.RuneSelf:
+		return "Common"
+	}
+	for _, name := range scriptNames {
+		if unicode.Is(unicode.Scripts[name], r) {
+			return name
+		}
+	}
+	return "Unknown"
+}
+
+// confusables maps non-ASCII letters to the ASCII text they are easily
+// mistaken for. It covers the Cyrillic, Greek, Armenian and Latin lookalikes
+// seen in homograph domains; compatibility forms such as fullwidth and
+// mathematical letters are caught by NFKC instead.
+var confusables = map[rune]string{
+	// Cyrillic
+	'а': "a", 'в': "b", 'е': "e", 'ё': "e", 'о': "o", 'р': "p", 'с': "c", 'у': "y", 'х': "x", 'ѕ': "s",
+	'і': "i", 'ї': "i", 'ј': "j", 'ԁ': "d", 'ԛ': "q", 'ԝ': "w", 'һ': "h", 'ӏ': "l", 'ɡ': "g", 'ь': "b",
+	'А': "A", 'В': "B", 'Е': "E", 'К': "K", 'М': "M", 'Н': "H", 'О': "O", 'Р': "P", 'С': "C", 'Т': "T",
+	'Х': "X", 'Ѕ': "S", 'І': "I", 'Ј': "J", 'Ү': "Y", 'Ԁ': "D", 'Ԛ': "Q", 'Ԝ': "W", 'Ӏ': "I",
+	// Greek
+	'α': "a", 'ο': "o", 'ν': "v", 'ρ': "p", 'ι': "i", 'κ': "k", 'υ': "u", 'ε': "e", 'τ': "t", 'ϲ': "c",
+	'Α': "A", 'Β': "B", 'Ε': "E", 'Ζ': "Z", 'Η': "H", 'Ι': "I", 'Κ': "K", 'Μ': "M", 'Ν': "N", 'Ο': "O",
+	'Ρ': "P", 'Τ': "T", 'Υ': "Y", 'Χ': "X",
+	// Armenian
+	'օ': "o", 'ս': "u", 'հ': "h", 'ո': "n", 'զ': "q", 'ց': "g", 'ե': "t",
+	// Latin
+	'ı': "i", 'ɑ': "a", 'ɩ': "i", 'ʋ': "u", 'ɒ': "a", 'ƅ': "b", 'ɗ': "d", 'ǀ': "l", 'ß': "ss",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ç': "c", 'è': "e", 'é': "e", 'ê': "e",
+	'ë': "e", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o",
+	'ö': "o", 'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'ÿ': "y",
+}
+
+// confusableWith returns the ASCII text a non-ASCII code point resembles.
+func confusableWith(r rune) (string, bool) {
+	if r < utf8.RuneSelf {
+		return "", false
+	}
+	if like, ok := confusables[r]; ok {
+		return like, true
+	}
+	folded := norm.NFKC.String(string(r))
+	if folded != string(r) && asciiOnly([]byte(folded)) && strings.TrimSpace(folded) != "" {
+		return folded, true
+	}
+	return "", false
+}
+
 func unicodeBOM(data []byte) string {
 	switch {
 	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
@@ -128,20 +220,29 @@ func nullPattern(data []byte) [4]int {
 	return counts
 }
 
+// String formats the code point as U+0430 'а' (Cyrillic).
+func (c confusableRune) String() string {
+	return fmt.Sprintf("U+%04X %q (%s)", c.r, c.r, c.script)
+}
+
 // NewPluginUnicodeInspect creates a Unicode and text encoding inspector.
 func NewPluginUnicodeInspect() *types.DeenPlugin {
 	p := types.NewPlugin()
 	p.Name = "unicode-inspect"
 	p.Aliases = []string{"utfinspect", "charset"}
 	p.Category = "codecs"
-	p.Description = "Inspect text bytes for UTF-8 validity, BOMs, code point counts and likely UTF-16/UTF-32 byte order."
+	p.Description = "Inspect text bytes for UTF-8 validity, BOMs, code point counts, likely UTF-16/UTF-32 byte order, scripts and confusable characters."
 	p.Process = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
 		data, err := io.ReadAll(r)
 		if err != nil {
 			return err
 		}
 		info := inspectUnicode(data)
-		_, err = fmt.Fprintf(w, "bytes: %d\nlikely: %s\nbom: %s\nutf-8 valid: %t\ncode points: %d\ninvalid utf-8 bytes: %d\ncontrol code points: %d\nnull bytes: even=%d odd=%d mod4=[%d %d %d %d]\n",
+		scripts := "none"
+		if len(info.scripts) > 0 {
+			scripts = strings.Join(info.scripts, ", ")
+		}
+		_, err = fmt.Fprintf(w, "bytes: %d\nlikely: %s\nbom: %s\nutf-8 valid: %t\ncode points: %d\ninvalid utf-8 bytes: %d\ncontrol code points: %d\nnull bytes: even=%d odd=%d mod4=[%d %d %d %d]\nscripts: %s\n",
 			info.bytes,
 			info.likely,
 			info.bom,
@@ -155,8 +256,17 @@ func NewPluginUnicodeInspect() *types.DeenPlugin {
 			info.nullMod4[1],
 			info.nullMod4[2],
 			info.nullMod4[3],
+			scripts,
 		)
-		return err
+		if err != nil {
+			return err
+		}
+		for _, c := range info.confusables {
+			if _, err := fmt.Fprintf(w, "confusable: %s looks like %q\n", c, c.like); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 	return p
 }
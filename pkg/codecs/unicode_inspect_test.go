@@ -100,3 +100,20 @@ func TestPluginUnicodeInspectLikelyEndianWithoutBOM(t *testing.T) {
 		})
 	}
 }
+
+func TestPluginUnicodeInspectConfusables(t *testing.T) {
+	p := NewPluginUnicodeInspect()
+	got := string(runCodec(t, p.Process, p.RegisterFlags, []byte("pаypal ｅxample 世界")))
+	for _, want := range []string{
+		"scripts: Latin, Cyrillic, Han",
+		`confusable: U+0430 'а' (Cyrillic) looks like "a"`,
+		`confusable: U+FF45 'ｅ' (Latin) looks like "e"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("unicode-inspect output missing %q:\n%s", want, got)
+		}
+	}
+	if got := string(runCodec(t, p.Process, p.RegisterFlags, []byte("plain"))); strings.Contains(got, "confusable:") {
+		t.Errorf("ASCII input reported confusables:\n%s", got)
+	}
+}
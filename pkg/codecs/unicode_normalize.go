@@ -40,6 +40,9 @@ func NewPluginUnicodeNormalize() *types.DeenPlugin {
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.String("form", "nfc", "normalization form (nfc, nfd, nfkc, nfkd)")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "form", Label: "Normalization form", Description: "Unicode normalization form.", Choices: []string{"nfc", "nfd", "nfkc", "nfkd"}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		form, err := normalizationForm(p.Command, flags)
 		if err != nil {
@@ -0,0 +1,226 @@
+package codecs
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/takeshixx/deen/pkg/helpers"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// uuLineBytes is the number of bytes per encoded line, written as 60
+// characters after the length character.
+const uuLineBytes = 45
+
+// uuCodec is a uuencode style line codec: a length character followed by
+// groups of four 6-bit characters for every three bytes.
+type uuCodec struct {
+	name     string
+	alphabet string    // characters for the values 0-63
+	digit    [256]byte // value + 1; 0 marks an invalid character
+}
+
+func newUUCodec(name, alphabet string, extra map[byte]byte) *uuCodec {
+	c := &uuCodec{name: name, alphabet: alphabet}
+	for i := 0; i < len(alphabet); i++ {
+		c.digit[alphabet[i]] = byte(i + 1)
+	}
+	for ch, v := range extra {
+		c.digit[ch] = v + 1
+	}
+	return c
+}
+
+var (
+	// uuencode writes ` for zero but reads a space as well.
+	uuCodecStd = newUUCodec("uuencode", "`!\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_", map[byte]byte{' ': 0})
+	xxCodec    = newUUCodec("xxencode", "+-0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", nil)
+)
+
+// encode writes a begin line with mode and name, the data lines, the
+// zero-length line and the end line.
+func (c *uuCodec) encode(w io.Writer, data []byte, mode, name string) error {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "begin %s %s\n", mode, name)
+	for len(data) > 0 {
+		n := min(len(data), uuLineBytes)
+		out.WriteByte(c.alphabet[n])
+		for i := 0; i < n; i += 3 {
+			var group [3]byte
+			copy(group[:], data[i:n])
+			v := uint(group[0])<<16 | uint(group[1])<<8 | uint(group[2])
+			out.Write([]byte{c.alphabet[v>>18&63], c.alphabet[v>>12&63], c.alphabet[v>>6&63], c.alphabet[v&63]})
+		}
+		out.WriteByte('\n')
+		data = data[n:]
+	}
+	out.WriteByte(c.alphabet[0])
+	out.WriteString("\nend\n")
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// decodeLine decodes one data line. ok is false when the line is not an
+// encoded line of this alphabet, e.g. a mail header between the parts of a
+// multi-part message. Trailing characters stripped by mail software are taken
+// as zero.
+func (c *uuCodec) decodeLine(line []byte) (data []byte, ok bool) {
+	if len(line) == 0 || c.digit[line[0]] == 0 {
+		return nil, false
+	}
+	n := int(c.digit[line[0]] - 1)
+	chars := line[1:]
+	if len(chars) < (n*4+2)/3 || len(chars) > (n+2)/3*4+2 {
+		return nil, false
+	}
+	data = make([]byte, 0, n+2)
+	for i := 0; i < (n+2)/3*4; i += 4 {
+		var v uint
+		for j := i; j < i+4; j++ {
+			var d byte
+			if j < len(chars) {
+				if d = c.digit[chars[j]]; d == 0 {
+					return nil, false
+				}
+				d--
+			}
+			v = v<<6 | uint(d)
+		}
+		data = append(data, byte(v>>16), byte(v>>8), byte(v))
+	}
+	return data[:n], true
+}
+
+// decode decodes the file after the first begin line, or the whole input as
+// bare lines when there is none. Lines that are not encoded lines are
+// skipped, which reassembles multi-part messages concatenated in order.
+func (c *uuCodec) decode(text []byte) ([]byte, error) {
+	lines := bytes.Split(text, []byte("\n"))
+	start, header := 0, false
+	for i, line := range lines {
+		if _, _, ok := parseUUBegin(line); ok {
+			start, header = i+1, true
+			break
+		}
+	}
+	var out []byte
+	decoded, ended := false, false
+	for _, line := range lines[start:] {
+		line = bytes.TrimRight(line, "\r")
+		if string(bytes.TrimSpace(line)) == "end" {
+			ended = true
+			break
+		}
+		data, ok := c.decodeLine(line)
+		if !ok {
+			continue
+		}
+		decoded = true
+		out = append(out, data...)
+	}
+	switch {
+	case header && !ended:
+		return nil, fmt.Errorf("%s input lacks the end line", c.name)
+	case !decoded:
+		return nil, fmt.Errorf("no %s lines found", c.name)
+	}
+	return out, nil
+}
+
+// parseUUBegin parses a "begin <mode> <name>" line.
+func parseUUBegin(line []byte) (mode, name string, ok bool) {
+	fields := strings.SplitN(strings.TrimRight(string(line), "\r"), " ", 3)
+	if len(fields) != 3 || fields[0] != "begin" || validateUUMode(fields[1]) != nil {
+		return "", "", false
+	}
+	return fields[1], fields[2], true
+}
+
+func validateUUMode(mode string) error {
+	if _, err := strconv.ParseUint(mode, 8, 12); err != nil || len(mode) < 3 {
+		return errors.New("must be an octal file mode such as 644")
+	}
+	return nil
+}
+
+// newUUPlugin builds the uuencode and xxencode plugins, which differ only in
+// their alphabet.
+func newUUPlugin(c *uuCodec, aliases []string, description string) *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = c.name
+	p.Aliases = aliases
+	p.Category = "codecs"
+	p.Description = description
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.String("name", "data", "file name written to the begin line")
+		flags.String("mode", "644", "octal file mode written to the begin line")
+	}
+	p.Options = []types.OptionSpec{
+		{Name: "name", Label: "File name", Description: "File name written to the begin line."},
+		{Name: "mode", Label: "File mode", Description: "Octal file mode written to the begin line.", Validate: validateUUMode},
+	}
+	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		mode := helpers.StringFlag(flags, "mode")
+		if err := validateUUMode(mode); err != nil {
+			return fmt.Errorf("mode %q %s", mode, err)
+		}
+		name := helpers.StringFlag(flags, "name")
+		if name == "" || strings.ContainsAny(name, "\r\n") {
+			return errors.New("name must be a single non-empty line")
+		}
+		return c.encode(w, data, mode, name)
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		decoded, err := c.decode(data)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(decoded)
+		return err
+	}
+	p.Detect = func(data []byte) (types.Detection, bool) {
+		lines := bytes.Split(data, []byte("\n"))
+		for i, line := range lines[:len(lines)-1] {
+			_, name, ok := parseUUBegin(line)
+			if !ok {
+				continue
+			}
+			// The alphabets overlap, so check the first data line.
+			if _, ok := c.decodeLine(bytes.TrimRight(lines[i+1], "\r")); !ok {
+				return types.Detection{}, false
+			}
+			return types.Detection{
+				Label:      "Decode " + c.name,
+				Reason:     fmt.Sprintf("input has a begin line for %q followed by %s lines", name, c.name),
+				Confidence: 90,
+			}, true
+		}
+		return types.Detection{}, false
+	}
+	return p
+}
+
+// NewPluginUUEncode creates a uuencode plugin.
+func NewPluginUUEncode() *types.DeenPlugin {
+	return newUUPlugin(uuCodecStd, []string{".uuencode", "uu", ".uu"},
+		"uuencode as used by mail spools and Usenet, with begin/end lines\ncarrying the file mode and name. Decoding skips text between the\nparts of multi-part messages.")
+}
+
+// NewPluginXXEncode creates an xxencode plugin.
+func NewPluginXXEncode() *types.DeenPlugin {
+	return newUUPlugin(xxCodec, []string{".xxencode", "xx", ".xx"},
+		"xxencode: uuencode with an alphanumeric alphabet that survives\nEBCDIC gateways.")
+}
@@ -0,0 +1,59 @@
+package codecs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPluginUUEncode(t *testing.T) {
+	p := NewPluginUUEncode()
+	assertCodec(t, p, p.Process, []byte("Cat"), []byte("begin 644 data\n#0V%T\n`\nend\n"))
+	assertCodec(t, p, p.Process, []byte("Hello, world!"), []byte("begin 600 hello.txt\n-2&5L;&\\L('=O<FQD(0``\n`\nend\n"), "-mode", "600", "-name", "hello.txt")
+	assertCodec(t, p, p.Unprocess, []byte("begin 600 hello.txt\r\n-2&5L;&\\L('=O<FQD(0``\r\n`\r\nend\r\n"), []byte("Hello, world!"))
+	// Trailing spaces stripped by mail software and a bare body.
+	assertCodec(t, p, p.Unprocess, []byte("-2&5L;&\\L('=O<FQD(0\n"), []byte("Hello, world!"))
+	assertCodec(t, p, p.Unprocess, []byte("begin 644 data\n#0V%T\n \nend"), []byte("Cat"))
+
+	for _, bad := range []string{"begin 644 data\n#0V%T\n", "no data here", "begin 644 data\n`\nend\n#0V%T"} {
+		got, err := tryCodec(p.Unprocess, p.RegisterFlags, []byte(bad))
+		if err == nil && len(got) > 0 {
+			t.Errorf("decoding %q = %q", bad, got)
+		}
+	}
+	if _, err := tryCodec(p.Process, p.RegisterFlags, []byte("x"), "-mode", "rw"); err == nil {
+		t.Error("encoding with a non-octal mode succeeded")
+	}
+}
+
+func TestPluginUUEncodeMultiPart(t *testing.T) {
+	p := NewPluginUUEncode()
+	input := bytes.Repeat([]byte("multi-part uuencode "), 10)
+	encoded := string(runCodec(t, p.Process, p.RegisterFlags, input, "-name", "parts.bin"))
+	lines := strings.SplitAfter(encoded, "\n")
+	split := strings.Join(lines[:3], "") + "\nFrom: poster@example.com\nSubject: parts.bin (2/2)\n\n" + strings.Join(lines[3:], "")
+	assertCodec(t, p, p.Unprocess, []byte(split), input)
+}
+
+func TestPluginXXEncode(t *testing.T) {
+	p := NewPluginXXEncode()
+	assertCodec(t, p, p.Process, []byte("Cat"), []byte("begin 644 data\n1Eq3o\n+\nend\n"))
+	assertCodec(t, p, p.Unprocess, []byte("begin 644 data\n1Eq3o\n+\nend\n"), []byte("Cat"))
+	in := allBytes()
+	assertCodec(t, p, p.Unprocess, runCodec(t, p.Process, p.RegisterFlags, in), in)
+}
+
+func TestPluginUUEncodeDetect(t *testing.T) {
+	uu, xx := NewPluginUUEncode(), NewPluginXXEncode()
+	uuText := []byte("begin 644 data\n#0V%T\n`\nend\n")
+	xxText := []byte("begin 644 data\n1Eq3o\n+\nend\n")
+	if _, ok := uu.Detect(uuText); !ok {
+		t.Error("uuencode not detected")
+	}
+	if _, ok := xx.Detect(xxText); !ok {
+		t.Error("xxencode not detected")
+	}
+	if _, ok := uu.Detect(xxText); ok {
+		t.Error("xxencode detected as uuencode")
+	}
+}
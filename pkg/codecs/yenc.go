@@ -0,0 +1,277 @@
+package codecs
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/takeshixx/deen/pkg/helpers"
+	"github.com/takeshixx/deen/pkg/types"
+)
+
+// yencEncode writes a single-part yEnc file. Besides the critical NUL, LF, CR
+// and "=", tabs and spaces at either end of a line and dots at the start of a
+// line are escaped so mail and news servers leave them alone.
+func yencEncode(w io.Writer, data []byte, name string, lineLen int) error {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "=ybegin line=%d size=%d name=%s\n", lineLen, len(data), name)
+	col := 0
+	for i, b := range data {
+		o := b + 42
+		escape := false
+		switch o {
+		case 0, '\n', '\r', '=':
+			escape = true
+		case '\t', ' ':
+			escape = col == 0 || col >= lineLen-1 || i == len(data)-1
+		case '.':
+			escape = col == 0
+		}
+		if escape {
+			out.WriteByte('=')
+			o += 64
+			col++
+		}
+		out.WriteByte(o)
+		if col++; col >= lineLen {
+			out.WriteByte('\n')
+			col = 0
+		}
+	}
+	if col > 0 {
+		out.WriteByte('\n')
+	}
+	fmt.Fprintf(&out, "=yend size=%d crc32=%08x\n", len(data), crc32.ChecksumIEEE(data))
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// yencPart is one =ybegin ... =yend block.
+type yencPart struct {
+	name       string
+	size       int // of the whole file
+	part       int // 0 for a single-part file
+	begin, end int // 1-based byte range of a part
+	data       []byte
+	crc        string // =yend crc32, of the whole file
+}
+
+// yencFields parses the key=value pairs of a =y line. name takes the rest of
+// the line since file names may contain spaces.
+func yencFields(line string) map[string]string {
+	fields := map[string]string{}
+	if i := strings.Index(line, " name="); i >= 0 {
+		fields["name"] = strings.TrimSpace(line[i+len(" name="):])
+		line = line[:i]
+	}
+	for _, f := range strings.Fields(line)[1:] {
+		if k, v, ok := strings.Cut(f, "="); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+func yencInt(fields map[string]string, key string) (int, error) {
+	v, err := strconv.Atoi(fields[key])
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("bad yEnc %s %q", key, fields[key])
+	}
+	return v, nil
+}
+
+// yencCRC verifies a hexadecimal CRC32 field against data.
+func yencCRC(field string, data []byte) error {
+	want, err := strconv.ParseUint(field, 16, 32)
+	if err != nil {
+		return fmt.Errorf("bad yEnc checksum %q", field)
+	}
+	if got := crc32.ChecksumIEEE(data); uint32(want) != got {
+		return fmt.Errorf("yEnc CRC32 mismatch: got %08x, want %08x", got, want)
+	}
+	return nil
+}
+
+// yencParts parses every block of text, ignoring the lines around them such
+// as mail headers.
+func yencParts(text []byte) ([]*yencPart, error) {
+	var parts []*yencPart
+	var cur *yencPart
+	for _, raw := range bytes.Split(text, []byte("\n")) {
+		line := bytes.TrimRight(raw, "\r")
+		switch {
+		case bytes.HasPrefix(line, []byte("=ybegin ")):
+			fields := yencFields(string(line))
+			size, err := yencInt(fields, "size")
+			if err != nil {
+				return nil, err
+			}
+			cur = &yencPart{name: fields["name"], size: size}
+			if fields["part"] != "" {
+				if cur.part, err = yencInt(fields, "part"); err != nil {
+					return nil, err
+				}
+			}
+		case cur == nil:
+			continue
+		case bytes.HasPrefix(line, []byte("=ypart ")):
+			fields := yencFields(string(line))
+			var err error
+			if cur.begin, err = yencInt(fields, "begin"); err != nil {
+				return nil, err
+			}
+			if cur.end, err = yencInt(fields, "end"); err != nil {
+				return nil, err
+			}
+		case bytes.HasPrefix(line, []byte("=yend")):
+			fields := yencFields(string(line))
+			size, err := yencInt(fields, "size")
+			if err != nil {
+				return nil, err
+			}
+			if size != len(cur.data) {
+				return nil, fmt.Errorf("yEnc part %d of %s has %d bytes, =yend says %d", cur.part, cur.name, len(cur.data), size)
+			}
+			if pcrc := fields["pcrc32"]; pcrc != "" {
+				if err := yencCRC(pcrc, cur.data); err != nil {
+					return nil, fmt.Errorf("part %d: %w", cur.part, err)
+				}
+			}
+			cur.crc = fields["crc32"]
+			parts = append(parts, cur)
+			cur = nil
+		default:
+			for i := 0; i < len(line); i++ {
+				b := line[i]
+				if b == '=' && i+1 < len(line) {
+					i++
+					b = line[i] - 64
+				}
+				cur.data = append(cur.data, b-42)
+			}
+		}
+	}
+	if cur != nil {
+		return nil, fmt.Errorf("yEnc file %s lacks its =yend line", cur.name)
+	}
+	if len(parts) == 0 {
+		return nil, errors.New("no =ybegin line found")
+	}
+	return parts, nil
+}
+
+// yencDecode decodes and verifies a yEnc file. The parts of a multi-part file
+// may come in any order and are put together by their byte ranges.
+func yencDecode(text []byte) ([]byte, error) {
+	parts, err := yencParts(text)
+	if err != nil {
+		return nil, err
+	}
+	first := parts[0]
+	for _, p := range parts[1:] {
+		if p.name != first.name {
+			return nil, fmt.Errorf("input holds more than one yEnc file (%s, %s)", first.name, p.name)
+		}
+	}
+	if first.part == 0 {
+		if len(parts) > 1 {
+			return nil, fmt.Errorf("yEnc file %s appears more than once", first.name)
+		}
+		if len(first.data) != first.size {
+			return nil, fmt.Errorf("yEnc file %s has %d bytes, =ybegin says %d", first.name, len(first.data), first.size)
+		}
+		if first.crc != "" {
+			if err := yencCRC(first.crc, first.data); err != nil {
+				return nil, err
+			}
+		}
+		return first.data, nil
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].begin < parts[j].begin })
+	out := make([]byte, 0, first.size)
+	var crc string
+	for _, p := range parts {
+		switch {
+		case p.begin == 0:
+			return nil, fmt.Errorf("yEnc part %d of %s lacks its =ypart line", p.part, first.name)
+		case p.begin <= len(out):
+			return nil, fmt.Errorf("yEnc part %d of %s overlaps an earlier part", p.part, first.name)
+		case p.begin > len(out)+1:
+			return nil, fmt.Errorf("yEnc file %s is missing bytes %d-%d", first.name, len(out)+1, p.begin-1)
+		}
+		if p.end-p.begin+1 != len(p.data) {
+			return nil, fmt.Errorf("yEnc part %d of %s has %d bytes, =ypart says %d", p.part, first.name, len(p.data), p.end-p.begin+1)
+		}
+		out = append(out, p.data...)
+		if p.crc != "" {
+			crc = p.crc
+		}
+	}
+	if len(out) != first.size {
+		return nil, fmt.Errorf("yEnc file %s has %d of %d bytes; parts are missing", first.name, len(out), first.size)
+	}
+	if crc != "" {
+		if err := yencCRC(crc, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// NewPluginYEnc creates a yEnc plugin.
+func NewPluginYEnc() *types.DeenPlugin {
+	p := types.NewPlugin()
+	p.Name = "yenc"
+	p.Aliases = []string{".yenc"}
+	p.Category = "codecs"
+	p.Description = "yEnc encoding as used for Usenet binaries. Decoding parses the\n=ybegin, =ypart and =yend lines, verifies sizes and CRC32 checksums and\nreassembles multi-part posts."
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.String("name", "data", "file name written to the =ybegin line")
+		flags.Int("line", 128, "encoded characters per line")
+	}
+	p.Options = []types.OptionSpec{
+		{Name: "name", Label: "File name", Description: "File name written to the =ybegin line."},
+		{Name: "line", Label: "Line length", Description: "Encoded characters per line.", Range: &types.OptionRange{Min: 16, Max: 997}},
+	}
+	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		name := helpers.StringFlag(flags, "name")
+		if name == "" || strings.ContainsAny(name, "\r\n") {
+			return errors.New("name must be a single non-empty line")
+		}
+		return yencEncode(w, data, name, helpers.IntFlag(flags, "line", 128))
+	}
+	p.Unprocess = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		decoded, err := yencDecode(data)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(decoded)
+		return err
+	}
+	p.Detect = func(data []byte) (types.Detection, bool) {
+		if !bytes.HasPrefix(data, []byte("=ybegin ")) && !bytes.Contains(data, []byte("\n=ybegin ")) {
+			return types.Detection{}, false
+		}
+		return types.Detection{
+			Label:      "Decode yEnc",
+			Reason:     "input has a =ybegin line",
+			Confidence: 95,
+		}, true
+	}
+	return p
+}
@@ -0,0 +1,74 @@
+package codecs
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestPluginYEnc(t *testing.T) {
+	p := NewPluginYEnc()
+	want := "=ybegin line=128 size=4 name=data\n\x8e\x8f\x8f\x98\n=yend size=4 crc32=699c653e\n"
+	assertCodec(t, p, p.Process, []byte("deen"), []byte(want))
+	assertCodec(t, p, p.Unprocess, []byte("Subject: deen\r\n\r\n"+strings.ReplaceAll(want, "\n", "\r\n")), []byte("deen"))
+
+	// Critical characters, a dot at the start of a line and a trailing space.
+	in := []byte{0xd6, 0xe0, 0xe3, 0x13, 0x04, 0xf6}
+	encoded := runCodec(t, p.Process, p.RegisterFlags, in, "-name", "my file.bin", "-line", "16")
+	if want := "=ybegin line=16 size=6 name=my file.bin\n=@=J=M=}.=`\n"; !strings.HasPrefix(string(encoded), want) {
+		t.Errorf("encoded = %q, want prefix %q", encoded, want)
+	}
+	assertCodec(t, p, p.Unprocess, encoded, in)
+	in = allBytes()
+	assertCodec(t, p, p.Unprocess, runCodec(t, p.Process, p.RegisterFlags, in, "-line", "16"), in)
+
+	for _, bad := range []string{
+		"no yEnc here",
+		"=ybegin line=128 size=4 name=data\n\x8e\x8f\x8f\x98\n",
+		"=ybegin line=128 size=4 name=data\n\x8e\x8f\x8f\x98\n=yend size=4 crc32=00000000\n",
+		"=ybegin line=128 size=5 name=data\n\x8e\x8f\x8f\x98\n=yend size=4\n",
+	} {
+		if _, err := tryCodec(p.Unprocess, p.RegisterFlags, []byte(bad)); err == nil {
+			t.Errorf("decoding %q succeeded", bad)
+		}
+	}
+}
+
+// yencMultiPart encodes data as yEnc parts split at the given offsets.
+func yencMultiPart(t *testing.T, data []byte, splits ...int) []string {
+	t.Helper()
+	bounds := append(append([]int{0}, splits...), len(data))
+	var parts []string
+	for i := 0; i+1 < len(bounds); i++ {
+		chunk := data[bounds[i]:bounds[i+1]]
+		var body bytes.Buffer
+		if err := yencEncode(&body, chunk, "x", 128); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(body.String(), "\n"), "\n")
+		parts = append(parts, fmt.Sprintf("=ybegin part=%d total=%d line=128 size=%d name=big.bin\n=ypart begin=%d end=%d\n%s\n=yend size=%d part=%d pcrc32=%08x crc32=%08x\n",
+			i+1, len(bounds)-1, len(data), bounds[i]+1, bounds[i+1], strings.Join(lines[1:len(lines)-1], "\n"), len(chunk), i+1, crc32.ChecksumIEEE(chunk), crc32.ChecksumIEEE(data)))
+	}
+	return parts
+}
+
+func TestPluginYEncMultiPart(t *testing.T) {
+	p := NewPluginYEnc()
+	data := append(allBytes(), []byte("multi-part yEnc")...)
+	parts := yencMultiPart(t, data, 100, 200)
+	input := parts[2] + "\nFrom: poster\n\n" + parts[0] + parts[1]
+	assertCodec(t, p, p.Unprocess, []byte(input), data)
+
+	for name, input := range map[string]string{
+		"missing part":   parts[0] + parts[2],
+		"duplicate part": parts[0] + parts[1] + parts[1] + parts[2],
+		"bad part crc":   strings.Replace(parts[0], "pcrc32=", "pcrc32=1", 1) + parts[1] + parts[2],
+		"other file":     parts[0] + strings.Replace(parts[1], "name=big.bin", "name=other.bin", 1) + parts[2],
+	} {
+		if _, err := tryCodec(p.Unprocess, p.RegisterFlags, []byte(input)); err == nil {
+			t.Errorf("%s: decoding succeeded", name)
+		}
+	}
+}
@@ -22,6 +22,10 @@ func NewPluginBrotli() *types.DeenPlugin {
 		flags.Int("level", brotli.DefaultCompression, "compression level (0-11)")
 		flags.Int("lgwin", 0, "sliding window size (0-24)")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "level", Description: "Compression level.", Range: &types.OptionRange{Min: 0, Max: 11}},
+		{Name: "lgwin", Label: "Window size", Description: "Brotli sliding window size.", Range: &types.OptionRange{Min: 0, Max: 24}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		level := helpers.IntFlag(flags, "level", brotli.DefaultCompression)
 		if level < 0 || level > 11 {
@@ -20,6 +20,9 @@ func NewPluginBzip2() *types.DeenPlugin {
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.Int("level", bzip2.DefaultCompression, "compression level from 1 (best speed) to 9 (best compression)")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "level", Description: "Compression level.", Range: &types.OptionRange{Min: bzip2.BestSpeed, Max: bzip2.BestCompression}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		level := helpers.IntFlag(flags, "level", bzip2.DefaultCompression)
 		if level < bzip2.BestSpeed || level > bzip2.BestCompression {
@@ -19,6 +19,9 @@ func NewPluginFlate() *types.DeenPlugin {
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.Int("level", flate.DefaultCompression, "compression level (-1 default, 0 none, 1 best speed, 9 best compression)")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "level", Description: "Compression level.", Range: &types.OptionRange{Min: flate.HuffmanOnly, Max: flate.BestCompression}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		level := helpers.IntFlag(flags, "level", flate.DefaultCompression)
 		return compressStream(r, w, func(w io.Writer) (io.WriteCloser, error) {
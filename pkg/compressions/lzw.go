@@ -20,6 +20,10 @@ func NewPluginLzw() *types.DeenPlugin {
 		flags.Int("order", int(lzw.LSB), "0 = LSB (GIF), 1 = MSB (TIFF & PDF)")
 		flags.Int("lit-width", 8, "number of bits per code, 2-8")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "lit-width", Label: "Literal width", Description: "Number of bits used for literal codes.", Choices: []string{"2", "3", "4", "5", "6", "7", "8"}},
+		{Name: "order", Description: "Bit order for LZW data.", Choices: []string{"0", "1"}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		order := lzw.Order(helpers.IntFlag(flags, "order", int(lzw.LSB)))
 		width := helpers.IntFlag(flags, "lit-width", 8)
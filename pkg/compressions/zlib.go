@@ -19,6 +19,9 @@ func NewPluginZlib() *types.DeenPlugin {
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.Int("level", zlib.DefaultCompression, "compression level from 1 (best speed) to 9 (best compression)")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "level", Description: "Compression level.", Range: &types.OptionRange{Min: zlib.HuffmanOnly, Max: zlib.BestCompression}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		level := helpers.IntFlag(flags, "level", zlib.DefaultCompression)
 		return compressStream(r, w, func(w io.Writer) (io.WriteCloser, error) {
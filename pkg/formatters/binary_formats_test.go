@@ -15,6 +15,25 @@ func TestMessagePackRoundTrip(t *testing.T) {
 	}
 }
 
+func TestMessagePackRejectsOversizedLengths(t *testing.T) {
+	p := NewPluginMessagePack()
+	for _, input := range [][]byte{
+		{0xdd, 0x7f, 0xff, 0xff, 0xff, 0x01},     // array32 of 2^31-1 items
+		{0x81, 0xdf, 0xff, 0xff, 0xff, 0xff},     // map32 nested in a fixmap
+		{0xdb, 0x00, 0x00, 0x10, 0x00, 'a', 'b'}, // str32 longer than the input
+		{0x92, 0x01},                             // fixarray missing an item
+	} {
+		var out bytes.Buffer
+		if err := p.Unprocess(bytes.NewReader(input), &out, nil); err == nil {
+			t.Fatalf("Unprocess(%x) succeeded", input)
+		}
+	}
+	valid := runFormat(t, p.Process, p.RegisterFlags, []byte(`{"a":[1,"x",true,null,1.5,-70000,{"b":"c"}]}`))
+	if err := checkMsgpackLengths(valid); err != nil {
+		t.Fatalf("checkMsgpackLengths(valid) = %v", err)
+	}
+}
+
 func TestCBORRoundTrip(t *testing.T) {
 	p := NewPluginCBOR()
 	packed := runFormat(t, p.Process, p.RegisterFlags, []byte(`{"name":"deen","ok":true}`))
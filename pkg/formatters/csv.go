@@ -64,6 +64,10 @@ func NewPluginCSV() *types.DeenPlugin {
 		flags.String("in", "csv", "input delimiter: csv, tsv, semicolon or a single character")
 		flags.String("out", "table", "output format: table, csv, tsv, semicolon or a single character")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "in", Label: "Input format", Description: "Input delimiter or format: csv, tsv, semicolon or a single character.", Choices: []string{"csv", "tsv", "semicolon"}, Open: true},
+		{Name: "out", Label: "Output format", Description: "Output delimiter or format: table, csv, tsv, semicolon or a single character.", Choices: []string{"table", "csv", "tsv", "semicolon"}, Open: true},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		inDelim, err := delimiter(helpers.StringFlag(flags, "in"))
 		if err != nil {
@@ -23,6 +23,11 @@ func NewPluginJQFormatter() *types.DeenPlugin {
 		flags.Bool("no-color", false, "omit colors in formatted output")
 		flags.Bool("plain", false, "print unformatted token")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "no-color", Label: "No color", Description: "Disable ANSI color in formatted output."},
+		{Name: "plain", Description: "Print compact JSON instead of formatted output."},
+		{Name: "q", Label: "Query", Description: "jq filter expression to run against the JSON input.", Multiline: true, HelpLabel: "jq syntax", HelpURL: "https://jqlang.github.io/jq/manual/"},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		queryStr := helpers.StringFlag(flags, "q")
 		if queryStr == "" {
@@ -23,6 +23,9 @@ func NewPluginJSONFormatter() *types.DeenPlugin {
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.Bool("no-color", false, "omit colors in output")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "no-color", Label: "No color", Description: "Disable ANSI color in formatted output."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		var data interface{}
 		if err := json.NewDecoder(r).Decode(&data); err != nil {
@@ -24,6 +24,10 @@ func NewPluginJWK() *types.DeenPlugin {
 		flags.Bool("public", false, "emit public keys when possible")
 		flags.Bool("thumbprint", false, "emit RFC 7638 SHA-256 thumbprints instead of keys")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "public", Description: "Output public JWK material when possible."},
+		{Name: "thumbprint", Description: "Output RFC 7638 SHA-256 thumbprints."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		data, err := io.ReadAll(r)
 		if err != nil {
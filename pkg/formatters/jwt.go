@@ -408,6 +408,7 @@ func NewPluginJwt() *types.DeenPlugin {
 	p.Aliases = []string{".jwt"}
 	p.Category = "formatters"
 	p.Description = "Encode and decode JSON Web Tokens (JWT) (RFC 7519)."
+	p.NonDeterministic = true
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		// Encoding flags.
 		flags.Bool("list", false, "list supported algorithms")
@@ -426,6 +427,22 @@ func NewPluginJwt() *types.DeenPlugin {
 		flags.String("key", "", "key file")
 		flags.Bool("decrypt", false, "decrypt JWE token")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "decrypt", Label: "Decrypt JWE", Description: "Decrypt a JWE token."},
+		{Name: "enc-alg", Label: "Encryption algorithm", Description: "Content encryption algorithm for creating JWE tokens.", Choices: []string{"", "A128CBC-HS256", "A192CBC-HS384", "A256CBC-HS512", "A128GCM", "A192GCM", "A256GCM"}},
+		{Name: "enc-keyfile", Label: "Encryption key file", Description: "Encryption key file for creating JWE tokens.", Secret: true},
+		{Name: "enc-secret", Label: "Encryption secret", Description: "Encryption secret for creating JWE tokens.", Secret: true},
+		{Name: "header", Label: "Token header", Description: "JSON header to use when creating a token.", Multiline: true},
+		{Name: "key", Label: "Verification key file", Description: "Public key file used when verifying a token."},
+		{Name: "key-alg", Label: "Key management algorithm", Description: "Key management algorithm for creating JWE tokens.", Choices: []string{"", "dir", "RSA1_5", "RSA-OAEP", "RSA-OAEP-256", "A128KW", "A192KW", "A256KW", "ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A192KW", "ECDH-ES+A256KW", "A128GCMKW", "A192GCMKW", "A256GCMKW", "PBES2-HS256+A128KW", "PBES2-HS384+A192KW", "PBES2-HS512+A256KW"}, Secret: true},
+		{Name: "list", Label: "List algorithms", Description: "Show supported JWT algorithms."},
+		{Name: "r", Label: "Recreate token, keep signature", Description: "Recreate the token with modified JSON while keeping the original signature."},
+		{Name: "secret", Label: "Verification secret", Description: "Secret used when verifying a token.", Secret: true},
+		{Name: "sign-alg", Label: "Signing algorithm", Description: "Signature algorithm for creating JWS tokens.", Choices: []string{"", "EdDSA", "HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "PS384", "PS512"}},
+		{Name: "sign-keyfile", Label: "Signing key file", Description: "Private key file used to sign a token.", Secret: true},
+		{Name: "sign-secret", Label: "Signing secret", Description: "Shared secret used to sign a token.", Secret: true},
+		{Name: "verify", Label: "Verify signature", Description: "Verify the token signature while decoding."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		if helpers.IsBoolFlag(flags, "list") {
 			_, err := io.WriteString(w, listAlgs())
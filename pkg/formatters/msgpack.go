@@ -1,8 +1,11 @@
 package formatters
 
 import (
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"io"
 
 	"github.com/takeshixx/deen/pkg/types"
@@ -33,6 +36,9 @@ func NewPluginMessagePack() *types.DeenPlugin {
 		if err != nil {
 			return err
 		}
+		if err := checkMsgpackLengths(input); err != nil {
+			return err
+		}
 		var data interface{}
 		if err := msgpack.Unmarshal(input, &data); err != nil {
 			return err
@@ -43,3 +49,86 @@ func NewPluginMessagePack() *types.DeenPlugin {
 	}
 	return p
 }
+
+var errMsgpackTruncated = errors.New("msgpack: input is shorter than its declared lengths")
+
+// checkMsgpackLengths walks the MessagePack value at the start of data and
+// rejects lengths that exceed the remaining input. The decoder allocates
+// arrays and maps at their declared size, so a few corrupt bytes could
+// otherwise request gigabytes.
+func checkMsgpackLengths(data []byte) error {
+	// length reads a big-endian length of size bytes at data[i:].
+	length := func(i, size int) (int, bool) {
+		if i+size > len(data) {
+			return 0, false
+		}
+		switch size {
+		case 1:
+			return int(data[i]), true
+		case 2:
+			return int(binary.BigEndian.Uint16(data[i:])), true
+		default:
+			return int(binary.BigEndian.Uint32(data[i:])), true
+		}
+	}
+	pending := 1
+	for i := 0; pending > 0; pending-- {
+		if i >= len(data) {
+			return errMsgpackTruncated
+		}
+		c := data[i]
+		i++
+		var items, skip int
+		switch {
+		case c <= 0x7f || c >= 0xe0 || c == 0xc0 || c == 0xc2 || c == 0xc3:
+		case c <= 0x8f:
+			items = 2 * int(c&0x0f)
+		case c <= 0x9f:
+			items = int(c & 0x0f)
+		case c <= 0xbf:
+			skip = int(c & 0x1f)
+		case c == 0xc4 || c == 0xd9:
+			skip, _ = length(i, 1)
+			i++
+		case c == 0xc5 || c == 0xda:
+			skip, _ = length(i, 2)
+			i += 2
+		case c == 0xc6 || c == 0xdb:
+			skip, _ = length(i, 4)
+			i += 4
+		case c >= 0xc7 && c <= 0xc9:
+			size := 1 << (c - 0xc7)
+			skip, _ = length(i, size)
+			i += size
+			skip++ // ext type
+		case c == 0xca:
+			skip = 4
+		case c == 0xcb:
+			skip = 8
+		case c >= 0xcc && c <= 0xcf:
+			skip = 1 << (c - 0xcc)
+		case c >= 0xd0 && c <= 0xd3:
+			skip = 1 << (c - 0xd0)
+		case c >= 0xd4 && c <= 0xd8:
+			skip = 1 + 1<<(c-0xd4)
+		case c == 0xdc || c == 0xdd:
+			size := 2 << (c - 0xdc)
+			items, _ = length(i, size)
+			i += size
+		case c == 0xde || c == 0xdf:
+			size := 2 << (c - 0xde)
+			items, _ = length(i, size)
+			items *= 2
+			i += size
+		default:
+			return fmt.Errorf("msgpack: invalid code %#x", c)
+		}
+		i += skip
+		// Every pending item takes at least one byte.
+		if i > len(data) || items > len(data)-i {
+			return errMsgpackTruncated
+		}
+		pending += items
+	}
+	return nil
+}
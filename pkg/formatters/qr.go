@@ -25,6 +25,9 @@ func NewPluginQR() *types.DeenPlugin {
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.Int("size", 256, "QR image size in pixels")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "size", Label: "Image size", Description: "Generated QR image size in pixels.", Range: &types.OptionRange{Min: 1, Max: 4096}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		input, err := io.ReadAll(r)
 		if err != nil {
@@ -26,6 +26,11 @@ func NewPluginSAML() *types.DeenPlugin {
 		flags.Bool("plain", false, "do not try DEFLATE decompression when decoding")
 		flags.Bool("url", false, "URL-escape encoded output; URL-unescape input before decoding")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "deflate", Description: "Use raw DEFLATE compression for SAML redirect payloads."},
+		{Name: "plain", Description: "Skip DEFLATE detection when decoding."},
+		{Name: "url", Label: "URL encoding", Description: "URL-escape encoded output or URL-unescape input."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		input, err := io.ReadAll(r)
 		if err != nil {
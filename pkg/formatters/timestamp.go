@@ -26,6 +26,11 @@ func NewPluginTimestamp() *types.DeenPlugin {
 		flags.String("layout", defaultTimestampLayout, "Go time layout for formatted output or parsing")
 		flags.Bool("utc", true, "format parsed times in UTC")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "layout", Description: "Go time layout for formatting or parsing time strings."},
+		{Name: "unit", Description: "Timestamp unit.", Choices: []string{"auto", "s", "ms", "us", "ns"}},
+		{Name: "utc", Label: "UTC output", Description: "Format parsed times in UTC."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		input, err := readTimestampInput(r)
 		if err != nil {
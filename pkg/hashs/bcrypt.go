@@ -15,9 +15,13 @@ func NewPluginBcrypt() *types.DeenPlugin {
 	p.Name = "bcrypt"
 	p.Category = "hashs"
 	p.Description = "bcrypt password hashing."
+	p.NonDeterministic = true
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.Int("cost", bcrypt.DefaultCost, "calculation cost")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "cost", Description: "bcrypt work factor.", Range: &types.OptionRange{Min: bcrypt.MinCost, Max: bcrypt.MaxCost}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		cost := helpers.IntFlag(flags, "cost", bcrypt.DefaultCost)
 		input, err := io.ReadAll(r)
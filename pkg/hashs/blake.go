@@ -54,6 +54,10 @@ func NewPluginBLAKE2x() *types.DeenPlugin {
 		flags.String("key", "", "MAC key")
 		flags.Int("len", 32, "length of the output hash in bytes, must be between 1 and 65535")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "key", Description: "Key for MAC or keyed hashing.", Secret: true},
+		{Name: "len", Description: "Number of digest bytes to output.", Range: &types.OptionRange{Min: 1, Max: 65535}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		length := helpers.IntFlag(flags, "len", 32)
 		if length < 1 || length > 65535 {
@@ -95,6 +99,10 @@ func NewPluginBLAKE2s() *types.DeenPlugin {
 		flags.String("key", "", "MAC key")
 		flags.Int("len", 32, "length of the output hash in bytes, must be either 16 or 32")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "key", Description: "Key for MAC or keyed hashing.", Secret: true},
+		{Name: "len", Description: "Number of digest bytes to output.", Choices: []string{"32", "16"}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		length := helpers.IntFlag(flags, "len", 32)
 		if length != 16 && length != 32 {
@@ -128,6 +136,10 @@ func NewPluginBLAKE2b() *types.DeenPlugin {
 		flags.String("key", "", "MAC key")
 		flags.Int("len", 64, "length of the output hash in bytes, must be between 1 and 64")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "key", Description: "Key for MAC or keyed hashing.", Secret: true},
+		{Name: "len", Description: "Number of digest bytes to output.", Range: &types.OptionRange{Min: 1, Max: 64}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		length := helpers.IntFlag(flags, "len", 64)
 		if length < 1 || length > 64 {
@@ -169,6 +181,12 @@ func NewPluginBLAKE3() *types.DeenPlugin {
 		flags.String("derive-key", "", "derive key")
 		flags.String("context", "", "context for key derivation")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "context", Description: "Context string for BLAKE3 key derivation."},
+		{Name: "derive-key", Label: "Derive key", Description: "Key material for BLAKE3 key derivation.", Secret: true},
+		{Name: "key", Description: "Key for MAC or keyed hashing.", Secret: true},
+		{Name: "length", Description: "Number of digest bytes to output."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		outLen := helpers.IntFlag(flags, "length", 32)
 		if outLen != 32 && outLen != 64 {
@@ -50,6 +50,10 @@ func NewPluginHMAC() *types.DeenPlugin {
 		flags.String("alg", "sha256", "hash algorithm ("+hmacAlgNames()+")")
 		flags.String("key", "", "secret key")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "alg", Description: "Hash algorithm for HMAC.", Choices: []string{"md5", "sha1", "sha224", "sha256", "sha384", "sha512", "sha3-256", "sha3-512"}},
+		{Name: "key", Description: "Key for MAC or keyed hashing.", Secret: true},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		alg := helpers.StringFlag(flags, "alg")
 		newHash, ok := hmacHashes[alg]
@@ -11,6 +11,10 @@ import (
 	"golang.org/x/crypto/scrypt"
 )
 
+// scryptMaxCost bounds the cost parameter. scrypt needs 128*r*cost bytes of
+// memory, 1 GiB at this cost and the default block size.
+const scryptMaxCost = 1 << 20
+
 // NewPluginScrypt creates a new plugin
 func NewPluginScrypt() *types.DeenPlugin {
 	p := types.NewPlugin()
@@ -24,7 +28,19 @@ func NewPluginScrypt() *types.DeenPlugin {
 		flags.Int("r", 8, "parallelization parameter")
 		flags.Int("p", 1, "blocksize parameter")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "cost", Description: "CPU and memory cost parameter, a power of two.", Range: &types.OptionRange{Min: 2, Max: scryptMaxCost}},
+		{Name: "len", Label: "Output length", Description: "Number of key bytes to output."},
+		{Name: "p", Label: "Parallelization", Description: "scrypt parallelization parameter."},
+		{Name: "r", Label: "Block size", Description: "scrypt block size parameter."},
+		{Name: "salt", Description: "Salt as a hex string."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+		// Callers of Process need not have validated the flags, and an
+		// unchecked cost can exhaust memory.
+		if err := p.ValidateFlags(flags); err != nil {
+			return err
+		}
 		cost := helpers.IntFlag(flags, "cost", 1<<15)
 		length := helpers.IntFlag(flags, "len", 32)
 		rParam := helpers.IntFlag(flags, "r", 8)
@@ -15,3 +15,11 @@ func TestPluginScrypt(t *testing.T) {
 		"CLf5VMJVqyLztPE4cK1fpoRbOwQDUSgYm4VWfxgdMvpH6dBbaJ2rD0+hRhC6vcbEaL0/XHQSJTFYifshfoIRh+B2RRhZKqeTpXqP+4jxhiuMVa1lgInQMlAflOQfSCaq",
 		"-cost", fmt.Sprintf("%d", 1<<12), "-len", "96", "-salt", scryptTestSaltHex)
 }
+
+func TestPluginScryptCostRange(t *testing.T) {
+	for _, cost := range []string{"1", fmt.Sprintf("%d", scryptMaxCost*2)} {
+		if _, err := tryHash(NewPluginScrypt(), scryptTestData, "-cost", cost); err == nil {
+			t.Errorf("cost %s: expected an error", cost)
+		}
+	}
+}
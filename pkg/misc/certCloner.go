@@ -164,12 +164,19 @@ func NewPluginCertCloner() *types.DeenPlugin {
 	p.Aliases = []string{"clone"}
 	p.Category = "misc"
 	p.Description = "Clone an x509 certificate with a freshly generated key of the\nsame type. Self-signs by default, or signs with a given CA."
+	p.NonDeterministic = true
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.String("ca-cert", "", "CA certificate (PEM); may also contain the CA key")
 		flags.String("ca-key", "", "CA private key (PEM), if not in -ca-cert")
 		flags.String("sig-alg", "", "signature hash algorithm (sha256/sha384/sha512); default inherits the original")
 		flags.String("o", "", "also write <prefix>.crt, <prefix>.key and <prefix>.pem files")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "ca-cert", Label: "CA certificate file", Description: "Path to a PEM CA certificate file. The file may also contain the CA private key."},
+		{Name: "ca-key", Label: "CA private key file", Description: "Path to a PEM CA private key file, if it is not bundled with the CA certificate.", Secret: true},
+		{Name: "o", Label: "Output prefix", Description: "Write cloned certificate files using this path prefix."},
+		{Name: "sig-alg", Label: "Signature algorithm", Description: "Signature hash for the cloned certificate.", Choices: []string{"", "sha256", "sha384", "sha512"}},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		input, err := io.ReadAll(r)
 		if err != nil {
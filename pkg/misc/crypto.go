@@ -119,6 +119,16 @@ func NewPluginAES() *types.DeenPlugin {
 		flags.Bool("skip-aead-verify", false, "decrypt GCM ciphertext without verifying the authentication tag")
 		flags.String("padding", "pkcs7", "CBC padding: pkcs7 or none")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "aad", Description: "Additional authenticated data required for GCM or AEAD verification."},
+		{Name: "iv", Label: "Nonce / IV", Description: "Nonce or initialization vector as hex or Base64. GCM expects 12 bytes; CBC and CTR expect 16 bytes."},
+		{Name: "nonce", Description: "Alias for nonce or initialization vector as hex or Base64.", Hidden: true},
+		{Name: "key", Description: "AES key as hex or Base64. Must be 16, 24, or 32 bytes.", Secret: true},
+		{Name: "mode", Description: "AES mode to use: GCM, CBC, or CTR.", Choices: []string{"gcm", "cbc", "ctr"}},
+		{Name: "padding", Description: "CBC padding mode: PKCS#7 compatible padding or no padding for block-aligned data.", Choices: []string{"pkcs7", "none"}},
+		{Name: "skip-aead-verify", Label: "Skip AEAD verify", Description: "Dangerous GCM decrypt mode: output unauthenticated plaintext even when tag verification fails."},
+		{Name: "tag-len", Description: "GCM authentication tag length in bytes. Must be between 12 and 16.", Choices: []string{"16", "15", "14", "13", "12"}},
+	}
 	p.Process = aesTransform(false)
 	p.Unprocess = aesTransform(true)
 	return p
@@ -260,6 +270,11 @@ func NewPluginChaCha20Poly1305() *types.DeenPlugin {
 		flags.String("nonce", "", "hex/base64 12-byte nonce")
 		flags.String("aad", "", "additional authenticated data")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "aad", Description: "Additional authenticated data required for GCM or AEAD verification."},
+		{Name: "key", Description: "ChaCha20-Poly1305 key as hex or Base64. Must be 32 bytes.", Secret: true},
+		{Name: "nonce", Description: "ChaCha20-Poly1305 nonce as hex or Base64. Must be 12 bytes."},
+	}
 	p.Process = chachaTransform(false)
 	p.Unprocess = chachaTransform(true)
 	return p
@@ -310,12 +325,19 @@ func NewPluginSign() *types.DeenPlugin {
 	p.Aliases = []string{"verify"}
 	p.Category = "misc"
 	p.Description = "Sign input; decode mode verifies signatures. Supports ed25519, rsa-pss and ecdsa."
+	p.NonDeterministic = true
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.String("alg", "ed25519", "algorithm: ed25519, rsa-pss or ecdsa")
 		flags.String("key", "", "private key: PEM path or hex/base64 Ed25519 private key/seed")
 		flags.String("pub", "", "public key: PEM path or hex/base64 Ed25519 public key")
 		flags.String("sig", "", "signature as hex/base64 or file path when verifying")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "alg", Description: "Signature algorithm.", Choices: []string{"ed25519", "rsa-pss", "ecdsa"}},
+		{Name: "key", Description: "Private key for signing. Accepts PEM path or hex/Base64 Ed25519 key material.", Secret: true},
+		{Name: "pub", Label: "Public key", Description: "Public key for verification. Accepts PEM path or hex/Base64 Ed25519 key material."},
+		{Name: "sig", Description: "Signature to verify, as hex, Base64, or a file path."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		input, err := io.ReadAll(r)
 		if err != nil {
@@ -23,6 +23,12 @@ func NewPluginRegex() *types.DeenPlugin {
 		flags.Int("group", 0, "capture group to extract")
 		flags.Bool("all", true, "extract all matches")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "all", Description: "Return all matches instead of the first match."},
+		{Name: "group", Description: "Capture group to extract."},
+		{Name: "re", Label: "Regular expression", Description: "Regular expression to match."},
+		{Name: "replace", Description: "Replacement text. When set, matching text is replaced."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		pattern := helpers.StringFlag(flags, "re")
 		if pattern == "" {
@@ -63,10 +63,15 @@ func NewPluginUUID() *types.DeenPlugin {
 	p.Aliases = []string{"guid"}
 	p.Category = "misc"
 	p.Description = "Generate UUID v4 values, format raw UUID bytes and inspect UUID text."
+	p.NonDeterministic = true
 	p.RegisterFlags = func(flags *flag.FlagSet) {
 		flags.Bool("gen", false, "generate a random UUID v4")
 		flags.Bool("info", false, "print UUID version and variant")
 	}
+	p.Options = []types.OptionSpec{
+		{Name: "gen", Label: "Generate UUID", Description: "Generate a random UUID v4."},
+		{Name: "info", Description: "Show UUID version, variant, and bytes."},
+	}
 	p.Process = func(r io.Reader, w io.Writer, flags *flag.FlagSet) error {
 		input, err := io.ReadAll(r)
 		if err != nil {
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OptionType is the value type of a plugin option.
+type OptionType string
+
+// Option value types. Select options carry their allowed values in Choices.
+const (
+	OptionText   OptionType = "text"
+	OptionNumber OptionType = "number"
+	OptionBool   OptionType = "bool"
+	OptionSelect OptionType = "select"
+)
+
+// OptionRange is an inclusive range for number options.
+type OptionRange struct {
+	Min int
+	Max int
+}
+
+// OptionSpec declares how a flag registered by RegisterFlags is presented and
+// validated. Flags without a spec fall back to their name, usage string and
+// flag type, so a spec only needs the fields that differ.
+type OptionSpec struct {
+	Name        string     // flag name as registered by RegisterFlags
+	Label       string     // short UI label; derived from Name when empty
+	Description string     // UI and help text; the flag usage when empty
+	Type        OptionType // inferred from the flag when empty
+	Choices     []string   // allowed values; implies OptionSelect unless Open
+	Range       *OptionRange
+	Secret      bool // mask the value in UIs and never echo it
+	Multiline   bool // render a multi-line editor
+	Hidden      bool // accepted, but not offered in UIs (e.g. legacy aliases)
+	HelpLabel   string
+	HelpURL     string
+
+	// ChoiceAliases are further values the plugin accepts besides Choices,
+	// e.g. alternative spellings. They pass Check but are not offered in UIs.
+	ChoiceAliases []string
+	// Open marks Choices as suggestions for an open-ended option such as a
+	// delimiter: any value passes Check.
+	Open bool
+
+	// Validate performs additional checks on a value. It may be nil.
+	Validate func(value string) error
+}
+
+// OptionSpec returns the declared spec of the named option.
+func (p *DeenPlugin) OptionSpec(name string) (OptionSpec, bool) {
+	for _, spec := range p.Options {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return OptionSpec{}, false
+}
+
+// Check reports whether value is acceptable for the option. Choices and their
+// aliases match regardless of case, since plugins fold the case of the values
+// they compare.
+func (o OptionSpec) Check(value string) error {
+	if len(o.Choices) > 0 && !o.Open {
+		found := false
+		for _, choice := range append(o.Choices[:len(o.Choices):len(o.Choices)], o.ChoiceAliases...) {
+			if strings.EqualFold(value, choice) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("must be one of %s", strings.Join(o.Choices, ", "))
+		}
+	}
+	if o.Type == OptionNumber || o.Range != nil {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		if o.Range != nil && (n < o.Range.Min || n > o.Range.Max) {
+			return fmt.Errorf("must be between %d and %d", o.Range.Min, o.Range.Max)
+		}
+	}
+	if o.Validate != nil {
+		return o.Validate(value)
+	}
+	return nil
+}
+
+// ValidateFlags checks every option that was set on flags against the
+// plugin's option specs and returns the first violation.
+func (p *DeenPlugin) ValidateFlags(flags *flag.FlagSet) error {
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		spec, ok := p.OptionSpec(f.Name)
+		if !ok {
+			return
+		}
+		value := f.Value.String()
+		if e := spec.Check(value); e != nil {
+			if spec.Secret {
+				err = fmt.Errorf("invalid value for -%s: %w", f.Name, e)
+				return
+			}
+			err = fmt.Errorf("invalid value %q for -%s: %w", value, f.Name, e)
+		}
+	})
+	return err
+}
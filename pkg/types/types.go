@@ -1,6 +1,7 @@
 package types
 
 import (
+	"context"
 	"flag"
 	"io"
 )
@@ -10,6 +11,11 @@ import (
 // Implementations must return (not continue) on the first error.
 type TransformFunc func(r io.Reader, w io.Writer, flags *flag.FlagSet) error
 
+// ContextTransformFunc is a TransformFunc that also receives the context of
+// the run. Transforms that start work outside the process, such as external
+// plugins, use it to stop that work once the run is canceled or times out.
+type ContextTransformFunc func(ctx context.Context, r io.Reader, w io.Writer, flags *flag.FlagSet) error
+
 // DeenPlugin describes a single encode/decode/hash/format operation.
 type DeenPlugin struct {
 	Name        string
@@ -19,11 +25,30 @@ type DeenPlugin struct {
 
 	// RegisterFlags registers plugin-specific CLI flags. It may be nil.
 	RegisterFlags func(*flag.FlagSet)
+	// Options describes the flags registered by RegisterFlags: labels, value
+	// types, choices, ranges and validation. It drives help output, the GUI
+	// and web UI option editors and MCP schemas, and values are checked
+	// against it before a transform runs. It may be nil.
+	Options []OptionSpec
 	// Process performs the forward operation (encode/compress/hash/format).
 	Process TransformFunc
 	// Unprocess performs the reverse operation (decode/decompress). A nil
 	// value means the plugin is one-way (e.g. hashes).
 	Unprocess TransformFunc
+	// ProcessContext and UnprocessContext, when set, are called instead of
+	// Process and Unprocess by runs that can be canceled, such as pipeline
+	// steps. Plugins setting them still set Process and Unprocess for
+	// callers without a context. They may be nil.
+	ProcessContext   ContextTransformFunc
+	UnprocessContext ContextTransformFunc
+	// Detect reports whether data looks like the output of Process, so
+	// suggestion lists can propose decoding it. It must be cheap and free of
+	// side effects. It may be nil.
+	Detect func(data []byte) (Detection, bool)
+	// NonDeterministic marks plugins whose output depends on more than the
+	// input and options, e.g. randomness, the clock or files read from
+	// disk. Pipelines never cache or reuse their results.
+	NonDeterministic bool
 
 	// Command is the command (alias) with which the plugin was invoked, with
 	// any leading "." stripped. Plugins that expose several aliases (e.g. the
@@ -31,6 +56,34 @@ type DeenPlugin struct {
 	Command string
 }
 
+// Detection is a plugin's guess that some data is its encoded form.
+type Detection struct {
+	Options    map[string]string // decode options, e.g. an alphabet; may be nil
+	Label      string            // short UI label such as "Decode Base58"
+	Reason     string            // why the data matched
+	Confidence int               // 0-100, comparable to pipeline suggestions
+}
+
+// Transform returns the forward transform, or the reverse one when unprocess
+// is set, taking a context: ProcessContext or UnprocessContext when set,
+// otherwise Process or Unprocess, which ignore it. It returns nil when the
+// plugin lacks that direction.
+func (p *DeenPlugin) Transform(unprocess bool) ContextTransformFunc {
+	fn, ctxFn := p.Process, p.ProcessContext
+	if unprocess {
+		fn, ctxFn = p.Unprocess, p.UnprocessContext
+	}
+	switch {
+	case ctxFn != nil:
+		return ctxFn
+	case fn != nil:
+		return func(_ context.Context, r io.Reader, w io.Writer, flags *flag.FlagSet) error {
+			return fn(r, w, flags)
+		}
+	}
+	return nil
+}
+
 // NewPlugin creates an empty plugin skeleton.
 func NewPlugin() *DeenPlugin {
 	return &DeenPlugin{}
@@ -1,7 +1,12 @@
 package types
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -11,3 +16,94 @@ func TestNewPlugin(t *testing.T) {
 		t.Errorf("Invalid return type for NewPlugin: %s", reflect.TypeOf(p))
 	}
 }
+
+func TestOptionSpecCheck(t *testing.T) {
+	tests := []struct {
+		spec  OptionSpec
+		value string
+		want  string
+	}{
+		{OptionSpec{Choices: []string{"gcm", "cbc"}}, "cbc", ""},
+		{OptionSpec{Choices: []string{"gcm", "cbc"}}, "ecb", "must be one of gcm, cbc"},
+		{OptionSpec{Choices: []string{"gcm", "cbc"}}, "GCM", ""},
+		{OptionSpec{Choices: []string{"utf8"}, ChoiceAliases: []string{"utf-8"}}, "UTF-8", ""},
+		{OptionSpec{Choices: []string{"csv", "tsv"}, Open: true}, ";", ""},
+		{OptionSpec{Range: &OptionRange{Min: 1, Max: 9}}, "9", ""},
+		{OptionSpec{Range: &OptionRange{Min: 1, Max: 9}}, "10", "must be between 1 and 9"},
+		{OptionSpec{Type: OptionNumber}, "x", "must be a number"},
+		{OptionSpec{Validate: func(string) error { return errors.New("nope") }}, "a", "nope"},
+		{OptionSpec{}, "anything", ""},
+	}
+	for _, tt := range tests {
+		err := tt.spec.Check(tt.value)
+		if tt.want == "" && err != nil {
+			t.Errorf("Check(%q) = %v, want nil", tt.value, err)
+		}
+		if tt.want != "" && (err == nil || err.Error() != tt.want) {
+			t.Errorf("Check(%q) = %v, want %q", tt.value, err, tt.want)
+		}
+	}
+}
+
+func TestValidateFlags(t *testing.T) {
+	p := NewPlugin()
+	p.RegisterFlags = func(flags *flag.FlagSet) {
+		flags.String("mode", "a", "mode")
+		flags.String("key", "", "key")
+	}
+	p.Options = []OptionSpec{
+		{Name: "mode", Choices: []string{"a", "b"}},
+		{Name: "key", Secret: true, Validate: func(v string) error {
+			if len(v) != 4 {
+				return errors.New("must be 4 characters")
+			}
+			return nil
+		}},
+	}
+	newFlags := func(args ...string) *flag.FlagSet {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		p.RegisterFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	}
+
+	if err := p.ValidateFlags(newFlags("-mode", "b", "-key", "abcd")); err != nil {
+		t.Fatalf("valid flags rejected: %v", err)
+	}
+	err := p.ValidateFlags(newFlags("-mode", "c"))
+	if err == nil || err.Error() != `invalid value "c" for -mode: must be one of a, b` {
+		t.Fatalf("invalid mode error = %v", err)
+	}
+	err = p.ValidateFlags(newFlags("-key", "hunter2"))
+	if err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Fatalf("secret error = %v, want error without the value", err)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	p := NewPlugin()
+	p.Process = func(r io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		_, err := io.WriteString(w, "plain")
+		return err
+	}
+	if p.Transform(true) != nil {
+		t.Fatal("Transform(true) of a one-way plugin is not nil")
+	}
+	var out strings.Builder
+	if err := p.Transform(false)(context.Background(), strings.NewReader(""), &out, nil); err != nil || out.String() != "plain" {
+		t.Fatalf("Transform(false) wrote %q, %v", out.String(), err)
+	}
+
+	type key struct{}
+	p.ProcessContext = func(ctx context.Context, _ io.Reader, w io.Writer, _ *flag.FlagSet) error {
+		_, err := io.WriteString(w, ctx.Value(key{}).(string))
+		return err
+	}
+	out.Reset()
+	ctx := context.WithValue(context.Background(), key{}, "ctx")
+	if err := p.Transform(false)(ctx, strings.NewReader(""), &out, nil); err != nil || out.String() != "ctx" {
+		t.Fatalf("Transform(false) wrote %q, %v, want the context-aware transform", out.String(), err)
+	}
+}